@@ -0,0 +1,21 @@
+-- 跟踪止盈
+ALTER TABLE symbols ADD trailing_stop INTEGER DEFAULT (0);
+ALTER TABLE symbols ADD trailing_activation REAL DEFAULT (0);
+ALTER TABLE symbols ADD trailing_callback REAL DEFAULT (0);
+ALTER TABLE strategy_templates ADD trailing_stop INTEGER DEFAULT (0);
+ALTER TABLE strategy_templates ADD trailing_activation REAL DEFAULT (0);
+ALTER TABLE strategy_templates ADD trailing_callback REAL DEFAULT (0);
+
+-- 持仓最高收益率表
+CREATE TABLE IF NOT EXISTS futures_position_peaks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol VARCHAR(50) NOT NULL,
+    side VARCHAR(10) NOT NULL,
+    entry_price VARCHAR(50) NOT NULL DEFAULT '0',
+    peak_roi REAL NOT NULL DEFAULT 0,
+    peak_price REAL NOT NULL DEFAULT 0,
+    create_time INTEGER NOT NULL DEFAULT 0,
+    update_time INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_futures_position_peaks_unique ON futures_position_peaks(symbol, side);
@@ -0,0 +1,6 @@
+-- 分批止盈
+ALTER TABLE symbols ADD take_profit_ladder VARCHAR(1000) DEFAULT ('');
+ALTER TABLE strategy_templates ADD take_profit_ladder VARCHAR(1000) DEFAULT ('');
+ALTER TABLE futures_position_peaks ADD amount VARCHAR(50) DEFAULT ('0');
+ALTER TABLE futures_position_peaks ADD ladder_step INTEGER DEFAULT (0);
+ALTER TABLE futures_position_peaks ADD realized_profit REAL DEFAULT (0);
@@ -0,0 +1,7 @@
+-- 开仓金额计算方式
+ALTER TABLE config ADD future_sizing_mode VARCHAR(50) DEFAULT ('fixed_usdt');
+ALTER TABLE config ADD future_sizing_value REAL DEFAULT (0);
+ALTER TABLE symbols ADD sizing_mode VARCHAR(50) DEFAULT ('global');
+ALTER TABLE symbols ADD sizing_value REAL DEFAULT (0);
+ALTER TABLE `order` ADD sizing_mode VARCHAR(50) DEFAULT ('');
+ALTER TABLE `order` ADD sizing_detail VARCHAR(255) DEFAULT ('');
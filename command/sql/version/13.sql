@@ -0,0 +1,11 @@
+-- 加仓(DCA)
+ALTER TABLE symbols ADD safety_order_step REAL DEFAULT (0);
+ALTER TABLE symbols ADD safety_order_multiplier REAL DEFAULT (1);
+ALTER TABLE symbols ADD safety_order_max_count INTEGER DEFAULT (0);
+ALTER TABLE strategy_templates ADD safety_order_step REAL DEFAULT (0);
+ALTER TABLE strategy_templates ADD safety_order_multiplier REAL DEFAULT (1);
+ALTER TABLE strategy_templates ADD safety_order_max_count INTEGER DEFAULT (0);
+ALTER TABLE `order` ADD parent_id INTEGER DEFAULT (0);
+ALTER TABLE futures_position_peaks ADD base_amount VARCHAR(50) DEFAULT ('0');
+ALTER TABLE futures_position_peaks ADD safety_count INTEGER DEFAULT (0);
+ALTER TABLE futures_position_peaks ADD parent_order_id INTEGER DEFAULT (0);
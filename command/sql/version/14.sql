@@ -0,0 +1,39 @@
+-- 合约网格
+CREATE TABLE IF NOT EXISTS futures_grids (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol VARCHAR(50) NOT NULL,
+    mode VARCHAR(10) NOT NULL DEFAULT 'neutral',
+    lower_price REAL NOT NULL DEFAULT 0,
+    upper_price REAL NOT NULL DEFAULT 0,
+    grid_count INTEGER NOT NULL DEFAULT 0,
+    investment REAL NOT NULL DEFAULT 0,
+    leverage INTEGER NOT NULL DEFAULT 1,
+    quantity REAL NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'stopped',
+    realized_profit REAL NOT NULL DEFAULT 0,
+    filled_count INTEGER NOT NULL DEFAULT 0,
+    create_time INTEGER NOT NULL DEFAULT 0,
+    update_time INTEGER NOT NULL DEFAULT 0
+);
+
+-- 合约网格挂单
+CREATE TABLE IF NOT EXISTS futures_grid_orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    grid_id INTEGER NOT NULL,
+    symbol VARCHAR(50) NOT NULL,
+    level INTEGER NOT NULL DEFAULT 0,
+    kind VARCHAR(10) NOT NULL,
+    side VARCHAR(10) NOT NULL,
+    position_side VARCHAR(10) NOT NULL,
+    price REAL NOT NULL DEFAULT 0,
+    open_price REAL NOT NULL DEFAULT 0,
+    quantity REAL NOT NULL DEFAULT 0,
+    order_id INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'NEW',
+    profit REAL NOT NULL DEFAULT 0,
+    create_time INTEGER NOT NULL DEFAULT 0,
+    update_time INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_futures_grid_orders_grid ON futures_grid_orders(grid_id, status);
+CREATE INDEX IF NOT EXISTS idx_futures_grid_orders_order ON futures_grid_orders(order_id);
@@ -0,0 +1,2 @@
+-- 基准币种和权重
+ALTER TABLE config ADD future_benchmark_basket VARCHAR(500) DEFAULT ('');
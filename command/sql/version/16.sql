@@ -0,0 +1,18 @@
+-- 持仓期间的统计
+ALTER TABLE futures_position_peaks ADD max_roi REAL DEFAULT (0);
+ALTER TABLE futures_position_peaks ADD min_roi REAL DEFAULT (0);
+ALTER TABLE futures_position_peaks ADD profit_time INTEGER DEFAULT (0);
+ALTER TABLE futures_position_peaks ADD check_time INTEGER DEFAULT (0);
+
+-- 自定义平仓策略的持仓状态
+CREATE TABLE IF NOT EXISTS futures_position_states (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol VARCHAR(50) NOT NULL,
+    side VARCHAR(10) NOT NULL,
+    state_key VARCHAR(100) NOT NULL,
+    value VARCHAR(1000) NOT NULL DEFAULT '',
+    create_time INTEGER NOT NULL DEFAULT 0,
+    update_time INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_futures_position_states_key ON futures_position_states(symbol, side, state_key);
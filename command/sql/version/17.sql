@@ -0,0 +1,42 @@
+-- 策略模板版本
+ALTER TABLE strategy_templates ADD version INTEGER DEFAULT (0);
+ALTER TABLE symbols ADD template_id INTEGER DEFAULT (0);
+ALTER TABLE symbols ADD template_version INTEGER DEFAULT (0);
+
+CREATE TABLE IF NOT EXISTS strategy_template_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0,
+    technology TEXT NOT NULL DEFAULT '',
+    strategy TEXT NOT NULL DEFAULT '',
+    trailing_stop INTEGER NOT NULL DEFAULT 0,
+    trailing_activation REAL NOT NULL DEFAULT 0,
+    trailing_callback REAL NOT NULL DEFAULT 0,
+    take_profit_ladder VARCHAR(1000) NOT NULL DEFAULT '',
+    safety_order_step REAL NOT NULL DEFAULT 0,
+    safety_order_multiplier REAL NOT NULL DEFAULT 1,
+    safety_order_max_count INTEGER NOT NULL DEFAULT 0,
+    comment VARCHAR(255) NOT NULL DEFAULT '',
+    create_time INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_strategy_template_versions_unique ON strategy_template_versions(template_id, version);
+
+-- 策略模板版本的发布记录
+CREATE TABLE IF NOT EXISTS strategy_template_rollouts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(20) NOT NULL DEFAULT 'rollout',
+    rollback_id INTEGER NOT NULL DEFAULT 0,
+    symbols TEXT NOT NULL DEFAULT '',
+    snapshot TEXT NOT NULL DEFAULT '',
+    comment VARCHAR(255) NOT NULL DEFAULT '',
+    create_time INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_strategy_template_rollouts_template ON strategy_template_rollouts(template_id);
+
+-- 已有的模板保存为第一个版本
+INSERT INTO strategy_template_versions (template_id, version, technology, strategy, trailing_stop, trailing_activation, trailing_callback, take_profit_ladder, safety_order_step, safety_order_multiplier, safety_order_max_count, comment, create_time) SELECT id, 1, IFNULL(technology, ''), IFNULL(strategy, ''), IFNULL(trailing_stop, 0), IFNULL(trailing_activation, 0), IFNULL(trailing_callback, 0), IFNULL(take_profit_ladder, ''), IFNULL(safety_order_step, 0), IFNULL(safety_order_multiplier, 1), IFNULL(safety_order_max_count, 0), 'init', IFNULL(updateTime, 0) FROM strategy_templates;
+UPDATE strategy_templates SET version = 1;
@@ -0,0 +1,7 @@
+-- 组合风控
+ALTER TABLE config ADD risk_max_notional REAL DEFAULT (0);
+ALTER TABLE config ADD risk_max_margin_percent REAL DEFAULT (0);
+ALTER TABLE config ADD risk_max_symbol_notional REAL DEFAULT (0);
+ALTER TABLE config ADD risk_max_net_notional REAL DEFAULT (0);
+ALTER TABLE config ADD risk_max_correlated INTEGER DEFAULT (0);
+ALTER TABLE config ADD risk_correlation REAL DEFAULT (0.8);
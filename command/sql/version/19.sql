@@ -0,0 +1,31 @@
+-- 账户熔断
+ALTER TABLE config ADD breaker_daily_loss REAL DEFAULT (0);
+ALTER TABLE config ADD breaker_weekly_loss REAL DEFAULT (0);
+ALTER TABLE config ADD breaker_max_drawdown REAL DEFAULT (0);
+ALTER TABLE config ADD breaker_flatten INTEGER DEFAULT (0);
+
+CREATE TABLE IF NOT EXISTS futures_circuit_breaker (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tripped INTEGER NOT NULL DEFAULT 0,
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    trip_time INTEGER NOT NULL DEFAULT 0,
+    rearm_time INTEGER NOT NULL DEFAULT 0,
+    day_pnl REAL NOT NULL DEFAULT 0,
+    week_pnl REAL NOT NULL DEFAULT 0,
+    pnl_source VARCHAR(20) NOT NULL DEFAULT '',
+    equity REAL NOT NULL DEFAULT 0,
+    high_water REAL NOT NULL DEFAULT 0,
+    drawdown REAL NOT NULL DEFAULT 0,
+    check_time INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS futures_circuit_breaker_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    action VARCHAR(20) NOT NULL DEFAULT '',
+    reason VARCHAR(500) NOT NULL DEFAULT '',
+    day_pnl REAL NOT NULL DEFAULT 0,
+    week_pnl REAL NOT NULL DEFAULT 0,
+    equity REAL NOT NULL DEFAULT 0,
+    drawdown REAL NOT NULL DEFAULT 0,
+    create_time INTEGER NOT NULL DEFAULT 0
+);
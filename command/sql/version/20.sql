@@ -0,0 +1,34 @@
+-- 策略冻结规则: 窗口内累计亏损, 最近 N 笔亏损次数, 重复冻结升级
+ALTER TABLE strategy_freeze ADD loss_amount REAL NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD loss_window_hours INTEGER NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD recent_trades INTEGER NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD recent_loss_count INTEGER NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD escalate_factor REAL NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD escalate_window_hours INTEGER NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD max_freeze_hours INTEGER NOT NULL DEFAULT (0);
+ALTER TABLE strategy_freeze ADD last_freeze_at INTEGER NOT NULL DEFAULT (0);
+
+CREATE TABLE IF NOT EXISTS strategy_freeze_trades (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol VARCHAR(50) NOT NULL,
+    strategy_name VARCHAR(100) NOT NULL,
+    trade_type VARCHAR(10) NOT NULL,
+    profit REAL NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_strategy_freeze_trades_strategy ON strategy_freeze_trades(strategy_name, trade_type, created_at);
+
+CREATE TABLE IF NOT EXISTS strategy_freeze_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    freeze_id INTEGER NOT NULL DEFAULT 0,
+    symbol VARCHAR(50) NOT NULL,
+    strategy_name VARCHAR(100) NOT NULL,
+    trade_type VARCHAR(10) NOT NULL,
+    action VARCHAR(20) NOT NULL DEFAULT '',
+    rule VARCHAR(20) NOT NULL DEFAULT '',
+    detail VARCHAR(500) NOT NULL DEFAULT '',
+    level INTEGER NOT NULL DEFAULT 0,
+    freeze_hours REAL NOT NULL DEFAULT 0,
+    freeze_until INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL
+);
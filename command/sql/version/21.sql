@@ -0,0 +1,15 @@
+-- 本地持仓/挂单和交易所的对账记录
+CREATE TABLE IF NOT EXISTS futures_reconcile_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind VARCHAR(20) NOT NULL DEFAULT '',
+    diff VARCHAR(20) NOT NULL DEFAULT '',
+    symbol VARCHAR(50) NOT NULL DEFAULT '',
+    side VARCHAR(10) NOT NULL DEFAULT '',
+    order_id VARCHAR(50) NOT NULL DEFAULT '',
+    field VARCHAR(50) NOT NULL DEFAULT '',
+    local_value VARCHAR(100) NOT NULL DEFAULT '',
+    remote_value VARCHAR(100) NOT NULL DEFAULT '',
+    healed INTEGER NOT NULL DEFAULT 0,
+    create_time INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_futures_reconcile_logs_time ON futures_reconcile_logs(create_time);
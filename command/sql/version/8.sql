@@ -0,0 +1,17 @@
+-- 合约k线表
+CREATE TABLE IF NOT EXISTS futures_klines (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    symbol VARCHAR(50) NOT NULL,
+    kline_interval VARCHAR(10) NOT NULL,
+    open_time INTEGER NOT NULL,
+    close_time INTEGER NOT NULL,
+    open VARCHAR(50) NOT NULL DEFAULT '0',
+    high VARCHAR(50) NOT NULL DEFAULT '0',
+    low VARCHAR(50) NOT NULL DEFAULT '0',
+    close VARCHAR(50) NOT NULL DEFAULT '0',
+    volume VARCHAR(50) NOT NULL DEFAULT '0',
+    quote_asset_volume VARCHAR(50) NOT NULL DEFAULT '0',
+    trade_num INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_futures_klines_unique ON futures_klines(symbol, kline_interval, open_time);
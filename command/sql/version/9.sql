@@ -0,0 +1,2 @@
+-- 交易所止盈止损条件单开关
+ALTER TABLE config ADD future_protective_order INTEGER DEFAULT (0);
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"encoding/json"
+	"go_binance_futures/feature/backtest"
+	"go_binance_futures/utils"
+	"strconv"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type BacktestController struct {
+	web.Controller
+}
+
+// 回测交易策略, 参数见 backtest.Params, 时间范围和币种数量由 backtest::max_days / max_symbols 限制
+func (ctrl *BacktestController) Post() {
+	var params backtest.Params
+	err := json.Unmarshal(ctrl.Ctx.Input.RequestBody, &params)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	
+	result, err := backtest.RunLimited(params)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": result,
+		"msg": "success",
+	})
+}
+
+type OptimizeSaveParams struct {
+	Candidate *int `json:"candidate"` // 参数组合的 index, 为空时保存排名第一的
+	Comment string `json:"comment"`
+}
+
+// 开始参数优化任务(后台运行), 参数见 backtest.OptimizeParams
+func (ctrl *BacktestController) Optimize() {
+	var params backtest.OptimizeParams
+	err := json.Unmarshal(ctrl.Ctx.Input.RequestBody, &params)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	job, err := backtest.StartOptimize(params)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": job,
+		"msg": "success",
+	})
+}
+
+// 优化任务列表
+func (ctrl *BacktestController) OptimizeJobs() {
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": backtest.GetOptimizeJobs(),
+		"msg": "success",
+	})
+}
+
+// 优化任务的进度和结果
+func (ctrl *BacktestController) OptimizeJob() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	job, err := backtest.GetOptimizeJob(id)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": job,
+		"msg": "success",
+	})
+}
+
+func (ctrl *BacktestController) CancelOptimize() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	err := backtest.CancelOptimize(id)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(utils.ResJson(200, nil))
+}
+
+// 把优化的参数保存为模板的新版本, 需要发布后币种才会更新
+func (ctrl *BacktestController) SaveOptimize() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	var params OptimizeSaveParams
+	if err := json.Unmarshal(ctrl.Ctx.Input.RequestBody, &params); err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	index := -1
+	if params.Candidate != nil {
+		index = *params.Candidate
+	}
+	version, err := backtest.SaveOptimizeResult(id, index, params.Comment)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": version,
+		"msg": "success",
+	})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/utils"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type BundleController struct {
+	web.Controller
+}
+
+// 导出配置包 ?format=json|yaml&sections=symbols,strategy_templates&symbols=BTCUSDT,ETHUSDT
+func (ctrl *BundleController) Export() {
+	format := ctrl.GetString("format", "json")
+	bundle, err := feature.ExportConfigBundle(splitParam(ctrl.GetString("sections")), splitParam(ctrl.GetString("symbols")))
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	data, err := feature.EncodeConfigBundle(bundle, format)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	if format == "yaml" {
+		ctrl.Ctx.Output.Header("Content-Type", "application/x-yaml; charset=utf-8")
+	} else {
+		format = "json"
+		ctrl.Ctx.Output.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	ctrl.Ctx.Output.Header("Content-Disposition", "attachment; filename=bundle_" + time.Now().Format("20060102150405") + "." + format)
+	ctrl.Ctx.Output.Body(data)
+}
+
+// 导入配置包(请求体为 json 或者 yaml) ?mode=preview|merge|overwrite, 默认 preview 只返回冲突不写入
+func (ctrl *BundleController) Import() {
+	bundle, err := feature.DecodeConfigBundle(ctrl.Ctx.Input.RequestBody)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	report, err := feature.ImportConfigBundle(bundle, ctrl.GetString("mode", feature.BundleImportPreview))
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	if len(report.Errors) > 0 {
+		// 检查或者写入失败, 同时返回冲突内容
+		ctrl.Ctx.Resp(map[string]interface{} {
+			"code": 400,
+			"data": report,
+			"msg": strings.Join(report.Errors, "; "),
+		})
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": report,
+		"msg": "success",
+	})
+}
+
+func splitParam(value string) []string {
+	params := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			params = append(params, item)
+		}
+	}
+	return params
+}
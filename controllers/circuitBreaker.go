@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/utils"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type CircuitBreakerController struct {
+	web.Controller
+}
+
+type CircuitBreakerParams struct {
+	Reason string `json:"reason"`
+	Flatten bool `json:"flatten"` // 熔断时平掉所有仓位
+	Comment string `json:"comment"` // 恢复的备注
+}
+
+// 熔断状态, 阈值和最近的熔断记录
+func (ctrl *CircuitBreakerController) Get() {
+	systemConfig, _ := utils.GetSystemConfig()
+	events, err := feature.GetCircuitBreakerEvents(50)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": map[string]interface{} {
+			"state": feature.GetCircuitBreaker(),
+			"limits": feature.GetBreakerLimits(systemConfig),
+			"flatten": systemConfig.BreakerFlatten,
+			"events": events,
+		},
+		"msg": "success",
+	})
+}
+
+// 手动熔断(停止开仓)
+func (ctrl *CircuitBreakerController) Trip() {
+	params := CircuitBreakerParams{}
+	ctrl.BindJSON(&params)
+	if params.Reason == "" {
+		params.Reason = "manual"
+	}
+	systemConfig, _ := utils.GetSystemConfig()
+	err := feature.TripCircuitBreaker(systemConfig, params.Reason, params.Flatten)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": feature.GetCircuitBreaker(),
+		"msg": "success",
+	})
+}
+
+// 手动恢复开仓
+func (ctrl *CircuitBreakerController) Rearm() {
+	params := CircuitBreakerParams{}
+	ctrl.BindJSON(&params)
+	state, err := feature.RearmCircuitBreaker(params.Comment)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": state,
+		"msg": "success",
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"go_binance_futures/feature"
 	fu "go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 	spot "go_binance_futures/spot/api/binance"
@@ -9,7 +10,6 @@ import (
 	"sync"
 	"time"
 
-	"github.com/adshao/go-binance/v2/futures"
 	"github.com/beego/beego/v2/client/orm"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
@@ -119,6 +119,7 @@ func (ctrl *EatRateController) Start() {
 	o := orm.NewOrm()
 	o.QueryTable("eat_rate_symbols").Filter("Id", id).One(&symbols)
 	
+	systemConfig, _ := utils.GetSystemConfig()
 	totalAmountFloat, _ := strconv.ParseFloat(symbols.TotalAmount, 64)
 	
 	futuresAmount := totalAmountFloat / float64(symbols.Leverage) // 合约
@@ -139,6 +140,21 @@ func (ctrl *EatRateController) Start() {
 	futuresQuantity := futuresAmount / futuresPriceFloat * float64(symbols.Leverage)  // 做空数量
 	futuresQuantity = utils.GetTradePrecision(futuresQuantity, symbols.StepSize) // 合理数量精度的价格
 	
+	shortIntent := feature.OrderIntent{
+		Action: feature.OrderActionOpen,
+		Symbol: symbols.FuturesSymbol,
+		PositionSide: "SHORT",
+		Quantity: futuresQuantity,
+		Price: futuresPriceFloat,
+		Leverage: symbols.Leverage,
+		SkipRiskCheck: true,
+	}
+	// 现货和合约同时下单, 先检查合约的风控, 避免现货买入后合约被拒绝没有对冲
+	if err := feature.CheckOpenRisk(systemConfig, shortIntent); err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	
 	var spotOrderId int64
 	var futuresOrderId int64
 	var wg sync.WaitGroup
@@ -158,7 +174,7 @@ func (ctrl *EatRateController) Start() {
 	go func() {
 		defer wg.Done()
 		// 做空合约
-		res, err := fu.SellMarket(symbols.FuturesSymbol, futuresQuantity, futures.PositionSideTypeShort)
+		res, err := feature.NewOrderExecutor(systemConfig).Execute(shortIntent)
 		if err != nil {
 			logs.Error("futures sell short fail, symbol:", symbols.FuturesSymbol)
 			logs.Error("err:", err.Error())
@@ -217,6 +233,7 @@ func (ctrl *EatRateController) End() {
 	var symbols models.EatRateSymbols
 	o := orm.NewOrm()
 	o.QueryTable("eat_rate_symbols").Filter("Id", id).One(&symbols)
+	systemConfig, _ := utils.GetSystemConfig()
 	
 	var spotOrderId int64
 	var futuresOrderId int64
@@ -237,7 +254,20 @@ func (ctrl *EatRateController) End() {
 	go func() {
 		defer wg.Done()
 		// 合约平仓
-		res, err := fu.BuyMarket(symbols.FuturesSymbol, symbols.FuturesQuantity, futures.PositionSideTypeShort)
+		futuresPriceFloat, _ := getFuturesPrice(symbols.FuturesSymbol)
+		openPriceFloat, _ := strconv.ParseFloat(symbols.FuturesPrice, 64)
+		if openPriceFloat == 0 {
+			openPriceFloat = futuresPriceFloat
+		}
+		res, err := feature.NewOrderExecutor(systemConfig).Execute(feature.OrderIntent{
+			Action: feature.OrderActionClose,
+			Symbol: symbols.FuturesSymbol,
+			PositionSide: "SHORT",
+			Quantity: symbols.FuturesQuantity,
+			Price: futuresPriceFloat,
+			Leverage: symbols.Leverage,
+			Profit: (openPriceFloat - futuresPriceFloat) * symbols.FuturesQuantity, // 空单收益
+		})
 		if err != nil {
 			logs.Error("futures sell short fail, symbol:", symbols.FuturesSymbol)
 			logs.Error("err:", err.Error())
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"go_binance_futures/feature"
 	"go_binance_futures/feature/strategy/line"
@@ -115,21 +116,44 @@ func (ctrl *FeatureController) Edit() {
 		feature.UpdateSymbolsTradePrecision() // 更新合约交易精度
 	}()
 	
+	oldSymbol := symbols.Symbol
+	oldSymbols := symbols
 	ctrl.BindJSON(&symbols)
 	
-	_, err := o.Update(&symbols) // _ 是受影响的条数
+	detached, err := feature.SyncSymbolTemplate(oldSymbols, &symbols) // 模板版本
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	err = validateSymbolStrategy(&symbols)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	_, err = o.Update(&symbols) // _ 是受影响的条数
     if err != nil {
         // 处理错误
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
 		return
     }
+	line.InvalidateProgramCache(oldSymbol)
+	line.InvalidateProgramCache(symbols.Symbol)
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"data": symbols,
+		"detached": detached, // 手动修改策略内容后不再关联模板
 		"msg": "success",
 	})
 }
 
+// 自定义策略保存前编译检查, 返回编译错误
+func validateSymbolStrategy(symbols *models.Symbols) error {
+	if symbols.StrategyType != "custom" || symbols.Strategy == "" {
+		return nil
+	}
+	return line.ValidateStrategy(symbols.Symbol, symbols.Technology, symbols.Strategy)
+}
+
 func (ctrl *FeatureController) Delete() {
 	id := ctrl.Ctx.Input.Param(":id")
 	symbols := new(models.Symbols)
@@ -143,6 +167,7 @@ func (ctrl *FeatureController) Delete() {
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
 		return
     }
+	line.InvalidateProgramCache("")
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"msg": "success",
@@ -171,6 +196,16 @@ func (ctrl *FeatureController) Post() {
 	symbols.CloseQty = 0.0
 	symbols.TradeCount = 0.0
 	
+	_, err := feature.SyncSymbolTemplate(models.Symbols{}, symbols) // 模板版本
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	err = validateSymbolStrategy(symbols)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
 	
 	o := orm.NewOrm()
 	id, err := o.Insert(symbols)
@@ -240,10 +275,25 @@ func (ctrl *FeatureController) BatchEdit() {
 	if (params.StrategyTemplateId != 0) {
 		var template models.StrategyTemplates
 		orm.NewOrm().QueryTable("strategy_templates").Filter("Id", params.StrategyTemplateId).One(&template)
+		version, err := feature.GetTemplateVersion(template.ID, 0) // 使用模板最新的版本
+		if err != nil {
+			ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+			return
+		}
+		// 复制关联版本的内容到币种, 和 SyncSymbolTemplate 一致
+		content := feature.VersionContentOf(version)
+		query += " template_id = ?, template_version = ?,"
+		bindData = append(bindData, template.ID, version.Version)
 		query += " technology = ?,"
-		bindData = append(bindData, template.Technology)
+		bindData = append(bindData, content.Technology)
 		query += " strategy = ?,"
-		bindData = append(bindData, template.Strategy)
+		bindData = append(bindData, content.Strategy)
+		query += " trailing_stop = ?, trailing_activation = ?, trailing_callback = ?,"
+		bindData = append(bindData, content.TrailingStop, content.TrailingActivation, content.TrailingCallback)
+		query += " take_profit_ladder = ?,"
+		bindData = append(bindData, content.TakeProfitLadder)
+		query += " safety_order_step = ?, safety_order_multiplier = ?, safety_order_max_count = ?,"
+		bindData = append(bindData, content.SafetyOrderStep, content.SafetyOrderMultiplier, content.SafetyOrderMaxCount)
 	}
 	
 	if strings.HasSuffix(query, ",") {
@@ -255,6 +305,7 @@ func (ctrl *FeatureController) BatchEdit() {
 			ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
 			return
 		}
+		line.InvalidateProgramCache("")
 	}
 	
 	// go func() {
@@ -331,7 +382,7 @@ func (ctrl *FeatureController) TestStrategyRule() {
 	
 	for _, strategy := range strategyConfig {
 		if strategy.Enable {
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := expr.Compile(strategy.Code, line.StrategyOptions(env)...)
 			if err != nil {
 				logs.Error("Error Strategy Compile:", err.Error())
 				ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
@@ -355,6 +406,129 @@ func (ctrl *FeatureController) TestStrategyRule() {
 	}
 }
 
+// 策略检查的模拟仓位, 不传时平仓策略使用当前真实的持仓(没有持仓时收益率为 0)
+type StrategyMockParams struct {
+	Roi float64 `json:"roi"` // 收益率
+	PeakRoi float64 `json:"peak_roi"` // 持仓期间的最高收益率
+	PeakPrice float64 `json:"peak_price"` // 最高收益率时的价格
+	Position types.FuturesPositionCode `json:"position"` // 仓位, side 为空时根据策略类型设置
+	Stats types.PositionStats `json:"stats"` // 持仓期间的统计
+	State map[string]interface{} `json:"state"` // 持仓状态(Get/Set)的初始值
+}
+
+type ValidateStrategyParams struct {
+	Mock *StrategyMockParams `json:"mock"`
+}
+
+// 检查币种的所有策略, 返回编译错误、运行结果和每个子表达式的值
+func (ctrl *FeatureController) ValidateStrategyRule() {
+	id := ctrl.Ctx.Input.Param(":id")
+	var symbols models.Symbols
+	o := orm.NewOrm()
+	o.QueryTable("symbols").Filter("Id", id).One(&symbols)
+	
+	ctrl.BindJSON(&symbols) // 可以传入未保存的 technology 和 strategy
+	var params ValidateStrategyParams
+	ctrl.BindJSON(&params)
+	
+	var strategyConfig technology.StrategyConfig
+	err := json.Unmarshal([]byte(symbols.Strategy), &strategyConfig)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	_, err = line.ParseIndicatorConfigs(symbols.Technology)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	env := line.InitParseEnv(symbols.Symbol, symbols.Technology)
+	
+	// 当前真实的持仓
+	realPositions := map[string]types.FuturesPositionCode{}
+	realRoi := map[string]float64{}
+	if params.Mock == nil {
+		positions, _ := feature.GetTransformPositions()
+		for _, position := range positions {
+			positionAmtFloat, _ := strconv.ParseFloat(position.Amount, 64)
+			positionAmtFloatAbs := math.Abs(positionAmtFloat)
+			if position.Symbol != symbols.Symbol || positionAmtFloatAbs < 0.0000000001 {
+				continue
+			}
+			unRealizedProfit, _ := strconv.ParseFloat(position.UnrealizedProfit, 64)
+			entryPrice_float64, _ := strconv.ParseFloat(position.EntryPrice, 64)
+			markPrice_float64, _ := strconv.ParseFloat(position.MarkPrice, 64)
+			realRoi[position.Side] = (unRealizedProfit / (positionAmtFloatAbs * markPrice_float64)) * float64(position.Leverage) * 100
+			realPositions[position.Side] = types.FuturesPositionCode{
+				Symbol: symbols.Symbol,
+				EntryPrice: entryPrice_float64,
+				MarkPrice: markPrice_float64,
+				Amount: positionAmtFloat,
+				UnrealizedProfit: unRealizedProfit,
+				Leverage: position.Leverage,
+				Side: position.Side,
+				Mock: false,
+				CreateTime: position.CreateTime,
+				SourceType: position.SourceType,
+			}
+		}
+	}
+	
+	results := []line.StrategyCheckResult{}
+	for _, strategy := range strategyConfig {
+		ruleEnv := env
+		if strategy.Type == "close_long" || strategy.Type == "close_short" {
+			side := "LONG"
+			if strategy.Type == "close_short" {
+				side = "SHORT"
+			}
+			ruleEnv = make(map[string]interface{}, len(env) + 2)
+			for k, v := range env {
+				ruleEnv[k] = v
+			}
+			roi, peakRoi, peakPrice := 0.0, 0.0, 0.0
+			position := types.FuturesPositionCode{Symbol: symbols.Symbol, Side: side, Mock: true}
+			stats := types.PositionStats{}
+			stateValues := map[string]interface{}{}
+			if params.Mock != nil {
+				roi, peakRoi, peakPrice = params.Mock.Roi, params.Mock.PeakRoi, params.Mock.PeakPrice
+				stats, stateValues = params.Mock.Stats, params.Mock.State
+				position = params.Mock.Position
+				position.Symbol = symbols.Symbol
+				position.Mock = true
+				if position.Side == "" {
+					position.Side = side
+				}
+			} else if realPosition, ok := realPositions[side]; ok {
+				roi = realRoi[side]
+				position = realPosition
+				var peak models.FuturesPositionPeak
+				if o.QueryTable("futures_position_peaks").Filter("symbol", symbols.Symbol).Filter("side", side).One(&peak) == nil {
+					peakRoi, peakPrice = peak.PeakRoi, peak.PeakPrice
+					stats = feature.GetPositionStats(peak, time.Now().UnixMilli())
+				}
+				stateValues = line.GetPositionState(symbols.Symbol, side).Values()
+			}
+			// 调试时 Set 只修改内存中的副本
+			state := line.NewMemoryPositionState(symbols.Symbol, position.Side, stateValues)
+			ruleEnv["ROI"] = roi
+			ruleEnv["PeakROI"] = peakRoi
+			ruleEnv["PeakPrice"] = peakPrice
+			ruleEnv["Position"] = position
+			ruleEnv["Stats"] = stats
+			ruleEnv["Get"] = state.Get
+			ruleEnv["Set"] = state.Set
+		}
+		results = append(results, line.CheckStrategyRule(symbols.Symbol, symbols.Technology, strategy.Name, strategy.Type, strategy.Code, strategy.Enable, ruleEnv))
+	}
+	
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": results,
+		"msg": "success",
+	})
+}
+
 func (ctrl *FeatureController) GetOptions() {
 	o := orm.NewOrm()
 	var symbols []models.Symbols
@@ -372,6 +546,7 @@ func (ctrl *FeatureController) GetOptions() {
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"data": symbolsArr,
+		"indicators": line.GetIndicatorOptions(), // 技术指标类型和参数
 		"msg": "success",
 	})
 }
\ No newline at end of file
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/server/web"
+)
+
+type GridController struct {
+	web.Controller
+}
+
+func (ctrl *GridController) Get() {
+	symbol := ctrl.GetString("symbol", "")
+	
+	o := orm.NewOrm()
+	var grids []models.FuturesGrid
+	query := o.QueryTable("futures_grids")
+	if symbol != "" {
+		query = query.Filter("Symbol__icontains", symbol)
+	}
+	_, err := query.OrderBy("-ID").All(&grids)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": grids,
+		"msg": "success",
+	})
+}
+
+func (ctrl *GridController) Post() {
+	grid := new(models.FuturesGrid)
+	ctrl.BindJSON(&grid)
+	
+	grid.Symbol = strings.ToUpper(grid.Symbol)
+	grid.Status = feature.GridStatusStopped // 创建后需要手动启动
+	grid.RealizedProfit = 0
+	grid.FilledCount = 0
+	grid.CreateTime = time.Now().UnixMilli()
+	grid.UpdateTime = time.Now().UnixMilli()
+	o := orm.NewOrm()
+	id, err := o.Insert(grid)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	grid.ID = id
+	
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": grid,
+		"msg": "success",
+	})
+}
+
+func (ctrl *GridController) Edit() {
+	id := ctrl.Ctx.Input.Param(":id")
+	var grid models.FuturesGrid
+	o := orm.NewOrm()
+	err := o.QueryTable("futures_grids").Filter("Id", id).One(&grid)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	if grid.Status == feature.GridStatusRunning {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, "please stop the grid first"))
+		return
+	}
+	
+	ctrl.BindJSON(&grid)
+	
+	grid.Symbol = strings.ToUpper(grid.Symbol)
+	grid.Status = feature.GridStatusStopped // 只能通过 start 接口启动
+	grid.UpdateTime = time.Now().UnixMilli()
+	_, err = o.Update(&grid)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": grid,
+		"msg": "success",
+	})
+}
+
+func (ctrl *GridController) Delete() {
+	id := ctrl.Ctx.Input.Param(":id")
+	intId, _ := strconv.ParseInt(id, 10, 64)
+	// 删除前撤销网格的挂单
+	_, err := feature.StopGrid(intId)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	o := orm.NewOrm()
+	_, err = o.Delete(&models.FuturesGrid{ID: intId})
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	o.QueryTable("futures_grid_orders").Filter("grid_id", intId).Delete()
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"msg": "success",
+	})
+}
+
+// 启动网格
+func (ctrl *GridController) Start() {
+	id := ctrl.Ctx.Input.Param(":id")
+	intId, _ := strconv.ParseInt(id, 10, 64)
+	grid, err := feature.StartGrid(intId)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": grid,
+		"msg": "success",
+	})
+}
+
+// 停止网格, 撤销挂单, 保留已有的仓位
+func (ctrl *GridController) Stop() {
+	id := ctrl.Ctx.Input.Param(":id")
+	intId, _ := strconv.ParseInt(id, 10, 64)
+	grid, err := feature.StopGrid(intId)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": grid,
+		"msg": "success",
+	})
+}
+
+// 网格的挂单和成交记录
+func (ctrl *GridController) Orders() {
+	id := ctrl.Ctx.Input.Param(":id")
+	status := ctrl.GetString("status", "")
+	
+	o := orm.NewOrm()
+	var orders []models.FuturesGridOrder
+	query := o.QueryTable("futures_grid_orders").Filter("grid_id", id)
+	if status != "" {
+		query = query.Filter("status", status)
+	}
+	_, err := query.OrderBy("-ID").Limit(500).All(&orders)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": orders,
+		"msg": "success",
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"go_binance_futures/feature/strategy/line"
 	"go_binance_futures/notify"
 	"go_binance_futures/utils"
 
@@ -79,6 +80,20 @@ func (ctrl *IndexController) GetServiceConfig() {
 			"listenFundingRate": listenFundingRate,
 			"lossMaxCount": lossMaxCount,
 			"lossAutoScale": systemConfig.LossAutoScale,
+			"futureProtectiveOrder": systemConfig.FutureProtectiveOrder,
+			"futureSizingMode": systemConfig.FutureSizingMode,
+			"futureSizingValue": systemConfig.FutureSizingValue,
+			"futureBenchmarkBasket": systemConfig.FutureBenchmarkBasket,
+			"riskMaxNotional": systemConfig.RiskMaxNotional,
+			"riskMaxMarginPercent": systemConfig.RiskMaxMarginPercent,
+			"riskMaxSymbolNotional": systemConfig.RiskMaxSymbolNotional,
+			"riskMaxNetNotional": systemConfig.RiskMaxNetNotional,
+			"riskMaxCorrelated": systemConfig.RiskMaxCorrelated,
+			"riskCorrelation": systemConfig.RiskCorrelation,
+			"breakerDailyLoss": systemConfig.BreakerDailyLoss,
+			"breakerWeeklyLoss": systemConfig.BreakerWeeklyLoss,
+			"breakerMaxDrawdown": systemConfig.BreakerMaxDrawdown,
+			"breakerFlatten": systemConfig.BreakerFlatten,
 			
 			"externalLinks": externalLinks,
 		},
@@ -90,6 +105,17 @@ func (ctrl *IndexController) EditServiceConfig() {
 	systemConfig, _ := utils.GetSystemConfig()
 	
 	ctrl.BindJSON(&systemConfig)
+	if systemConfig.FutureBenchmarkBasket != "" {
+		if _, err := line.ParseBenchmarkBasket(systemConfig.FutureBenchmarkBasket); err != nil {
+			ctrl.Ctx.Resp(utils.ResJson(400, nil, "future_benchmark_basket error: " + err.Error()))
+			return
+		}
+	}
+	
+	if systemConfig.RiskCorrelation < 0 || systemConfig.RiskCorrelation > 1 {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, "risk_correlation must be between 0 and 1"))
+		return
+	}
 	
 	_, err := orm.NewOrm().Update(&systemConfig) // _ 是受影响的条数
     if err != nil {
@@ -97,6 +123,8 @@ func (ctrl *IndexController) EditServiceConfig() {
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, "edit failed"))
 		return
     }
+	line.SetBenchmarkBasket(systemConfig.FutureBenchmarkBasket)
+	line.InvalidateProgramCache("") // 编译的策略环境包含指数篮子
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"data": systemConfig,
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/utils"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type KlineController struct {
+	web.Controller
+}
+
+// 本地保存的合约k线(时间升序), 不完整时从币安获取
+func (ctrl *KlineController) Get() {
+	symbol := ctrl.GetString("symbol")
+	interval := ctrl.GetString("interval", "1h")
+	endTime, _ := ctrl.GetInt64("end_time", time.Now().UnixMilli())
+	startTime, _ := ctrl.GetInt64("start_time", endTime - (time.Hour * 24 * 7).Milliseconds())
+	if symbol == "" {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, "symbol is required"))
+		return
+	}
+	
+	klines, err := binance.GetHistoryKlineDataCached(symbol, interval, startTime, endTime)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": klines,
+		"msg": "success",
+	})
+}
@@ -56,6 +56,7 @@ func (ctrl *ListenCoinController) Edit() {
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, "修改失败"))
 		return
     }
+	line.InvalidateProgramCache(symbols.Symbol)
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"data": symbols,
@@ -76,6 +77,7 @@ func (ctrl *ListenCoinController) Delete() {
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, "删除错误"))
 		return
     }
+	line.InvalidateProgramCache("")
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"msg": "success",
@@ -239,7 +241,7 @@ func (ctrl *ListenCoinController) TestStrategyRule() {
 	env := line.InitParseEnv(symbols.Symbol, symbols.Technology)
 	for _, strategy := range strategyConfig {
 		if strategy.Enable {
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := expr.Compile(strategy.Code, line.StrategyOptions(env)...)
 			if err != nil {
 				logs.Error("Error Strategy Compile:", err.Error())
 				ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
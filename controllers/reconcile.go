@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/utils"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type ReconcileController struct {
+	web.Controller
+}
+
+// 对账状态和差异记录
+func (ctrl *ReconcileController) Get() {
+	page, _ := ctrl.GetInt("page", 1)
+	pageSize, _ := ctrl.GetInt("pageSize", 20)
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	records, total, err := feature.GetReconcileLogs(ctrl.GetString("kind"), ctrl.GetString("symbol"), page, pageSize)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": map[string]interface{} {
+			"status": feature.GetReconcileStatus(),
+			"list": records,
+			"total": total,
+		},
+		"msg": "success",
+	})
+}
+
+// 立即对账
+func (ctrl *ReconcileController) Post() {
+	diffs, err := feature.Reconcile()
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": diffs,
+		"msg": "success",
+	})
+}
@@ -104,6 +104,24 @@ func (c *StrategyFreezeController) Post() {
 		return
 	}
 	
+	if freeze.LossAmount < 0 || freeze.LossWindowHours < 0 || freeze.RecentTrades < 0 || freeze.RecentLossCount < 0 ||
+		freeze.EscalateFactor < 0 || freeze.EscalateWindowHours < 0 || freeze.MaxFreezeHours < 0 {
+		c.Data["json"] = map[string]interface{}{
+			"code":    400,
+			"message": "冻结规则不能为负数",
+		}
+		c.ServeJSON()
+		return
+	}
+	if freeze.RecentLossCount > freeze.RecentTrades {
+		c.Data["json"] = map[string]interface{}{
+			"code":    400,
+			"message": "recent_loss_count不能大于recent_trades",
+		}
+		c.ServeJSON()
+		return
+	}
+	
 	// 设置默认值
 	if freeze.FreezeOnLossCount <= 0 {
 		freeze.FreezeOnLossCount = 5
@@ -120,6 +138,7 @@ func (c *StrategyFreezeController) Post() {
 		// 更新现有配置
 		existingFreeze.FreezeOnLossCount = freeze.FreezeOnLossCount
 		existingFreeze.FreezeHours = freeze.FreezeHours
+		setFreezeRules(existingFreeze, freeze)
 		if freeze.LossCount >= 0 {
 			existingFreeze.LossCount = freeze.LossCount
 		}
@@ -161,6 +180,7 @@ func (c *StrategyFreezeController) Post() {
 		// 更新配置
 		newFreeze.FreezeOnLossCount = freeze.FreezeOnLossCount
 		newFreeze.FreezeHours = freeze.FreezeHours
+		setFreezeRules(newFreeze, freeze)
 		if freeze.LossCount >= 0 {
 			newFreeze.LossCount = freeze.LossCount
 		}
@@ -190,6 +210,55 @@ func (c *StrategyFreezeController) Post() {
 	c.ServeJSON()
 }
 
+// 冻结规则(累计亏损, 最近 N 笔亏损次数, 重复冻结升级)
+func setFreezeRules(dst *models.StrategyFreeze, src models.StrategyFreeze) {
+	dst.LossAmount = src.LossAmount
+	dst.LossWindowHours = src.LossWindowHours
+	dst.RecentTrades = src.RecentTrades
+	dst.RecentLossCount = src.RecentLossCount
+	dst.EscalateFactor = src.EscalateFactor
+	dst.EscalateWindowHours = src.EscalateWindowHours
+	dst.MaxFreezeHours = src.MaxFreezeHours
+}
+
+// 获取冻结历史(冻结和手动解冻)
+func (c *StrategyFreezeController) History() {
+	page, _ := c.GetInt("page", 1)
+	pageSize, _ := c.GetInt("pageSize", 20)
+	
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	
+	freezeService := utils.NewFreezeService()
+	histories, total, err := freezeService.GetFreezeHistory(c.GetString("symbol"), c.GetString("strategy_name"), c.GetString("trade_type"), page, pageSize)
+	if err != nil {
+		logs.Error("获取冻结历史失败:", err)
+		c.Data["json"] = map[string]interface{}{
+			"code":    500,
+			"message": "获取冻结历史失败",
+			"error":   err.Error(),
+		}
+		c.ServeJSON()
+		return
+	}
+	
+	c.Data["json"] = map[string]interface{}{
+		"code":    200,
+		"message": "获取成功",
+		"data": map[string]interface{}{
+			"list":     histories,
+			"total":    total,
+			"page":     page,
+			"pageSize": pageSize,
+		},
+	}
+	c.ServeJSON()
+}
+
 // 获取单个冻结配置（兼容query和body）
 func (c *StrategyFreezeController) GetOne() {
 	var symbol, strategyName, tradeType string
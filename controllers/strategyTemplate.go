@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/json"
+	"go_binance_futures/feature"
 	"go_binance_futures/feature/strategy/line"
 	"go_binance_futures/models"
 	"go_binance_futures/technology"
@@ -19,6 +20,16 @@ type StrategyTemplateController struct {
 	web.Controller
 }
 
+type TemplateVersionParams struct {
+	Comment string `json:"comment"` // 版本说明
+}
+
+type TemplateRolloutParams struct {
+	Version int64 `json:"version"` // 0 为最新的版本
+	Symbols []string `json:"symbols"` // 为空时发布到所有使用这个模板的币种
+	Comment string `json:"comment"`
+}
+
 func (ctrl *StrategyTemplateController) Post() {
 	templates := new(models.StrategyTemplates)
 	ctrl.BindJSON(&templates)
@@ -32,6 +43,13 @@ func (ctrl *StrategyTemplateController) Post() {
 		return
     }
 	templates.ID = id
+	var params TemplateVersionParams
+	ctrl.BindJSON(&params)
+	_, _, err = feature.SaveTemplateVersion(templates, params.Comment) // 第一个版本
+	if err != nil {
+		logs.Error("save template version error:", err.Error())
+	}
+	line.InvalidateProgramCache("")
 	
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
@@ -67,7 +85,11 @@ func (ctrl *StrategyTemplateController) Edit() {
 	o := orm.NewOrm()
 	o.QueryTable("strategy_templates").Filter("Id", id).One(&templates)
 	
+	version := templates.Version
 	ctrl.BindJSON(&templates)
+	templates.Version = version // 版本号只由 SaveTemplateVersion 维护
+	var params TemplateVersionParams
+	ctrl.BindJSON(&params)
 	
 	_, err := o.Update(&templates) // _ 是受影响的条数
     if err != nil {
@@ -75,6 +97,13 @@ func (ctrl *StrategyTemplateController) Edit() {
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
 		return
     }
+	// 策略内容变化时保存新的版本, 使用模板的币种需要发布后才会更新
+	_, _, err = feature.SaveTemplateVersion(&templates, params.Comment)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	line.InvalidateProgramCache("")
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"data": templates,
@@ -95,6 +124,11 @@ func (ctrl *StrategyTemplateController) Delete() {
 		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
 		return
     }
+	// 删除版本和发布记录, 使用模板的币种保留当前的策略内容
+	o.QueryTable("strategy_template_versions").Filter("TemplateId", intId).Delete()
+	o.QueryTable("strategy_template_rollouts").Filter("TemplateId", intId).Delete()
+	o.Raw("UPDATE symbols SET template_id = 0, template_version = 0 WHERE template_id = ?", intId).Exec()
+	line.InvalidateProgramCache("")
 	ctrl.Ctx.Resp(map[string]interface{} {
 		"code": 200,
 		"msg": "success",
@@ -132,7 +166,7 @@ func (ctrl *StrategyTemplateController) TestStrategyRule() {
 	}
 	for _, strategy := range strategyConfig {
 		if strategy.Enable {
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := expr.Compile(strategy.Code, line.StrategyOptions(env)...)
 			if err != nil {
 				logs.Error("Error Strategy Compile:", err.Error())
 				ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
@@ -154,4 +188,122 @@ func (ctrl *StrategyTemplateController) TestStrategyRule() {
 			})
 		}
 	}
-}
\ No newline at end of file
+}
+// 模板的所有版本和使用每个版本的币种
+func (ctrl *StrategyTemplateController) Versions() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	_, err := feature.GetTemplateVersion(id, 0) // 没有版本时保存第一个版本
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	o := orm.NewOrm()
+	var versions []models.StrategyTemplateVersion
+	o.QueryTable("strategy_template_versions").Filter("TemplateId", id).OrderBy("-Version").All(&versions)
+	var symbols []models.Symbols
+	o.QueryTable("symbols").Filter("TemplateId", id).All(&symbols, "Symbol", "TemplateVersion")
+	versionSymbols := map[int64][]string{}
+	for _, symbol := range symbols {
+		versionSymbols[symbol.TemplateVersion] = append(versionSymbols[symbol.TemplateVersion], symbol.Symbol)
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": map[string]interface{} {
+			"versions": versions,
+			"symbols": versionSymbols, // version => symbols
+		},
+		"msg": "success",
+	})
+}
+
+// 比较两个版本, from 默认为 to 的上一个版本, to 默认为最新的版本
+func (ctrl *StrategyTemplateController) Diff() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	to, _ := ctrl.GetInt64("to", 0)
+	from, _ := ctrl.GetInt64("from", 0)
+	toVersion, err := feature.GetTemplateVersion(id, to)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	if from == 0 {
+		from = toVersion.Version - 1
+	}
+	fromVersion := models.StrategyTemplateVersion{TemplateId: id} // 第一个版本和空的版本比较
+	if from > 0 {
+		fromVersion, err = feature.GetTemplateVersion(id, from)
+		if err != nil {
+			ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+			return
+		}
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": feature.DiffTemplateVersions(fromVersion, toVersion),
+		"msg": "success",
+	})
+}
+
+// 发布版本到币种
+func (ctrl *StrategyTemplateController) Rollout() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	var params TemplateRolloutParams
+	ctrl.BindJSON(&params)
+	rollout, err := feature.RolloutTemplateVersion(id, params.Version, params.Symbols, params.Comment)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": rollout,
+		"msg": "success",
+	})
+}
+
+// 发布和回滚记录
+func (ctrl *StrategyTemplateController) Rollouts() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	var rollouts []models.StrategyTemplateRollout
+	_, err := orm.NewOrm().QueryTable("strategy_template_rollouts").Filter("TemplateId", id).OrderBy("-ID").Limit(100).All(&rollouts)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": rollouts,
+		"msg": "success",
+	})
+}
+
+// 回滚一次发布, 币种恢复到发布前的配置
+func (ctrl *StrategyTemplateController) Rollback() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	rollout, err := feature.RollbackTemplateRollout(id)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": rollout,
+		"msg": "success",
+	})
+}
+
+// 模板恢复到某个版本的内容(保存为新的版本), 需要发布后币种才会更新
+func (ctrl *StrategyTemplateController) Revert() {
+	id, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":id"), 10, 64)
+	version, _ := strconv.ParseInt(ctrl.Ctx.Input.Param(":version"), 10, 64)
+	templateVersion, err := feature.RevertTemplate(id, version)
+	if err != nil {
+		ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
+		return
+	}
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": templateVersion,
+		"msg": "success",
+	})
+}
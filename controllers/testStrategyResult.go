@@ -177,7 +177,7 @@ func (ctrl *TestStrategyResultController) TestStrategyRule() {
 	
 	for _, strategy := range strategyConfig {
 		if strategy.Enable {
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := expr.Compile(strategy.Code, line.StrategyOptions(env)...)
 			if err != nil {
 				logs.Error("Error Strategy Compile:", err.Error())
 				ctrl.Ctx.Resp(utils.ResJson(400, nil, err.Error()))
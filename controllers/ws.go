@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"go_binance_futures/feature/api/binance"
+	spot_api "go_binance_futures/spot/api/binance"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+type WsController struct {
+	web.Controller
+}
+
+// 用户数据 ws 的连接状态和重连次数
+func (ctrl *WsController) UserData() {
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": binance.GetUserDataHealth(),
+		"msg": "success",
+	})
+}
+
+// 合约和现货行情 ws 的连接状态和订阅数量
+func (ctrl *WsController) Market() {
+	ctrl.Ctx.Resp(map[string]interface{} {
+		"code": 200,
+		"data": map[string]interface{} {
+			"futures": binance.GetFuturesMarketHealth(),
+			"spot": spot_api.GetSpotMarketHealth(),
+		},
+		"msg": "success",
+	})
+}
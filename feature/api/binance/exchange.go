@@ -0,0 +1,99 @@
+package binance
+
+import (
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 交易所实现: binance(真实交易所), mock(内存模拟交易所, 不需要 api key 和网络)
+var exchangeMode, _ = config.String("binance::exchange")
+
+// 下单参数(所有下单方式的统一入口)
+type CreateOrderParams struct {
+	Symbol          string
+	Side            futures.SideType
+	PositionSide    futures.PositionSideType
+	Type            futures.OrderType
+	TimeInForce     futures.TimeInForceType
+	Quantity        float64
+	Price           float64 // 限价单价格
+	StopPrice       float64 // 条件单触发价格
+	ClosePosition   bool    // 是否市价全平(和 Quantity 互斥)
+	ReduceOnly      bool    // 只减仓(双向持仓模式下不能使用)
+	CallbackRate    float64 // 跟踪止损回调比例(%), 0.1 ~ 5
+	ActivationPrice float64 // 跟踪止损激活价格
+	ClientOrderID   string  // 自定义订单id, 为空时交易所自动生成
+}
+
+// 合约交易所接口, binance 的 api 和模拟交易所都实现了这个接口
+type FuturesExchange interface {
+	// 账户
+	GetFuturesAccount() (*futures.Account, error)
+	GetIncome(incomeParams IncomeParams) ([]*futures.IncomeHistory, error)
+
+	// 仓位
+	GetPosition(positionParams PositionParams) ([]*futures.PositionRisk, error)
+	SetLeverage(symbol string, leverage int) (*futures.SymbolLeverage, error)
+	SetMarginType(symbol string, marginType futures.MarginType) error
+
+	// 订单
+	CreateOrder(params CreateOrderParams) (*futures.CreateOrderResponse, error)
+	CancelOrder(symbol string, orderId int64) (*futures.CancelOrderResponse, error)
+	GetOrders(listOrderParams ListOrderParams) ([]*futures.Order, error)
+	GetOrder(orderParams OrderParams) (*futures.Order, error)
+	GetOpenOrder(symbol string) ([]*futures.Order, error)
+
+	// 行情
+	GetDepth(symbol string, limit int) (*futures.DepthResponse, error)
+	GetTickerPrice(symbol string) ([]*futures.SymbolPrice, error)
+	GetKlineData(symbol string, interval string, limit int) ([]*futures.Kline, error)
+	GetHistoryKlineData(symbol string, interval string, startTime int64, endTime int64) ([]*futures.Kline, error) // 一段时间内的k线(时间升序)
+	GetExchangeInfo() (*futures.ExchangeInfo, error)
+
+	// 资金费率
+	GetFundingRate(params FundingRateParams) ([]*futures.PremiumIndex, error)
+	GetFundingRateHistory(params FundingRateParams) ([]*futures.FundingRate, error)
+
+	// 用户数据 ws 的 listenKey
+	StartUserStream() (string, error)
+	KeepaliveUserStream(listenKey string) error
+}
+
+var exchange FuturesExchange
+
+func initExchange() {
+	switch exchangeMode {
+		case "mock":
+			logs.Info("use futures exchange: mock")
+			exchange = NewMockExchange(10000)
+		default:
+			exchange = NewBinanceExchange(futuresClient)
+	}
+}
+
+// 当前使用的交易所
+func GetExchange() FuturesExchange {
+	return exchange
+}
+
+// 替换交易所实现(测试或者模拟环境使用)
+func SetExchange(e FuturesExchange) {
+	exchange = e
+}
+
+// 交易所当前时间(毫秒时间戳), 回测时为回放到的时间
+func GetNowTime(e FuturesExchange) int64 {
+	if clock, ok := e.(interface{ NowTime() int64 }); ok {
+		return clock.NowTime()
+	}
+	return time.Now().UnixMilli()
+}
+
+// 当前是否是模拟交易所
+func IsMockExchange() bool {
+	_, ok := exchange.(*MockExchange)
+	return ok
+}
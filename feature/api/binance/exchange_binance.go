@@ -0,0 +1,214 @@
+package binance
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// 币安合约交易所(真实 api)
+type BinanceExchange struct {
+	client *futures.Client
+}
+
+func NewBinanceExchange(client *futures.Client) *BinanceExchange {
+	return &BinanceExchange{client: client}
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func (b *BinanceExchange) GetFuturesAccount() (res *futures.Account, err error) {
+	return b.client.NewGetAccountService().Do(context.Background())
+}
+
+func (b *BinanceExchange) GetIncome(incomeParams IncomeParams) (res []*futures.IncomeHistory, err error) {
+	query := b.client.NewGetIncomeHistoryService()
+	if (incomeParams.Symbol != "") {
+		query = query.Symbol(incomeParams.Symbol)
+	}
+	if (incomeParams.IncomeType != "") {
+		query = query.IncomeType(incomeParams.IncomeType)
+	}
+	if (incomeParams.StartTime != 0) {
+		query = query.StartTime(incomeParams.StartTime)
+	}
+	if (incomeParams.EndTime != 0) {
+		query = query.EndTime(incomeParams.EndTime)
+	}
+	if (incomeParams.Limit != 0) {
+		query = query.Limit(incomeParams.Limit)
+	}
+	return query.Do(context.Background())
+}
+
+func (b *BinanceExchange) GetPosition(positionParams PositionParams) (res []*futures.PositionRisk, err error) {
+	query := b.client.NewGetPositionRiskService()
+	if (positionParams.Symbol != "") {
+		query = query.Symbol(positionParams.Symbol)
+	}
+	return query.Do(context.Background())
+}
+
+func (b *BinanceExchange) SetLeverage(symbol string, leverage int) (res *futures.SymbolLeverage, err error) {
+	return b.client.NewChangeLeverageService().Symbol(symbol).Leverage(leverage).Do(context.Background())
+}
+
+func (b *BinanceExchange) SetMarginType(symbol string, marginType futures.MarginType) (err error) {
+	return b.client.NewChangeMarginTypeService().Symbol(symbol).MarginType(marginType).Do(context.Background())
+}
+
+func (b *BinanceExchange) CreateOrder(params CreateOrderParams) (order *futures.CreateOrderResponse, err error) {
+	service := b.client.NewCreateOrderService().
+		Symbol(params.Symbol).
+		Side(params.Side).
+		PositionSide(params.PositionSide).
+		Type(params.Type)
+	if params.TimeInForce != "" {
+		service = service.TimeInForce(params.TimeInForce)
+	}
+	if params.Quantity != 0 {
+		service = service.Quantity(formatFloat(params.Quantity))
+	}
+	if params.Price != 0 {
+		service = service.Price(formatFloat(params.Price))
+	}
+	if params.StopPrice != 0 {
+		service = service.StopPrice(formatFloat(params.StopPrice)) // 触发价格
+	}
+	if params.ClosePosition {
+		service = service.ClosePosition(true) // 是否市价全平(和quantity参数互斥)
+	}
+	if params.ReduceOnly {
+		service = service.ReduceOnly(true)
+	}
+	if params.CallbackRate != 0 {
+		service = service.CallbackRate(formatFloat(params.CallbackRate))
+	}
+	if params.ActivationPrice != 0 {
+		service = service.ActivationPrice(formatFloat(params.ActivationPrice))
+	}
+	if params.ClientOrderID != "" {
+		service = service.NewClientOrderID(params.ClientOrderID)
+	}
+	return service.Do(context.Background())
+}
+
+func (b *BinanceExchange) CancelOrder(symbol string, orderId int64) (res *futures.CancelOrderResponse, err error) {
+	return b.client.NewCancelOrderService().Symbol(symbol).OrderID(orderId).Do(context.Background())
+}
+
+func (b *BinanceExchange) GetOrders(listOrderParams ListOrderParams) (res []*futures.Order, err error) {
+	service := b.client.NewListOrdersService()
+	if listOrderParams.Symbol != "" {
+		service = service.Symbol(listOrderParams.Symbol)
+	}
+	if listOrderParams.OrderID != 0 {
+		service = service.OrderID(listOrderParams.OrderID)
+	}
+	if listOrderParams.StartTime != 0 {
+		service = service.StartTime(listOrderParams.StartTime)
+	}
+	if listOrderParams.EndTime != 0 {
+		service = service.EndTime(listOrderParams.EndTime)
+	}
+	if listOrderParams.Limit != 0 {
+		service = service.Limit(listOrderParams.Limit)
+	}
+	return service.Do(context.Background())
+}
+
+func (b *BinanceExchange) GetOrder(orderParams OrderParams) (res *futures.Order, err error) {
+	service := b.client.NewGetOrderService()
+	if orderParams.Symbol != "" {
+		service = service.Symbol(orderParams.Symbol)
+	}
+	if orderParams.OrderID != 0 {
+		service = service.OrderID(orderParams.OrderID)
+	}
+	return service.Do(context.Background())
+}
+
+func (b *BinanceExchange) GetOpenOrder(symbol string) (res []*futures.Order, err error) {
+	service := b.client.NewListOpenOrdersService()
+	if symbol != "" {
+		service = service.Symbol(symbol)
+	}
+	return service.Do(context.Background())
+}
+
+func (b *BinanceExchange) GetDepth(symbol string, limit int) (res *futures.DepthResponse, err error) {
+	return b.client.NewDepthService().Symbol(symbol).Limit(limit).Do(context.Background())
+}
+
+func (b *BinanceExchange) GetTickerPrice(symbol string) (res []*futures.SymbolPrice, err error) {
+	return b.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+}
+
+func (b *BinanceExchange) GetKlineData(symbol string, interval string, limit int) (klines []*futures.Kline, err error) {
+	return b.client.NewKlinesService().Symbol(symbol).Interval(interval).Limit(limit).Do(context.Background())
+}
+
+// 每次最多请求 1500 条, 自动分页
+func (b *BinanceExchange) GetHistoryKlineData(symbol string, interval string, startTime int64, endTime int64) (klines []*futures.Kline, err error) {
+	for startTime < endTime {
+		res, err := b.client.NewKlinesService().
+			Symbol(symbol).
+			Interval(interval).
+			StartTime(startTime).
+			EndTime(endTime).
+			Limit(1500).
+			Do(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if len(res) == 0 {
+			break
+		}
+		klines = append(klines, res...)
+		startTime = res[len(res) - 1].CloseTime + 1
+		if len(res) < 1500 {
+			break
+		}
+	}
+	return klines, nil
+}
+
+func (b *BinanceExchange) GetExchangeInfo() (res *futures.ExchangeInfo, err error) {
+	return b.client.NewExchangeInfoService().Do(context.Background())
+}
+
+func (b *BinanceExchange) GetFundingRate(params FundingRateParams) (res []*futures.PremiumIndex, err error) {
+	service := b.client.NewPremiumIndexService()
+	if params.Symbol != "" {
+		service = service.Symbol(params.Symbol)
+	}
+	return service.Do(context.Background())
+}
+
+func (b *BinanceExchange) GetFundingRateHistory(params FundingRateParams) (res []*futures.FundingRate, err error) {
+	service := b.client.NewFundingRateService()
+	if params.Symbol != "" {
+		service = service.Symbol(params.Symbol)
+	}
+	if params.StartTime != 0 {
+		service = service.StartTime(params.StartTime)
+	}
+	if params.EndTime != 0 {
+		service = service.EndTime(params.EndTime)
+	}
+	if params.Limit != 0 {
+		service = service.Limit(params.Limit)
+	}
+	return service.Do(context.Background())
+}
+
+func (b *BinanceExchange) StartUserStream() (listenKey string, err error) {
+	return b.client.NewStartUserStreamService().Do(context.Background())
+}
+
+func (b *BinanceExchange) KeepaliveUserStream(listenKey string) (err error) {
+	return b.client.NewKeepaliveUserStreamService().ListenKey(listenKey).Do(context.Background())
+}
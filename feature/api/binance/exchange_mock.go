@@ -0,0 +1,905 @@
+package binance
+
+import (
+	"errors"
+	"fmt"
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// 内存模拟交易所, 不需要 api key 和网络, 用于测试和模拟盘
+// 价格通过 SetPrice 推动, 限价单/条件单在价格穿越时成交
+type MockExchange struct {
+	mu sync.Mutex
+
+	balance float64 // 钱包余额(USDT)
+	feeRate float64 // 手续费率(吃单)
+
+	prices       map[string]float64
+	filters      map[string][2]string // symbol => [tickSize, stepSize]
+	fundingRates map[string]float64
+	klines       map[string][]*futures.Kline // symbol_interval => klines
+	leverages    map[string]int
+	marginTypes  map[string]futures.MarginType
+
+	positions map[string]*mockPosition // symbol_positionSide => position
+	orders    map[int64]*futures.Order
+	trailing  map[int64]float64 // 跟踪止损单 orderId => 激活后的最高(低)价
+	incomes   []*futures.IncomeHistory
+
+	nextOrderId int64
+	nextTranId  int64
+
+	clock func() int64 // 当前时间(毫秒), 回测时为回放的时间
+}
+
+type mockPosition struct {
+	Symbol       string
+	PositionSide futures.PositionSideType
+	Amount       float64 // 多仓为正, 空仓为负
+	EntryPrice   float64
+}
+
+func NewMockExchange(balance float64) *MockExchange {
+	return &MockExchange{
+		balance:      balance,
+		feeRate:      0.0004,
+		prices:       map[string]float64{},
+		filters:      map[string][2]string{},
+		fundingRates: map[string]float64{},
+		klines:       map[string][]*futures.Kline{},
+		leverages:    map[string]int{},
+		marginTypes:  map[string]futures.MarginType{},
+		positions:    map[string]*mockPosition{},
+		orders:       map[int64]*futures.Order{},
+		trailing:     map[int64]float64{},
+		nextOrderId:  1,
+		nextTranId:   1,
+	}
+}
+
+// 设置时钟(回测时订单和收益记录使用回放的时间)
+func (m *MockExchange) SetClock(clock func() int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clock = clock
+}
+
+func (m *MockExchange) nowTime() int64 {
+	if m.clock != nil {
+		return m.clock()
+	}
+	return time.Now().UnixMilli()
+}
+
+// 设置手续费率
+func (m *MockExchange) SetFeeRate(feeRate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.feeRate = feeRate
+}
+
+// 从 symbols 表的数据初始化价格和精度
+func (m *MockExchange) LoadSymbols(symbols []models.Symbols) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, symbol := range symbols {
+		price, _ := strconv.ParseFloat(symbol.Close, 64)
+		if price > 0 {
+			m.prices[symbol.Symbol] = price
+		}
+		if symbol.TickSize != "" && symbol.StepSize != "" {
+			m.filters[symbol.Symbol] = [2]string{symbol.TickSize, symbol.StepSize}
+		}
+		if symbol.Leverage > 0 {
+			m.leverages[symbol.Symbol] = int(symbol.Leverage)
+		}
+	}
+}
+
+// 更新最新价格, 并撮合挂单
+func (m *MockExchange) SetPrice(symbol string, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prices[symbol] = price
+	m.matchOrders(symbol, price)
+}
+
+// 所有交易对的最新价格
+func (m *MockExchange) GetPrices() map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prices := make(map[string]float64, len(m.prices))
+	for symbol, price := range m.prices {
+		prices[symbol] = price
+	}
+	return prices
+}
+
+// 所有价格随机游走一次(模拟盘使用)
+// @param volatility 单次最大涨跌幅 ex: 0.002
+func (m *MockExchange) RandomWalk(volatility float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for symbol, price := range m.prices {
+		price = price * (1 + (rand.Float64() * 2 - 1) * volatility)
+		m.prices[symbol] = price
+		m.matchOrders(symbol, price)
+	}
+}
+
+// 设置 k 线数据(不设置时根据最新价格生成)
+func (m *MockExchange) SetKlines(symbol string, interval string, klines []*futures.Kline) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.klines[symbol + "_" + interval] = klines
+}
+
+func (m *MockExchange) SetFundingRate(symbol string, rate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fundingRates[symbol] = rate
+}
+
+// 资金费结算, 多仓支付正费率, 空仓收取正费率
+func (m *MockExchange) SettleFunding() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, position := range m.positions {
+		if position.Amount == 0 {
+			continue
+		}
+		notional := position.Amount * m.prices[position.Symbol]
+		fee := -notional * m.fundingRates[position.Symbol]
+		m.balance += fee
+		m.addIncome(position.Symbol, "FUNDING_FEE", fee)
+	}
+}
+
+func (m *MockExchange) addIncome(symbol string, incomeType string, income float64) {
+	m.incomes = append(m.incomes, &futures.IncomeHistory{
+		Asset:      "USDT",
+		Income:     formatFloat(income),
+		IncomeType: incomeType,
+		Symbol:     symbol,
+		Time:       m.nowTime(),
+		TranID:     m.nextTranId,
+	})
+	m.nextTranId++
+}
+
+func (m *MockExchange) getPrice(symbol string) (float64, error) {
+	price, ok := m.prices[symbol]
+	if !ok || price <= 0 {
+		return 0, fmt.Errorf("mock exchange: no price for symbol %s", symbol)
+	}
+	return price, nil
+}
+
+func (m *MockExchange) getPosition(symbol string, positionSide futures.PositionSideType) *mockPosition {
+	if positionSide == "" {
+		positionSide = futures.PositionSideTypeBoth
+	}
+	key := symbol + "_" + string(positionSide)
+	position, ok := m.positions[key]
+	if !ok {
+		position = &mockPosition{Symbol: symbol, PositionSide: positionSide}
+		m.positions[key] = position
+	}
+	return position
+}
+
+func (m *MockExchange) getLeverage(symbol string) int {
+	if leverage, ok := m.leverages[symbol]; ok {
+		return leverage
+	}
+	return 20
+}
+
+// 未实现盈亏和保证金
+func (m *MockExchange) positionStat(position *mockPosition) (unRealizedProfit float64, margin float64) {
+	price := m.prices[position.Symbol]
+	unRealizedProfit = (price - position.EntryPrice) * position.Amount
+	margin = math.Abs(position.Amount) * price / float64(m.getLeverage(position.Symbol))
+	return unRealizedProfit, margin
+}
+
+func (m *MockExchange) GetFuturesAccount() (res *futures.Account, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var totalUnRealizedProfit, totalMargin float64
+	positions := []*futures.AccountPosition{}
+	for _, position := range m.positions {
+		unRealizedProfit, margin := m.positionStat(position)
+		totalUnRealizedProfit += unRealizedProfit
+		totalMargin += margin
+		positions = append(positions, &futures.AccountPosition{
+			Isolated:              m.marginTypes[position.Symbol] == futures.MarginTypeIsolated,
+			Leverage:              strconv.Itoa(m.getLeverage(position.Symbol)),
+			InitialMargin:         formatFloat(margin),
+			PositionInitialMargin: formatFloat(margin),
+			Symbol:                position.Symbol,
+			UnrealizedProfit:      formatFloat(unRealizedProfit),
+			EntryPrice:            formatFloat(position.EntryPrice),
+			PositionSide:          position.PositionSide,
+			PositionAmt:           formatFloat(position.Amount),
+			Notional:              formatFloat(position.Amount * m.prices[position.Symbol]),
+			UpdateTime:            m.nowTime(),
+		})
+	}
+	marginBalance := m.balance + totalUnRealizedProfit
+	available := marginBalance - totalMargin
+	return &futures.Account{
+		Assets: []*futures.AccountAsset{{
+			Asset:                 "USDT",
+			InitialMargin:         formatFloat(totalMargin),
+			MarginBalance:         formatFloat(marginBalance),
+			MaxWithdrawAmount:     formatFloat(available),
+			PositionInitialMargin: formatFloat(totalMargin),
+			UnrealizedProfit:      formatFloat(totalUnRealizedProfit),
+			WalletBalance:         formatFloat(m.balance),
+			CrossWalletBalance:    formatFloat(m.balance),
+			CrossUnPnl:            formatFloat(totalUnRealizedProfit),
+			AvailableBalance:      formatFloat(available),
+			MarginAvailable:       true,
+			UpdateTime:            m.nowTime(),
+		}},
+		CanTrade:                   true,
+		UpdateTime:                 m.nowTime(),
+		TotalInitialMargin:         formatFloat(totalMargin),
+		TotalWalletBalance:         formatFloat(m.balance),
+		TotalUnrealizedProfit:      formatFloat(totalUnRealizedProfit),
+		TotalMarginBalance:         formatFloat(marginBalance),
+		TotalPositionInitialMargin: formatFloat(totalMargin),
+		TotalCrossWalletBalance:    formatFloat(m.balance),
+		TotalCrossUnPnl:            formatFloat(totalUnRealizedProfit),
+		AvailableBalance:           formatFloat(available),
+		MaxWithdrawAmount:          formatFloat(available),
+		Positions:                  positions,
+	}, nil
+}
+
+func (m *MockExchange) GetIncome(incomeParams IncomeParams) (res []*futures.IncomeHistory, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res = []*futures.IncomeHistory{}
+	for _, income := range m.incomes {
+		if incomeParams.Symbol != "" && income.Symbol != incomeParams.Symbol {
+			continue
+		}
+		if incomeParams.IncomeType != "" && income.IncomeType != incomeParams.IncomeType {
+			continue
+		}
+		if incomeParams.StartTime != 0 && income.Time < incomeParams.StartTime {
+			continue
+		}
+		if incomeParams.EndTime != 0 && income.Time > incomeParams.EndTime {
+			continue
+		}
+		copied := *income
+		res = append(res, &copied)
+	}
+	if incomeParams.Limit != 0 && int64(len(res)) > incomeParams.Limit {
+		res = res[int64(len(res)) - incomeParams.Limit:]
+	}
+	return res, nil
+}
+
+// 和 binance 一样, 返回所有交易对的多空仓位(没有仓位数量为 0)
+func (m *MockExchange) GetPosition(positionParams PositionParams) (res []*futures.PositionRisk, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res = []*futures.PositionRisk{}
+	for _, position := range m.positions {
+		if positionParams.Symbol != "" && position.Symbol != positionParams.Symbol {
+			continue
+		}
+		unRealizedProfit, margin := m.positionStat(position)
+		marginType := m.marginTypes[position.Symbol]
+		if marginType == "" {
+			marginType = futures.MarginTypeCrossed
+		}
+		res = append(res, &futures.PositionRisk{
+			EntryPrice:       formatFloat(position.EntryPrice),
+			BreakEvenPrice:   formatFloat(position.EntryPrice),
+			MarginType:       string(marginType),
+			IsolatedMargin:   formatFloat(margin),
+			Leverage:         strconv.Itoa(m.getLeverage(position.Symbol)),
+			LiquidationPrice: "0",
+			MarkPrice:        formatFloat(m.prices[position.Symbol]),
+			PositionAmt:      formatFloat(position.Amount),
+			Symbol:           position.Symbol,
+			UnRealizedProfit: formatFloat(unRealizedProfit),
+			PositionSide:     string(position.PositionSide),
+			Notional:         formatFloat(position.Amount * m.prices[position.Symbol]),
+			IsolatedWallet:   formatFloat(margin),
+		})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Symbol == res[j].Symbol {
+			return res[i].PositionSide < res[j].PositionSide
+		}
+		return res[i].Symbol < res[j].Symbol
+	})
+	return res, nil
+}
+
+func (m *MockExchange) SetLeverage(symbol string, leverage int) (res *futures.SymbolLeverage, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if leverage < 1 || leverage > 125 {
+		return nil, fmt.Errorf("mock exchange: invalid leverage %d", leverage)
+	}
+	m.leverages[symbol] = leverage
+	return &futures.SymbolLeverage{Leverage: leverage, Symbol: symbol, MaxNotionalValue: "1000000"}, nil
+}
+
+func (m *MockExchange) SetMarginType(symbol string, marginType futures.MarginType) (err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.marginTypes[symbol] == marginType {
+		return errors.New("mock exchange: No need to change margin type.")
+	}
+	m.marginTypes[symbol] = marginType
+	return nil
+}
+
+func (m *MockExchange) CreateOrder(params CreateOrderParams) (res *futures.CreateOrderResponse, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	price, err := m.getPrice(params.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if params.Quantity <= 0 && !params.ClosePosition {
+		return nil, errors.New("mock exchange: quantity must be greater than 0")
+	}
+	if params.PositionSide == "" {
+		params.PositionSide = futures.PositionSideTypeBoth
+	}
+	now := m.nowTime()
+	if params.ClientOrderID == "" {
+		params.ClientOrderID = "mock_" + strconv.FormatInt(m.nextOrderId, 10)
+	}
+	order := &futures.Order{
+		Symbol:           params.Symbol,
+		OrderID:          m.nextOrderId,
+		ClientOrderID:    params.ClientOrderID,
+		Price:            formatFloat(params.Price),
+		ReduceOnly:       params.ReduceOnly,
+		OrigQuantity:     formatFloat(params.Quantity),
+		ExecutedQuantity: "0",
+		CumQuote:         "0",
+		Status:           futures.OrderStatusTypeNew,
+		TimeInForce:      params.TimeInForce,
+		Type:             params.Type,
+		Side:             params.Side,
+		StopPrice:        formatFloat(params.StopPrice),
+		Time:             now,
+		UpdateTime:       now,
+		ActivatePrice:    formatFloat(params.ActivationPrice),
+		PriceRate:        formatFloat(params.CallbackRate),
+		AvgPrice:         "0",
+		OrigType:         params.Type,
+		PositionSide:     params.PositionSide,
+		ClosePosition:    params.ClosePosition,
+	}
+	switch params.Type {
+		case futures.OrderTypeMarket:
+			if err = m.fill(order, price); err != nil {
+				return nil, err
+			}
+		case futures.OrderTypeLimit:
+			if params.Price <= 0 {
+				return nil, errors.New("mock exchange: price must be greater than 0")
+			}
+		case futures.OrderTypeStopMarket, futures.OrderTypeTakeProfitMarket:
+			if params.StopPrice <= 0 {
+				return nil, errors.New("mock exchange: stopPrice must be greater than 0")
+			}
+			if m.shouldTrigger(order, price) {
+				// binance 会直接拒绝会立即触发的条件单
+				return nil, errors.New("mock exchange: Order would immediately trigger.")
+			}
+		case futures.OrderTypeTrailingStopMarket:
+			if params.CallbackRate < 0.1 || params.CallbackRate > 5 {
+				return nil, errors.New("mock exchange: callbackRate must be between 0.1 and 5")
+			}
+		default:
+			return nil, fmt.Errorf("mock exchange: unsupported order type %s", params.Type)
+	}
+	m.orders[order.OrderID] = order
+	m.nextOrderId++
+	if order.Status == futures.OrderStatusTypeNew {
+		m.matchOrders(params.Symbol, price)
+	}
+	return &futures.CreateOrderResponse{
+		Symbol:           order.Symbol,
+		OrderID:          order.OrderID,
+		ClientOrderID:    order.ClientOrderID,
+		Price:            order.Price,
+		OrigQuantity:     order.OrigQuantity,
+		ExecutedQuantity: order.ExecutedQuantity,
+		CumQuote:         order.CumQuote,
+		ReduceOnly:       order.ReduceOnly,
+		Status:           order.Status,
+		StopPrice:        order.StopPrice,
+		TimeInForce:      order.TimeInForce,
+		Type:             order.Type,
+		Side:             order.Side,
+		UpdateTime:       order.UpdateTime,
+		ActivatePrice:    order.ActivatePrice,
+		PriceRate:        order.PriceRate,
+		AvgPrice:         order.AvgPrice,
+		PositionSide:     order.PositionSide,
+		ClosePosition:    order.ClosePosition,
+		CumQty:           order.ExecutedQuantity,
+		OrigType:         order.OrigType,
+	}, nil
+}
+
+// 条件单是否触发
+func (m *MockExchange) shouldTrigger(order *futures.Order, price float64) bool {
+	stopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+	switch order.Type {
+		case futures.OrderTypeLimit:
+			limitPrice, _ := strconv.ParseFloat(order.Price, 64)
+			if order.Side == futures.SideTypeBuy {
+				return price <= limitPrice
+			}
+			return price >= limitPrice
+		case futures.OrderTypeStopMarket:
+			if order.Side == futures.SideTypeBuy {
+				return price >= stopPrice
+			}
+			return price <= stopPrice
+		case futures.OrderTypeTakeProfitMarket:
+			if order.Side == futures.SideTypeBuy {
+				return price <= stopPrice
+			}
+			return price >= stopPrice
+		case futures.OrderTypeTrailingStopMarket:
+			activationPrice, _ := strconv.ParseFloat(order.ActivatePrice, 64)
+			callbackRate, _ := strconv.ParseFloat(order.PriceRate, 64)
+			extreme, activated := m.trailing[order.OrderID]
+			if !activated {
+				if activationPrice != 0 && ((order.Side == futures.SideTypeSell && price < activationPrice) || (order.Side == futures.SideTypeBuy && price > activationPrice)) {
+					return false
+				}
+				m.trailing[order.OrderID] = price
+				return false
+			}
+			if order.Side == futures.SideTypeSell {
+				if price > extreme {
+					m.trailing[order.OrderID] = price
+					return false
+				}
+				return price <= extreme * (1 - callbackRate / 100)
+			}
+			if price < extreme {
+				m.trailing[order.OrderID] = price
+				return false
+			}
+			return price >= extreme * (1 + callbackRate / 100)
+	}
+	return false
+}
+
+// 撮合挂单
+func (m *MockExchange) matchOrders(symbol string, price float64) {
+	orderIds := []int64{}
+	for orderId, order := range m.orders {
+		if order.Symbol == symbol && order.Status == futures.OrderStatusTypeNew {
+			orderIds = append(orderIds, orderId)
+		}
+	}
+	sort.Slice(orderIds, func(i, j int) bool {
+		return orderIds[i] < orderIds[j]
+	})
+	for _, orderId := range orderIds {
+		order := m.orders[orderId]
+		if !m.shouldTrigger(order, price) {
+			continue
+		}
+		fillPrice := price
+		if order.Type == futures.OrderTypeLimit {
+			fillPrice, _ = strconv.ParseFloat(order.Price, 64)
+		}
+		if err := m.fill(order, fillPrice); err != nil {
+			order.Status = futures.OrderStatusTypeExpired
+			order.UpdateTime = m.nowTime()
+		}
+		delete(m.trailing, orderId)
+	}
+}
+
+// 成交, 更新仓位/余额/收益记录
+func (m *MockExchange) fill(order *futures.Order, price float64) error {
+	position := m.getPosition(order.Symbol, order.PositionSide)
+	quantity, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+	direction := 1.0
+	if order.Side == futures.SideTypeSell {
+		direction = -1.0
+	}
+	// 双向持仓时, 多仓卖出和空仓买入是平仓
+	reduce := order.ReduceOnly || order.ClosePosition ||
+		(order.PositionSide == futures.PositionSideTypeLong && order.Side == futures.SideTypeSell) ||
+		(order.PositionSide == futures.PositionSideTypeShort && order.Side == futures.SideTypeBuy)
+	if reduce {
+		if position.Amount == 0 || position.Amount * direction > 0 {
+			return errors.New("mock exchange: ReduceOnly Order is rejected.")
+		}
+		if order.ClosePosition || quantity > math.Abs(position.Amount) {
+			quantity = math.Abs(position.Amount)
+		}
+	}
+	if !reduce && order.PositionSide != futures.PositionSideTypeBoth && direction * position.Amount < 0 {
+		return errors.New("mock exchange: Order's position side does not match user's setting.")
+	}
+
+	realizedProfit := 0.0
+	remain := quantity
+	if position.Amount != 0 && position.Amount * direction < 0 {
+		closeQuantity := math.Min(remain, math.Abs(position.Amount))
+		realizedProfit = (price - position.EntryPrice) * closeQuantity * -direction
+		position.Amount += closeQuantity * direction
+		remain -= closeQuantity
+		if position.Amount == 0 {
+			position.EntryPrice = 0
+		}
+	}
+	if remain > 0 {
+		total := math.Abs(position.Amount) + remain
+		position.EntryPrice = (position.EntryPrice * math.Abs(position.Amount) + price * remain) / total
+		position.Amount += remain * direction
+	}
+	commission := price * quantity * m.feeRate
+	m.balance += realizedProfit - commission
+	if realizedProfit != 0 {
+		m.addIncome(order.Symbol, "REALIZED_PNL", realizedProfit)
+	}
+	m.addIncome(order.Symbol, "COMMISSION", -commission)
+
+	order.Status = futures.OrderStatusTypeFilled
+	order.OrigQuantity = formatFloat(quantity)
+	order.ExecutedQuantity = formatFloat(quantity)
+	order.CumQuantity = order.ExecutedQuantity
+	order.CumQuote = formatFloat(price * quantity)
+	order.AvgPrice = formatFloat(price)
+	order.UpdateTime = m.nowTime()
+	return nil
+}
+
+func (m *MockExchange) CancelOrder(symbol string, orderId int64) (res *futures.CancelOrderResponse, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[orderId]
+	if !ok || order.Symbol != symbol {
+		return nil, errors.New("mock exchange: Unknown order sent.")
+	}
+	if order.Status != futures.OrderStatusTypeNew {
+		return nil, errors.New("mock exchange: Order has already been " + string(order.Status))
+	}
+	order.Status = futures.OrderStatusTypeCanceled
+	order.UpdateTime = m.nowTime()
+	delete(m.trailing, orderId)
+	return &futures.CancelOrderResponse{
+		ClientOrderID:    order.ClientOrderID,
+		CumQuote:         order.CumQuote,
+		ExecutedQuantity: order.ExecutedQuantity,
+		OrderID:          order.OrderID,
+		OrigQuantity:     order.OrigQuantity,
+		Price:            order.Price,
+		ReduceOnly:       order.ReduceOnly,
+		Side:             order.Side,
+		Status:           order.Status,
+		StopPrice:        order.StopPrice,
+		Symbol:           order.Symbol,
+		TimeInForce:      order.TimeInForce,
+		Type:             order.Type,
+		UpdateTime:       order.UpdateTime,
+		ActivatePrice:    order.ActivatePrice,
+		PriceRate:        order.PriceRate,
+		OrigType:         string(order.OrigType),
+		PositionSide:     order.PositionSide,
+	}, nil
+}
+
+func (m *MockExchange) listOrders(filter func(order *futures.Order) bool) []*futures.Order {
+	res := []*futures.Order{}
+	for _, order := range m.orders {
+		if filter(order) {
+			copied := *order
+			res = append(res, &copied)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].OrderID < res[j].OrderID
+	})
+	return res
+}
+
+func (m *MockExchange) GetOrders(listOrderParams ListOrderParams) (res []*futures.Order, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res = m.listOrders(func(order *futures.Order) bool {
+		return (listOrderParams.Symbol == "" || order.Symbol == listOrderParams.Symbol) &&
+			(listOrderParams.OrderID == 0 || order.OrderID >= listOrderParams.OrderID) &&
+			(listOrderParams.StartTime == 0 || order.Time >= listOrderParams.StartTime) &&
+			(listOrderParams.EndTime == 0 || order.Time <= listOrderParams.EndTime)
+	})
+	if listOrderParams.Limit != 0 && len(res) > listOrderParams.Limit {
+		res = res[:listOrderParams.Limit]
+	}
+	return res, nil
+}
+
+func (m *MockExchange) GetOrder(orderParams OrderParams) (res *futures.Order, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	order, ok := m.orders[orderParams.OrderID]
+	if !ok || (orderParams.Symbol != "" && order.Symbol != orderParams.Symbol) {
+		return nil, errors.New("mock exchange: Order does not exist.")
+	}
+	copied := *order
+	return &copied, nil
+}
+
+func (m *MockExchange) GetOpenOrder(symbol string) (res []*futures.Order, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listOrders(func(order *futures.Order) bool {
+		return (symbol == "" || order.Symbol == symbol) && order.Status == futures.OrderStatusTypeNew
+	}), nil
+}
+
+// 以最新价格为中心生成深度, 每档间隔 0.01%
+func (m *MockExchange) GetDepth(symbol string, limit int) (res *futures.DepthResponse, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	price, err := m.getPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	res = &futures.DepthResponse{
+		Time:         m.nowTime(),
+		TradeTime:    m.nowTime(),
+		Bids:         []futures.Bid{},
+		Asks:         []futures.Ask{},
+	}
+	for i := 1; i <= limit; i++ {
+		res.Bids = append(res.Bids, futures.Bid{Price: formatFloat(price * (1 - 0.0001 * float64(i))), Quantity: "1"})
+		res.Asks = append(res.Asks, futures.Ask{Price: formatFloat(price * (1 + 0.0001 * float64(i))), Quantity: "1"})
+	}
+	return res, nil
+}
+
+func (m *MockExchange) GetTickerPrice(symbol string) (res []*futures.SymbolPrice, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	price, err := m.getPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return []*futures.SymbolPrice{{Symbol: symbol, Price: formatFloat(price)}}, nil
+}
+
+// 返回设置的 k 线, 没有设置时以最新价格为最后收盘价, 按交易对生成固定的随机走势(时间升序)
+func (m *MockExchange) GetKlineData(symbol string, interval string, limit int) (klines []*futures.Kline, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data, ok := m.klines[symbol + "_" + interval]; ok {
+		if len(data) > limit {
+			sorted := append([]*futures.Kline{}, data...)
+			sort.Slice(sorted, func(i, j int) bool {
+				return sorted[i].OpenTime < sorted[j].OpenTime
+			})
+			data = sorted[len(sorted) - limit:]
+		}
+		klines = make([]*futures.Kline, len(data))
+		for i, kline := range data {
+			copied := *kline
+			klines[i] = &copied
+		}
+		return klines, nil
+	}
+	price, err := m.getPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	openTime := time.Now().Truncate(duration).UnixMilli() - int64(limit - 1) * duration.Milliseconds()
+	return randomKlines(symbol, interval, price, openTime, limit, duration), nil
+}
+
+// 返回设置的k线中这段时间的数据, 没有设置时和 GetKlineData 一样生成随机走势(最后一根的收盘价为最新价格)
+func (m *MockExchange) GetHistoryKlineData(symbol string, interval string, startTime int64, endTime int64) (klines []*futures.Kline, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data, ok := m.klines[symbol + "_" + interval]; ok {
+		for _, kline := range data {
+			if kline.OpenTime >= startTime && kline.OpenTime <= endTime {
+				copied := *kline
+				klines = append(klines, &copied)
+			}
+		}
+		sort.Slice(klines, func(i, j int) bool {
+			return klines[i].OpenTime < klines[j].OpenTime
+		})
+		return klines, nil
+	}
+	price, err := m.getPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	ms := duration.Milliseconds()
+	openTime := (startTime + ms - 1) / ms * ms
+	if openTime > endTime {
+		return []*futures.Kline{}, nil
+	}
+	return randomKlines(symbol, interval, price, openTime, int((endTime - openTime) / ms) + 1, duration), nil
+}
+
+// 按交易对生成固定的随机走势(时间升序), 最后一根的收盘价为 price
+func randomKlines(symbol string, interval string, price float64, openTime int64, limit int, duration time.Duration) (klines []*futures.Kline) {
+	hash := fnv.New64a()
+	hash.Write([]byte(symbol + "_" + interval))
+	random := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	closes := make([]float64, limit)
+	for i := limit - 1; i >= 0; i-- {
+		if i == limit - 1 {
+			closes[i] = price
+		} else {
+			closes[i] = closes[i + 1] / (1 + (random.Float64() * 2 - 1) * 0.005)
+		}
+	}
+	klines = make([]*futures.Kline, limit)
+	for i := 0; i < limit; i++ {
+		open := closes[i]
+		if i > 0 {
+			open = closes[i - 1]
+		}
+		high := math.Max(open, closes[i]) * (1 + random.Float64() * 0.002)
+		low := math.Min(open, closes[i]) * (1 - random.Float64() * 0.002)
+		volume := 100 + random.Float64() * 1000
+		klines[i] = &futures.Kline{
+			OpenTime:         openTime + int64(i) * duration.Milliseconds(),
+			Open:             formatFloat(open),
+			High:             formatFloat(high),
+			Low:              formatFloat(low),
+			Close:            formatFloat(closes[i]),
+			Volume:           formatFloat(volume),
+			CloseTime:        openTime + int64(i + 1) * duration.Milliseconds() - 1,
+			QuoteAssetVolume: formatFloat(volume * closes[i]),
+			TradeNum:         int64(volume),
+		}
+	}
+	return klines
+}
+
+func (m *MockExchange) GetExchangeInfo() (res *futures.ExchangeInfo, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res = &futures.ExchangeInfo{
+		Timezone:   "UTC",
+		ServerTime: m.nowTime(),
+		Symbols:    []futures.Symbol{},
+	}
+	for symbol := range m.prices {
+		filter, ok := m.filters[symbol]
+		if !ok {
+			filter = [2]string{"0.0001", "0.001"}
+		}
+		res.Symbols = append(res.Symbols, futures.Symbol{
+			Symbol:       symbol,
+			Pair:         symbol,
+			ContractType: futures.ContractTypePerpetual,
+			Status:       "TRADING",
+			QuoteAsset:   "USDT",
+			MarginAsset:  "USDT",
+			Filters: []map[string]interface{}{
+				{"filterType": string(futures.SymbolFilterTypePrice), "tickSize": filter[0], "minPrice": filter[0], "maxPrice": "1000000"},
+				{"filterType": string(futures.SymbolFilterTypeLotSize), "stepSize": filter[1], "minQty": filter[1], "maxQty": "1000000"},
+			},
+		})
+	}
+	sort.Slice(res.Symbols, func(i, j int) bool {
+		return res.Symbols[i].Symbol < res.Symbols[j].Symbol
+	})
+	return res, nil
+}
+
+func (m *MockExchange) GetFundingRate(params FundingRateParams) (res []*futures.PremiumIndex, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res = []*futures.PremiumIndex{}
+	// 下一次资金费时间(每 8 小时)
+	nextFundingTime := time.Now().Truncate(time.Hour * 8).Add(time.Hour * 8).UnixMilli()
+	for symbol, price := range m.prices {
+		if params.Symbol != "" && symbol != params.Symbol {
+			continue
+		}
+		res = append(res, &futures.PremiumIndex{
+			Symbol:          symbol,
+			MarkPrice:       formatFloat(price),
+			IndexPrice:      formatFloat(price),
+			LastFundingRate: formatFloat(m.fundingRates[symbol]),
+			NextFundingTime: nextFundingTime,
+			InterestRate:    "0.0001",
+			Time:            m.nowTime(),
+		})
+	}
+	return res, nil
+}
+
+func (m *MockExchange) GetFundingRateHistory(params FundingRateParams) (res []*futures.FundingRate, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res = []*futures.FundingRate{}
+	for symbol, price := range m.prices {
+		if params.Symbol != "" && symbol != params.Symbol {
+			continue
+		}
+		res = append(res, &futures.FundingRate{
+			Symbol:      symbol,
+			FundingRate: formatFloat(m.fundingRates[symbol]),
+			FundingTime: time.Now().Truncate(time.Hour * 8).UnixMilli(),
+			MarkPrice:   formatFloat(price),
+		})
+	}
+	return res, nil
+}
+
+// 模拟交易所没有用户数据 ws, 仓位和订单直接查询模拟交易所
+func (m *MockExchange) StartUserStream() (listenKey string, err error) {
+	return "", errors.New("mock exchange: user data stream not supported")
+}
+
+func (m *MockExchange) KeepaliveUserStream(listenKey string) (err error) {
+	return errors.New("mock exchange: user data stream not supported")
+}
+
+// 模拟交易所行情(代替 ws 推送): 从 symbols 表初始化价格, 随机游走后写回 symbols 表
+func UpdateCoinByMock(systemConfig *models.Config) {
+	mock, ok := exchange.(*MockExchange)
+	if !ok {
+		return
+	}
+	o := orm.NewOrm()
+	var symbols []models.Symbols
+	o.QueryTable("symbols").All(&symbols)
+	mock.LoadSymbols(symbols)
+	for {
+		if systemConfig.WsFuturesEnable == 1 {
+			mock.RandomWalk(0.002)
+			now := time.Now().UnixMilli()
+			for symbol, price := range mock.GetPrices() {
+				o.Raw(
+					"UPDATE `symbols` set `close` = ?, `updateTime` = ?, `lastClose` = close, `lastUpdateTime` = updateTime WHERE `symbol` = ?",
+					formatFloat(price),
+					now,
+					symbol,
+				).Exec()
+			}
+		}
+		time.Sleep(time.Second * 3) // 3 秒间隔
+	}
+}
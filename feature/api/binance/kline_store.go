@@ -0,0 +1,340 @@
+package binance
+
+import (
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 使用 ws 订阅k线, 策略和监听使用本地缓存的k线, 代替每次请求 http api
+var wsFuturesKline, _ = config.String("ws::futures_kline")
+var klineKeepDays = config.DefaultInt("ws::futures_kline_keep_days", 0) // 0 不删除(回测使用保存的历史k线)
+
+const (
+	klineCacheMax = 1000 // 每个币种周期最多缓存的k线数量
+	klineStaleMs = 60 * 1000 // 超过这个时间没有收到 ws 推送, 缓存失效
+)
+
+type klineCache struct {
+	klines     []*futures.Kline // 时间升序, 最后一根可能未完结
+	synced     bool             // 和 ws 推送是连续的(没有缺失的k线)
+	updateTime int64            // 最后一次收到 ws 推送的时间
+}
+
+var klineStore = struct {
+	sync.RWMutex
+	caches  map[string]*klineCache          // symbol_interval => 缓存
+	wanted  map[string]map[string]bool      // interval => symbols, 请求过的币种周期, 由同步服务订阅
+	pending map[string][]*futures.Kline     // symbol_interval => 待写入数据库的完结k线
+}{
+	caches:  map[string]*klineCache{},
+	wanted:  map[string]map[string]bool{},
+	pending: map[string][]*futures.Kline{},
+}
+
+func init() {
+	futuresMarket.OnDisconnect(onKlineDisconnect)
+}
+
+// 是否开启k线同步(模拟交易所不使用)
+func IsKlineSyncEnable() bool {
+	return wsFuturesKline == "1" && !IsMockExchange()
+}
+
+func klineKey(symbol string, interval string) string {
+	return symbol + "_" + interval
+}
+
+// 从缓存获取k线(时间升序), 缓存不可用时返回 false
+func GetCachedKlineData(symbol string, interval string, limit int) ([]*futures.Kline, bool) {
+	key := klineKey(symbol, interval)
+	klineStore.Lock()
+	defer klineStore.Unlock()
+	if _, ok := klineStore.wanted[interval]; !ok {
+		klineStore.wanted[interval] = map[string]bool{}
+	}
+	klineStore.wanted[interval][symbol] = true
+	cache, ok := klineStore.caches[key]
+	if !ok || !cache.synced || len(cache.klines) < limit || time.Now().UnixMilli() - cache.updateTime > klineStaleMs {
+		return nil, false
+	}
+	klines := make([]*futures.Kline, 0, limit)
+	for _, kline := range cache.klines[len(cache.klines) - limit:] {
+		copied := *kline
+		klines = append(klines, &copied)
+	}
+	return klines, true
+}
+
+// 使用 http api 的结果重置缓存, 已经完结的k线写入数据库
+func SetCachedKlineData(symbol string, interval string, klines []*futures.Kline) {
+	if len(klines) == 0 {
+		return
+	}
+	sorted := append([]*futures.Kline{}, klines...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].OpenTime < sorted[j].OpenTime
+	})
+	key := klineKey(symbol, interval)
+	klineStore.Lock()
+	defer klineStore.Unlock()
+	cache, ok := klineStore.caches[key]
+	if !ok {
+		cache = &klineCache{}
+		klineStore.caches[key] = cache
+	}
+	// 保留比 http 结果更新的 ws k线
+	newer := []*futures.Kline{}
+	last := sorted[len(sorted) - 1]
+	for _, kline := range cache.klines {
+		if kline.OpenTime > last.OpenTime {
+			newer = append(newer, kline)
+		}
+	}
+	cache.klines = append(sorted, newer...)
+	cache.synced = true
+	cache.updateTime = time.Now().UnixMilli()
+	if len(cache.klines) > klineCacheMax {
+		cache.klines = cache.klines[len(cache.klines) - klineCacheMax:]
+	}
+	now := time.Now().UnixMilli()
+	for _, kline := range sorted {
+		if kline.CloseTime < now {
+			klineStore.pending[key] = append(klineStore.pending[key], kline)
+		}
+	}
+}
+
+// ws 推送的k线
+func onKlineEvent(event *futures.WsKlineEvent) {
+	k := event.Kline
+	kline := &futures.Kline{
+		OpenTime:                 k.StartTime,
+		Open:                     k.Open,
+		High:                     k.High,
+		Low:                      k.Low,
+		Close:                    k.Close,
+		Volume:                   k.Volume,
+		CloseTime:                k.EndTime,
+		QuoteAssetVolume:         k.QuoteVolume,
+		TradeNum:                 k.TradeNum,
+		TakerBuyBaseAssetVolume:  k.ActiveBuyVolume,
+		TakerBuyQuoteAssetVolume: k.ActiveBuyQuoteVolume,
+	}
+	key := klineKey(k.Symbol, k.Interval)
+	klineStore.Lock()
+	defer klineStore.Unlock()
+	cache, ok := klineStore.caches[key]
+	if !ok {
+		cache = &klineCache{}
+		klineStore.caches[key] = cache
+	}
+	cache.updateTime = time.Now().UnixMilli()
+	size := len(cache.klines)
+	if size > 0 && cache.klines[size - 1].OpenTime == kline.OpenTime {
+		cache.klines[size - 1] = kline
+	} else if size > 0 && cache.klines[size - 1].CloseTime + 1 == kline.OpenTime {
+		cache.klines = append(cache.klines, kline)
+	} else if size == 0 || kline.OpenTime > cache.klines[size - 1].OpenTime {
+		// 中间有缺失的k线, 等待下次 http 请求重新填充
+		cache.klines = []*futures.Kline{kline}
+		cache.synced = false
+	}
+	if len(cache.klines) > klineCacheMax {
+		cache.klines = cache.klines[len(cache.klines) - klineCacheMax:]
+	}
+	if k.IsFinal {
+		klineStore.pending[key] = append(klineStore.pending[key], kline)
+	}
+}
+
+// k线同步服务, 订阅请求过的币种周期, 定时把完结的k线写入数据库
+func StartKlineSync() {
+	for {
+		syncKlineStreams()
+		flushPendingKlines()
+		time.Sleep(time.Second * 10) // 10秒间隔
+	}
+}
+
+// 新增的币种周期通过合约行情 ws 订阅, 已经订阅的跳过
+func syncKlineStreams() {
+	klineStore.RLock()
+	streams := []string{}
+	for interval, wantedSymbols := range klineStore.wanted {
+		for symbol := range wantedSymbols {
+			streams = append(streams, strings.ToLower(symbol) + "@kline_" + interval)
+		}
+	}
+	klineStore.RUnlock()
+	sort.Strings(streams)
+	futuresMarket.Subscribe(streams...)
+}
+
+// ws 断开期间的k线可能缺失, 等待下次 http 请求重新填充
+func onKlineDisconnect(streams []string) {
+	klineStore.Lock()
+	defer klineStore.Unlock()
+	for _, stream := range streams {
+		index := strings.Index(stream, "@kline_")
+		if index < 0 {
+			continue
+		}
+		key := klineKey(strings.ToUpper(stream[:index]), stream[index + len("@kline_"):])
+		if cache, ok := klineStore.caches[key]; ok {
+			cache.synced = false
+		}
+	}
+}
+
+func flushPendingKlines() {
+	klineStore.Lock()
+	pending := klineStore.pending
+	klineStore.pending = map[string][]*futures.Kline{}
+	klineStore.Unlock()
+	for key, klines := range pending {
+		index := strings.LastIndex(key, "_")
+		err := SaveLocalKlines(key[:index], key[index + 1:], klines)
+		if err != nil {
+			logs.Error("save futures klines error:", err)
+		}
+	}
+}
+
+// 保存完结的k线到数据库, 已经存在的跳过
+func SaveLocalKlines(symbol string, interval string, klines []*futures.Kline) error {
+	if len(klines) == 0 {
+		return nil
+	}
+	minOpenTime, maxOpenTime := klines[0].OpenTime, klines[0].OpenTime
+	for _, kline := range klines {
+		if kline.OpenTime < minOpenTime {
+			minOpenTime = kline.OpenTime
+		}
+		if kline.OpenTime > maxOpenTime {
+			maxOpenTime = kline.OpenTime
+		}
+	}
+	o := orm.NewOrm()
+	var exists []models.FuturesKline
+	_, err := o.QueryTable("futures_klines").
+		Filter("symbol", symbol).
+		Filter("kline_interval", interval).
+		Filter("open_time__gte", minOpenTime).
+		Filter("open_time__lte", maxOpenTime).
+		All(&exists, "OpenTime")
+	if err != nil {
+		return err
+	}
+	existMap := map[int64]bool{}
+	for _, item := range exists {
+		existMap[item.OpenTime] = true
+	}
+	items := []models.FuturesKline{}
+	for _, kline := range klines {
+		if existMap[kline.OpenTime] {
+			continue
+		}
+		existMap[kline.OpenTime] = true
+		items = append(items, models.FuturesKline{
+			Symbol: symbol,
+			KlineInterval: interval,
+			OpenTime: kline.OpenTime,
+			CloseTime: kline.CloseTime,
+			Open: kline.Open,
+			High: kline.High,
+			Low: kline.Low,
+			Close: kline.Close,
+			Volume: kline.Volume,
+			QuoteAssetVolume: kline.QuoteAssetVolume,
+			TradeNum: kline.TradeNum,
+		})
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	_, err = o.InsertMulti(100, items)
+	return err
+}
+
+// 数据库中一段时间内的k线(时间升序)
+// @param startTime 毫秒时间戳
+// @param endTime 毫秒时间戳
+func GetLocalKlineData(symbol string, interval string, startTime int64, endTime int64) (klines []*futures.Kline, err error) {
+	var items []models.FuturesKline
+	_, err = orm.NewOrm().QueryTable("futures_klines").
+		Filter("symbol", symbol).
+		Filter("kline_interval", interval).
+		Filter("open_time__gte", startTime).
+		Filter("open_time__lte", endTime).
+		OrderBy("open_time").
+		Limit(-1).
+		All(&items)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		klines = append(klines, &futures.Kline{
+			OpenTime: item.OpenTime,
+			Open: item.Open,
+			High: item.High,
+			Low: item.Low,
+			Close: item.Close,
+			Volume: item.Volume,
+			CloseTime: item.CloseTime,
+			QuoteAssetVolume: item.QuoteAssetVolume,
+			TradeNum: item.TradeNum,
+		})
+	}
+	return klines, nil
+}
+
+// 获取一段时间内的历史k线(时间升序), 优先使用数据库, 数据库不完整时请求 http api 并保存
+func GetHistoryKlineDataCached(symbol string, interval string, startTime int64, endTime int64) (klines []*futures.Kline, err error) {
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	ms := duration.Milliseconds()
+	startTime = startTime / ms * ms
+	endTime = endTime / ms * ms
+	local, err := GetLocalKlineData(symbol, interval, startTime, endTime)
+	if err == nil && int64(len(local)) == (endTime - startTime) / ms + 1 {
+		return local, nil
+	}
+	klines, err = GetHistoryKlineData(symbol, interval, startTime, endTime + ms - 1)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UnixMilli()
+	closed := []*futures.Kline{}
+	for _, kline := range klines {
+		if kline.CloseTime < now {
+			closed = append(closed, kline)
+		}
+	}
+	if err := SaveLocalKlines(symbol, interval, closed); err != nil {
+		logs.Error("save futures klines error:", err)
+	}
+	return klines, nil
+}
+
+// 删除过期的k线, 默认不删除, 需要配置 futures_kline_keep_days 开启(每天清理一次)
+func CleanLocalKlines() {
+	if klineKeepDays <= 0 {
+		return
+	}
+	expire := time.Now().Add(-time.Hour * 24 * time.Duration(klineKeepDays)).UnixMilli()
+	_, err := orm.NewOrm().QueryTable("futures_klines").Filter("open_time__lt", expire).Delete()
+	if err != nil {
+		logs.Error("clean futures klines error:", err)
+	}
+}
@@ -0,0 +1,552 @@
+package binance
+
+import (
+	"encoding/json"
+	"go_binance_futures/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	marketStreamMax = 200 // 每个连接最多订阅的数量
+	marketReadLimit = 1 << 22 // 全市场 ticker 的消息比较大
+	marketReadTimeout = time.Minute * 5 // 超过这个时间没有收到消息(包括 ping)时重新连接
+	marketMinBackoff = time.Second
+	marketMaxBackoff = time.Minute
+	marketStableTime = time.Minute
+	marketPriceStaleMs = 60 * 1000 // 超过这个时间没有收到推送, 缓存的价格失效
+)
+
+var marketFlushInterval = config.DefaultInt("ws::market_flush_interval", 3)
+
+// 行情 ws 单个连接的状态
+type MarketShardHealth struct {
+	Id int `json:"id"`
+	Streams int `json:"streams"` // 订阅的数量
+	Connected bool `json:"connected"`
+	ConnectTime int64 `json:"connect_time"`
+	DisconnectTime int64 `json:"disconnect_time"`
+	LastEventTime int64 `json:"last_event_time"`
+	Messages int64 `json:"messages"` // 收到的消息数量
+	Connects int64 `json:"connects"`
+	Reconnects int64 `json:"reconnects"`
+	Backoff int64 `json:"backoff"` // 当前的重连间隔(秒)
+	LastError string `json:"last_error"`
+	LastErrorTime int64 `json:"last_error_time"`
+}
+
+type MarketStreamHealth struct {
+	Name string `json:"name"`
+	Shards []MarketShardHealth `json:"shards"`
+}
+
+// 多路复用的行情 ws, 一个连接(combined stream)订阅多个 stream, 超过 200 个时新建连接, 断开后退避重连
+type MarketStream struct {
+	name string
+	endpoint func() string // combined stream 地址, ex: wss://fstream.binance.com/stream?streams=
+	handler func(stream string, data []byte)
+	lock sync.Mutex
+	shards []*marketShard
+	subscribed map[string]bool
+	disconnectHandlers []func(streams []string)
+}
+
+type marketShard struct {
+	health MarketShardHealth
+	streams []string
+	conn *websocket.Conn
+	writeLock sync.Mutex
+	requestId int64
+	messages int64 // atomic
+	lastEventTime int64 // atomic
+}
+
+type combinedMessage struct {
+	Stream string `json:"stream"`
+	Data json.RawMessage `json:"data"`
+}
+
+func NewMarketStream(name string, endpoint func() string, handler func(stream string, data []byte)) *MarketStream {
+	return &MarketStream{
+		name: name,
+		endpoint: endpoint,
+		handler: handler,
+		subscribed: map[string]bool{},
+	}
+}
+
+// 注册连接断开的回调(ex: k线缓存不再连续)
+func (m *MarketStream) OnDisconnect(handler func(streams []string)) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.disconnectHandlers = append(m.disconnectHandlers, handler)
+}
+
+// 订阅, 已经订阅的跳过, 已经连接的发送 SUBSCRIBE, 没有空位时新建连接
+func (m *MarketStream) Subscribe(streams ...string) {
+	m.lock.Lock()
+	added := map[*marketShard][]string{}
+	conns := map[*marketShard]*websocket.Conn{}
+	newShards := []*marketShard{}
+	for _, stream := range streams {
+		if stream == "" || m.subscribed[stream] {
+			continue
+		}
+		m.subscribed[stream] = true
+		var shard *marketShard
+		if len(m.shards) > 0 && len(m.shards[len(m.shards) - 1].streams) < marketStreamMax {
+			shard = m.shards[len(m.shards) - 1]
+		} else {
+			shard = &marketShard{health: MarketShardHealth{Id: len(m.shards)}}
+			m.shards = append(m.shards, shard)
+			newShards = append(newShards, shard)
+		}
+		shard.streams = append(shard.streams, stream)
+		if shard.conn != nil {
+			added[shard] = append(added[shard], stream)
+			conns[shard] = shard.conn
+		}
+	}
+	m.lock.Unlock()
+
+	for shard, streams := range added {
+		if err := shard.subscribe(conns[shard], streams); err != nil {
+			logs.Error(m.name + " market ws subscribe error:", err) // 连接断开, 重连时会重新订阅
+		}
+	}
+	for _, shard := range newShards {
+		go m.run(shard)
+	}
+}
+
+func (m *MarketStream) Health() MarketStreamHealth {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	health := MarketStreamHealth{Name: m.name, Shards: []MarketShardHealth{}}
+	for _, shard := range m.shards {
+		item := shard.health
+		item.Streams = len(shard.streams)
+		item.Messages = atomic.LoadInt64(&shard.messages)
+		item.LastEventTime = atomic.LoadInt64(&shard.lastEventTime)
+		health.Shards = append(health.Shards, item)
+	}
+	return health
+}
+
+// 一直保持连接, 断开后退避重连
+func (m *MarketStream) run(shard *marketShard) {
+	backoff := time.Duration(0)
+	for {
+		connectTime, err := m.serve(shard)
+		m.lock.Lock()
+		if err != nil {
+			logs.Error(m.name + " market ws error:", err)
+			shard.health.LastError = err.Error()
+			shard.health.LastErrorTime = time.Now().UnixMilli()
+		}
+		stable := !connectTime.IsZero() && time.Since(connectTime) >= marketStableTime
+		backoff = NextBackoff(backoff, stable, marketMinBackoff, marketMaxBackoff)
+		shard.health.Backoff = int64(backoff / time.Second)
+		m.lock.Unlock()
+		time.Sleep(backoff)
+	}
+}
+
+// 建立一次连接, 直到断开, 返回连接成功的时间
+func (m *MarketStream) serve(shard *marketShard) (connectTime time.Time, err error) {
+	m.lock.Lock()
+	streams := append([]string{}, shard.streams...)
+	m.lock.Unlock()
+
+	dialer := websocket.Dialer{Proxy: http.ProxyFromEnvironment, HandshakeTimeout: 45 * time.Second}
+	if proxy_url != "" {
+		proxy, err := url.Parse(proxy_url)
+		if err != nil {
+			return connectTime, err
+		}
+		dialer.Proxy = http.ProxyURL(proxy)
+	}
+	conn, _, err := dialer.Dial(m.endpoint() + strings.Join(streams, "/"), nil)
+	if err != nil {
+		return connectTime, err
+	}
+	conn.SetReadLimit(marketReadLimit)
+	conn.SetReadDeadline(time.Now().Add(marketReadTimeout))
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(marketReadTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second * 10))
+	})
+
+	connectTime = time.Now()
+	m.lock.Lock()
+	shard.conn = conn
+	shard.health.Connected = true
+	shard.health.ConnectTime = connectTime.UnixMilli()
+	shard.health.Connects++
+	if shard.health.Connects > 1 {
+		shard.health.Reconnects++
+	}
+	extra := append([]string{}, shard.streams[len(streams):]...) // 连接期间新增的订阅
+	m.lock.Unlock()
+	logs.Info(m.name + " market ws connected:", len(streams) + len(extra), "streams")
+	if len(extra) > 0 {
+		if err := shard.subscribe(conn, extra); err != nil {
+			logs.Error(m.name + " market ws subscribe error:", err)
+		}
+	}
+
+	for {
+		_, message, readErr := conn.ReadMessage()
+		if readErr != nil {
+			err = readErr
+			break
+		}
+		conn.SetReadDeadline(time.Now().Add(marketReadTimeout))
+		var combined combinedMessage
+		if json.Unmarshal(message, &combined) != nil || combined.Stream == "" {
+			continue // 订阅的响应
+		}
+		atomic.AddInt64(&shard.messages, 1)
+		atomic.StoreInt64(&shard.lastEventTime, time.Now().UnixMilli())
+		m.handler(combined.Stream, combined.Data)
+	}
+	conn.Close()
+
+	m.lock.Lock()
+	shard.conn = nil
+	shard.health.Connected = false
+	shard.health.DisconnectTime = time.Now().UnixMilli()
+	streams = append([]string{}, shard.streams...)
+	handlers := m.disconnectHandlers
+	m.lock.Unlock()
+	for _, handler := range handlers {
+		handler(streams)
+	}
+	return connectTime, err
+}
+
+func (shard *marketShard) subscribe(conn *websocket.Conn, streams []string) error {
+	shard.writeLock.Lock()
+	defer shard.writeLock.Unlock()
+	shard.requestId++
+	return conn.WriteJSON(map[string]interface{}{
+		"method": "SUBSCRIBE",
+		"params": streams,
+		"id": shard.requestId,
+	})
+}
+
+// 最新行情, ticker(24小时变化), 标记价格和最优挂单
+type MarketPrice struct {
+	Symbol string `json:"symbol"`
+	Close string `json:"close"`
+	Open string `json:"open"`
+	High string `json:"high"`
+	Low string `json:"low"`
+	PercentChange string `json:"percent_change"`
+	BaseVolume string `json:"base_volume"` // 成交量
+	QuoteVolume string `json:"quote_volume"` // 成交额
+	CloseQty string `json:"close_qty"` // 最新成交价格上的成交量
+	TradeCount int64 `json:"trade_count"` // 成交数
+	UpdateTime int64 `json:"update_time"` // ticker 推送的时间
+	MarkPrice string `json:"mark_price"`
+	IndexPrice string `json:"index_price"`
+	FundingRate string `json:"funding_rate"`
+	NextFundingTime int64 `json:"next_funding_time"`
+	BidPrice string `json:"bid_price"`
+	BidQty string `json:"bid_qty"`
+	AskPrice string `json:"ask_price"`
+	AskQty string `json:"ask_qty"`
+	ReceiveTime int64 `json:"receive_time"` // 最近一次收到推送的时间
+	TickerTime int64 `json:"ticker_time"` // 最近一次收到 ticker 的时间
+	MarkTime int64 `json:"mark_time"` // 最近一次收到标记价格的时间
+	BookTime int64 `json:"book_time"` // 最近一次收到最优挂单的时间
+}
+
+// 内存中的最新行情, ticker 变化的币种定时批量写入数据库
+type PriceCache struct {
+	lock sync.RWMutex
+	prices map[string]*MarketPrice
+	dirty map[string]bool
+}
+
+func NewPriceCache() *PriceCache {
+	return &PriceCache{prices: map[string]*MarketPrice{}, dirty: map[string]bool{}}
+}
+
+func (c *PriceCache) get(symbol string) *MarketPrice {
+	price, ok := c.prices[symbol]
+	if !ok {
+		price = &MarketPrice{Symbol: symbol}
+		c.prices[symbol] = price
+	}
+	price.ReceiveTime = time.Now().UnixMilli()
+	return price
+}
+
+// 更新 ticker 的字段
+func (c *PriceCache) SetTicker(ticker MarketPrice) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	price := c.get(ticker.Symbol)
+	price.Close, price.Open, price.High, price.Low = ticker.Close, ticker.Open, ticker.High, ticker.Low
+	price.PercentChange, price.BaseVolume, price.QuoteVolume = ticker.PercentChange, ticker.BaseVolume, ticker.QuoteVolume
+	price.CloseQty, price.TradeCount, price.UpdateTime = ticker.CloseQty, ticker.TradeCount, ticker.UpdateTime
+	price.TickerTime = price.ReceiveTime
+	if ticker.BidPrice != "" {
+		price.BidPrice, price.BidQty, price.AskPrice, price.AskQty = ticker.BidPrice, ticker.BidQty, ticker.AskPrice, ticker.AskQty
+		price.BookTime = price.ReceiveTime
+	}
+	c.dirty[ticker.Symbol] = true
+}
+
+func (c *PriceCache) SetMarkPrice(symbol string, markPrice string, indexPrice string, fundingRate string, nextFundingTime int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	price := c.get(symbol)
+	price.MarkPrice, price.IndexPrice, price.FundingRate, price.NextFundingTime = markPrice, indexPrice, fundingRate, nextFundingTime
+	price.MarkTime = price.ReceiveTime
+}
+
+func (c *PriceCache) SetBookTicker(symbol string, bidPrice string, bidQty string, askPrice string, askQty string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	price := c.get(symbol)
+	price.BidPrice, price.BidQty, price.AskPrice, price.AskQty = bidPrice, bidQty, askPrice, askQty
+	price.BookTime = price.ReceiveTime
+}
+
+// 最新行情, 超过 60 秒没有推送时返回 false
+// ticker, 标记价格和最优挂单分别判断, 超过 60 秒没有推送的部分返回空值(ex: 只有标记价格在推送时 Close 为空)
+func (c *PriceCache) Get(symbol string) (MarketPrice, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	cached, ok := c.prices[symbol]
+	now := time.Now().UnixMilli()
+	if !ok || now - cached.ReceiveTime > marketPriceStaleMs {
+		return MarketPrice{}, false
+	}
+	price := *cached
+	if now - price.TickerTime > marketPriceStaleMs {
+		price.Close, price.Open, price.High, price.Low = "", "", "", ""
+		price.PercentChange, price.BaseVolume, price.QuoteVolume = "", "", ""
+		price.CloseQty, price.TradeCount, price.UpdateTime = "", 0, 0
+	}
+	if now - price.MarkTime > marketPriceStaleMs {
+		price.MarkPrice, price.IndexPrice, price.FundingRate, price.NextFundingTime = "", "", "", 0
+	}
+	if now - price.BookTime > marketPriceStaleMs {
+		price.BidPrice, price.BidQty, price.AskPrice, price.AskQty = "", "", "", ""
+	}
+	return price, true
+}
+
+// 最新成交价格
+func (c *PriceCache) GetLatestPrice(symbol string) (float64, bool) {
+	price, ok := c.Get(symbol)
+	if !ok || price.Close == "" {
+		return 0, false
+	}
+	close, err := strconv.ParseFloat(price.Close, 64)
+	return close, err == nil
+}
+
+// 取出 ticker 变化的币种
+func (c *PriceCache) TakeDirty() []MarketPrice {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	prices := []MarketPrice{}
+	for symbol := range c.dirty {
+		prices = append(prices, *c.prices[symbol])
+	}
+	c.dirty = map[string]bool{}
+	return prices
+}
+
+func (c *PriceCache) markDirty(prices []MarketPrice) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, price := range prices {
+		c.dirty[price.Symbol] = true
+	}
+}
+
+// ticker 变化的币种在一个事务中批量写入数据库(symbols, spot_symbols)
+func (c *PriceCache) Flush(table string) (int, error) {
+	prices := c.TakeDirty()
+	if len(prices) == 0 {
+		return 0, nil
+	}
+	err := flushPrices(table, prices)
+	if err != nil {
+		c.markDirty(prices) // 下次重新写入
+		return 0, err
+	}
+	return len(prices), nil
+}
+
+func flushPrices(table string, prices []MarketPrice) error {
+	tx, err := orm.NewOrm().Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Raw("UPDATE `" + table + "` set `percentChange` = ?, `close` = ?, `open` = ?, `low` = ?, `high` = ?, `updateTime` = ?, `baseVolume` = ?, `quoteVolume` = ?, `closeQty` = ?,  `tradeCount` = ?, `lastClose` = close, `lastUpdateTime` = updateTime WHERE `symbol` = ?").Prepare()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, price := range prices {
+		_, err = stmt.Exec(
+			price.PercentChange,
+			price.Close,
+			price.Open,
+			price.Low,
+			price.High,
+			price.UpdateTime,
+			price.BaseVolume,
+			price.QuoteVolume,
+			price.CloseQty,
+			price.TradeCount,
+			price.Symbol,
+		)
+		if err != nil {
+			stmt.Close()
+			tx.Rollback()
+			return err
+		}
+	}
+	stmt.Close()
+	return tx.Commit()
+}
+
+// 定时把最新行情写入数据库, enable 为 false 时不写入(首页的 websocket 开关)
+func RunPriceFlush(name string, cache *PriceCache, table string, enable func() bool) {
+	interval := marketFlushInterval
+	if interval <= 0 {
+		interval = 3
+	}
+	enabled := false
+	for {
+		time.Sleep(time.Second * time.Duration(interval))
+		if enable() != enabled {
+			enabled = !enabled
+			if enabled {
+				logs.Info(name + " ws start")
+			} else {
+				logs.Info(name + " ws stop")
+			}
+		}
+		if !enabled {
+			continue
+		}
+		if _, err := cache.Flush(table); err != nil {
+			logs.Error(name + " ws save price error:", err)
+		}
+	}
+}
+
+// 合约行情, 订阅的 stream 由 ws::futures_market_streams 配置(ticker,mark_price,book_ticker), k线由k线同步服务按需订阅
+// 全市场的最优挂单(!bookTicker)每次挂单变化都会推送, 消息量很大, 默认不订阅
+var futuresMarketStreams = config.DefaultString("ws::futures_market_streams", "ticker,mark_price")
+var futuresPrices = NewPriceCache()
+var futuresMarket = NewMarketStream("futures", func() string {
+	if futures.UseTestnet {
+		return "wss://stream.binancefuture.com/stream?streams="
+	}
+	return "wss://fstream.binance.com/stream?streams="
+}, HandleFuturesMarketMessage)
+
+// 分发合约行情推送
+func HandleFuturesMarketMessage(stream string, data []byte) {
+	switch {
+		case stream == "!ticker@arr":
+			var event futures.WsAllMarketTickerEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				logs.Error("futures ticker parse error:", err)
+				return
+			}
+			for _, ticker := range event {
+				futuresPrices.SetTicker(MarketPrice{
+					Symbol: ticker.Symbol,
+					Close: ticker.ClosePrice,
+					Open: ticker.OpenPrice,
+					High: ticker.HighPrice,
+					Low: ticker.LowPrice,
+					PercentChange: ticker.PriceChangePercent,
+					BaseVolume: ticker.BaseVolume,
+					QuoteVolume: ticker.QuoteVolume,
+					CloseQty: ticker.CloseQty,
+					TradeCount: ticker.TradeCount,
+					UpdateTime: ticker.Time,
+				})
+			}
+		case strings.HasPrefix(stream, "!markPrice@arr"):
+			var event futures.WsAllMarkPriceEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				logs.Error("futures mark price parse error:", err)
+				return
+			}
+			for _, item := range event {
+				futuresPrices.SetMarkPrice(item.Symbol, item.MarkPrice, item.IndexPrice, item.FundingRate, item.NextFundingTime)
+			}
+		case stream == "!bookTicker":
+			var event futures.WsBookTickerEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				logs.Error("futures book ticker parse error:", err)
+				return
+			}
+			futuresPrices.SetBookTicker(event.Symbol, event.BestBidPrice, event.BestBidQty, event.BestAskPrice, event.BestAskQty)
+		case strings.Contains(stream, "@kline_"):
+			event := new(futures.WsKlineEvent)
+			if err := json.Unmarshal(data, event); err != nil {
+				logs.Error("futures kline parse error:", err)
+				return
+			}
+			onKlineEvent(event)
+	}
+}
+
+// 启动合约行情 ws, 代替之前每次推送都直接写数据库的 UpdateCoinByWs
+// 最新行情保存在内存中, 首页开启 websocket 时定时批量写入 symbols 表
+func StartFuturesMarket(systemConfig *models.Config) {
+	streams := []string{}
+	for _, name := range strings.Split(futuresMarketStreams, ",") {
+		switch strings.TrimSpace(name) {
+			case "ticker":
+				streams = append(streams, "!ticker@arr")
+			case "mark_price":
+				streams = append(streams, "!markPrice@arr@1s")
+			case "book_ticker":
+				streams = append(streams, "!bookTicker")
+		}
+	}
+	futuresMarket.Subscribe(streams...)
+	RunPriceFlush("futures", futuresPrices, "symbols", func() bool {
+		return systemConfig.WsFuturesEnable == 1
+	})
+}
+
+// ws 推送的最新行情(ticker, 标记价格, 最优挂单), 超过 60 秒没有推送时返回 false, 超过 60 秒没有推送的部分为空
+func GetFuturesPrice(symbol string) (MarketPrice, bool) {
+	return futuresPrices.Get(symbol)
+}
+
+// ws 推送的最新成交价格, 策略可以代替请求 http api
+func GetFuturesLatestPrice(symbol string) (float64, bool) {
+	return futuresPrices.GetLatestPrice(symbol)
+}
+
+func GetFuturesMarketHealth() MarketStreamHealth {
+	return futuresMarket.Health()
+}
@@ -0,0 +1,306 @@
+package binance
+
+import (
+	"errors"
+	"go_binance_futures/models"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	userDataMinBackoff = time.Second // 重连的最小间隔
+	userDataMaxBackoff = time.Minute // 重连的最大间隔
+	userDataStableTime = time.Minute // 连接超过 1 分钟后重连间隔重置
+	userDataKeepalive = time.Minute * 20 // listenKey 1小时过期， 20 分钟延长一次
+)
+
+// 用户数据 ws 的连接状态
+type UserDataHealth struct {
+	Running bool `json:"running"` // 是否已经启动
+	Connected bool `json:"connected"`
+	ConnectTime int64 `json:"connect_time"` // 最近一次连接成功的时间
+	DisconnectTime int64 `json:"disconnect_time"` // 最近一次断开的时间
+	LastEventTime int64 `json:"last_event_time"` // 最近一次收到推送的时间
+	Connects int64 `json:"connects"` // 连接成功的次数
+	Reconnects int64 `json:"reconnects"` // 断开后重连成功的次数
+	ListenKeys int64 `json:"listen_keys"` // 获取 listenKey 的次数
+	KeyExpired int64 `json:"key_expired"` // listenKey 过期的次数
+	KeepaliveErrors int64 `json:"keepalive_errors"` // 延长 listenKey 失败的次数
+	Resyncs int64 `json:"resyncs"` // 重连后调用接口同步的次数
+	Backoff int64 `json:"backoff"` // 当前的重连间隔(秒)
+	LastError string `json:"last_error"`
+	LastErrorTime int64 `json:"last_error_time"`
+}
+
+type userDataStream struct {
+	lock sync.Mutex
+	health UserDataHealth
+	listenKey string
+	restartC chan struct{} // listenKey 过期或者延长失败时重新连接
+}
+
+var userData = &userDataStream{restartC: make(chan struct{}, 1)}
+
+var orderUpdateHandlers []func(order futures.WsOrderTradeUpdate)
+var userDataResyncHandlers []func()
+
+// 注册订单更新的回调, ws 用户数据推送 ORDER_TRADE_UPDATE 时调用(ex: 网格成交后挂反向单)
+func OnOrderUpdate(handler func(order futures.WsOrderTradeUpdate)) {
+	orderUpdateHandlers = append(orderUpdateHandlers, handler)
+}
+
+// 注册重连后的回调, 用于调用接口同步断开期间漏掉的推送
+func OnUserDataResync(handler func()) {
+	userDataResyncHandlers = append(userDataResyncHandlers, handler)
+}
+
+func GetUserDataHealth() UserDataHealth {
+	userData.lock.Lock()
+	defer userData.lock.Unlock()
+	return userData.health
+}
+
+// @see https://binance-docs.github.io/apidocs/futures/cn/#listenkey-user_stream
+// 不能实时推送仓位信息，只有仓位变化才会推送, 结合查询接口联合使用
+// 断开后按照退避间隔重连, listenKey 过期时重新获取, 重连后调用接口同步断开期间的数据
+func WsUserData() {
+	userData.lock.Lock()
+	if userData.health.Running {
+		userData.lock.Unlock()
+		return
+	}
+	userData.health.Running = true
+	userData.lock.Unlock()
+	logs.Info("futures_user_data ws start: auto update db futures position")
+
+	go userData.keepalive()
+	backoff := time.Duration(0)
+	for {
+		connectTime, err := userData.serve()
+		if err != nil {
+			userData.setError(err)
+		}
+		stable := !connectTime.IsZero() && time.Since(connectTime) >= userDataStableTime
+		backoff = NextBackoff(backoff, stable, userDataMinBackoff, userDataMaxBackoff)
+		userData.lock.Lock()
+		userData.health.Backoff = int64(backoff / time.Second)
+		userData.lock.Unlock()
+		time.Sleep(backoff)
+	}
+}
+
+// 重连间隔, 每次失败翻倍, 不超过 max, 连接稳定后重置为 min
+func NextBackoff(backoff time.Duration, stable bool, min time.Duration, max time.Duration) time.Duration {
+	if stable || backoff < min {
+		return min
+	}
+	if backoff * 2 > max {
+		return max
+	}
+	return backoff * 2
+}
+
+// 建立一次连接, 直到断开或者需要重新连接, 返回连接成功的时间
+func (stream *userDataStream) serve() (connectTime time.Time, err error) {
+	listenKey, err := stream.getListenKey()
+	if err != nil {
+		return connectTime, err
+	}
+	doneC, stopC, err := futures.WsUserDataServe(listenKey, handleUserDataEvent, stream.setError)
+	if err != nil {
+		stream.lock.Lock()
+		stream.listenKey = "" // 连接失败时下次重新获取 listenKey
+		stream.lock.Unlock()
+		return connectTime, err
+	}
+	connectTime = time.Now()
+	stream.lock.Lock()
+	stream.health.Connected = true
+	stream.health.ConnectTime = connectTime.UnixMilli()
+	stream.health.Connects++
+	reconnect := stream.health.Connects > 1
+	if reconnect {
+		stream.health.Reconnects++
+	}
+	stream.lock.Unlock()
+
+	select {
+		case <-stream.restartC: // 清空之前的重连信号
+		default:
+	}
+	if reconnect {
+		logs.Info("futures_user_data ws reconnected, resync by api")
+		stream.resync()
+	}
+
+	select {
+		case <-doneC:
+		case <-stream.restartC:
+			close(stopC)
+			<-doneC
+	}
+	stream.lock.Lock()
+	stream.health.Connected = false
+	stream.health.DisconnectTime = time.Now().UnixMilli()
+	stream.lock.Unlock()
+	logs.Warn("futures_user_data ws disconnected")
+	return connectTime, nil
+}
+
+// 使用当前的 listenKey, 过期后重新获取
+func (stream *userDataStream) getListenKey() (string, error) {
+	stream.lock.Lock()
+	listenKey := stream.listenKey
+	stream.lock.Unlock()
+	if listenKey != "" {
+		return listenKey, nil
+	}
+	listenKey, err := GetListenKey()
+	if err != nil {
+		return "", err
+	}
+	if listenKey == "" {
+		return "", errors.New("empty listenKey")
+	}
+	stream.lock.Lock()
+	stream.listenKey = listenKey
+	stream.health.ListenKeys++
+	stream.lock.Unlock()
+	return listenKey, nil
+}
+
+// listenKey 失效, 下次连接时重新获取
+func (stream *userDataStream) expireListenKey() {
+	stream.lock.Lock()
+	stream.listenKey = ""
+	stream.health.KeyExpired++
+	stream.lock.Unlock()
+	select {
+		case stream.restartC <- struct{}{}:
+		default:
+	}
+}
+
+// 只有一个延长 listenKey 的协程, 延长失败时(已经过期)重新获取并连接
+func (stream *userDataStream) keepalive() {
+	for {
+		time.Sleep(userDataKeepalive)
+		stream.lock.Lock()
+		listenKey := stream.listenKey
+		stream.lock.Unlock()
+		if listenKey == "" {
+			continue
+		}
+		if err := UpdateListenKey(listenKey); err != nil {
+			logs.Error("futures_user_data keepalive listenKey error:", err.Error())
+			stream.setError(err)
+			stream.lock.Lock()
+			stream.health.KeepaliveErrors++
+			stream.lock.Unlock()
+			stream.expireListenKey()
+		}
+	}
+}
+
+func (stream *userDataStream) resync() {
+	for _, handler := range userDataResyncHandlers {
+		handler()
+	}
+	stream.lock.Lock()
+	stream.health.Resyncs++
+	stream.lock.Unlock()
+}
+
+func (stream *userDataStream) setError(err error) {
+	logs.Error("futures_user_data ws error:", err)
+	stream.lock.Lock()
+	stream.health.LastError = err.Error()
+	stream.health.LastErrorTime = time.Now().UnixMilli()
+	stream.lock.Unlock()
+}
+
+func handleUserDataEvent(event *futures.WsUserDataEvent) {
+	userData.lock.Lock()
+	userData.health.LastEventTime = time.Now().UnixMilli()
+	userData.lock.Unlock()
+
+	o := orm.NewOrm()
+	if (event.Event == "ACCOUNT_UPDATE") {
+		for _, v := range event.AccountUpdate.Positions {
+			floatAmount, _ := strconv.ParseFloat(v.Amount, 64)
+			var position models.FuturesPosition
+			var symbols models.Symbols
+			o.QueryTable("symbols").Filter("symbol", v.Symbol).One(&symbols)
+			o.QueryTable("futures_positions").Filter("symbol", v.Symbol).Filter("side", v.Side).One(&position)
+			position.Symbol = v.Symbol
+			position.Side = string(v.Side)
+			position.Amount = strconv.FormatFloat(floatAmount, 'f', -1, 64) // 清仓时推送持仓数量为 0
+			position.MarginType = string(v.MarginType)
+			position.Leverage = symbols.Leverage // 杠杆倍数没在这里推送(默认为1), 只能暂时调用接口获取 TODO
+			position.IsolatedWallet = v.IsolatedWallet
+			position.EntryPrice = v.EntryPrice
+			position.MarkPrice = v.MarkPrice
+			position.UnrealizedProfit = v.UnrealizedPnL
+			position.AccumulatedRealized = v.AccumulatedRealized
+			position.MaintenanceMarginRequired = v.MaintenanceMarginRequired
+			position.UpdateTime = event.Time
+			if position.ID == 0 {
+				position.CreateTime = event.Time
+				o.Insert(&position)
+			} else {
+				o.Update(&position)
+			}
+		}
+	}  else if (event.Event == "ORDER_TRADE_UPDATE") {
+		order := event.OrderTradeUpdate
+		var orderModel models.FuturesOrder
+		o.QueryTable("futures_orders").Filter("order_id", order.ID).One(&orderModel)
+		orderModel.Symbol = order.Symbol
+		orderModel.ClientOrderId = order.ClientOrderID
+		orderModel.OrderId = strconv.FormatInt(order.ID, 10)
+		orderModel.Side = string(order.Side)
+		orderModel.PositionSide = string(order.PositionSide)
+		orderModel.Type = string(order.Type)
+		orderModel.Status = string(order.Status)
+		orderModel.Price = order.OriginalPrice
+		orderModel.OrigQty = order.OriginalQty
+		orderModel.ExecutedQty = order.AccumulatedFilledQty
+		orderModel.AveragePrice = order.AveragePrice
+		orderModel.StopPrice = order.StopPrice
+		orderModel.CommissionAsset = order.CommissionAsset
+		orderModel.Commission = order.Commission
+		orderModel.RealizedPnL = order.RealizedPnL
+
+		orderModel.UpdateTime = event.Time
+		if orderModel.ID == 0 {
+			orderModel.CreateTime = event.Time
+			o.Insert(&orderModel)
+		} else {
+			o.Update(&orderModel)
+		}
+		for _, handler := range orderUpdateHandlers {
+			handler(order)
+		}
+	} else if (event.Event == "ACCOUNT_CONFIG_UPDATE") {
+		config := event.AccountConfigUpdate
+		if config.Leverage == 0 {
+			// 其它推送不处理
+			return
+		}
+		var positionModels []models.FuturesPosition
+		o.QueryTable("futures_positions").Filter("symbol", config.Symbol).All(&positionModels)
+		for _, positionModel := range positionModels {
+			positionModel.Leverage = config.Leverage
+			o.Update(&positionModel)
+		}
+	} else if (event.Event == "listenKeyExpired") {
+		// listenKey 过期后不能再延长, 重新获取 listenKey 并连接
+		logs.Info("futures_user_data ws listenKeyExpired")
+		userData.expireListenKey()
+	}
+}
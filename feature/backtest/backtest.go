@@ -0,0 +1,606 @@
+package backtest
+
+import (
+	"errors"
+	"fmt"
+	"go_binance_futures/feature"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/feature/strategy"
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"go_binance_futures/utils"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 回测参数, 为 0 或者空的参数使用 symbols 表中的配置或者默认值
+type Params struct {
+	Symbols      []string `json:"symbols"`       // 回测的币种, 为空时使用所有开启的币种
+	LineStrategy string   `json:"line_strategy"` // 交易策略(line1 ~ line7, custom), 为空时使用系统配置
+	CoinStrategy string   `json:"coin_strategy"` // 选币策略(coin1 ~ coin6), 为空时不选币, 所有币种都尝试开仓
+	Interval     string   `json:"interval"`      // 回放的基础k线周期, 默认 1m
+	StartTime    int64    `json:"start_time"`    // 开始时间(毫秒时间戳)
+	EndTime      int64    `json:"end_time"`      // 结束时间(毫秒时间戳)
+	WarmupDays   int      `json:"warmup_days"`   // 开始前预加载的天数(用于计算指标), 默认 7
+	Balance      float64  `json:"balance"`       // 初始资金, 默认 10000
+	Usdt         float64  `json:"usdt"`          // 每次开仓金额
+	Leverage     int64    `json:"leverage"`      // 合约倍数
+	Profit       float64  `json:"profit"`        // 止盈率%
+	Loss         float64  `json:"loss"`          // 止损率%
+	FeeRate      float64  `json:"fee_rate"`      // 手续费率, 默认 0.0004
+	FundingRate  float64  `json:"funding_rate"`  // 每 8 小时的资金费率
+	MaxCount     int      `json:"max_count"`     // 最大持仓数量
+	LossMaxCount int      `json:"loss_max_count"` // 亏损仓位数量达到后停止开仓
+	AllowLong    *int     `json:"allow_long"`    // 是否允许开多 1:允许 0:不允许, 为空时使用系统配置
+	AllowShort   *int     `json:"allow_short"`   // 是否允许开空 1:允许 0:不允许, 为空时使用系统配置
+
+	Klines map[string][]*futures.Kline `json:"klines,omitempty"` // 导入的k线数据(基础周期), 为空时使用本地保存的k线, 不完整时从币安获取
+	Coins  []*models.Symbols           `json:"-"`                // 币种配置, 为空时从 symbols 表读取
+
+	symbolStrategy bool // 没有指定交易策略时, 币种的独立策略生效
+	limited        bool // 检查时间范围和币种数量(接口调用)
+}
+
+// 回测的成交记录(一次开仓到平仓)
+type Trade struct {
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // LONG, SHORT
+	EntryTime  int64   `json:"entry_time"`
+	ExitTime   int64   `json:"exit_time"`
+	EntryPrice float64 `json:"entry_price"`
+	ExitPrice  float64 `json:"exit_price"`
+	Quantity   float64 `json:"quantity"`
+	Leverage   int64   `json:"leverage"`
+	Fee        float64 `json:"fee"`    // 开仓和平仓的手续费
+	Profit     float64 `json:"profit"` // 扣除手续费后的收益
+	Roi        float64 `json:"roi"`    // 收益率%
+	Reason     string  `json:"reason"` // auto_stop(策略平仓), stop_loss(止损), target_profit(止盈), end(回测结束)
+}
+
+// 资金曲线的点
+type EquityPoint struct {
+	Time   int64   `json:"time"`
+	Equity float64 `json:"equity"`
+}
+
+type Result struct {
+	Trades []Trade        `json:"trades"`
+	Equity []EquityPoint  `json:"equity"`
+	Stats  Stats          `json:"stats"`
+	Params Params         `json:"params"`
+}
+
+// 持仓中的开仓信息
+type openPosition struct {
+	entryTime int64
+	fee       float64
+	peakRoi   float64 // 最高收益率, 用于跟踪止盈
+	peakPrice float64
+	stats     models.FuturesPositionPeak // 持仓期间的统计
+	state     *line.PositionState // 自定义平仓策略的持仓状态, 只保存在内存中
+}
+
+const maxEquityPoints = 500 // 资金曲线最多返回的点数
+
+// 接口和参数优化的回测的时间范围和币种数量限制, 避免长时间阻塞请求和超出 api 频率限制, 0 不限制
+var backtestMaxDays = config.DefaultInt("backtest::max_days", 90)
+var backtestMaxSymbols = config.DefaultInt("backtest::max_symbols", 20)
+
+// 检查回测的时间范围(不包括预热)和币种数量
+func CheckLimits(params Params, symbolCount int) error {
+	if backtestMaxDays > 0 && params.EndTime - params.StartTime > (time.Hour * 24 * time.Duration(backtestMaxDays)).Milliseconds() {
+		return fmt.Errorf("backtest range must be less than %d days", backtestMaxDays)
+	}
+	if backtestMaxSymbols > 0 && symbolCount > backtestMaxSymbols {
+		return fmt.Errorf("backtest symbols must be less than %d", backtestMaxSymbols)
+	}
+	return nil
+}
+
+// 接口调用的回测, 超过时间范围和币种数量限制时返回错误
+func RunLimited(params Params) (result Result, err error) {
+	params.limited = true
+	return Run(params)
+}
+
+func Run(params Params) (result Result, err error) {
+	systemConfig, err := utils.GetSystemConfig()
+	if err != nil && params.LineStrategy == "" {
+		return result, err
+	}
+	params.symbolStrategy = params.LineStrategy == ""
+	params = withDefault(params, systemConfig)
+	if params.StartTime >= params.EndTime {
+		return result, errors.New("start_time must be less than end_time")
+	}
+
+	coins, err := loadCoins(params)
+	if err != nil {
+		return result, err
+	}
+	if len(coins) == 0 {
+		return result, errors.New("no symbols to backtest")
+	}
+	if params.limited {
+		if err := CheckLimits(params, len(coins)); err != nil {
+			return result, err
+		}
+	}
+	klines := params.Klines
+	if len(klines) == 0 {
+		klines, err = loadKlines(coins, params)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	replay, err := NewReplayExchange(params.Interval, klines, params.Balance)
+	if err != nil {
+		return result, err
+	}
+	replay.SetFeeRate(params.FeeRate)
+	replay.LoadSymbols(coinsWithoutPrice(coins))
+	for _, coin := range coins {
+		replay.SetFundingRate(coin.Symbol, params.FundingRate)
+	}
+
+	lineStrategy := feature.GetLineStrategy(params.LineStrategy)
+	var coinStrategy strategy.CoinStrategy
+	if params.CoinStrategy != "" {
+		coinStrategy = feature.GetCoinStrategy(params.CoinStrategy)
+	}
+
+	opened := map[string]*openPosition{} // symbol_side => 开仓信息
+	lastFunding := int64(-1)
+	fundingMs := (time.Hour * 8).Milliseconds()
+	for _, openTime := range replay.Timeline() {
+		replay.Step(openTime)
+		if openTime < params.StartTime {
+			continue // 预热数据, 只用于计算指标
+		}
+		if openTime > params.EndTime {
+			break
+		}
+		// 资金费每 8 小时结算一次
+		if funding := replay.NowTime() / fundingMs; params.FundingRate != 0 && lastFunding != -1 && funding != lastFunding {
+			replay.SettleFunding()
+		}
+		lastFunding = replay.NowTime() / fundingMs
+
+		positionCount, lossCount := closePositions(replay, params, coins, lineStrategy, opened, &result.Trades)
+		openPositions(replay, params, coins, lineStrategy, coinStrategy, opened, positionCount, lossCount)
+
+		account, err := replay.GetFuturesAccount()
+		if err == nil {
+			equity, _ := strconv.ParseFloat(account.TotalMarginBalance, 64)
+			result.Equity = append(result.Equity, EquityPoint{Time: replay.NowTime(), Equity: equity})
+		}
+	}
+
+	// 回测结束, 平掉剩余的仓位
+	positions := getPositions(replay)
+	for _, position := range positions {
+		closePosition(replay, position, params, opened, "end", &result.Trades)
+	}
+	if account, err := replay.GetFuturesAccount(); err == nil && len(result.Equity) > 0 {
+		equity, _ := strconv.ParseFloat(account.TotalMarginBalance, 64)
+		result.Equity[len(result.Equity) - 1].Equity = equity
+	}
+
+	result.Stats = CalculateStats(result.Trades, result.Equity, params.Balance)
+	result.Equity = sampleEquity(result.Equity, maxEquityPoints)
+	params.Klines = nil
+	result.Params = params
+	return result, nil
+}
+
+func withDefault(params Params, systemConfig models.Config) Params {
+	if params.LineStrategy == "" {
+		params.LineStrategy = systemConfig.FutureStrategyTrade
+	}
+	if params.Interval == "" {
+		params.Interval = "1m"
+	}
+	if params.EndTime == 0 {
+		params.EndTime = time.Now().UnixMilli()
+	}
+	if params.StartTime == 0 {
+		params.StartTime = params.EndTime - (time.Hour * 24).Milliseconds()
+	}
+	if params.WarmupDays == 0 {
+		params.WarmupDays = 7
+	}
+	if params.Balance == 0 {
+		params.Balance = 10000
+	}
+	if params.FeeRate == 0 {
+		params.FeeRate = 0.0004
+	}
+	if params.MaxCount == 0 {
+		params.MaxCount = systemConfig.FutureMaxCount
+	}
+	if params.MaxCount == 0 {
+		params.MaxCount = 1
+	}
+	if params.LossMaxCount == 0 {
+		params.LossMaxCount = systemConfig.LossMaxCount
+	}
+	if params.LossMaxCount == 0 {
+		params.LossMaxCount = params.MaxCount
+	}
+	if params.AllowLong == nil {
+		allowLong := systemConfig.FutureAllowLong
+		if systemConfig.ID == 0 {
+			allowLong = 1
+		}
+		params.AllowLong = &allowLong
+	}
+	if params.AllowShort == nil {
+		allowShort := systemConfig.FutureAllowShort
+		if systemConfig.ID == 0 {
+			allowShort = 1
+		}
+		params.AllowShort = &allowShort
+	}
+	return params
+}
+
+// 回测的币种配置, 参数中的值覆盖 symbols 表中的配置
+func loadCoins(params Params) (coins []*models.Symbols, err error) {
+	coins = params.Coins
+	if len(coins) == 0 {
+		query := orm.NewOrm().QueryTable("symbols")
+		if len(params.Symbols) > 0 {
+			query = query.Filter("symbol__in", params.Symbols)
+		} else {
+			query = query.Filter("enable", 1)
+		}
+		_, err = query.All(&coins)
+		if err != nil && len(params.Klines) == 0 {
+			return coins, err
+		}
+	}
+	exist := map[string]bool{}
+	for _, coin := range coins {
+		exist[coin.Symbol] = true
+	}
+	// 导入的k线中有但是表中没有的币种
+	for symbol := range params.Klines {
+		if !exist[symbol] {
+			coins = append(coins, &models.Symbols{Symbol: symbol, StrategyType: "global", Profit: "0", Loss: "0", TickSize: "0.0001", StepSize: "0.001"})
+		}
+	}
+	for _, coin := range coins {
+		if params.Usdt > 0 {
+			coin.Usdt = strconv.FormatFloat(params.Usdt, 'f', -1, 64)
+		}
+		if params.Leverage > 0 {
+			coin.Leverage = params.Leverage
+		}
+		if params.Profit > 0 {
+			coin.Profit = strconv.FormatFloat(params.Profit, 'f', -1, 64)
+		}
+		if params.Loss > 0 {
+			coin.Loss = strconv.FormatFloat(params.Loss, 'f', -1, 64)
+		}
+		if coin.Leverage <= 0 {
+			coin.Leverage = 1
+		}
+		if coin.Usdt == "" || coin.Usdt == "0" {
+			coin.Usdt = "10"
+		}
+	}
+	return coins, nil
+}
+
+// 价格由回放的k线决定, 去掉表中的最新价格
+func coinsWithoutPrice(coins []*models.Symbols) (symbols []models.Symbols) {
+	for _, coin := range coins {
+		symbol := *coin
+		symbol.Close = ""
+		symbols = append(symbols, symbol)
+	}
+	return symbols
+}
+
+func loadKlines(coins []*models.Symbols, params Params) (klines map[string][]*futures.Kline, err error) {
+	klines = map[string][]*futures.Kline{}
+	startTime := params.StartTime - (time.Hour * 24 * time.Duration(params.WarmupDays)).Milliseconds()
+	for _, coin := range coins {
+		items, err := binance.GetHistoryKlineDataCached(coin.Symbol, params.Interval, startTime, params.EndTime)
+		if err != nil {
+			return klines, err
+		}
+		klines[coin.Symbol] = items
+	}
+	return klines, nil
+}
+
+func getPositions(replay *ReplayExchange) (positions []types.FuturesPosition) {
+	res, err := replay.GetPosition(binance.PositionParams{})
+	if err != nil {
+		return positions
+	}
+	for _, position := range res {
+		positionAmtFloat, _ := strconv.ParseFloat(position.PositionAmt, 64)
+		if math.Abs(positionAmtFloat) < 0.0000000001 {
+			continue
+		}
+		leverage, _ := strconv.ParseInt(position.Leverage, 10, 64)
+		positions = append(positions, types.FuturesPosition{
+			Symbol: position.Symbol,
+			Side: position.PositionSide,
+			Amount: position.PositionAmt,
+			MarginType: position.MarginType,
+			Leverage: leverage,
+			IsolatedWallet: position.IsolatedWallet,
+			EntryPrice: position.EntryPrice,
+			MarkPrice: position.MarkPrice,
+			UnrealizedProfit: position.UnRealizedProfit,
+			SourceType: "api",
+		})
+	}
+	return positions
+}
+
+// 和实盘相同的持仓统计, 回测的k线间隔可能较长, 盈利时长按照两次统计的间隔计算
+func updatePositionStats(stats *models.FuturesPositionPeak, roi float64, now int64) {
+	if stats.CheckTime == 0 {
+		stats.MaxRoi = roi
+		stats.MinRoi = roi
+	}
+	if stats.CheckTime > 0 && roi > 0 {
+		stats.ProfitTime += now - stats.CheckTime
+	}
+	stats.CheckTime = now
+	stats.MaxRoi = math.Max(stats.MaxRoi, roi)
+	stats.MinRoi = math.Min(stats.MinRoi, roi)
+}
+
+// 和 StartTrade 相同的平仓逻辑, 返回继续持有的仓位数量和亏损的仓位数量
+func closePositions(replay *ReplayExchange, params Params, coins []*models.Symbols, globalLineStrategy strategy.LineStrategy, opened map[string]*openPosition, trades *[]Trade) (positionCount int, lossCount int) {
+	for _, position := range getPositions(replay) {
+		coin_profit_float64 := 10000.0
+		coin_loss_float64 := 10000.0
+		coin_line_strategy := globalLineStrategy
+		var findCoin *models.Symbols
+		for _, coin := range coins {
+			if coin.Symbol == position.Symbol {
+				findCoin = coin
+				if coin.Profit != "0" && coin.Profit != "" {
+					coin_profit_float64, _ = strconv.ParseFloat(coin.Profit, 64)
+				}
+				if coin.Loss != "0" && coin.Loss != "" {
+					coin_loss_float64, _ = strconv.ParseFloat(coin.Loss, 64)
+				}
+				if coin.StrategyType != "global" && coin.StrategyType != "" && params.symbolStrategy {
+					coin_line_strategy = feature.GetLineStrategy(coin.StrategyType)
+				}
+				break
+			}
+		}
+		nowProfit := positionProfit(position)
+		if nowProfit < -0.1 {
+			lossCount += 1
+		}
+		closeParams := strategy.CloseParams{
+			Symbols: findCoin,
+			Position: position,
+			NowProfit: nowProfit,
+			Exchange: replay,
+		}
+		if open, ok := opened[position.Symbol + "_" + position.Side]; ok {
+			if nowProfit > open.peakRoi {
+				open.peakRoi = nowProfit
+				open.peakPrice, _ = strconv.ParseFloat(position.MarkPrice, 64)
+			}
+			closeParams.PeakProfit = open.peakRoi
+			closeParams.PeakPrice = open.peakPrice
+			updatePositionStats(&open.stats, nowProfit, replay.NowTime())
+			closeParams.Stats = feature.GetPositionStats(open.stats, replay.NowTime())
+			closeParams.State = open.state
+		}
+		if coin_line_strategy.AutoStopOrder(closeParams).Complete {
+			closePosition(replay, position, params, opened, "auto_stop", trades)
+			continue
+		}
+		// 交易所跟踪止盈单按照k线收盘价近似模拟
+		if feature.IsTrailingStop(findCoin, closeParams.PeakProfit, nowProfit) {
+			closePosition(replay, position, params, opened, "trailing_stop", trades)
+			continue
+		}
+		if nowProfit <= -coin_loss_float64 && coin_line_strategy.CanOrderComplete(closeParams).Complete {
+			closePosition(replay, position, params, opened, "stop_loss", trades)
+			continue
+		}
+		if nowProfit >= coin_profit_float64 && coin_line_strategy.CanOrderComplete(closeParams).Complete {
+			closePosition(replay, position, params, opened, "target_profit", trades)
+			continue
+		}
+		positionCount += 1
+	}
+	return positionCount, lossCount
+}
+
+// 当前收益率(正为盈利，负为亏损)
+func positionProfit(position types.FuturesPosition) float64 {
+	positionAmtFloat, _ := strconv.ParseFloat(position.Amount, 64)
+	positionAmtFloatAbs := math.Abs(positionAmtFloat)
+	unRealizedProfit, _ := strconv.ParseFloat(position.UnrealizedProfit, 64)
+	markPrice_float64, _ := strconv.ParseFloat(position.MarkPrice, 64)
+	if positionAmtFloatAbs * markPrice_float64 == 0 {
+		return 0
+	}
+	return (unRealizedProfit / (positionAmtFloatAbs * markPrice_float64)) * float64(position.Leverage) * 100
+}
+
+func closePosition(replay *ReplayExchange, position types.FuturesPosition, params Params, opened map[string]*openPosition, reason string, trades *[]Trade) {
+	positionAmtFloat, _ := strconv.ParseFloat(position.Amount, 64)
+	positionAmtFloatAbs := math.Abs(positionAmtFloat)
+	side := futures.SideTypeSell
+	if position.Side == "SHORT" {
+		side = futures.SideTypeBuy
+	}
+	order, err := replay.CreateOrder(binance.CreateOrderParams{
+		Symbol: position.Symbol,
+		Side: side,
+		PositionSide: futures.PositionSideType(position.Side),
+		Type: futures.OrderTypeMarket,
+		Quantity: positionAmtFloatAbs,
+	})
+	if err != nil {
+		logs.Error("backtest close order error:", err.Error())
+		return
+	}
+	entryPrice, _ := strconv.ParseFloat(position.EntryPrice, 64)
+	exitPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+	direction := 1.0
+	if position.Side == "SHORT" {
+		direction = -1.0
+	}
+	key := position.Symbol + "_" + position.Side
+	trade := Trade{
+		Symbol: position.Symbol,
+		Side: position.Side,
+		ExitTime: replay.NowTime(),
+		EntryPrice: entryPrice,
+		ExitPrice: exitPrice,
+		Quantity: positionAmtFloatAbs,
+		Leverage: position.Leverage,
+		Fee: exitPrice * positionAmtFloatAbs * params.FeeRate,
+		Reason: reason,
+	}
+	if open, ok := opened[key]; ok {
+		trade.EntryTime = open.entryTime
+		trade.Fee += open.fee
+		delete(opened, key)
+	}
+	trade.Profit = (exitPrice - entryPrice) * positionAmtFloatAbs * direction - trade.Fee
+	if entryPrice * positionAmtFloatAbs > 0 {
+		trade.Roi = trade.Profit / (entryPrice * positionAmtFloatAbs) * float64(position.Leverage) * 100
+	}
+	*trades = append(*trades, trade)
+}
+
+// 和 StartTrade 相同的开仓逻辑
+func openPositions(replay *ReplayExchange, params Params, coins []*models.Symbols, globalLineStrategy strategy.LineStrategy, coinStrategy strategy.CoinStrategy, opened map[string]*openPosition, positionCount int, lossCount int) {
+	if lossCount >= params.LossMaxCount || positionCount >= params.MaxCount {
+		return
+	}
+	if *params.AllowLong != 1 && *params.AllowShort != 1 {
+		return
+	}
+	selected := coins
+	if coinStrategy != nil {
+		allCoins, err := replayCoins(replay, coins)
+		if err != nil {
+			return
+		}
+		selected = coinStrategy.SelectCoins(allCoins, replay)
+	}
+	for _, coin := range selected {
+		if positionCount >= params.MaxCount {
+			return
+		}
+		if replay.NowKline(coin.Symbol) == nil {
+			continue
+		}
+		_, hasLong := opened[coin.Symbol + "_LONG"]
+		_, hasShort := opened[coin.Symbol + "_SHORT"]
+		if hasLong || hasShort {
+			continue
+		}
+		coin_line_strategy := globalLineStrategy
+		if coin.StrategyType != "global" && coin.StrategyType != "" && params.symbolStrategy {
+			coin_line_strategy = feature.GetLineStrategy(coin.StrategyType)
+		}
+		openResult := coin_line_strategy.GetCanLongOrShort(strategy.OpenParams{
+			Symbols: coin,
+			Exchange: replay,
+		})
+		positionSide := ""
+		if *params.AllowLong == 1 && openResult.CanLong {
+			positionSide = "LONG"
+		} else if *params.AllowShort == 1 && openResult.CanShort {
+			positionSide = "SHORT"
+		}
+		if positionSide == "" {
+			continue
+		}
+		price, _ := strconv.ParseFloat(replay.NowKline(coin.Symbol).Close, 64)
+		usdt_float64, _ := strconv.ParseFloat(coin.Usdt, 64)
+		quantity := utils.GetTradePrecision((usdt_float64 / price) * float64(coin.Leverage), coin.StepSize)
+		if quantity <= 0 {
+			continue
+		}
+		replay.SetLeverage(coin.Symbol, int(coin.Leverage))
+		side := futures.SideTypeBuy
+		if positionSide == "SHORT" {
+			side = futures.SideTypeSell
+		}
+		order, err := replay.CreateOrder(binance.CreateOrderParams{
+			Symbol: coin.Symbol,
+			Side: side,
+			PositionSide: futures.PositionSideType(positionSide),
+			Type: futures.OrderTypeMarket,
+			Quantity: quantity,
+		})
+		if err != nil {
+			logs.Error("backtest open order error:", err.Error())
+			continue
+		}
+		avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+		opened[coin.Symbol + "_" + positionSide] = &openPosition{
+			entryTime: replay.NowTime(),
+			fee: avgPrice * quantity * params.FeeRate,
+			stats: models.FuturesPositionPeak{CreateTime: replay.NowTime()},
+			state: line.NewMemoryPositionState(coin.Symbol, positionSide, nil),
+		}
+		positionCount += 1
+	}
+}
+
+// 选币策略使用的币种信息(24小时涨跌幅等由回放的k线计算), 保留 symbols 表中的配置
+func replayCoins(replay *ReplayExchange, coins []*models.Symbols) (allCoins []*models.Symbols, err error) {
+	symbols := []string{}
+	coinMap := map[string]*models.Symbols{}
+	for _, coin := range coins {
+		if replay.NowKline(coin.Symbol) != nil {
+			symbols = append(symbols, coin.Symbol)
+			coinMap[coin.Symbol] = coin
+		}
+	}
+	stats, err := line.GetSymbolsFrom(replay, symbols...)
+	if err != nil {
+		return allCoins, err
+	}
+	for _, stat := range stats {
+		coin := *coinMap[stat.Symbol]
+		coin.PercentChange = stat.PercentChange
+		coin.Close = stat.Close
+		coin.Open = stat.Open
+		coin.Low = stat.Low
+		coin.High = stat.High
+		allCoins = append(allCoins, &coin)
+	}
+	return allCoins, nil
+}
+
+func sampleEquity(equity []EquityPoint, max int) []EquityPoint {
+	if len(equity) <= max {
+		return equity
+	}
+	step := float64(len(equity) - 1) / float64(max - 1)
+	sampled := make([]EquityPoint, 0, max)
+	for i := 0; i < max; i++ {
+		sampled = append(sampled, equity[int(math.Round(float64(i) * step))])
+	}
+	return sampled
+}
@@ -0,0 +1,106 @@
+package backtest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// 命令行回测
+// ex: go_binance_futures backtest -symbols BTCUSDT,ETHUSDT -strategy custom -start 2024-01-01 -end 2024-02-01
+func RunCli(args []string) {
+	var (
+		params Params
+		symbols, start, end, klinesFile, output string
+		allowLong, allowShort int
+	)
+	flagSet := flag.NewFlagSet("backtest", flag.ExitOnError)
+	flagSet.StringVar(&symbols, "symbols", "", "币种, 多个用逗号分隔, 为空时使用所有开启的币种")
+	flagSet.StringVar(&params.LineStrategy, "strategy", "", "交易策略 line1 ~ line7, custom, 为空时使用系统配置")
+	flagSet.StringVar(&params.CoinStrategy, "coin-strategy", "", "选币策略 coin1 ~ coin6, 为空时不选币")
+	flagSet.StringVar(&params.Interval, "interval", "1m", "回放的基础k线周期")
+	flagSet.StringVar(&start, "start", "", "开始日期 2006-01-02 或者毫秒时间戳, 默认 24 小时前")
+	flagSet.StringVar(&end, "end", "", "结束日期 2006-01-02 或者毫秒时间戳, 默认当前时间")
+	flagSet.IntVar(&params.WarmupDays, "warmup", 7, "预加载的天数")
+	flagSet.Float64Var(&params.Balance, "balance", 10000, "初始资金")
+	flagSet.Float64Var(&params.Usdt, "usdt", 0, "每次开仓金额, 0 使用币种配置")
+	flagSet.Int64Var(&params.Leverage, "leverage", 0, "合约倍数, 0 使用币种配置")
+	flagSet.Float64Var(&params.Profit, "profit", 0, "止盈率%, 0 使用币种配置")
+	flagSet.Float64Var(&params.Loss, "loss", 0, "止损率%, 0 使用币种配置")
+	flagSet.Float64Var(&params.FeeRate, "fee", 0.0004, "手续费率")
+	flagSet.Float64Var(&params.FundingRate, "funding", 0, "每 8 小时的资金费率")
+	flagSet.IntVar(&params.MaxCount, "max-count", 0, "最大持仓数量, 0 使用系统配置")
+	flagSet.IntVar(&params.LossMaxCount, "loss-max-count", 0, "亏损仓位数量达到后停止开仓, 0 使用系统配置")
+	flagSet.IntVar(&allowLong, "allow-long", -1, "是否允许开多 1:允许 0:不允许, -1 使用系统配置")
+	flagSet.IntVar(&allowShort, "allow-short", -1, "是否允许开空 1:允许 0:不允许, -1 使用系统配置")
+	flagSet.StringVar(&klinesFile, "klines", "", "导入的k线 json 文件, 格式 {\"BTCUSDT\": [kline, ...]}")
+	flagSet.StringVar(&output, "output", "", "结果输出的 json 文件, 为空时输出到终端")
+	flagSet.Parse(args)
+
+	if symbols != "" {
+		params.Symbols = strings.Split(symbols, ",")
+	}
+	if allowLong != -1 {
+		params.AllowLong = &allowLong
+	}
+	if allowShort != -1 {
+		params.AllowShort = &allowShort
+	}
+	var err error
+	if params.StartTime, err = ParseTime(start); err != nil {
+		exit(err)
+	}
+	if params.EndTime, err = ParseTime(end); err != nil {
+		exit(err)
+	}
+	if klinesFile != "" {
+		content, err := os.ReadFile(klinesFile)
+		if err != nil {
+			exit(err)
+		}
+		params.Klines = map[string][]*futures.Kline{}
+		if err = json.Unmarshal(content, &params.Klines); err != nil {
+			exit(err)
+		}
+	}
+
+	result, err := Run(params)
+	if err != nil {
+		exit(err)
+	}
+	content, _ := json.MarshalIndent(result, "", "  ")
+	if output != "" {
+		if err = os.WriteFile(output, content, 0644); err != nil {
+			exit(err)
+		}
+		content, _ = json.MarshalIndent(result.Stats, "", "  ")
+	}
+	fmt.Println(string(content))
+}
+
+// 解析日期(2006-01-02, 2006-01-02 15:04:05) 或者毫秒时间戳, 空字符串返回 0
+func ParseTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UnixMilli(), nil
+		}
+	}
+	var timestamp int64
+	if _, err := fmt.Sscanf(value, "%d", &timestamp); err != nil {
+		return 0, fmt.Errorf("invalid time: %s", value)
+	}
+	return timestamp, nil
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, "backtest error:", err.Error())
+	os.Exit(1)
+}
@@ -0,0 +1,835 @@
+package backtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_binance_futures/feature"
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"go_binance_futures/technology"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 优化的目标, 越大越好
+const (
+	ObjectiveTotalProfit = "total_profit"
+	ObjectiveSharpe = "sharpe"
+	ObjectiveProfitFactor = "profit_factor"
+	ObjectiveWinRate = "win_rate"
+	ObjectiveMaxDrawdown = "max_drawdown" // 最大回撤越小越好
+	ObjectiveProfitDrawdown = "profit_drawdown" // 总收益 / 最大回撤
+)
+
+// 优化任务的状态
+const (
+	OptimizeStatusRunning = "running"
+	OptimizeStatusDone = "done"
+	OptimizeStatusFailed = "failed"
+	OptimizeStatusCanceled = "canceled"
+)
+
+const optimizeMaxJobs = 20 // 内存中最多保留的任务
+const optimizeTopCandidates = 50 // 结果中返回的排名靠前的参数组合
+
+// 参数优化的配置
+type OptimizeParams struct {
+	TemplateId int64 `json:"template_id"` // 优化的策略模板
+	Parameters []OptimizeParameter `json:"parameters"` // 搜索的参数
+	Method string `json:"method"` // grid: 所有组合, random: 随机 samples 组
+	Samples int `json:"samples"` // 随机搜索的组数, 默认 20
+	MaxCandidates int `json:"max_candidates"` // 网格搜索最多的组合数, 默认 200
+	Seed int64 `json:"seed"` // 随机种子, 0 使用当前时间
+	Objective string `json:"objective"` // 排名的目标, 默认 total_profit
+	Folds int `json:"folds"` // walk-forward 的窗口数量, 默认 3
+	TrainRatio float64 `json:"train_ratio"` // 每个窗口中样本内(训练)的比例, 默认 0.7
+	Backtest Params `json:"backtest"` // 回测参数, symbols / start_time / end_time 为整个优化的范围, 交易策略固定为 custom
+}
+
+// 搜索的参数, name 的格式:
+// technology.<指标名称>.<参数>: 技术指标的参数, 例如 technology.ema1.period
+// strategy.<策略名称>.<n>: 策略代码中第 n 个数字(从 1 开始, 不包括下标 [0]), 例如 strategy.close1.1 为 ROI > 10 中的 10
+// trailing_activation, trailing_callback: 模板的字段(回测不模拟加仓和分批止盈, 不支持 safety_order 和 take_profit_ladder)
+type OptimizeParameter struct {
+	Name string `json:"name"`
+	Values []float64 `json:"values"` // 可选值, 为空时使用 min ~ max, 每次增加 step
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+	Step float64 `json:"step"` // 网格搜索时必须大于 0, 随机搜索时为 0 表示连续的值
+}
+
+// 样本内 / 样本外的时间窗口
+type OptimizeWindow struct {
+	TrainStart int64 `json:"train_start"`
+	TrainEnd int64 `json:"train_end"`
+	TestStart int64 `json:"test_start"`
+	TestEnd int64 `json:"test_end"`
+}
+
+// 一组参数在所有窗口的结果
+type OptimizeCandidate struct {
+	Index int `json:"index"`
+	Baseline bool `json:"baseline"` // 模板当前的参数
+	Values map[string]float64 `json:"values"`
+	InSample float64 `json:"in_sample"` // 样本内目标的平均值
+	OutSample float64 `json:"out_sample"` // 样本外目标的平均值, 按照这个值排名
+	Folds []OptimizeFold `json:"folds"`
+	Error string `json:"error,omitempty"`
+
+	content feature.TemplateContent
+}
+
+type OptimizeFold struct {
+	InSample Stats `json:"in_sample"`
+	OutSample Stats `json:"out_sample"`
+}
+
+// 每个窗口中样本内最好的参数和它在样本外的表现
+type OptimizeWalkForward struct {
+	Window OptimizeWindow `json:"window"`
+	Candidate int `json:"candidate"`
+	InSample float64 `json:"in_sample"`
+	OutSample float64 `json:"out_sample"`
+}
+
+type OptimizeJob struct {
+	ID int64 `json:"id"`
+	TemplateId int64 `json:"template_id"`
+	TemplateVersion int64 `json:"template_version"` // 优化时模板的版本
+	Status string `json:"status"`
+	Progress int `json:"progress"` // 已完成的回测次数
+	Total int `json:"total"` // 总的回测次数
+	Error string `json:"error,omitempty"`
+	StartTime int64 `json:"start_time"`
+	EndTime int64 `json:"end_time"`
+	Params OptimizeParams `json:"params"`
+	Windows []OptimizeWindow `json:"windows"`
+	Candidates []OptimizeCandidate `json:"candidates"` // 按照样本外的目标排名
+	WalkForward []OptimizeWalkForward `json:"walk_forward"`
+	Efficiency float64 `json:"efficiency"` // walk-forward 效率: 样本外 / 样本内
+	SavedVersion int64 `json:"saved_version"` // 保存的模板版本
+
+	canceled bool
+}
+
+var optimizeJobs = map[int64]*OptimizeJob{}
+var optimizeJobId int64
+var optimizeLock sync.RWMutex
+
+// 开始后台优化任务, 同时只能运行一个
+func StartOptimize(params OptimizeParams) (job OptimizeJob, err error) {
+	params, err = optimizeWithDefault(params)
+	if err != nil {
+		return job, err
+	}
+	var template models.StrategyTemplates
+	err = orm.NewOrm().QueryTable("strategy_templates").Filter("Id", params.TemplateId).One(&template)
+	if err != nil {
+		return job, errors.New("template not found")
+	}
+	base := feature.TemplateContentOf(template)
+	candidates, err := GenerateCandidates(params.Parameters, params.Method, params.Samples, params.MaxCandidates, params.Seed)
+	if err != nil {
+		return job, err
+	}
+	// 第一组为模板当前的参数, 作为比较的基准
+	baseline := OptimizeCandidate{Baseline: true, Values: map[string]float64{}, content: base}
+	for _, parameter := range params.Parameters {
+		if value, err := GetTemplateParam(base, parameter.Name); err == nil {
+			baseline.Values[parameter.Name] = value
+		}
+	}
+	list := []OptimizeCandidate{baseline}
+	for _, values := range candidates {
+		content, err := ApplyOptimizeValues(base, values)
+		if err != nil {
+			return job, err
+		}
+		list = append(list, OptimizeCandidate{Values: values, content: content})
+	}
+	for i := range list {
+		list[i].Index = i
+	}
+	windows := WalkForwardWindows(params.Backtest.StartTime, params.Backtest.EndTime, params.Folds, params.TrainRatio)
+
+	optimizeLock.Lock()
+	for _, item := range optimizeJobs {
+		if item.Status == OptimizeStatusRunning {
+			optimizeLock.Unlock()
+			return job, fmt.Errorf("optimize job %d is running", item.ID)
+		}
+	}
+	optimizeJobId++
+	newJob := &OptimizeJob{
+		ID: optimizeJobId,
+		TemplateId: template.ID,
+		TemplateVersion: template.Version,
+		Status: OptimizeStatusRunning,
+		Total: len(list) * len(windows) * 2,
+		StartTime: time.Now().UnixMilli(),
+		Params: params,
+		Windows: windows,
+		Candidates: list,
+	}
+	optimizeJobs[newJob.ID] = newJob
+	cleanOptimizeJobs()
+	job = newJob.copy()
+	optimizeLock.Unlock()
+
+	go runOptimize(newJob)
+	return job, nil
+}
+
+func optimizeWithDefault(params OptimizeParams) (OptimizeParams, error) {
+	if params.TemplateId == 0 {
+		return params, errors.New("template_id is required")
+	}
+	if len(params.Parameters) == 0 {
+		return params, errors.New("parameters is required")
+	}
+	if len(params.Backtest.Symbols) == 0 && len(params.Backtest.Klines) == 0 {
+		return params, errors.New("backtest.symbols is required")
+	}
+	if params.Method == "" {
+		params.Method = "grid"
+	}
+	if params.Samples <= 0 {
+		params.Samples = 20
+	}
+	if params.MaxCandidates <= 0 {
+		params.MaxCandidates = 200
+	}
+	if params.Seed == 0 {
+		params.Seed = time.Now().UnixNano()
+	}
+	if params.Objective == "" {
+		params.Objective = ObjectiveTotalProfit
+	}
+	if _, err := ObjectiveScore(Stats{}, params.Objective); err != nil {
+		return params, err
+	}
+	if params.Folds <= 0 {
+		params.Folds = 3
+	}
+	if params.TrainRatio <= 0 || params.TrainRatio >= 1 {
+		params.TrainRatio = 0.7
+	}
+	if params.Backtest.EndTime == 0 {
+		params.Backtest.EndTime = time.Now().UnixMilli()
+	}
+	if params.Backtest.StartTime == 0 {
+		params.Backtest.StartTime = params.Backtest.EndTime - (time.Hour * 24 * 30).Milliseconds()
+	}
+	if params.Backtest.StartTime >= params.Backtest.EndTime {
+		return params, errors.New("start_time must be less than end_time")
+	}
+	if params.Backtest.Interval == "" {
+		params.Backtest.Interval = "1m"
+	}
+	if params.Backtest.WarmupDays == 0 {
+		params.Backtest.WarmupDays = 7
+	}
+	symbolCount := len(params.Backtest.Symbols)
+	if len(params.Backtest.Klines) > symbolCount {
+		symbolCount = len(params.Backtest.Klines)
+	}
+	if err := CheckLimits(params.Backtest, symbolCount); err != nil {
+		return params, err
+	}
+	params.Backtest.LineStrategy = "custom"
+	params.Backtest.CoinStrategy = ""
+	return params, nil
+}
+
+func runOptimize(job *OptimizeJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			finishOptimize(job, fmt.Errorf("panic: %v", r))
+		}
+	}()
+	params := job.Params.Backtest
+	coins, err := loadCoins(params)
+	if err == nil && len(coins) == 0 {
+		err = errors.New("no symbols to backtest")
+	}
+	if err != nil {
+		finishOptimize(job, err)
+		return
+	}
+	// k线只获取一次, 样本外窗口之前的k线作为预热数据
+	klines := params.Klines
+	if len(klines) == 0 {
+		klines, err = loadKlines(coins, params)
+		if err != nil {
+			finishOptimize(job, err)
+			return
+		}
+	}
+	params.Klines = klines
+
+	for i := range job.Candidates {
+		candidate := &job.Candidates[i]
+		for _, window := range job.Windows {
+			var fold OptimizeFold
+			fold.InSample, err = runOptimizeBacktest(params, coins, candidate.content, window.TrainStart, window.TrainEnd)
+			if err == nil && !job.isCanceled() {
+				job.step()
+				fold.OutSample, err = runOptimizeBacktest(params, coins, candidate.content, window.TestStart, window.TestEnd)
+			}
+			if job.isCanceled() {
+				finishOptimize(job, nil)
+				return
+			}
+			job.step()
+			optimizeLock.Lock()
+			if err != nil {
+				candidate.Error = err.Error()
+			}
+			candidate.Folds = append(candidate.Folds, fold)
+			optimizeLock.Unlock()
+			if err != nil {
+				break
+			}
+		}
+	}
+	finishOptimize(job, nil)
+}
+
+// 使用一组参数回测一个时间窗口
+func runOptimizeBacktest(params Params, coins []*models.Symbols, content feature.TemplateContent, startTime int64, endTime int64) (Stats, error) {
+	params.StartTime = startTime
+	params.EndTime = endTime
+	params.Coins = []*models.Symbols{}
+	for _, coin := range coins {
+		symbol := *coin
+		symbol.StrategyType = "custom"
+		feature.ApplyTemplateContent(&symbol, content)
+		params.Coins = append(params.Coins, &symbol)
+	}
+	result, err := Run(params)
+	return result.Stats, err
+}
+
+// 计算排名和 walk-forward 的结果
+func finishOptimize(job *OptimizeJob, err error) {
+	optimizeLock.Lock()
+	defer optimizeLock.Unlock()
+	job.EndTime = time.Now().UnixMilli()
+	if job.canceled {
+		job.Status = OptimizeStatusCanceled
+	} else if err != nil {
+		job.Status = OptimizeStatusFailed
+		job.Error = err.Error()
+		logs.Error("optimize job", job.ID, "failed:", err.Error())
+		return
+	} else {
+		job.Status = OptimizeStatusDone
+		job.Progress = job.Total
+	}
+	objective := job.Params.Objective
+	for i := range job.Candidates {
+		candidate := &job.Candidates[i]
+		inSample, outSample := 0.0, 0.0
+		for _, fold := range candidate.Folds {
+			inScore, _ := ObjectiveScore(fold.InSample, objective)
+			outScore, _ := ObjectiveScore(fold.OutSample, objective)
+			inSample += inScore
+			outSample += outScore
+		}
+		if len(candidate.Folds) > 0 {
+			candidate.InSample = inSample / float64(len(candidate.Folds))
+			candidate.OutSample = outSample / float64(len(candidate.Folds))
+		}
+	}
+	// 每个窗口选择样本内最好的参数, 检查样本外的表现
+	job.WalkForward = []OptimizeWalkForward{}
+	inSample, outSample := 0.0, 0.0
+	for i, window := range job.Windows {
+		best := -1
+		bestScore := math.Inf(-1)
+		for _, candidate := range job.Candidates {
+			if candidate.Error != "" || len(candidate.Folds) <= i {
+				continue
+			}
+			score, _ := ObjectiveScore(candidate.Folds[i].InSample, objective)
+			if score > bestScore {
+				best, bestScore = candidate.Index, score
+			}
+		}
+		if best == -1 {
+			continue
+		}
+		outScore, _ := ObjectiveScore(job.Candidates[best].Folds[i].OutSample, objective)
+		job.WalkForward = append(job.WalkForward, OptimizeWalkForward{Window: window, Candidate: best, InSample: bestScore, OutSample: outScore})
+		inSample += bestScore
+		outSample += outScore
+	}
+	if inSample != 0 {
+		job.Efficiency = outSample / inSample
+	}
+	job.Candidates = RankCandidates(job.Candidates, len(job.Windows))
+	logs.Info("optimize job", job.ID, job.Status)
+}
+
+// 按照样本外的平均值排名, 相同时比较样本内, 没有完成所有窗口的排在后面
+func RankCandidates(candidates []OptimizeCandidate, folds int) []OptimizeCandidate {
+	complete := func(candidate OptimizeCandidate) bool {
+		return candidate.Error == "" && len(candidate.Folds) == folds
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if complete(a) != complete(b) {
+			return complete(a)
+		}
+		if a.OutSample != b.OutSample {
+			return a.OutSample > b.OutSample
+		}
+		return a.InSample > b.InSample
+	})
+	return candidates
+}
+
+// 目标的分数, 越大越好
+func ObjectiveScore(stats Stats, objective string) (float64, error) {
+	switch objective {
+		case ObjectiveTotalProfit:
+			return stats.TotalProfit, nil
+		case ObjectiveSharpe:
+			return stats.Sharpe, nil
+		case ObjectiveProfitFactor:
+			return stats.ProfitFactor, nil
+		case ObjectiveWinRate:
+			return stats.WinRate, nil
+		case ObjectiveMaxDrawdown:
+			return -stats.MaxDrawdown, nil
+		case ObjectiveProfitDrawdown:
+			if stats.MaxDrawdown == 0 {
+				return stats.TotalProfit, nil
+			}
+			return stats.TotalProfit / stats.MaxDrawdown, nil
+	}
+	return 0, errors.New("invalid objective: " + objective)
+}
+
+// 把时间范围分成 folds 个滚动的窗口, 每个窗口前 trainRatio 为样本内, 之后为样本外, 样本外的部分不重叠并且连续
+func WalkForwardWindows(startTime int64, endTime int64, folds int, trainRatio float64) (windows []OptimizeWindow) {
+	if folds <= 0 || endTime <= startTime {
+		return windows
+	}
+	testRatio := 1 - trainRatio
+	length := float64(endTime - startTime) / (1 + float64(folds - 1) * testRatio)
+	trainLength := int64(length * trainRatio)
+	testLength := int64(length * testRatio)
+	for i := 0; i < folds; i++ {
+		trainStart := startTime + int64(i) * testLength
+		window := OptimizeWindow{
+			TrainStart: trainStart,
+			TrainEnd: trainStart + trainLength,
+			TestStart: trainStart + trainLength,
+			TestEnd: trainStart + trainLength + testLength,
+		}
+		if i == folds - 1 {
+			window.TestEnd = endTime
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// 生成参数组合, grid 为所有组合, random 为随机的 samples 组(不重复)
+func GenerateCandidates(parameters []OptimizeParameter, method string, samples int, maxCandidates int, seed int64) (candidates []map[string]float64, err error) {
+	values := map[string][]float64{}
+	names := []string{}
+	for _, parameter := range parameters {
+		if _, ok := values[parameter.Name]; ok {
+			return candidates, errors.New("duplicate parameter: " + parameter.Name)
+		}
+		list := parameter.Values
+		if len(list) == 0 {
+			if parameter.Max < parameter.Min {
+				return candidates, errors.New("parameter " + parameter.Name + ": max must be greater than min")
+			}
+			if parameter.Step > 0 {
+				for value := parameter.Min; value <= parameter.Max + parameter.Step / 1e6; value += parameter.Step {
+					list = append(list, roundStep(value, parameter.Step))
+				}
+			} else if method == "grid" {
+				return candidates, errors.New("parameter " + parameter.Name + ": values or step is required")
+			}
+		}
+		names = append(names, parameter.Name)
+		values[parameter.Name] = list
+	}
+
+	switch method {
+		case "grid":
+			total := 1
+			for _, name := range names {
+				total *= len(values[name])
+				if total > maxCandidates {
+					return candidates, fmt.Errorf("too many combinations, max %d, use random method or fewer values", maxCandidates)
+				}
+			}
+			candidates = []map[string]float64{{}}
+			for _, name := range names {
+				next := []map[string]float64{}
+				for _, candidate := range candidates {
+					for _, value := range values[name] {
+						item := map[string]float64{name: value}
+						for key, v := range candidate {
+							item[key] = v
+						}
+						next = append(next, item)
+					}
+				}
+				candidates = next
+			}
+		case "random":
+			random := rand.New(rand.NewSource(seed))
+			exist := map[string]bool{}
+			for i := 0; len(candidates) < samples && i < samples * 20; i++ {
+				item := map[string]float64{}
+				keys := []string{}
+				for _, parameter := range parameters {
+					list := values[parameter.Name]
+					var value float64
+					if len(list) > 0 {
+						value = list[random.Intn(len(list))]
+					} else {
+						value = parameter.Min + random.Float64() * (parameter.Max - parameter.Min)
+					}
+					item[parameter.Name] = value
+					keys = append(keys, strconv.FormatFloat(value, 'f', -1, 64))
+				}
+				key := strings.Join(keys, ",")
+				if !exist[key] {
+					exist[key] = true
+					candidates = append(candidates, item)
+				}
+			}
+		default:
+			return candidates, errors.New("invalid method: " + method)
+	}
+	return candidates, nil
+}
+
+func roundStep(value float64, step float64) float64 {
+	value = math.Round(value / step) * step
+	rounded, _ := strconv.ParseFloat(strconv.FormatFloat(value, 'f', 10, 64), 64)
+	return rounded
+}
+
+// 把参数写入模板的策略内容
+func ApplyOptimizeValues(content feature.TemplateContent, values map[string]float64) (feature.TemplateContent, error) {
+	names := []string{}
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		err := setTemplateParam(&content, name, values[name])
+		if err != nil {
+			return content, err
+		}
+	}
+	return content, nil
+}
+
+// 模板策略内容中参数的值
+func GetTemplateParam(content feature.TemplateContent, name string) (float64, error) {
+	parts := strings.Split(name, ".")
+	switch {
+		case len(parts) == 3 && parts[0] == "technology":
+			configs, err := line.ParseIndicatorConfigs(content.Technology)
+			if err != nil {
+				return 0, err
+			}
+			for _, config := range configs {
+				if config.Name == parts[1] {
+					if value, ok := config.Params[parts[2]]; ok {
+						return value, nil
+					}
+					return 0, fmt.Errorf("parameter %s: %s not set", name, parts[2])
+				}
+			}
+			return 0, fmt.Errorf("parameter %s: indicator %s not found", name, parts[1])
+		case len(parts) == 3 && parts[0] == "strategy":
+			code, literals, err := strategyLiterals(content.Strategy, name)
+			if err != nil {
+				return 0, err
+			}
+			index, _ := strconv.Atoi(parts[2])
+			return strconv.ParseFloat(code[literals[index - 1][0]:literals[index - 1][1]], 64)
+	}
+	switch name {
+		case "trailing_activation":
+			return content.TrailingActivation, nil
+		case "trailing_callback":
+			return content.TrailingCallback, nil
+	}
+	return 0, errors.New("invalid parameter: " + name)
+}
+
+func setTemplateParam(content *feature.TemplateContent, name string, value float64) error {
+	parts := strings.Split(name, ".")
+	switch {
+		case len(parts) == 3 && parts[0] == "technology":
+			configs, err := line.ParseIndicatorConfigs(content.Technology)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %s", name, err.Error())
+			}
+			found := false
+			for i := range configs {
+				if configs[i].Name == parts[1] {
+					if configs[i].Params == nil {
+						configs[i].Params = map[string]float64{}
+					}
+					configs[i].Params[parts[2]] = value
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("parameter %s: indicator %s not found", name, parts[1])
+			}
+			content.Technology = marshalContent(configs)
+			return nil
+		case len(parts) == 3 && parts[0] == "strategy":
+			var strategyConfig technology.StrategyConfig
+			err := json.Unmarshal([]byte(content.Strategy), &strategyConfig)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %s", name, err.Error())
+			}
+			index, _ := strconv.Atoi(parts[2])
+			for i := range strategyConfig {
+				if strategyConfig[i].Name != parts[1] {
+					continue
+				}
+				literals := NumberLiterals(strategyConfig[i].Code)
+				if index < 1 || index > len(literals) {
+					return fmt.Errorf("parameter %s: strategy %s has %d numbers", name, parts[1], len(literals))
+				}
+				literal := literals[index - 1]
+				number := strconv.FormatFloat(value, 'f', -1, 64)
+				if value < 0 {
+					number = "(" + number + ")"
+				}
+				strategyConfig[i].Code = strategyConfig[i].Code[:literal[0]] + number + strategyConfig[i].Code[literal[1]:]
+				content.Strategy = marshalContent(strategyConfig)
+				return nil
+			}
+			return fmt.Errorf("parameter %s: strategy %s not found", name, parts[1])
+	}
+	switch name {
+		case "trailing_activation":
+			content.TrailingActivation = value
+		case "trailing_callback":
+			content.TrailingCallback = value
+		default:
+			return errors.New("invalid parameter: " + name)
+	}
+	return nil
+}
+
+// 策略代码中的 > < & 不转义, 保存后和手动编辑的一致
+func marshalContent(value interface{}) string {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSpace(buffer.String())
+}
+
+// 策略的代码和其中的数字位置
+func strategyLiterals(strStrategy string, name string) (code string, literals [][2]int, err error) {
+	parts := strings.Split(name, ".")
+	var strategyConfig technology.StrategyConfig
+	err = json.Unmarshal([]byte(strStrategy), &strategyConfig)
+	if err != nil {
+		return code, literals, err
+	}
+	index, _ := strconv.Atoi(parts[2])
+	for _, strategy := range strategyConfig {
+		if strategy.Name == parts[1] {
+			literals = NumberLiterals(strategy.Code)
+			if index < 1 || index > len(literals) {
+				return code, literals, fmt.Errorf("parameter %s: strategy %s has %d numbers", name, parts[1], len(literals))
+			}
+			return strategy.Code, literals, nil
+		}
+	}
+	return code, literals, fmt.Errorf("parameter %s: strategy %s not found", name, parts[1])
+}
+
+// 代码中数字的位置 [start, end), 不包括变量名中的数字、字符串中的数字和下标 [0]
+func NumberLiterals(code string) (literals [][2]int) {
+	var quote byte
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' || c == '`' {
+			quote = c
+			continue
+		}
+		if c < '0' || c > '9' {
+			continue
+		}
+		start := i
+		for i + 1 < len(code) && (isDigit(code[i + 1]) || (code[i + 1] == '.' && i + 2 < len(code) && isDigit(code[i + 2]))) {
+			i++
+		}
+		if start > 0 && (isIdentifier(code[start - 1]) || code[start - 1] == '.' || code[start - 1] == '[') {
+			continue
+		}
+		literals = append(literals, [2]int{start, i + 1})
+	}
+	return literals
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return isDigit(c) || c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// 把一组参数保存为模板的新版本, index 为 -1 时保存排名第一的参数
+func SaveOptimizeResult(jobId int64, index int, comment string) (version models.StrategyTemplateVersion, err error) {
+	optimizeLock.RLock()
+	job, ok := optimizeJobs[jobId]
+	if !ok {
+		optimizeLock.RUnlock()
+		return version, errors.New("optimize job not found")
+	}
+	if job.Status != OptimizeStatusDone {
+		optimizeLock.RUnlock()
+		return version, errors.New("optimize job is not done")
+	}
+	var candidate *OptimizeCandidate
+	for i := range job.Candidates {
+		if (index < 0 && i == 0) || job.Candidates[i].Index == index {
+			candidate = &job.Candidates[i]
+			break
+		}
+	}
+	optimizeLock.RUnlock()
+	if candidate == nil {
+		return version, errors.New("candidate not found")
+	}
+	if candidate.Error != "" {
+		return version, errors.New("candidate failed: " + candidate.Error)
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("optimize #%d candidate %d, %s out of sample %.4f", job.ID, candidate.Index, job.Params.Objective, candidate.OutSample)
+	}
+	version, err = feature.SaveTemplateContent(job.TemplateId, candidate.content, comment)
+	if err != nil {
+		return version, err
+	}
+	optimizeLock.Lock()
+	job.SavedVersion = version.Version
+	optimizeLock.Unlock()
+	return version, nil
+}
+
+func CancelOptimize(jobId int64) error {
+	optimizeLock.Lock()
+	defer optimizeLock.Unlock()
+	job, ok := optimizeJobs[jobId]
+	if !ok {
+		return errors.New("optimize job not found")
+	}
+	if job.Status != OptimizeStatusRunning {
+		return errors.New("optimize job is not running")
+	}
+	job.canceled = true
+	return nil
+}
+
+// 任务的进度和结果, 运行中时返回已经完成的部分
+func GetOptimizeJob(jobId int64) (job OptimizeJob, err error) {
+	optimizeLock.RLock()
+	defer optimizeLock.RUnlock()
+	item, ok := optimizeJobs[jobId]
+	if !ok {
+		return job, errors.New("optimize job not found")
+	}
+	return item.copy(), nil
+}
+
+// 所有的任务(不包含每组参数的结果)
+func GetOptimizeJobs() (jobs []OptimizeJob) {
+	optimizeLock.RLock()
+	defer optimizeLock.RUnlock()
+	jobs = []OptimizeJob{}
+	for _, item := range optimizeJobs {
+		job := item.copy()
+		job.Candidates = nil
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID > jobs[j].ID
+	})
+	return jobs
+}
+
+func (job *OptimizeJob) step() {
+	optimizeLock.Lock()
+	job.Progress++
+	optimizeLock.Unlock()
+}
+
+func (job *OptimizeJob) isCanceled() bool {
+	optimizeLock.RLock()
+	defer optimizeLock.RUnlock()
+	return job.canceled
+}
+
+// 返回给接口的副本, 只保留排名靠前的参数, 调用时需要持有锁
+func (job *OptimizeJob) copy() OptimizeJob {
+	result := *job
+	result.Params.Backtest.Klines = nil
+	count := len(job.Candidates)
+	if count > optimizeTopCandidates {
+		count = optimizeTopCandidates
+	}
+	result.Candidates = make([]OptimizeCandidate, count)
+	copy(result.Candidates, job.Candidates[:count])
+	for i := range result.Candidates {
+		result.Candidates[i].Folds = append([]OptimizeFold{}, result.Candidates[i].Folds...)
+	}
+	return result
+}
+
+// 超过最大数量时删除最早完成的任务, 调用时需要持有锁
+func cleanOptimizeJobs() {
+	for len(optimizeJobs) > optimizeMaxJobs {
+		var oldest *OptimizeJob
+		for _, job := range optimizeJobs {
+			if job.Status != OptimizeStatusRunning && (oldest == nil || job.ID < oldest.ID) {
+				oldest = job
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(optimizeJobs, oldest.ID)
+	}
+}
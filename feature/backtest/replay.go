@@ -0,0 +1,205 @@
+package backtest
+
+import (
+	"errors"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/utils"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+)
+
+// 回放历史k线的交易所
+// 下单/仓位/余额使用模拟交易所, k线只返回当前回放时间之前的数据, 大周期的k线由基础周期合成
+type ReplayExchange struct {
+	*binance.MockExchange
+
+	interval   string // 基础周期
+	intervalMs int64
+	klines     map[string][]*futures.Kline // 基础周期k线(时间升序)
+	index      map[string]int              // 每个币种当前回放到的位置
+	nowTime    int64
+	groups     map[string]*klineGroups // symbol_interval => 合成的大周期k线
+}
+
+// 合成的大周期k线
+type klineGroups struct {
+	groupOf []int            // 基础k线所属的分组
+	starts  []int            // 分组的第一根基础k线
+	merged  []*futures.Kline // 分组合成后的k线(最后一个分组可能不完整, 使用时重新合成)
+}
+
+func NewReplayExchange(interval string, klines map[string][]*futures.Kline, balance float64) (*ReplayExchange, error) {
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	replay := &ReplayExchange{
+		MockExchange: binance.NewMockExchange(balance),
+		interval:     interval,
+		intervalMs:   duration.Milliseconds(),
+		klines:       map[string][]*futures.Kline{},
+		index:        map[string]int{},
+		groups:       map[string]*klineGroups{},
+	}
+	replay.SetClock(replay.NowTime)
+	for symbol, items := range klines {
+		sorted := append([]*futures.Kline{}, items...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].OpenTime < sorted[j].OpenTime
+		})
+		replay.klines[symbol] = sorted
+		replay.index[symbol] = -1
+	}
+	return replay, nil
+}
+
+// 回放的时间线(所有币种基础k线的开盘时间, 升序)
+func (r *ReplayExchange) Timeline() []int64 {
+	exist := map[int64]bool{}
+	timeline := []int64{}
+	for _, items := range r.klines {
+		for _, kline := range items {
+			if !exist[kline.OpenTime] {
+				exist[kline.OpenTime] = true
+				timeline = append(timeline, kline.OpenTime)
+			}
+		}
+	}
+	sort.Slice(timeline, func(i, j int) bool {
+		return timeline[i] < timeline[j]
+	})
+	return timeline
+}
+
+// 回放到 openTime 这根k线收盘, 更新最新价格(会撮合挂单)
+func (r *ReplayExchange) Step(openTime int64) {
+	r.nowTime = openTime + r.intervalMs - 1
+	for symbol, items := range r.klines {
+		i := r.index[symbol]
+		for i + 1 < len(items) && items[i + 1].OpenTime <= openTime {
+			i++
+		}
+		if i == r.index[symbol] {
+			continue
+		}
+		r.index[symbol] = i
+		price, _ := strconv.ParseFloat(items[i].Close, 64)
+		r.SetPrice(symbol, price)
+	}
+}
+
+// 当前回放到的时间(毫秒时间戳)
+func (r *ReplayExchange) NowTime() int64 {
+	return r.nowTime
+}
+
+// 当前回放到的k线
+func (r *ReplayExchange) NowKline(symbol string) *futures.Kline {
+	i, ok := r.index[symbol]
+	if !ok || i < 0 {
+		return nil
+	}
+	return r.klines[symbol][i]
+}
+
+func (r *ReplayExchange) GetKlineData(symbol string, interval string, limit int) (klines []*futures.Kline, err error) {
+	items, ok := r.klines[symbol]
+	i := r.index[symbol]
+	if !ok || i < 0 {
+		return nil, errors.New("replay exchange: no kline for symbol " + symbol)
+	}
+	if interval == r.interval {
+		start := int(math.Max(0, float64(i - limit + 1)))
+		for _, kline := range items[start:i + 1] {
+			copied := *kline
+			klines = append(klines, &copied)
+		}
+		return klines, nil
+	}
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil {
+		return nil, err
+	}
+	ms := duration.Milliseconds()
+	if ms < r.intervalMs || ms % r.intervalMs != 0 {
+		return nil, errors.New("replay exchange: interval " + interval + " can not be merged from " + r.interval)
+	}
+	groups := r.getGroups(symbol, interval, ms)
+	g := groups.groupOf[i]
+	start := int(math.Max(0, float64(g - limit + 1)))
+	for gi := start; gi < g; gi++ {
+		copied := *groups.merged[gi]
+		klines = append(klines, &copied)
+	}
+	// 当前分组还没有结束, 和实盘一样返回未完成的k线
+	klines = append(klines, mergeKlines(items[groups.starts[g]:i + 1], groupOpenTime(items[i].OpenTime, ms), ms))
+	return klines, nil
+}
+
+func (r *ReplayExchange) getGroups(symbol string, interval string, ms int64) *klineGroups {
+	key := symbol + "_" + interval
+	if groups, ok := r.groups[key]; ok {
+		return groups
+	}
+	items := r.klines[symbol]
+	groups := &klineGroups{groupOf: make([]int, len(items))}
+	lastOpenTime := int64(-1)
+	for i, kline := range items {
+		openTime := groupOpenTime(kline.OpenTime, ms)
+		if openTime != lastOpenTime {
+			groups.starts = append(groups.starts, i)
+			lastOpenTime = openTime
+		}
+		groups.groupOf[i] = len(groups.starts) - 1
+	}
+	for g, start := range groups.starts {
+		end := len(items)
+		if g + 1 < len(groups.starts) {
+			end = groups.starts[g + 1]
+		}
+		groups.merged = append(groups.merged, mergeKlines(items[start:end], groupOpenTime(items[start].OpenTime, ms), ms))
+	}
+	r.groups[key] = groups
+	return groups
+}
+
+// 大周期k线的开盘时间, 周线从周一开始(1970-01-01 是周四)
+func groupOpenTime(openTime int64, ms int64) int64 {
+	offset := int64(0)
+	if ms == (time.Hour * 24 * 7).Milliseconds() {
+		offset = (time.Hour * 24 * 4).Milliseconds()
+	}
+	return (openTime - offset) / ms * ms + offset
+}
+
+func mergeKlines(items []*futures.Kline, openTime int64, ms int64) *futures.Kline {
+	high, low := 0.0, math.MaxFloat64
+	volume, quoteVolume := 0.0, 0.0
+	tradeNum := int64(0)
+	for _, kline := range items {
+		klineHigh, _ := strconv.ParseFloat(kline.High, 64)
+		klineLow, _ := strconv.ParseFloat(kline.Low, 64)
+		klineVolume, _ := strconv.ParseFloat(kline.Volume, 64)
+		klineQuoteVolume, _ := strconv.ParseFloat(kline.QuoteAssetVolume, 64)
+		high = math.Max(high, klineHigh)
+		low = math.Min(low, klineLow)
+		volume += klineVolume
+		quoteVolume += klineQuoteVolume
+		tradeNum += kline.TradeNum
+	}
+	return &futures.Kline{
+		OpenTime:         openTime,
+		Open:             items[0].Open,
+		High:             strconv.FormatFloat(high, 'f', -1, 64),
+		Low:              strconv.FormatFloat(low, 'f', -1, 64),
+		Close:            items[len(items) - 1].Close,
+		Volume:           strconv.FormatFloat(volume, 'f', -1, 64),
+		CloseTime:        openTime + ms - 1,
+		QuoteAssetVolume: strconv.FormatFloat(quoteVolume, 'f', -1, 64),
+		TradeNum:         tradeNum,
+	}
+}
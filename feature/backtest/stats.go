@@ -0,0 +1,109 @@
+package backtest
+
+import (
+	"math"
+	"time"
+)
+
+// 回测统计
+type Stats struct {
+	TradeCount   int     `json:"trade_count"`   // 交易次数
+	WinCount     int     `json:"win_count"`     // 盈利次数
+	LossCount    int     `json:"loss_count"`    // 亏损次数
+	WinRate      float64 `json:"win_rate"`      // 胜率%
+	TotalProfit  float64 `json:"total_profit"`  // 总收益(包含手续费和资金费)
+	TotalFee     float64 `json:"total_fee"`     // 总手续费
+	ProfitFactor float64 `json:"profit_factor"` // 盈亏比(总盈利 / 总亏损)
+	MaxDrawdown  float64 `json:"max_drawdown"`  // 最大回撤%
+	Sharpe       float64 `json:"sharpe"`        // 夏普比率(按日收益率年化)
+	FinalEquity  float64 `json:"final_equity"`  // 最终权益
+}
+
+func CalculateStats(trades []Trade, equity []EquityPoint, balance float64) (stats Stats) {
+	grossProfit, grossLoss := 0.0, 0.0
+	for _, trade := range trades {
+		stats.TradeCount += 1
+		stats.TotalFee += trade.Fee
+		if trade.Profit > 0 {
+			stats.WinCount += 1
+			grossProfit += trade.Profit
+		} else {
+			stats.LossCount += 1
+			grossLoss += -trade.Profit
+		}
+	}
+	if stats.TradeCount > 0 {
+		stats.WinRate = float64(stats.WinCount) / float64(stats.TradeCount) * 100
+	}
+	if grossLoss > 0 {
+		stats.ProfitFactor = grossProfit / grossLoss
+	} else if grossProfit > 0 {
+		stats.ProfitFactor = math.Inf(1)
+	}
+
+	stats.FinalEquity = balance
+	if len(equity) > 0 {
+		stats.FinalEquity = equity[len(equity) - 1].Equity
+	}
+	stats.TotalProfit = stats.FinalEquity - balance
+	stats.MaxDrawdown = MaxDrawdown(equity, balance)
+	stats.Sharpe = Sharpe(equity, balance)
+	if math.IsInf(stats.ProfitFactor, 1) {
+		stats.ProfitFactor = 999 // json 不支持 Inf
+	}
+	return stats
+}
+
+// 最大回撤%
+func MaxDrawdown(equity []EquityPoint, balance float64) float64 {
+	peak := balance
+	maxDrawdown := 0.0
+	for _, point := range equity {
+		if point.Equity > peak {
+			peak = point.Equity
+		}
+		if peak > 0 {
+			maxDrawdown = math.Max(maxDrawdown, (peak - point.Equity) / peak * 100)
+		}
+	}
+	return maxDrawdown
+}
+
+// 夏普比率, 使用每天最后的权益计算日收益率, 无风险利率为 0
+func Sharpe(equity []EquityPoint, balance float64) float64 {
+	dayMs := (time.Hour * 24).Milliseconds()
+	dailyEquity := []float64{balance}
+	lastDay := int64(-1)
+	for _, point := range equity {
+		day := point.Time / dayMs
+		if day != lastDay {
+			dailyEquity = append(dailyEquity, point.Equity)
+			lastDay = day
+		} else {
+			dailyEquity[len(dailyEquity) - 1] = point.Equity
+		}
+	}
+	returns := []float64{}
+	for i := 1; i < len(dailyEquity); i++ {
+		if dailyEquity[i - 1] != 0 {
+			returns = append(returns, dailyEquity[i] / dailyEquity[i - 1] - 1)
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	std := math.Sqrt(variance / float64(len(returns) - 1))
+	if std == 0 {
+		return 0
+	}
+	return mean / std * math.Sqrt(365)
+}
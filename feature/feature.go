@@ -5,7 +5,6 @@ import (
 	"go_binance_futures/feature/strategy"
 	"go_binance_futures/feature/strategy/coin"
 	"go_binance_futures/feature/strategy/line"
-	"go_binance_futures/lang"
 	"go_binance_futures/models"
 	"go_binance_futures/notify"
 	"go_binance_futures/types"
@@ -41,6 +40,7 @@ func StartTrade(systemConfig models.Config) {
 	
 	globalCoinStrategy := GetCoinStrategy(systemConfig.FutureStrategyCoin) // 交易策略
 	globalLineStrategy := GetLineStrategy(systemConfig.FutureStrategyTrade) // 选币策略
+	executor := NewOrderExecutor(systemConfig) // 下单
 	
 	/************************************************寻找交易币种 start******************************************************************* */
 	allCoins, err := GetAllSymbols()
@@ -48,7 +48,7 @@ func StartTrade(systemConfig models.Config) {
 		logs.Error("GetAllSymbols err:", err)
 		return
 	}
-	coins := globalCoinStrategy.SelectCoins(allCoins)
+	coins := globalCoinStrategy.SelectCoins(allCoins, nil)
 	if coins == nil {
 		logs.Error("coins SelectCoins is nil")
 		return
@@ -76,6 +76,19 @@ func StartTrade(systemConfig models.Config) {
 	
 	/*************************************************挂单已经超过设置的超时时间，撤销挂单 start************************************************************ */
 	exclude_symbols_map := GetExcludeSymbolsMap(systemConfig.FutureExcludeSymbols)
+	// 网格交易的币种不参与自动交易, 网格的挂单也不计入持仓数量
+	// 交易所的止盈止损单由 SyncProtectiveOrders 维护, 不会超时撤销, 也不计入持仓数量
+	gridSymbols := GetRunningGridSymbols()
+	gridOpenOrders := allOpenOrders
+	allOpenOrders = []types.FuturesOrder{}
+	for _, order := range gridOpenOrders {
+		if !gridSymbols[order.Symbol] && !IsProtectiveOrder(order.ClientOrderId, order.Type) {
+			allOpenOrders = append(allOpenOrders, order)
+		}
+	}
+	for symbol := range gridSymbols {
+		exclude_symbols_map[symbol] = true
+	}
 	cancelTimeoutOrder(exclude_symbols_map, allOpenOrders, int64(systemConfig.FutureBuyTimeout))
 	/*************************************************挂单已经超过设置的超时时间，撤销挂单 end************************************************************ */
 	
@@ -83,6 +96,13 @@ func StartTrade(systemConfig models.Config) {
 	/*************************************************平仓(止盈或止损)已经有持仓的币(排除手动交易白名单) start************************************************************ */
 	positionCount := 0 // 当前仓位数量
 	lossCount := 0 // 亏损的仓位数量
+	activePositions := []types.FuturesPosition{} // 当前所有的持仓(包括跳过的), 用于清理已平仓的最高收益率记录和持仓状态
+	for _, position := range positions {
+		positionAmtFloat, _ := strconv.ParseFloat(position.Amount, 64)
+		if math.Abs(positionAmtFloat) >= 0.0000000001 {
+			activePositions = append(activePositions, position)
+		}
+	}
 	for _, position := range positions {
 		// 在白名单内, 不参与自动平仓交易
 		if _, exist := exclude_symbols_map[position.Symbol]; exist {
@@ -125,248 +145,112 @@ func StartTrade(systemConfig models.Config) {
 		if nowProfit < -0.1 {
 			lossCount += 1
 		}
+		peak := UpdatePositionPeak(position, nowProfit, markPrice_float64) // 持仓期间的最高收益率
 		
-		closeResult := coin_line_strategy.AutoStopOrder(strategy.CloseParams{
+		closeParams := strategy.CloseParams{
 			Symbols: findCoin,
 			Position: position,
 			NowProfit: nowProfit,
-		})
+			PeakProfit: peak.PeakRoi,
+			PeakPrice: peak.PeakPrice,
+			Stats: GetPositionStats(peak, time.Now().UnixMilli()),
+		}
+		closeIntent := OrderIntent{
+			Action: OrderActionClose,
+			Symbol: position.Symbol,
+			PositionSide: position.Side,
+			Quantity: positionAmtFloatAbs,
+			Price: markPrice_float64,
+			Leverage: position.Leverage,
+			Profit: unRealizedProfit,
+			RealizedProfit: peak.RealizedProfit,
+			StrategyName: getStrategyNameFromConfig(systemConfig),
+		}
+		closeResult := coin_line_strategy.AutoStopOrder(closeParams)
 		if closeResult.Complete { // 触发策略,风向改变,强制平仓
 			logs.Info("%s:auto_stop_start", position.Symbol)
-			if position.Side == "LONG" {
-				order, err := binance.SellMarket(position.Symbol, positionAmtFloatAbs, futures.PositionSideTypeLong)
-				if err == nil {
-					// 数据库写入订单
-					insertCloseOrder(position, positionAmtFloatAbs, unRealizedProfit, position.MarkPrice, order.OrderID, systemConfig)
-					
-					markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
-					pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.close_notice_title"),
-						Symbol: position.Symbol,
-						Side: "sell",
-						PositionSide: "long",
-						Price: markPrice,
-						Quantity: positionAmtFloat,
-						Leverage: leverage_float64,
-						Profit: unRealizedProfit,
-						Remarks: lang.Lang("futures.wind_of_change"),
-						Status: "success",
-					})
-				} else {
-					pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.close_notice_title"),
-						Symbol: position.Symbol,
-						Side: "sell",
-						PositionSide: "long",
-						Quantity: positionAmtFloat,
-						Leverage: leverage_float64,
-						Profit: unRealizedProfit,
-						Remarks: lang.Lang("futures.wind_of_change"),
-						Status: "fail",
-						Error: err.Error(),
-					})
-				}
-			}
-			if position.Side == "SHORT" {
-				order, err := binance.BuyMarket(position.Symbol, positionAmtFloatAbs, futures.PositionSideTypeShort)
-				if err == nil {
-					// 数据库写入订单
-					insertCloseOrder(position, positionAmtFloatAbs, unRealizedProfit, position.MarkPrice, order.OrderID, systemConfig)
-					
-					markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
-					pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.close_notice_title"),
-						Symbol: position.Symbol,
-						Side: "buy",
-						PositionSide: "short",
-						Price: markPrice,
-						Quantity: positionAmtFloat,
-						Leverage: leverage_float64,
-						Profit: unRealizedProfit,
-						Remarks: lang.Lang("futures.wind_of_change"),
-						Status: "success",
-					})
-				} else {
-					pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.close_notice_title"),
-						Symbol: position.Symbol,
-						Side: "buy",
-						PositionSide: "short",
-						Quantity: positionAmtFloat,
-						Leverage: leverage_float64,
-						Profit: unRealizedProfit,
-						Remarks: lang.Lang("futures.wind_of_change"),
-						Status: "fail",
-						Error: err.Error(),
-					})
-				}
-			}
+			closeIntent.Reason = "wind_of_change"
+			executor.Execute(closeIntent)
 			logs.Info("%s:auto_stop_end", position.Symbol)
 			continue
 		}
+		// 平仓(跟踪止盈, 收益率从最高点回撤), 使用交易所跟踪止盈单时由交易所平仓
+		if findCoin != nil && findCoin.TrailingStop == TrailingStopLocal && IsTrailingStop(findCoin, peak.PeakRoi, nowProfit) {
+			logs.Info("%s:trailing_stop, peak roi: %f, roi: %f", position.Symbol, peak.PeakRoi, nowProfit)
+			closeIntent.Reason = "trailing_stop"
+			executor.Execute(closeIntent)
+			continue
+		}
 		if nowProfit <= -coin_loss_float64 { // 平仓(止损)
-			// position.Symbol, position.PositionSide
-			closeResult := coin_line_strategy.CanOrderComplete(strategy.CloseParams{
-				Symbols: findCoin,
-				Position: position,
-				NowProfit: nowProfit,
-			})
-			if closeResult.Complete { // 
-				if position.Side == "LONG" {
-					order, err := binance.SellMarket(position.Symbol, positionAmtFloatAbs, futures.PositionSideTypeLong)
-					if err == nil {
-						// 数据库写入订单
-						insertCloseOrder(position, positionAmtFloatAbs, unRealizedProfit, position.MarkPrice, order.OrderID, systemConfig)
-						
-						markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "sell",
-							PositionSide: "long",
-							Price: markPrice,
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.stop_loss"),
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "sell",
-							PositionSide: "long",
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.stop_loss"),
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				}
-				if position.Side == "SHORT" {
-					order, err := binance.BuyMarket(position.Symbol, positionAmtFloatAbs, futures.PositionSideTypeShort)
-					if err == nil {
-						// 数据库写入订单
-						insertCloseOrder(position, positionAmtFloatAbs, unRealizedProfit, position.MarkPrice, order.OrderID, systemConfig)
-						
-						markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "buy",
-							PositionSide: "short",
-							Price: markPrice,
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.stop_loss"),
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "buy",
-							PositionSide: "short",
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.stop_loss"),
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				}
+			closeResult := coin_line_strategy.CanOrderComplete(closeParams)
+			if closeResult.Complete {
+				closeIntent.Reason = "stop_loss"
+				executor.Execute(closeIntent)
 				continue
 			}
 		}
-		if nowProfit >= coin_profit_float64 { // 平仓(止盈)
-			closeResult := coin_line_strategy.CanOrderComplete(strategy.CloseParams{
-				Symbols: findCoin,
-				Position: position,
-				NowProfit: nowProfit,
+		// 加仓(DCA), 新的开仓均价在下一轮中重新计算止盈止损
+		safetyQuantity := GetSafetyOrderQuantity(findCoin, peak, positionAmtFloatAbs, nowProfit)
+		if safetyQuantity > 0 && canSafetyOrder(systemConfig, position, positions, len(allOpenOrders)) {
+			parentOrderId := peak.ParentOrderId
+			if parentOrderId == 0 {
+				parentOrderId = getParentOrderId(position.Symbol, position.Side)
+			}
+			_, err := executor.Execute(OrderIntent{
+				Action: OrderActionOpen,
+				Symbol: position.Symbol,
+				PositionSide: position.Side,
+				Quantity: safetyQuantity,
+				Price: markPrice_float64,
+				Leverage: position.Leverage,
+				TickSize: findCoin.TickSize,
+				StepSize: findCoin.StepSize,
+				Reason: "safety_order",
+				ParentOrderId: parentOrderId,
 			})
+			if err == nil {
+				RecordSafetyOrder(position.Symbol, position.Side, peak.SafetyCount + 1, parentOrderId)
+			}
+			positionCount += 1
+			continue
+		}
+		// 分批止盈, 每次只平一档, 剩余的仓位继续走止盈, 跟踪止盈和策略平仓
+		ladderQuantity, ladderAll := GetLadderCloseQuantity(findCoin, peak, positionAmtFloatAbs, nowProfit)
+		if ladderQuantity > 0 {
+			ladderIntent := closeIntent
+			ladderIntent.Reason = "partial_profit"
+			ladderIntent.Quantity = ladderQuantity
+			ladderIntent.Profit = unRealizedProfit * ladderQuantity / positionAmtFloatAbs // 按比例的收益
+			ladderIntent.Partial = !ladderAll
+			_, err := executor.Execute(ladderIntent)
+			if err == nil && !ladderAll {
+				RecordLadderStep(position.Symbol, position.Side, peak.LadderStep + 1, ladderIntent.Profit)
+			}
+			if !ladderAll {
+				positionCount += 1 // 剩余的仓位在下一轮中处理
+			}
+			continue
+		}
+		if nowProfit >= coin_profit_float64 { // 平仓(止盈)
+			closeResult := coin_line_strategy.CanOrderComplete(closeParams)
 			if closeResult.Complete {
-				if position.Side == "LONG" {
-					order, err := binance.SellMarket(position.Symbol, positionAmtFloatAbs, futures.PositionSideTypeLong)
-					if err == nil {
-						// 数据库写入订单
-						insertCloseOrder(position, positionAmtFloatAbs, unRealizedProfit, position.MarkPrice, order.OrderID, systemConfig)
-						
-						markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "sell",
-							PositionSide: "long",
-							Price: markPrice,
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.target_profit"),
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "sell",
-							PositionSide: "long",
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.target_profit"),
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				}
-				if position.Side == "SHORT" {
-					order, err := binance.BuyMarket(position.Symbol, positionAmtFloatAbs, futures.PositionSideTypeShort)
-					if err == nil {
-						// 数据库写入订单
-						insertCloseOrder(position, positionAmtFloatAbs, unRealizedProfit, position.MarkPrice, order.OrderID, systemConfig)
-						
-						markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "buy",
-							PositionSide: "short",
-							Price: markPrice,
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.target_profit"),
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesCloseOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.close_notice_title"),
-							Symbol: position.Symbol,
-							Side: "buy",
-							PositionSide: "short",
-							Quantity: positionAmtFloat,
-							Leverage: leverage_float64,
-							Profit: unRealizedProfit,
-							Remarks: lang.Lang("futures.target_profit"),
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				}
+				closeIntent.Reason = "target_profit"
+				executor.Execute(closeIntent)
 				continue
 			}
 		}
 		// 继续持有
 		positionCount += 1
 	}
+	CleanPositionPeaks(activePositions)
 	/*************************************************平仓 end************************************************************ */
 	
 	/*************************************************检查当前仓位数量 start************************************************************ */
+	// 账户熔断时，停止开仓
+	if tripped, reason := IsCircuitBreakerTripped(); tripped {
+		logs.Info("the circuit breaker is tripped: %s, stop open new order", reason)
+		return
+	}
 	// 当前亏损的数量过多时，停止开仓
 	if lossCount >= systemConfig.LossMaxCount {
 		logs.Info("the loss count is %d, is over max %d, stop open new order", lossCount, systemConfig.LossMaxCount)
@@ -411,7 +295,14 @@ func StartTrade(systemConfig models.Config) {
 		tickSize := coin.TickSize // 交易金额精度
 		stepSize := coin.StepSize // 交易数量精度
 		usdt_float64, _ := strconv.ParseFloat(coin.Usdt, 64) // 交易金额
-		leverage_float64 := float64(coin.Leverage) // 合约倍数
+		orderType := "LIMIT" // 下单类型
+		if systemConfig.FutureOrderType == "MARKET" {
+			orderType = "MARKET"
+		}
+		marginType := "ISOLATED" // 仓位模式
+		if coin.MarginType == "CROSSED" {
+			marginType = "CROSSED"
+		}
 		coin_line_strategy := globalLineStrategy // 默认为全局
 		if coin.StrategyType != "global" {
 			// 独立的策略
@@ -456,144 +347,31 @@ func StartTrade(systemConfig models.Config) {
 			}
 		}
 		
+		openIntent := OrderIntent{
+			Action: OrderActionOpen,
+			Symbol: symbol,
+			OrderType: orderType,
+			Usdt: usdt_float64,
+			Leverage: coin.Leverage,
+			MarginType: marginType,
+			TickSize: tickSize,
+			StepSize: stepSize,
+		}
 		if systemConfig.FutureAllowLong == 1 && hasPositionLong == false && hasBuyOrderLong == false && openResult.CanLong {
 			buyPrice, _, err := binance.GetDepthAvgPrice(symbol, 5) // 平均买价
-			if err == nil {
-				buyPrice = utils.GetTradePrecision(buyPrice, tickSize) // 合理精度的价格
-				quantity := (usdt_float64 / buyPrice) * leverage_float64  // 购买数量
-				quantity = utils.GetTradePrecision(quantity, stepSize) // 合理精度的价格
-				
-				UpdateSymbolTradeInfo(coin) // 更新倍率和仓位模式
-				
-				if systemConfig.FutureOrderType == "MARKET" {
-					order, err := binance.BuyMarket(symbol, quantity, futures.PositionSideTypeLong)
-					if err == nil {
-						// 数据库写入订单
-						buyPrice := utils.GetTradePrecision(buyPrice * 1.0012, coin.TickSize) // 价格上浮 0.1%(原因是市价买入通常会比当前价格高)
-						insertOpenOrder(symbol, quantity, strconv.FormatFloat(buyPrice, 'f', -1, 64), "LONG", int64(leverage_float64), order.OrderID)
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "buy",
-							PositionSide: "long",
-							Price: buyPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "buy",
-							PositionSide: "long",
-							Price: buyPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				} else {
-					order, err := binance.BuyLimit(symbol, quantity, buyPrice, futures.PositionSideTypeLong)
-					if err == nil {
-						// 数据库写入订单(可能没有买入)
-						insertOpenOrder(symbol, quantity, strconv.FormatFloat(buyPrice, 'f', -1, 64), "LONG", int64(leverage_float64), order.OrderID)
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "buy",
-							PositionSide: "long",
-							Price: buyPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "buy",
-							PositionSide: "long",
-							Price: buyPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				}
+			if err == nil && applyOrderSizing(systemConfig, coin, &openIntent, buyPrice) {
+				openIntent.PositionSide = positionSideLong
+				openIntent.Price = buyPrice
+				executor.Execute(openIntent)
 				isOpen = true
 			}
 		}
-		if systemConfig.FutureAllowShort == 1 && hasPositionShort == false && hasPositionShort == false && openResult.CanShort {
-			
+		if systemConfig.FutureAllowShort == 1 && hasPositionShort == false && openResult.CanShort {
 			_, sellPrice, err := binance.GetDepthAvgPrice(symbol, 5) // 平均卖价
-			if err == nil {
-				sellPrice = utils.GetTradePrecision(sellPrice, tickSize) // 合理精度的价格
-				quantity := (usdt_float64 / sellPrice) * leverage_float64  // 购买数量
-				quantity = utils.GetTradePrecision(quantity, stepSize) // 合理精度的价格
-				
-				UpdateSymbolTradeInfo(coin) // 更新倍率和仓位模式
-				
-				if systemConfig.FutureOrderType == "MARKET" {
-					order, err := binance.SellMarket(symbol, quantity, futures.PositionSideTypeShort)
-					if err == nil {
-						// 数据库写入订单
-						sellPrice := utils.GetTradePrecision(sellPrice * 0.9988, coin.TickSize) // 价格下调 0.12%(原因是市价买入通常会比当前价格高)
-						insertOpenOrder(symbol, quantity, strconv.FormatFloat(sellPrice, 'f', -1, 64), "SHORT", int64(leverage_float64), order.OrderID)
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "sell",
-							PositionSide: "short",
-							Price: sellPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "sell",
-							PositionSide: "short",
-							Price: sellPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				} else {
-					order, err := binance.SellLimit(symbol, quantity, sellPrice, futures.PositionSideTypeShort)
-					if err == nil {
-						// 数据库写入订单(可能没有买入)
-						insertOpenOrder(symbol, quantity, strconv.FormatFloat(sellPrice, 'f', -1, 64), "SHORT", int64(leverage_float64), order.OrderID)
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "sell",
-							PositionSide: "short",
-							Price: sellPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "success",
-						})
-					} else {
-						pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-							Title: lang.Lang("futures.open_notice_title"),
-							Symbol: symbol,
-							Side: "sell",
-							PositionSide: "short",
-							Price: sellPrice,
-							Quantity: quantity,
-							Leverage: leverage_float64,
-							Status: "fail",
-							Error: err.Error(),
-						})
-					}
-				}
+			if err == nil && applyOrderSizing(systemConfig, coin, &openIntent, sellPrice) {
+				openIntent.PositionSide = positionSideShort
+				openIntent.Price = sellPrice
+				executor.Execute(openIntent)
 				isOpen = true
 			}
 		}
@@ -647,61 +425,6 @@ func cancelTimeoutOrder(explodeSymbolsMap map[string]bool, allOpenOrders []types
 	}
 }
 
-func insertOpenOrder(symbol string, quantity float64, avg_price string, positionSide string, leverage int64, orderId int64) {
-	order := new(models.Order)
-	order.Symbol = symbol
-	order.Amount = strconv.FormatFloat(quantity, 'f', -1, 64)
-	order.Avg_price = avg_price
-	order.PositionSide = positionSide
-	order.Leverage = leverage
-	order.Inexact_profit = "0.0" // 预估收益
-	order.Side = "open"
-	order.OrderId = orderId
-	order.UpdateTime = time.Now().Unix() * 1000 
-	
-	o := orm.NewOrm()
-	o.Insert(order)
-}
-
-func insertCloseOrder(position types.FuturesPosition, positionAmtFloat float64, unRealizedProfit float64, avg_price string, orderId int64, systemConfig models.Config) {
-	// 数据库写入订单
-	order := new(models.Order)
-	order.Symbol = position.Symbol
-	order.Amount = strconv.FormatFloat(positionAmtFloat, 'f', -1, 64)
-	order.Avg_price = avg_price // 平仓价格
-	order.Inexact_profit = strconv.FormatFloat(unRealizedProfit, 'f', -1, 64)
-	order.PositionSide = position.Side
-	order.Leverage = position.Leverage
-	order.Side = "close"
-	order.OrderId = orderId
-	order.UpdateTime = time.Now().Unix() * 1000 
-	
-	o := orm.NewOrm()
-	o.Insert(order)
-	
-	// 自动缩放
-	AutoLossScale(systemConfig, unRealizedProfit >= 0)
-	
-	// 风控处理 - 记录盈亏
-	freezeService := utils.NewFreezeService()
-	strategyName := getStrategyNameFromConfig(systemConfig)
-	tradeType := "real" // 默认为实盘交易
-	
-	if unRealizedProfit < 0 {
-		// 亏损，记录亏损次数
-		err := freezeService.RecordLoss(position.Symbol, strategyName, tradeType)
-		if err != nil {
-			logs.Error("记录策略亏损失败:", err)
-		}
-	} else {
-		// 盈利，清零亏损次数
-		err := freezeService.RecordProfit(position.Symbol, strategyName, tradeType)
-		if err != nil {
-			logs.Error("记录策略盈利失败:", err)
-		}
-	}
-}
-
 // 根据配置获取策略名称
 func getStrategyNameFromConfig(systemConfig models.Config) string {
 	return systemConfig.FutureStrategyTrade + "_" + systemConfig.FutureStrategyCoin
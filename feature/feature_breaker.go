@@ -0,0 +1,367 @@
+package feature
+
+import (
+	"errors"
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/lang"
+	"go_binance_futures/models"
+	"go_binance_futures/notify"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const RiskCircuitBreaker = "circuit_breaker" // 账户熔断, 停止所有开仓
+
+// 熔断记录的类型
+const (
+	BreakerActionTrip = "trip"
+	BreakerActionRearm = "rearm"
+	BreakerActionFlatten = "flatten"
+)
+
+const breakerIncomeLimit = 1000 // 资金流水每次最多获取的数量
+
+// 计入已实现盈亏的资金流水类型
+var breakerIncomeTypes = map[string]bool{
+	"REALIZED_PNL": true,
+	"COMMISSION": true,
+	"FUNDING_FEE": true,
+}
+
+// 熔断的阈值, 为 0 时不限制
+type BreakerLimits struct {
+	DailyLoss float64
+	WeeklyLoss float64
+	MaxDrawdown float64
+}
+
+var breakerState *models.FuturesCircuitBreaker
+var breakerLock sync.Mutex
+
+func GetBreakerLimits(systemConfig models.Config) BreakerLimits {
+	return BreakerLimits{
+		DailyLoss: systemConfig.BreakerDailyLoss,
+		WeeklyLoss: systemConfig.BreakerWeeklyLoss,
+		MaxDrawdown: systemConfig.BreakerMaxDrawdown,
+	}
+}
+
+func (limits BreakerLimits) Enable() bool {
+	return limits.DailyLoss > 0 || limits.WeeklyLoss > 0 || limits.MaxDrawdown > 0
+}
+
+// 超过阈值时返回熔断原因
+func EvaluateBreaker(limits BreakerLimits, dayPnl float64, weekPnl float64, drawdown float64) string {
+	if limits.DailyLoss > 0 && -dayPnl >= limits.DailyLoss {
+		return fmt.Sprintf("daily loss %.2f >= %g", -dayPnl, limits.DailyLoss)
+	}
+	if limits.WeeklyLoss > 0 && -weekPnl >= limits.WeeklyLoss {
+		return fmt.Sprintf("weekly loss %.2f >= %g", -weekPnl, limits.WeeklyLoss)
+	}
+	if limits.MaxDrawdown > 0 && drawdown >= limits.MaxDrawdown {
+		return fmt.Sprintf("drawdown %.2f%% >= %g%%", drawdown, limits.MaxDrawdown)
+	}
+	return ""
+}
+
+// 当天和本周(周一开始)的开始时间, UTC
+func BreakerPeriodStart(now time.Time) (dayStart int64, weekStart int64) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	weekday := (int(day.Weekday()) + 6) % 7 // 周一为 0
+	return day.UnixMilli(), day.AddDate(0, 0, -weekday).UnixMilli()
+}
+
+// 更新权益的最高点, 返回最高点和回撤%
+func UpdateHighWater(highWater float64, equity float64) (float64, float64) {
+	if equity > highWater {
+		highWater = equity
+	}
+	if highWater <= 0 {
+		return highWater, 0
+	}
+	return highWater, math.Max(0, (highWater - equity) / highWater * 100)
+}
+
+// 熔断的状态, 第一次调用时从数据库读取, 没有时创建
+func getBreakerState() *models.FuturesCircuitBreaker {
+	if breakerState != nil {
+		return breakerState
+	}
+	o := orm.NewOrm()
+	state := models.FuturesCircuitBreaker{ID: 1}
+	err := o.Read(&state)
+	if err == orm.ErrNoRows {
+		_, err = o.Insert(&state)
+	}
+	if err != nil {
+		logs.Error("load circuit breaker error:", err.Error())
+		return &state // 下次重新读取
+	}
+	breakerState = &state
+	return breakerState
+}
+
+// 是否已经熔断(停止开仓)
+func IsCircuitBreakerTripped() (bool, string) {
+	breakerLock.Lock()
+	defer breakerLock.Unlock()
+	state := getBreakerState()
+	return state.Tripped == 1, state.Reason
+}
+
+func GetCircuitBreaker() models.FuturesCircuitBreaker {
+	breakerLock.Lock()
+	defer breakerLock.Unlock()
+	return *getBreakerState()
+}
+
+// 定时统计当天、本周的已实现盈亏和权益回撤, 超过阈值时熔断
+func CheckCircuitBreaker(systemConfig models.Config) {
+	limits := GetBreakerLimits(systemConfig)
+	if !limits.Enable() {
+		return
+	}
+	account, err := binance.GetFuturesAccount()
+	if err != nil {
+		logs.Error("circuit breaker get account error:", err.Error())
+		return
+	}
+	equity, _ := strconv.ParseFloat(account.TotalMarginBalance, 64)
+
+	breakerLock.Lock()
+	state := getBreakerState()
+	dayStart, weekStart := BreakerPeriodStart(time.Now())
+	// 手动恢复之前的亏损不再统计
+	daySince, weekSince := math.Max(float64(dayStart), float64(state.RearmTime)), math.Max(float64(weekStart), float64(state.RearmTime))
+	breakerLock.Unlock()
+
+	dayPnl, weekPnl, source := GetRealizedPnl(int64(daySince), int64(weekSince))
+
+	breakerLock.Lock()
+	state.DayPnl = dayPnl
+	state.WeekPnl = weekPnl
+	state.PnlSource = source
+	state.Equity = equity
+	state.HighWater, state.Drawdown = UpdateHighWater(state.HighWater, equity)
+	state.CheckTime = time.Now().UnixMilli()
+	orm.NewOrm().Update(state)
+	tripped := state.Tripped == 1
+	drawdown := state.Drawdown
+	breakerLock.Unlock()
+
+	if tripped {
+		return
+	}
+	if reason := EvaluateBreaker(limits, dayPnl, weekPnl, drawdown); reason != "" {
+		TripCircuitBreaker(systemConfig, reason, systemConfig.BreakerFlatten == 1)
+	}
+}
+
+// 已实现盈亏(包括手续费和资金费), 优先使用资金流水, 获取失败时使用订单表中平仓的预估收益
+func GetRealizedPnl(daySince int64, weekSince int64) (dayPnl float64, weekPnl float64, source string) {
+	startTime := daySince
+	if weekSince < startTime {
+		startTime = weekSince
+	}
+	incomes, err := getIncomes(startTime)
+	if err == nil {
+		for _, income := range incomes {
+			if !breakerIncomeTypes[income.IncomeType] {
+				continue
+			}
+			value, _ := strconv.ParseFloat(income.Income, 64)
+			if income.Time >= weekSince {
+				weekPnl += value
+			}
+			if income.Time >= daySince {
+				dayPnl += value
+			}
+		}
+		return dayPnl, weekPnl, "income"
+	}
+	logs.Error("circuit breaker get income error, use order profit:", err.Error())
+	var orders []models.Order
+	orm.NewOrm().QueryTable("order").Filter("side", OrderActionClose).Filter("updateTime__gte", startTime).All(&orders, "Inexact_profit", "UpdateTime")
+	for _, order := range orders {
+		value, _ := strconv.ParseFloat(order.Inexact_profit, 64)
+		if order.UpdateTime >= weekSince {
+			weekPnl += value
+		}
+		if order.UpdateTime >= daySince {
+			dayPnl += value
+		}
+	}
+	return dayPnl, weekPnl, "order"
+}
+
+// 分页获取资金流水
+func getIncomes(startTime int64) (incomes []*futures.IncomeHistory, err error) {
+	for i := 0; i < 20; i++ {
+		res, err := binance.GetIncome(binance.IncomeParams{StartTime: startTime, Limit: breakerIncomeLimit})
+		if err != nil {
+			return incomes, err
+		}
+		for _, item := range res {
+			incomes = append(incomes, item)
+			if item.Time >= startTime {
+				startTime = item.Time + 1
+			}
+		}
+		if len(res) < breakerIncomeLimit {
+			break
+		}
+	}
+	return incomes, nil
+}
+
+// 熔断: 停止开仓, 停止网格, 可选平掉所有仓位, 需要手动恢复
+func TripCircuitBreaker(systemConfig models.Config, reason string, flatten bool) error {
+	breakerLock.Lock()
+	state := getBreakerState()
+	if state.Tripped == 1 {
+		breakerLock.Unlock()
+		return errors.New("circuit breaker is already tripped")
+	}
+	state.Tripped = 1
+	state.Reason = reason
+	state.TripTime = time.Now().UnixMilli()
+	_, err := orm.NewOrm().Update(state)
+	event := breakerEvent(state, BreakerActionTrip, reason)
+	breakerLock.Unlock()
+	if err != nil {
+		return err
+	}
+	logs.Warn("circuit breaker tripped:", reason)
+
+	var grids []models.FuturesGrid
+	orm.NewOrm().QueryTable("futures_grids").Filter("status", GridStatusRunning).All(&grids)
+	for _, grid := range grids {
+		StopGrid(grid.ID)
+	}
+	content := breakerNoticeContent(event)
+	if flatten {
+		closed, failed := FlattenPositions(systemConfig)
+		detail := fmt.Sprintf("closed %d positions, failed %d", closed, failed)
+		breakerEvent(state, BreakerActionFlatten, detail)
+		content = append(content, lang.Lang("futures.circuit_breaker") + ": " + detail)
+	}
+	pusher.FuturesAlert(notify.FuturesAlertParams{
+		Title: lang.Lang("futures.circuit_breaker_title"),
+		Content: content,
+	})
+	return nil
+}
+
+// 手动恢复开仓, 之前的亏损不再统计, 权益的最高点重置为当前权益
+func RearmCircuitBreaker(comment string) (models.FuturesCircuitBreaker, error) {
+	breakerLock.Lock()
+	defer breakerLock.Unlock()
+	state := getBreakerState()
+	if state.Tripped != 1 {
+		return *state, errors.New("circuit breaker is not tripped")
+	}
+	state.Tripped = 0
+	state.Reason = ""
+	state.RearmTime = time.Now().UnixMilli()
+	state.DayPnl = 0
+	state.WeekPnl = 0
+	state.HighWater = state.Equity
+	state.Drawdown = 0
+	_, err := orm.NewOrm().Update(state)
+	if err != nil {
+		return *state, err
+	}
+	event := breakerEvent(state, BreakerActionRearm, comment)
+	logs.Info("circuit breaker rearmed:", comment)
+	pusher.FuturesAlert(notify.FuturesAlertParams{
+		Title: lang.Lang("futures.circuit_breaker_rearm_title"),
+		Content: breakerNoticeContent(event),
+	})
+	return *state, nil
+}
+
+// 撤销开仓挂单并平掉所有仓位(排除手动交易的币种), 返回平仓成功和失败的数量
+func FlattenPositions(systemConfig models.Config) (closed int, failed int) {
+	excludeSymbols := GetExcludeSymbolsMap(systemConfig.FutureExcludeSymbols)
+	orders, err := getTransformOpenOrders()
+	if err == nil {
+		for _, order := range orders {
+			isOpen := (order.PositionSide == "LONG" && order.Side == "BUY") || (order.PositionSide == "SHORT" && order.Side == "SELL")
+			if excludeSymbols[order.Symbol] || !isOpen || order.Type != "LIMIT" {
+				continue
+			}
+			if _, err := binance.CancelOrder(order.Symbol, order.OrderId); err != nil {
+				logs.Error("circuit breaker cancel order error:", order.Symbol, err.Error())
+			}
+		}
+	}
+	positions, err := GetTransformPositions()
+	if err != nil {
+		logs.Error("circuit breaker get positions error:", err.Error())
+		return closed, failed + 1
+	}
+	executor := NewOrderExecutor(systemConfig)
+	for _, position := range positions {
+		amount, _ := strconv.ParseFloat(position.Amount, 64)
+		if excludeSymbols[position.Symbol] || math.Abs(amount) < 0.0000000001 {
+			continue
+		}
+		markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
+		unRealizedProfit, _ := strconv.ParseFloat(position.UnrealizedProfit, 64)
+		_, err := executor.Execute(OrderIntent{
+			Action: OrderActionClose,
+			Symbol: position.Symbol,
+			PositionSide: position.Side,
+			Quantity: math.Abs(amount),
+			Price: markPrice,
+			Leverage: position.Leverage,
+			Profit: unRealizedProfit,
+			Reason: RiskCircuitBreaker,
+		})
+		if err != nil {
+			failed++
+		} else {
+			closed++
+		}
+	}
+	return closed, failed
+}
+
+// 熔断和恢复的记录
+func GetCircuitBreakerEvents(limit int) (events []models.FuturesCircuitBreakerEvent, err error) {
+	_, err = orm.NewOrm().QueryTable("futures_circuit_breaker_events").OrderBy("-ID").Limit(limit).All(&events)
+	return events, err
+}
+
+func breakerEvent(state *models.FuturesCircuitBreaker, action string, reason string) models.FuturesCircuitBreakerEvent {
+	event := models.FuturesCircuitBreakerEvent{
+		Action: action,
+		Reason: reason,
+		DayPnl: state.DayPnl,
+		WeekPnl: state.WeekPnl,
+		Equity: state.Equity,
+		Drawdown: state.Drawdown,
+		CreateTime: time.Now().UnixMilli(),
+	}
+	if _, err := orm.NewOrm().Insert(&event); err != nil {
+		logs.Error("save circuit breaker event error:", err.Error())
+	}
+	return event
+}
+
+func breakerNoticeContent(event models.FuturesCircuitBreakerEvent) []string {
+	return []string{
+		lang.Lang("futures.remarks") + ": " + event.Reason,
+		lang.Lang("futures.day_pnl") + ": " + strconv.FormatFloat(event.DayPnl, 'f', 2, 64),
+		lang.Lang("futures.week_pnl") + ": " + strconv.FormatFloat(event.WeekPnl, 'f', 2, 64),
+		lang.Lang("futures.drawdown") + ": " + strconv.FormatFloat(event.Drawdown, 'f', 2, 64) + "%",
+	}
+}
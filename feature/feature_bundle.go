@@ -0,0 +1,687 @@
+package feature
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"go_binance_futures/spot"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"gopkg.in/yaml.v3"
+)
+
+const BundleFormatVersion = 1 // 配置包的格式版本, 不兼容的修改时 +1
+
+// 配置包包含的内容
+const (
+	BundleSectionTemplates = "strategy_templates"
+	BundleSectionSymbols = "symbols"
+	BundleSectionListenSymbols = "listen_symbols"
+	BundleSectionNoticeSymbols = "notice_symbols"
+	BundleSectionStrategyFreeze = "strategy_freeze"
+)
+
+var BundleSections = []string{BundleSectionTemplates, BundleSectionSymbols, BundleSectionListenSymbols, BundleSectionNoticeSymbols, BundleSectionStrategyFreeze}
+
+// 导入方式
+const (
+	BundleImportPreview = "preview" // 只检查, 返回会新增、修改的内容和冲突
+	BundleImportMerge = "merge" // 新增不存在的, 冲突(已存在且内容不同)的跳过
+	BundleImportOverwrite = "overwrite" // 新增不存在的, 冲突的使用配置包的内容覆盖
+)
+
+// 配置包, 只包含配置, 不包含 id、行情、通知状态等运行时的数据, 币种之间通过名称关联
+type ConfigBundle struct {
+	Version int `json:"version"` // 格式版本
+	ExportTime int64 `json:"export_time"`
+	StrategyTemplates []BundleTemplate `json:"strategy_templates,omitempty"`
+	Symbols []BundleSymbol `json:"symbols,omitempty"`
+	ListenSymbols []BundleListenSymbol `json:"listen_symbols,omitempty"`
+	NoticeSymbols []BundleNoticeSymbol `json:"notice_symbols,omitempty"`
+	StrategyFreeze []BundleStrategyFreeze `json:"strategy_freeze,omitempty"`
+}
+
+// 策略模板, 按照 name 匹配
+type BundleTemplate struct {
+	Name string `json:"name"`
+	TemplateContent
+}
+
+// 合约币种的交易配置, 按照 symbol 匹配
+type BundleSymbol struct {
+	Symbol string `json:"symbol"`
+	Enable int `json:"enable"`
+	Type string `json:"type"`
+	Leverage int64 `json:"leverage"`
+	MarginType string `json:"marginType"`
+	Usdt string `json:"usdt"`
+	Profit string `json:"profit"`
+	Loss string `json:"loss"`
+	StrategyType string `json:"strategy_type"`
+	SizingMode string `json:"sizing_mode"`
+	SizingValue float64 `json:"sizing_value"`
+	Template string `json:"template"` // 使用的策略模板名称, 导入时关联内容相同的版本
+	TemplateContent
+}
+
+// 行情监听, 按照 symbol + type + listen_type 匹配
+type BundleListenSymbol struct {
+	Symbol string `json:"symbol"`
+	Type int64 `json:"type"`
+	ListenType string `json:"listen_type"`
+	Enable int `json:"enable"`
+	KlineInterval string `json:"kline_interval"`
+	ChangePercent string `json:"change_percent"`
+	NoticeLimitMin int64 `json:"notice_limit_min"`
+	Technology string `json:"technology"`
+	Strategy string `json:"strategy"`
+}
+
+// 币种通知, 按照 symbol + type 匹配
+type BundleNoticeSymbol struct {
+	Symbol string `json:"symbol"`
+	Type int64 `json:"type"`
+	Enable int `json:"enable"`
+	NoticePrice string `json:"notice_price"`
+	AutoOrder int64 `json:"auto_order"`
+	ProfitPrice string `json:"profit_price"`
+	LossPrice string `json:"loss_price"`
+	Leverage int64 `json:"leverage"`
+	MarginType string `json:"marginType"`
+	Usdt string `json:"usdt"`
+	Side string `json:"side"`
+	Quantity string `json:"quantity"`
+}
+
+// 策略冻结配置(不包含当前的亏损次数和冻结时间), 按照 symbol + strategy_name + trade_type 匹配
+type BundleStrategyFreeze struct {
+	Symbol string `json:"symbol"`
+	StrategyName string `json:"strategy_name"`
+	TradeType string `json:"trade_type"`
+	FreezeOnLossCount int `json:"freeze_on_loss_count"`
+	FreezeHours int `json:"freeze_hours"`
+	LossAmount float64 `json:"loss_amount"`
+	LossWindowHours int `json:"loss_window_hours"`
+	RecentTrades int `json:"recent_trades"`
+	RecentLossCount int `json:"recent_loss_count"`
+	EscalateFactor float64 `json:"escalate_factor"`
+	EscalateWindowHours int `json:"escalate_window_hours"`
+	MaxFreezeHours int `json:"max_freeze_hours"`
+}
+
+// 导入的结果
+type BundleImportReport struct {
+	Mode string `json:"mode"`
+	Applied bool `json:"applied"` // 是否写入了数据库
+	Sections map[string]*BundleSectionReport `json:"sections"`
+	Errors []string `json:"errors"` // 检查失败时不会写入
+}
+
+type BundleSectionReport struct {
+	Created []string `json:"created"` // 新增
+	Updated []string `json:"updated"` // 覆盖
+	Unchanged []string `json:"unchanged"` // 内容相同
+	Skipped []string `json:"skipped"` // 冲突跳过
+	Conflicts []BundleConflict `json:"conflicts"` // 已存在且内容不同
+}
+
+type BundleConflict struct {
+	Key string `json:"key"`
+	Fields []BundleFieldConflict `json:"fields"`
+}
+
+type BundleFieldConflict struct {
+	Field string `json:"field"`
+	Local interface{} `json:"local"`
+	Bundle interface{} `json:"bundle"`
+}
+
+// 导出配置包, sections 为空时导出所有内容, symbols 不为空时只导出这些币种(策略模板不过滤)
+func ExportConfigBundle(sections []string, symbols []string) (bundle ConfigBundle, err error) {
+	bundle = ConfigBundle{
+		Version: BundleFormatVersion,
+		ExportTime: time.Now().UnixMilli(),
+	}
+	include := sectionSet(sections)
+	o := orm.NewOrm()
+	templateNames := map[int64]string{}
+	var templates []models.StrategyTemplates
+	_, err = o.QueryTable("strategy_templates").OrderBy("ID").All(&templates)
+	if err != nil {
+		return bundle, err
+	}
+	for _, template := range templates {
+		templateNames[template.ID] = template.Name
+		if include[BundleSectionTemplates] {
+			bundle.StrategyTemplates = append(bundle.StrategyTemplates, BundleTemplate{Name: template.Name, TemplateContent: TemplateContentOf(template)})
+		}
+	}
+	if include[BundleSectionSymbols] {
+		var coins []models.Symbols
+		_, err = filterSymbols(o.QueryTable("symbols"), symbols).OrderBy("ID").All(&coins)
+		if err != nil {
+			return bundle, err
+		}
+		for _, coin := range coins {
+			bundle.Symbols = append(bundle.Symbols, bundleSymbolOf(coin, templateNames))
+		}
+	}
+	if include[BundleSectionListenSymbols] {
+		var coins []models.ListenSymbols
+		_, err = filterSymbols(o.QueryTable("listen_symbols"), symbols).OrderBy("ID").All(&coins)
+		if err != nil {
+			return bundle, err
+		}
+		for _, coin := range coins {
+			bundle.ListenSymbols = append(bundle.ListenSymbols, bundleListenSymbolOf(coin))
+		}
+	}
+	if include[BundleSectionNoticeSymbols] {
+		var coins []models.NoticeSymbols
+		_, err = filterSymbols(o.QueryTable("notice_symbols"), symbols).OrderBy("ID").All(&coins)
+		if err != nil {
+			return bundle, err
+		}
+		for _, coin := range coins {
+			bundle.NoticeSymbols = append(bundle.NoticeSymbols, bundleNoticeSymbolOf(coin))
+		}
+	}
+	if include[BundleSectionStrategyFreeze] {
+		var freezes []models.StrategyFreeze
+		_, err = filterSymbols(o.QueryTable("strategy_freeze"), symbols).OrderBy("ID").All(&freezes)
+		if err != nil {
+			return bundle, err
+		}
+		for _, freeze := range freezes {
+			bundle.StrategyFreeze = append(bundle.StrategyFreeze, bundleStrategyFreezeOf(freeze))
+		}
+	}
+	return bundle, nil
+}
+
+// 配置包转换为 json 或者 yaml, yaml 的字段名和 json 相同
+func EncodeConfigBundle(bundle ConfigBundle, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil || format != "yaml" {
+		return data, err
+	}
+	var value interface{}
+	err = json.Unmarshal(data, &value)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(value)
+}
+
+// 解析 json 或者 yaml 格式的配置包
+func DecodeConfigBundle(data []byte) (bundle ConfigBundle, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return bundle, errors.New("empty bundle")
+	}
+	if data[0] != '{' {
+		var value interface{}
+		err = yaml.Unmarshal(data, &value)
+		if err != nil {
+			return bundle, fmt.Errorf("invalid bundle: %s", err.Error())
+		}
+		data, err = json.Marshal(value)
+		if err != nil {
+			return bundle, fmt.Errorf("invalid bundle: %s", err.Error())
+		}
+	}
+	err = json.Unmarshal(data, &bundle)
+	if err != nil {
+		return bundle, fmt.Errorf("invalid bundle: %s", err.Error())
+	}
+	if bundle.Version <= 0 || bundle.Version > BundleFormatVersion {
+		return bundle, fmt.Errorf("unsupported bundle version %d, support <= %d", bundle.Version, BundleFormatVersion)
+	}
+	return bundle, nil
+}
+
+// 导入配置包, 先检查所有内容(自定义策略编译检查), 有错误时不写入
+func ImportConfigBundle(bundle ConfigBundle, mode string) (report BundleImportReport, err error) {
+	if mode == "" {
+		mode = BundleImportPreview
+	}
+	if mode != BundleImportPreview && mode != BundleImportMerge && mode != BundleImportOverwrite {
+		return report, errors.New("invalid import mode: " + mode)
+	}
+	report = BundleImportReport{
+		Mode: mode,
+		Sections: map[string]*BundleSectionReport{},
+		Errors: validateConfigBundle(bundle),
+	}
+	for _, section := range BundleSections {
+		report.Sections[section] = &BundleSectionReport{
+			Created: []string{},
+			Updated: []string{},
+			Unchanged: []string{},
+			Skipped: []string{},
+			Conflicts: []BundleConflict{},
+		}
+	}
+	write := mode != BundleImportPreview && len(report.Errors) == 0
+	importer := bundleImporter{mode: mode, write: write, report: &report}
+	if !write {
+		importer.o = orm.NewOrm()
+		importer.importAll(bundle)
+		return report, nil
+	}
+	// 所有写入在一个事务中, 有错误时回滚
+	err = orm.NewOrm().DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
+		importer.o = txOrm
+		importer.importAll(bundle)
+		if len(report.Errors) > 0 {
+			return errors.New(report.Errors[0])
+		}
+		return nil
+	})
+	if err != nil {
+		if len(report.Errors) == 0 {
+			report.Errors = append(report.Errors, err.Error()) // 提交失败
+		}
+		return report, nil
+	}
+	report.Applied = true
+	line.InvalidateProgramCache("")
+	logs.Info("import config bundle:", mode)
+	return report, nil
+}
+
+// 检查重复的 key 和自定义策略
+func validateConfigBundle(bundle ConfigBundle) []string {
+	errs := []string{}
+	keys := map[string]bool{}
+	checkKey := func(section string, key string) {
+		if keys[section + ":" + key] {
+			errs = append(errs, fmt.Sprintf("%s: duplicate %s", section, key))
+		}
+		keys[section + ":" + key] = true
+	}
+	templates := map[string]bool{}
+	for _, item := range bundle.StrategyTemplates {
+		checkKey(BundleSectionTemplates, item.Name)
+		templates[item.Name] = true
+		if _, err := line.ParseIndicatorConfigs(item.Technology); item.Technology != "" && err != nil {
+			errs = append(errs, fmt.Sprintf("%s %s: %s", BundleSectionTemplates, item.Name, err.Error()))
+		}
+	}
+	var localTemplates []models.StrategyTemplates
+	orm.NewOrm().QueryTable("strategy_templates").All(&localTemplates, "Name")
+	for _, template := range localTemplates {
+		templates[template.Name] = true
+	}
+	for _, item := range bundle.Symbols {
+		checkKey(BundleSectionSymbols, item.Symbol)
+		if item.Template != "" && !templates[item.Template] {
+			errs = append(errs, fmt.Sprintf("%s %s: template %s not found", BundleSectionSymbols, item.Symbol, item.Template))
+		}
+		if item.StrategyType == "custom" && item.Strategy != "" {
+			if err := line.ValidateStrategy(item.Symbol, item.Technology, item.Strategy); err != nil {
+				errs = append(errs, fmt.Sprintf("%s %s: %s", BundleSectionSymbols, item.Symbol, err.Error()))
+			}
+		}
+	}
+	for _, item := range bundle.ListenSymbols {
+		checkKey(BundleSectionListenSymbols, listenSymbolKey(item))
+	}
+	for _, item := range bundle.NoticeSymbols {
+		checkKey(BundleSectionNoticeSymbols, noticeSymbolKey(item))
+	}
+	for _, item := range bundle.StrategyFreeze {
+		checkKey(BundleSectionStrategyFreeze, strategyFreezeKey(item))
+	}
+	return errs
+}
+
+type bundleImporter struct {
+	mode string
+	write bool
+	report *BundleImportReport
+	o orm.QueryExecutor // 写入时为事务
+}
+
+func (importer *bundleImporter) importAll(bundle ConfigBundle) {
+	importer.importTemplates(bundle.StrategyTemplates)
+	importer.importSymbols(bundle.Symbols)
+	importer.importListenSymbols(bundle.ListenSymbols)
+	importer.importNoticeSymbols(bundle.NoticeSymbols)
+	importer.importStrategyFreeze(bundle.StrategyFreeze)
+}
+
+// 比较已有的配置和配置包, 返回是否需要写入
+func (importer *bundleImporter) compare(section string, key string, exists bool, local interface{}, bundle interface{}) bool {
+	sectionReport := importer.report.Sections[section]
+	if !exists {
+		sectionReport.Created = append(sectionReport.Created, key)
+		return importer.write
+	}
+	fields := DiffBundleItem(local, bundle)
+	if len(fields) == 0 {
+		sectionReport.Unchanged = append(sectionReport.Unchanged, key)
+		return false
+	}
+	sectionReport.Conflicts = append(sectionReport.Conflicts, BundleConflict{Key: key, Fields: fields})
+	if importer.mode == BundleImportOverwrite {
+		sectionReport.Updated = append(sectionReport.Updated, key)
+		return importer.write
+	}
+	if importer.mode == BundleImportMerge {
+		sectionReport.Skipped = append(sectionReport.Skipped, key)
+	}
+	return false
+}
+
+func (importer *bundleImporter) error(section string, key string, err error) {
+	importer.report.Errors = append(importer.report.Errors, fmt.Sprintf("%s %s: %s", section, key, err.Error()))
+}
+
+func (importer *bundleImporter) importTemplates(items []BundleTemplate) {
+	o := importer.o
+	for _, item := range items {
+		var template models.StrategyTemplates
+		exists := o.QueryTable("strategy_templates").Filter("Name", item.Name).One(&template) == nil
+		if !importer.compare(BundleSectionTemplates, item.Name, exists, BundleTemplate{Name: template.Name, TemplateContent: TemplateContentOf(template)}, item) {
+			continue
+		}
+		now := time.Now().UnixMilli()
+		ApplyTemplateContentToTemplate(&template, item.TemplateContent)
+		template.Name = item.Name
+		template.UpdateTime = now
+		var err error
+		if exists {
+			_, err = o.Update(&template)
+		} else {
+			template.CreateTime = now
+			template.ID, err = o.Insert(&template)
+		}
+		if err == nil {
+			_, _, err = saveTemplateVersion(o, &template, "import")
+		}
+		if err != nil {
+			importer.error(BundleSectionTemplates, item.Name, err)
+		}
+	}
+}
+
+func (importer *bundleImporter) importSymbols(items []BundleSymbol) {
+	o := importer.o
+	templateIds := map[string]int64{}
+	templateNames := map[int64]string{}
+	var templates []models.StrategyTemplates
+	o.QueryTable("strategy_templates").All(&templates, "ID", "Name")
+	for _, template := range templates {
+		templateIds[template.Name] = template.ID
+		templateNames[template.ID] = template.Name
+	}
+	for _, item := range items {
+		var coin models.Symbols
+		exists := o.QueryTable("symbols").Filter("Symbol", item.Symbol).One(&coin) == nil
+		if !importer.compare(BundleSectionSymbols, item.Symbol, exists, bundleSymbolOf(coin, templateNames), item) {
+			continue
+		}
+		applyBundleSymbol(&coin, item)
+		// 关联模板中内容相同的版本, 没有相同的版本时不关联
+		coin.TemplateId, coin.TemplateVersion = 0, 0
+		if templateId, ok := templateIds[item.Template]; ok {
+			var version models.StrategyTemplateVersion
+			err := o.QueryTable("strategy_template_versions").Filter("TemplateId", templateId).OrderBy("-Version").Filter("Technology", item.Technology).Filter("Strategy", item.Strategy).One(&version)
+			if err == nil && VersionContentOf(version) == item.TemplateContent {
+				coin.TemplateId, coin.TemplateVersion = templateId, version.Version
+			}
+		}
+		var err error
+		if exists {
+			_, err = o.Update(&coin)
+		} else {
+			// 和新增币种的默认值一致, 交易精度由定时任务更新
+			coin.Close, coin.Open, coin.Low, coin.High = "0", "0", "0", "0"
+			coin.StepSize, coin.TickSize = "0.1", "0.1"
+			coin.KlineInterval = "1d"
+			_, err = o.Insert(&coin)
+		}
+		if err != nil {
+			importer.error(BundleSectionSymbols, item.Symbol, err)
+		}
+	}
+}
+
+func (importer *bundleImporter) importListenSymbols(items []BundleListenSymbol) {
+	o := importer.o
+	for _, item := range items {
+		var coin models.ListenSymbols
+		exists := o.QueryTable("listen_symbols").Filter("Symbol", item.Symbol).Filter("Type", item.Type).Filter("ListenType", item.ListenType).One(&coin) == nil
+		key := listenSymbolKey(item)
+		if !importer.compare(BundleSectionListenSymbols, key, exists, bundleListenSymbolOf(coin), item) {
+			continue
+		}
+		coin.Symbol, coin.Type, coin.ListenType = item.Symbol, item.Type, item.ListenType
+		coin.Enable, coin.KlineInterval, coin.ChangePercent, coin.NoticeLimitMin = item.Enable, item.KlineInterval, item.ChangePercent, item.NoticeLimitMin
+		coin.Technology, coin.Strategy = item.Technology, item.Strategy
+		coin.UpdateTime = time.Now().UnixMilli()
+		var err error
+		if exists {
+			_, err = o.Update(&coin)
+		} else {
+			coin.CreateTime = coin.UpdateTime
+			_, err = o.Insert(&coin)
+		}
+		if err != nil {
+			importer.error(BundleSectionListenSymbols, key, err)
+		}
+	}
+}
+
+func (importer *bundleImporter) importNoticeSymbols(items []BundleNoticeSymbol) {
+	o := importer.o
+	for _, item := range items {
+		var coin models.NoticeSymbols
+		exists := o.QueryTable("notice_symbols").Filter("Symbol", item.Symbol).Filter("Type", item.Type).OrderBy("ID").One(&coin) == nil
+		key := noticeSymbolKey(item)
+		if !importer.compare(BundleSectionNoticeSymbols, key, exists, bundleNoticeSymbolOf(coin), item) {
+			continue
+		}
+		coin.Symbol, coin.Type, coin.Enable = item.Symbol, item.Type, item.Enable
+		coin.NoticePrice, coin.AutoOrder, coin.ProfitPrice, coin.LossPrice = item.NoticePrice, item.AutoOrder, item.ProfitPrice, item.LossPrice
+		coin.Leverage, coin.MarginType, coin.Usdt, coin.Side, coin.Quantity = item.Leverage, item.MarginType, item.Usdt, item.Side, item.Quantity
+		coin.HasNotice = 0 // 修改后重新通知
+		coin.UpdateTime = time.Now().UnixMilli()
+		var err error
+		if exists {
+			_, err = o.Update(&coin)
+		} else {
+			coin.CreateTime = coin.UpdateTime
+			// 交易精度和新增通知时一样从交易所获取
+			if coin.Type == 1 {
+				coin.TickSize, coin.StepSize = spot.GetCoinOrderSize(coin.Symbol)
+			} else {
+				coin.TickSize, coin.StepSize = GetCoinOrderSize(coin.Symbol)
+			}
+			_, err = o.Insert(&coin)
+		}
+		if err != nil {
+			importer.error(BundleSectionNoticeSymbols, key, err)
+		}
+	}
+}
+
+func (importer *bundleImporter) importStrategyFreeze(items []BundleStrategyFreeze) {
+	o := importer.o
+	for _, item := range items {
+		var freeze models.StrategyFreeze
+		exists := o.QueryTable("strategy_freeze").Filter("Symbol", item.Symbol).Filter("StrategyName", item.StrategyName).Filter("TradeType", item.TradeType).One(&freeze) == nil
+		key := strategyFreezeKey(item)
+		if !importer.compare(BundleSectionStrategyFreeze, key, exists, bundleStrategyFreezeOf(freeze), item) {
+			continue
+		}
+		freeze.Symbol, freeze.StrategyName, freeze.TradeType = item.Symbol, item.StrategyName, item.TradeType
+		freeze.FreezeOnLossCount, freeze.FreezeHours = item.FreezeOnLossCount, item.FreezeHours
+		freeze.LossAmount, freeze.LossWindowHours = item.LossAmount, item.LossWindowHours
+		freeze.RecentTrades, freeze.RecentLossCount = item.RecentTrades, item.RecentLossCount
+		freeze.EscalateFactor, freeze.EscalateWindowHours, freeze.MaxFreezeHours = item.EscalateFactor, item.EscalateWindowHours, item.MaxFreezeHours
+		freeze.UpdatedAt = time.Now().Unix()
+		var err error
+		if exists {
+			_, err = o.Update(&freeze, "FreezeOnLossCount", "FreezeHours", "LossAmount", "LossWindowHours", "RecentTrades", "RecentLossCount", "EscalateFactor", "EscalateWindowHours", "MaxFreezeHours", "UpdatedAt")
+		} else {
+			freeze.CreatedAt = freeze.UpdatedAt
+			_, err = o.Insert(&freeze)
+		}
+		if err != nil {
+			importer.error(BundleSectionStrategyFreeze, key, err)
+		}
+	}
+}
+
+// 比较两个配置的字段(json 字段名), 返回不同的字段
+func DiffBundleItem(local interface{}, bundle interface{}) []BundleFieldConflict {
+	localMap, bundleMap := bundleItemMap(local), bundleItemMap(bundle)
+	fields := []string{}
+	for field := range bundleMap {
+		fields = append(fields, field)
+	}
+	for field := range localMap {
+		if _, ok := bundleMap[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	conflicts := []BundleFieldConflict{}
+	for _, field := range fields {
+		if !reflect.DeepEqual(localMap[field], bundleMap[field]) {
+			conflicts = append(conflicts, BundleFieldConflict{Field: field, Local: localMap[field], Bundle: bundleMap[field]})
+		}
+	}
+	return conflicts
+}
+
+func bundleItemMap(item interface{}) map[string]interface{} {
+	data, _ := json.Marshal(item)
+	value := map[string]interface{}{}
+	json.Unmarshal(data, &value)
+	return value
+}
+
+func bundleSymbolOf(coin models.Symbols, templateNames map[int64]string) BundleSymbol {
+	return BundleSymbol{
+		Symbol: coin.Symbol,
+		Enable: coin.Enable,
+		Type: coin.Type,
+		Leverage: coin.Leverage,
+		MarginType: coin.MarginType,
+		Usdt: coin.Usdt,
+		Profit: coin.Profit,
+		Loss: coin.Loss,
+		StrategyType: coin.StrategyType,
+		SizingMode: coin.SizingMode,
+		SizingValue: coin.SizingValue,
+		Template: templateNames[coin.TemplateId],
+		TemplateContent: SymbolContentOf(coin),
+	}
+}
+
+func applyBundleSymbol(coin *models.Symbols, item BundleSymbol) {
+	coin.Symbol = item.Symbol
+	coin.Enable = item.Enable
+	coin.Type = item.Type
+	coin.Leverage = item.Leverage
+	coin.MarginType = item.MarginType
+	coin.Usdt = item.Usdt
+	coin.Profit = item.Profit
+	coin.Loss = item.Loss
+	coin.StrategyType = item.StrategyType
+	coin.SizingMode = item.SizingMode
+	coin.SizingValue = item.SizingValue
+	ApplyTemplateContent(coin, item.TemplateContent)
+}
+
+func bundleListenSymbolOf(coin models.ListenSymbols) BundleListenSymbol {
+	return BundleListenSymbol{
+		Symbol: coin.Symbol,
+		Type: coin.Type,
+		ListenType: coin.ListenType,
+		Enable: coin.Enable,
+		KlineInterval: coin.KlineInterval,
+		ChangePercent: coin.ChangePercent,
+		NoticeLimitMin: coin.NoticeLimitMin,
+		Technology: coin.Technology,
+		Strategy: coin.Strategy,
+	}
+}
+
+func bundleNoticeSymbolOf(coin models.NoticeSymbols) BundleNoticeSymbol {
+	return BundleNoticeSymbol{
+		Symbol: coin.Symbol,
+		Type: coin.Type,
+		Enable: coin.Enable,
+		NoticePrice: coin.NoticePrice,
+		AutoOrder: coin.AutoOrder,
+		ProfitPrice: coin.ProfitPrice,
+		LossPrice: coin.LossPrice,
+		Leverage: coin.Leverage,
+		MarginType: coin.MarginType,
+		Usdt: coin.Usdt,
+		Side: coin.Side,
+		Quantity: coin.Quantity,
+	}
+}
+
+func bundleStrategyFreezeOf(freeze models.StrategyFreeze) BundleStrategyFreeze {
+	return BundleStrategyFreeze{
+		Symbol: freeze.Symbol,
+		StrategyName: freeze.StrategyName,
+		TradeType: freeze.TradeType,
+		FreezeOnLossCount: freeze.FreezeOnLossCount,
+		FreezeHours: freeze.FreezeHours,
+		LossAmount: freeze.LossAmount,
+		LossWindowHours: freeze.LossWindowHours,
+		RecentTrades: freeze.RecentTrades,
+		RecentLossCount: freeze.RecentLossCount,
+		EscalateFactor: freeze.EscalateFactor,
+		EscalateWindowHours: freeze.EscalateWindowHours,
+		MaxFreezeHours: freeze.MaxFreezeHours,
+	}
+}
+
+func listenSymbolKey(item BundleListenSymbol) string {
+	return fmt.Sprintf("%s_%d_%s", item.Symbol, item.Type, item.ListenType)
+}
+
+func noticeSymbolKey(item BundleNoticeSymbol) string {
+	return fmt.Sprintf("%s_%d", item.Symbol, item.Type)
+}
+
+func strategyFreezeKey(item BundleStrategyFreeze) string {
+	return item.Symbol + "_" + item.StrategyName + "_" + item.TradeType
+}
+
+func sectionSet(sections []string) map[string]bool {
+	include := map[string]bool{}
+	for _, section := range sections {
+		include[strings.TrimSpace(section)] = true
+	}
+	if len(include) == 0 {
+		for _, section := range BundleSections {
+			include[section] = true
+		}
+	}
+	return include
+}
+
+func filterSymbols(query orm.QuerySeter, symbols []string) orm.QuerySeter {
+	if len(symbols) > 0 {
+		return query.Filter("Symbol__in", symbols)
+	}
+	return query
+}
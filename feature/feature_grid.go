@@ -0,0 +1,398 @@
+package feature
+
+import (
+	"errors"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	GridModeNeutral = "neutral" // 中性, 当前价格下方挂开多单, 上方挂开空单
+	GridModeLong = "long" // 做多, 当前价格下方挂开多单, 成交后在上一格挂平多单
+	GridModeShort = "short" // 做空, 当前价格上方挂开空单, 成交后在下一格挂平空单
+
+	GridStatusRunning = "running"
+	GridStatusStopped = "stopped"
+
+	gridOrderOpen = "open"
+	gridOrderClose = "close"
+)
+
+var gridLock sync.Mutex
+
+// 启动网格机器人, 成交通过 ws 用户数据推送(开启 futures_user_data 时)和定时查询挂单状态两种方式处理
+func StartGridBot() {
+	binance.OnOrderUpdate(func(order futures.WsOrderTradeUpdate) {
+		if order.Status != futures.OrderStatusTypeFilled {
+			return
+		}
+		price, _ := strconv.ParseFloat(order.AveragePrice, 64)
+		HandleGridOrderFilled(order.ID, price)
+	})
+	for {
+		SyncGrids()
+		time.Sleep(time.Second * 10) // 10秒间隔
+	}
+}
+
+// 运行中的网格的币种
+func GetRunningGridSymbols() map[string]bool {
+	var grids []models.FuturesGrid
+	orm.NewOrm().QueryTable("futures_grids").Filter("status", GridStatusRunning).All(&grids, "Symbol")
+	symbols := map[string]bool{}
+	for _, grid := range grids {
+		symbols[grid.Symbol] = true
+	}
+	return symbols
+}
+
+// 网格的价格, 等差网格, 一共 grid_count + 1 个价格
+func GetGridPrices(grid models.FuturesGrid, tickSize string) []float64 {
+	prices := []float64{}
+	if grid.GridCount <= 0 || grid.UpperPrice <= grid.LowerPrice {
+		return prices
+	}
+	step := (grid.UpperPrice - grid.LowerPrice) / float64(grid.GridCount)
+	for i := 0; i <= grid.GridCount; i++ {
+		price := grid.LowerPrice + step * float64(i)
+		if tickSize != "" {
+			price = utils.GetTradePrecision(price, tickSize)
+		}
+		prices = append(prices, price)
+	}
+	return prices
+}
+
+// 网格开仓单的格子和方向, 做多的开仓单在当前价格下方(最高一格除外), 做空的开仓单在当前价格上方(最低一格除外)
+func GetGridOpenLevels(grid models.FuturesGrid, prices []float64, nowPrice float64) (longLevels []int, shortLevels []int) {
+	for level, price := range prices {
+		if (grid.Mode == GridModeLong || grid.Mode == GridModeNeutral) && price < nowPrice && level < len(prices) - 1 {
+			longLevels = append(longLevels, level)
+		}
+		if (grid.Mode == GridModeShort || grid.Mode == GridModeNeutral) && price > nowPrice && level > 0 {
+			shortLevels = append(shortLevels, level)
+		}
+	}
+	return longLevels, shortLevels
+}
+
+// 启动网格, 计算每格的数量, 修改合约倍数, 挂开仓单
+func StartGrid(id int64) (grid models.FuturesGrid, err error) {
+	gridLock.Lock()
+	defer gridLock.Unlock()
+
+	o := orm.NewOrm()
+	err = o.QueryTable("futures_grids").Filter("id", id).One(&grid)
+	if err != nil {
+		return grid, err
+	}
+	if grid.Status == GridStatusRunning {
+		return grid, errors.New("grid is running")
+	}
+	if tripped, reason := IsCircuitBreakerTripped(); tripped {
+		return grid, errors.New("circuit breaker is tripped: " + reason)
+	}
+	if grid.Mode != GridModeNeutral && grid.Mode != GridModeLong && grid.Mode != GridModeShort {
+		return grid, errors.New("invalid grid mode: " + grid.Mode)
+	}
+	if grid.GridCount < 2 || grid.UpperPrice <= grid.LowerPrice || grid.Investment <= 0 {
+		return grid, errors.New("invalid grid params")
+	}
+	var coin models.Symbols
+	err = o.QueryTable("symbols").Filter("symbol", grid.Symbol).One(&coin)
+	if err != nil {
+		return grid, errors.New("symbol not found: " + grid.Symbol)
+	}
+	ticker, err := binance.GetTickerPrice(grid.Symbol)
+	if err != nil || len(ticker) == 0 {
+		return grid, errors.New("get ticker price error: " + grid.Symbol)
+	}
+	nowPrice, _ := strconv.ParseFloat(ticker[0].Price, 64)
+	if nowPrice <= grid.LowerPrice || nowPrice >= grid.UpperPrice {
+		return grid, errors.New("the price is out of the grid range")
+	}
+
+	leverage := math.Max(1, float64(grid.Leverage))
+	grid.Quantity = utils.GetTradePrecision(grid.Investment * leverage / float64(grid.GridCount) / nowPrice, coin.StepSize)
+	if grid.Quantity <= 0 {
+		return grid, errors.New("the investment is too small")
+	}
+	binance.SetLeverage(grid.Symbol, int(leverage))
+
+	prices := GetGridPrices(grid, coin.TickSize)
+	longLevels, shortLevels := GetGridOpenLevels(grid, prices, nowPrice)
+	placed := 0
+	var placeErr error
+	for _, level := range longLevels {
+		if placeErr = placeGridOrder(grid, level, gridOrderOpen, "LONG", prices[level], 0, grid.Quantity); placeErr == nil {
+			placed++
+		}
+	}
+	for _, level := range shortLevels {
+		if placeErr = placeGridOrder(grid, level, gridOrderOpen, "SHORT", prices[level], 0, grid.Quantity); placeErr == nil {
+			placed++
+		}
+	}
+	if placed == 0 {
+		// 一个挂单都没有成功时不启动网格
+		if placeErr == nil {
+			placeErr = errors.New("no grid order to place")
+		}
+		return grid, errors.New("place grid orders error: " + placeErr.Error())
+	}
+	grid.Status = GridStatusRunning
+	grid.UpdateTime = time.Now().UnixMilli()
+	_, err = o.Update(&grid)
+	logs.Info("grid start:", grid.Symbol, grid.Mode, "orders:", placed, "/", len(longLevels) + len(shortLevels))
+	return grid, err
+}
+
+// 停止网格, 撤销所有挂单, 已经成交的仓位保留(需要手动平仓)
+func StopGrid(id int64) (grid models.FuturesGrid, err error) {
+	gridLock.Lock()
+	defer gridLock.Unlock()
+
+	o := orm.NewOrm()
+	err = o.QueryTable("futures_grids").Filter("id", id).One(&grid)
+	if err != nil {
+		return grid, err
+	}
+	grid.Status = GridStatusStopped
+	grid.UpdateTime = time.Now().UnixMilli()
+	_, err = o.Update(&grid)
+	if err != nil {
+		return grid, err
+	}
+	logs.Info("grid stop:", grid.Symbol)
+	// 撤销失败的挂单保持 NEW, 由 SyncGrids 重新撤销
+	return grid, cancelGridOrders(id)
+}
+
+// 撤销网格所有的挂单, 交易所已经不是挂单状态(成交, 撤销)的按照交易所的状态更新
+func cancelGridOrders(gridId int64) (err error) {
+	var gridOrders []models.FuturesGridOrder
+	orm.NewOrm().QueryTable("futures_grid_orders").Filter("grid_id", gridId).Filter("status", string(futures.OrderStatusTypeNew)).All(&gridOrders)
+	for _, gridOrder := range gridOrders {
+		_, cancelErr := binance.CancelOrder(gridOrder.Symbol, gridOrder.OrderId)
+		if cancelErr == nil {
+			updateGridOrderStatus(&gridOrder, string(futures.OrderStatusTypeCanceled))
+			continue
+		}
+		order, getErr := binance.GetOrder(binance.OrderParams{Symbol: gridOrder.Symbol, OrderID: gridOrder.OrderId})
+		if getErr == nil && order.Status != futures.OrderStatusTypeNew && order.Status != futures.OrderStatusTypePartiallyFilled {
+			updateGridOrderStatus(&gridOrder, string(order.Status))
+			continue
+		}
+		logs.Error("cancel grid order error:", gridOrder.Symbol, gridOrder.OrderId, cancelErr.Error())
+		err = cancelErr
+	}
+	return err
+}
+
+// 查询运行中网格的挂单状态, 处理 ws 没有推送(或者没有开启 ws)的成交, 被撤销的挂单重新挂单
+// 已经停止的网格还有挂单时(停止时撤销失败)重新撤销
+func SyncGrids() {
+	var stoppedGrids []models.FuturesGrid
+	orm.NewOrm().QueryTable("futures_grids").Filter("status", GridStatusStopped).All(&stoppedGrids, "ID")
+	for _, grid := range stoppedGrids {
+		if orm.NewOrm().QueryTable("futures_grid_orders").Filter("grid_id", grid.ID).Filter("status", string(futures.OrderStatusTypeNew)).Exist() {
+			gridLock.Lock()
+			cancelGridOrders(grid.ID)
+			gridLock.Unlock()
+		}
+	}
+
+	var grids []models.FuturesGrid
+	orm.NewOrm().QueryTable("futures_grids").Filter("status", GridStatusRunning).All(&grids)
+	for _, grid := range grids {
+		openOrders, err := binance.GetOpenOrder(grid.Symbol)
+		if err != nil {
+			logs.Error("get grid open orders error:", grid.Symbol, err.Error())
+			continue
+		}
+		openOrderIds := map[int64]bool{}
+		for _, order := range openOrders {
+			openOrderIds[order.OrderID] = true
+		}
+		var gridOrders []models.FuturesGridOrder
+		orm.NewOrm().QueryTable("futures_grid_orders").Filter("grid_id", grid.ID).Filter("status", string(futures.OrderStatusTypeNew)).All(&gridOrders)
+		for _, gridOrder := range gridOrders {
+			if openOrderIds[gridOrder.OrderId] {
+				continue
+			}
+			order, err := binance.GetOrder(binance.OrderParams{Symbol: gridOrder.Symbol, OrderID: gridOrder.OrderId})
+			if err != nil {
+				logs.Error("get grid order error:", gridOrder.Symbol, gridOrder.OrderId, err.Error())
+				continue
+			}
+			switch order.Status {
+				case futures.OrderStatusTypeFilled:
+					price, _ := strconv.ParseFloat(order.AvgPrice, 64)
+					HandleGridOrderFilled(order.OrderID, price)
+				case futures.OrderStatusTypeCanceled, futures.OrderStatusTypeExpired, futures.OrderStatusTypeRejected:
+					// 被手动撤销或者过期, 按照原来的价格重新挂单, 部分成交的数量按照成交处理
+					executedQuantity, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+					price, _ := strconv.ParseFloat(order.AvgPrice, 64)
+					replaceGridOrder(grid.ID, gridOrder.ID, executedQuantity, price)
+			}
+		}
+	}
+}
+
+// 网格挂单成交, 开仓单成交后在相邻的格子挂平仓单, 平仓单成交后记录收益并在原来的格子重新挂开仓单
+func HandleGridOrderFilled(orderId int64, avgPrice float64) {
+	gridLock.Lock()
+	defer gridLock.Unlock()
+
+	o := orm.NewOrm()
+	var gridOrder models.FuturesGridOrder
+	err := o.QueryTable("futures_grid_orders").Filter("order_id", orderId).Filter("status", string(futures.OrderStatusTypeNew)).One(&gridOrder)
+	if err != nil {
+		return // 不是网格的挂单, 或者已经处理过了
+	}
+	var grid models.FuturesGrid
+	err = o.QueryTable("futures_grids").Filter("id", gridOrder.GridId).One(&grid)
+	if err != nil {
+		return
+	}
+	updateGridOrderStatus(&gridOrder, string(futures.OrderStatusTypeFilled))
+	if grid.Status != GridStatusRunning {
+		return
+	}
+	onGridOrderFilled(grid, gridOrder, avgPrice)
+}
+
+// 成交后挂下一个订单, gridOrder.Quantity 为成交的数量
+func onGridOrderFilled(grid models.FuturesGrid, gridOrder models.FuturesGridOrder, avgPrice float64) {
+	o := orm.NewOrm()
+	if avgPrice <= 0 {
+		avgPrice = gridOrder.Price
+	}
+	var coin models.Symbols
+	o.QueryTable("symbols").Filter("symbol", grid.Symbol).One(&coin)
+	prices := GetGridPrices(grid, coin.TickSize)
+	if gridOrder.Level < 0 || gridOrder.Level >= len(prices) {
+		return
+	}
+
+	if gridOrder.Kind == gridOrderOpen {
+		closeLevel := gridOrder.Level + 1 // 多单在上一格平仓
+		if gridOrder.PositionSide == "SHORT" {
+			closeLevel = gridOrder.Level - 1 // 空单在下一格平仓
+		}
+		if closeLevel < 0 || closeLevel >= len(prices) {
+			return
+		}
+		placeGridOrder(grid, gridOrder.Level, gridOrderClose, gridOrder.PositionSide, prices[closeLevel], avgPrice, gridOrder.Quantity)
+		return
+	}
+
+	profit := GetGridProfit(gridOrder.PositionSide, gridOrder.OpenPrice, avgPrice, gridOrder.Quantity)
+	gridOrder.Profit = profit
+	gridOrder.UpdateTime = time.Now().UnixMilli()
+	o.Update(&gridOrder, "profit", "update_time")
+	o.Raw("UPDATE futures_grids SET realized_profit = realized_profit + ?, filled_count = filled_count + 1, update_time = ? WHERE id = ?", profit, gridOrder.UpdateTime, grid.ID).Exec()
+	logs.Info("grid close order filled:", grid.Symbol, gridOrder.PositionSide, "profit:", profit)
+	placeGridOrder(grid, gridOrder.Level, gridOrderOpen, gridOrder.PositionSide, prices[gridOrder.Level], 0, gridOrder.Quantity)
+}
+
+// 一次网格套利的收益(不含手续费)
+func GetGridProfit(positionSide string, openPrice float64, closePrice float64, quantity float64) float64 {
+	if positionSide == "SHORT" {
+		return (openPrice - closePrice) * quantity
+	}
+	return (closePrice - openPrice) * quantity
+}
+
+// 挂单并记录, 多单开仓和空单平仓是买入, 空单开仓和多单平仓是卖出
+func placeGridOrder(grid models.FuturesGrid, level int, kind string, positionSide string, price float64, openPrice float64, quantity float64) error {
+	var res *futures.CreateOrderResponse
+	var err error
+	side := futures.SideTypeSell
+	if (kind == gridOrderOpen) == (positionSide == "LONG") {
+		side = futures.SideTypeBuy
+		res, err = binance.BuyLimit(grid.Symbol, quantity, price, futures.PositionSideType(positionSide))
+	} else {
+		res, err = binance.SellLimit(grid.Symbol, quantity, price, futures.PositionSideType(positionSide))
+	}
+	if err != nil {
+		logs.Error("create grid order error:", grid.Symbol, kind, positionSide, price, err.Error())
+		return err
+	}
+	now := time.Now().UnixMilli()
+	gridOrder := models.FuturesGridOrder{
+		GridId: grid.ID,
+		Symbol: grid.Symbol,
+		Level: level,
+		Kind: kind,
+		Side: string(side),
+		PositionSide: positionSide,
+		Price: price,
+		OpenPrice: openPrice,
+		Quantity: quantity,
+		OrderId: res.OrderID,
+		Status: string(futures.OrderStatusTypeNew),
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	_, err = orm.NewOrm().Insert(&gridOrder)
+	if err != nil {
+		logs.Error("insert grid order error:", grid.Symbol, err.Error())
+	}
+	return nil
+}
+
+// 被撤销的挂单按照原来的价格重新挂单, 部分成交的数量按照成交挂下一个订单, 只重新挂没有成交的数量
+func replaceGridOrder(gridId int64, gridOrderId int64, executedQuantity float64, avgPrice float64) {
+	gridLock.Lock()
+	defer gridLock.Unlock()
+
+	o := orm.NewOrm()
+	var grid models.FuturesGrid
+	var gridOrder models.FuturesGridOrder
+	if o.QueryTable("futures_grids").Filter("id", gridId).One(&grid) != nil || grid.Status != GridStatusRunning {
+		return
+	}
+	if o.QueryTable("futures_grid_orders").Filter("id", gridOrderId).Filter("status", string(futures.OrderStatusTypeNew)).One(&gridOrder) != nil {
+		return
+	}
+	updateGridOrderStatus(&gridOrder, string(futures.OrderStatusTypeCanceled))
+	var coin models.Symbols
+	o.QueryTable("symbols").Filter("symbol", grid.Symbol).One(&coin)
+	remainQuantity := GetGridRemainQuantity(gridOrder.Quantity, executedQuantity, coin.StepSize)
+	if remainQuantity > 0 {
+		placeGridOrder(grid, gridOrder.Level, gridOrder.Kind, gridOrder.PositionSide, gridOrder.Price, gridOrder.OpenPrice, remainQuantity)
+	}
+	if executedQuantity > 0 {
+		filled := gridOrder
+		filled.Quantity = executedQuantity
+		onGridOrderFilled(grid, filled, avgPrice)
+	}
+}
+
+// 部分成交后没有成交的数量
+func GetGridRemainQuantity(quantity float64, executedQuantity float64, stepSize string) float64 {
+	remain := quantity - executedQuantity
+	if stepSize != "" {
+		remain = utils.GetTradePrecision(remain, stepSize)
+	}
+	return math.Max(0, remain)
+}
+
+func updateGridOrderStatus(gridOrder *models.FuturesGridOrder, status string) {
+	gridOrder.Status = status
+	gridOrder.UpdateTime = time.Now().UnixMilli()
+	_, err := orm.NewOrm().Update(gridOrder)
+	if err != nil {
+		logs.Error("update grid order error:", gridOrder.Symbol, err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package feature
+
+import (
+	"encoding/json"
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 分批止盈的一档, 收益率达到 Roi% 时平掉开仓数量的 Percent%
+type LadderStep struct {
+	Roi float64 `json:"roi"`
+	Percent float64 `json:"percent"`
+}
+
+// 解析币种的分批止盈配置, 为空或者格式错误时返回 nil
+func ParseTakeProfitLadder(ladder string) []LadderStep {
+	ladder = strings.TrimSpace(ladder)
+	if ladder == "" {
+		return nil
+	}
+	var steps []LadderStep
+	err := json.Unmarshal([]byte(ladder), &steps)
+	if err != nil {
+		logs.Error("parse take profit ladder error:", ladder, err.Error())
+		return nil
+	}
+	return steps
+}
+
+// 当前收益率下分批止盈需要平仓的数量, 剩余数量不足一个精度时全部平仓
+// @return quantity 平仓数量, 为 0 时不需要平仓
+// @return all 是否全部平仓
+func GetLadderCloseQuantity(coin *models.Symbols, peak models.FuturesPositionPeak, positionAmount float64, roi float64) (quantity float64, all bool) {
+	if coin == nil {
+		return 0, false
+	}
+	steps := ParseTakeProfitLadder(coin.TakeProfitLadder)
+	if peak.LadderStep >= len(steps) {
+		return 0, false
+	}
+	step := steps[peak.LadderStep]
+	if roi < step.Roi || step.Percent <= 0 {
+		return 0, false
+	}
+	baseAmount, _ := strconv.ParseFloat(peak.Amount, 64)
+	if baseAmount <= 0 {
+		baseAmount = positionAmount
+	}
+	quantity = baseAmount * math.Min(step.Percent, 100) / 100
+	if coin.StepSize != "" {
+		quantity = utils.GetTradePrecision(quantity, coin.StepSize)
+	}
+	stepSize, _ := strconv.ParseFloat(coin.StepSize, 64)
+	if quantity >= positionAmount - stepSize {
+		return positionAmount, true
+	}
+	if quantity <= 0 {
+		return 0, false
+	}
+	return quantity, false
+}
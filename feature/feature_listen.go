@@ -67,7 +67,7 @@ func klineCustomListen(coin models.ListenSymbols) {
 	env := line.InitParseEnv(coin.Symbol, coin.Technology)
 	for _, strategy := range strategyConfig {
 		if strategy.Enable {
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := line.GetProgram(coin.Symbol, coin.Technology, strategy.Type, strategy.Code, env)
 			if err != nil {
 				logs.Error("Error Strategy Compile:", err.Error())
 				continue
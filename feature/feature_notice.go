@@ -79,103 +79,51 @@ func NoticeAndAutoOrder(systemConfig models.Config) {
 			orm.NewOrm().Update(&coin)
 		}
 		if coin.AutoOrder == 1 && canOrder {
-			// 修改仓位模式
+			usdt_float64, _ := strconv.ParseFloat(coin.Usdt, 64) // 交易金额
+			positionSide := "LONG"
+			if coin.Side == "sell" {
+				positionSide = "SHORT"
+			} else if coin.Side != "buy" {
+				continue
+			}
+			marginType := "CROSSED"
 			if coin.MarginType == "ISOLATED" {
-				binance.SetMarginType(coin.Symbol, futures.MarginTypeIsolated)
-			} else {
-				binance.SetMarginType(coin.Symbol, futures.MarginTypeCrossed)
+				marginType = "ISOLATED"
 			}
-			binance.SetLeverage(coin.Symbol, int(coin.Leverage))  // 修改合约倍数
-			
-			usdt_float64, _ := strconv.ParseFloat(coin.Usdt, 64) // 交易金额
-			leverage_float64 := float64(coin.Leverage) // 合约倍数
-			quantity := (usdt_float64 / nowPrice) * leverage_float64  // 购买数量
-			quantity = utils.GetTradePrecision(quantity, coin.StepSize) // 合理精度的数量
-			
-			if coin.Side == "buy" {
-				_, err := binance.BuyMarket(coin.Symbol, quantity, futures.PositionSideTypeLong)
-				if err != nil {
-					logs.Info("合约做多失败symbol:", coin.Symbol)
-					pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.open_notice_title"),
-						Symbol: coin.Symbol,
-						Side: "buy",
-						PositionSide: "long",
-						Price: nowPrice,
-						Quantity: quantity,
-						Leverage: leverage_float64,
-						Status: "fail",
-						Error: err.Error(),
-					})
-				} else {
-					pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.open_notice_title"),
-						Symbol: coin.Symbol,
-						Side: "buy",
-						PositionSide: "long",
-						Price: nowPrice,
-						Quantity: quantity,
-						Leverage: leverage_float64,
-						Status: "success",
-					})
-					if (coin.ProfitPrice != "0") {
-						// 挂一个止盈单
-						profit_price_float64, _ := strconv.ParseFloat(coin.ProfitPrice, 64) // 交易金额
-						profit_price_float64 = utils.GetTradePrecision(profit_price_float64, coin.TickSize) // 合理精度价格
-						binance.OrderTakeProfit(coin.Symbol, profit_price_float64, futures.SideTypeSell, futures.PositionSideTypeLong)
-					}
-					if (coin.LossPrice != "0") {
-						// 挂一个止损单
-						loss_price_float64, _ := strconv.ParseFloat(coin.LossPrice, 64) // 交易金额
-						loss_price_float64 = utils.GetTradePrecision(loss_price_float64, coin.TickSize) // 合理精度价格
-						binance.OrderStopLoss(coin.Symbol, loss_price_float64, futures.SideTypeSell, futures.PositionSideTypeLong)
-					}
-				}
-			} else if coin.Side == "sell" {
-				_, err := binance.SellMarket(coin.Symbol, quantity, futures.PositionSideTypeShort)
-				if err != nil {
-					logs.Info("合约做空失败symbol:", coin.Symbol)
-					pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.open_notice_title"),
-						Symbol: coin.Symbol,
-						Side: "sell",
-						PositionSide: "short",
-						Price: nowPrice,
-						Quantity: quantity,
-						Leverage: leverage_float64,
-						Status: "fail",
-						Error: err.Error(),
-					})
-				} else {
-					pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-						Title: lang.Lang("futures.open_notice_title"),
-						Symbol: coin.Symbol,
-						Side: "sell",
-						PositionSide: "short",
-						Price: nowPrice,
-						Quantity: quantity,
-						Leverage: leverage_float64,
-						Status: "success",
-					})
-					if (coin.ProfitPrice != "0") {
-						// 挂一个止盈单
-						profit_price_float64, _ := strconv.ParseFloat(coin.ProfitPrice, 64) // 交易金额
-						profit_price_float64 = utils.GetTradePrecision(profit_price_float64, coin.TickSize) // 合理精度价格
-						binance.OrderTakeProfit(coin.Symbol, profit_price_float64, futures.SideTypeBuy, futures.PositionSideTypeShort)
-					}
-					if (coin.LossPrice != "0") {
-						// 挂一个止损单
-						loss_price_float64, _ := strconv.ParseFloat(coin.LossPrice, 64) // 交易金额
-						loss_price_float64 = utils.GetTradePrecision(loss_price_float64, coin.TickSize) // 合理精度价格
-						binance.OrderStopLoss(coin.Symbol, loss_price_float64, futures.SideTypeBuy, futures.PositionSideTypeShort)
-					}
-				}
+			_, err := NewOrderExecutor(systemConfig).Execute(OrderIntent{
+				Action: OrderActionOpen,
+				Symbol: coin.Symbol,
+				PositionSide: positionSide,
+				Usdt: usdt_float64,
+				Price: nowPrice,
+				Leverage: coin.Leverage,
+				MarginType: marginType,
+				StepSize: coin.StepSize,
+			})
+			if err != nil {
+				logs.Info("合约开仓失败symbol:", coin.Symbol)
+				continue
+			}
+			closeSide := futures.SideTypeSell
+			if positionSide == "SHORT" {
+				closeSide = futures.SideTypeBuy
+			}
+			if (coin.ProfitPrice != "0") {
+				// 挂一个止盈单
+				profit_price_float64, _ := strconv.ParseFloat(coin.ProfitPrice, 64) // 交易金额
+				profit_price_float64 = utils.GetTradePrecision(profit_price_float64, coin.TickSize) // 合理精度价格
+				binance.OrderTakeProfit(coin.Symbol, profit_price_float64, closeSide, futures.PositionSideType(positionSide))
+			}
+			if (coin.LossPrice != "0") {
+				// 挂一个止损单
+				loss_price_float64, _ := strconv.ParseFloat(coin.LossPrice, 64) // 交易金额
+				loss_price_float64 = utils.GetTradePrecision(loss_price_float64, coin.TickSize) // 合理精度价格
+				binance.OrderStopLoss(coin.Symbol, loss_price_float64, closeSide, futures.PositionSideType(positionSide))
 			}
 		}
 	}
 }
 
-
 type PositionNoticeInfo struct {
 	Status string // income_positive:盈利 income_negative:亏损
 	NoticeTime int64
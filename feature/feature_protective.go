@@ -0,0 +1,504 @@
+package feature
+
+import (
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"go_binance_futures/utils"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 机器人挂的止盈止损单的自定义订单id前缀, 只管理带前缀的订单, 不影响手动或者币种通知挂的止盈止损单
+const protectiveOrderPrefix = "protect_"
+
+var protectiveLock sync.Mutex
+var protectiveOrderExist = false // 上一次同步时交易所是否有程序挂的订单, 没有且功能都关闭时不再请求 api
+
+// 程序挂的订单对应的仓位, 订单成交(交易所平仓)时记录平仓订单和风控盈亏
+// 只保存在内存中, 启动对账时根据交易所的挂单重新记录, 停机期间成交的订单根据本地的开仓订单补记平仓
+var protectiveTrackLock sync.Mutex
+var protectiveTracked = map[int64]protectiveTrack{} // orderId => 仓位
+
+type protectiveTrack struct {
+	OrderType futures.OrderType
+	Position types.FuturesPosition
+}
+
+// 挂在交易所的止盈止损单(STOP_MARKET/TAKE_PROFIT_MARKET, closePosition), 程序异常退出时也能止盈止损
+// 止盈止损价格根据币种的 profit/loss 和仓位的合约倍数计算, 和 StartTrade 中的收益率止盈止损保持一致
+// 交易所触发时直接平仓, 不经过策略的 CanOrderComplete 检查
+// 币种开启交易所跟踪止盈(trailing_stop = 2)时同时维护 TRAILING_STOP_MARKET 订单
+// 定时同步, 市价开仓(包括加仓)成交后也会马上同步
+func SyncProtectiveOrders(systemConfig models.Config) {
+	syncProtectiveOrders(systemConfig, false)
+}
+
+// 启动时对账, 撤销之前遗留的没有对应仓位(或者功能已关闭)的订单, 补齐缺少的订单
+func ReconcileProtectiveOrders(systemConfig models.Config) {
+	logs.Info("reconcile protective orders, enable:", systemConfig.FutureProtectiveOrder == 1)
+	syncProtectiveOrders(systemConfig, true)
+}
+
+// 订单成交通过 ws 用户数据推送(开启 futures_user_data 时)和定时同步时查询订单状态两种方式处理
+func WatchProtectiveOrders() {
+	binance.OnOrderUpdate(func(order futures.WsOrderTradeUpdate) {
+		if order.Status != futures.OrderStatusTypeFilled || !strings.HasPrefix(order.ClientOrderID, protectiveOrderPrefix) {
+			return
+		}
+		systemConfig, err := utils.GetSystemConfig()
+		if err != nil {
+			logs.Error("get system config error in protective order update:", err.Error())
+			return
+		}
+		avgPrice, _ := strconv.ParseFloat(order.AveragePrice, 64)
+		quantity, _ := strconv.ParseFloat(order.AccumulatedFilledQty, 64)
+		HandleProtectiveOrderFilled(systemConfig, order.ID, avgPrice, quantity)
+	})
+}
+
+// 记录程序挂的订单对应的仓位(挂单和每次同步时更新开仓价格)
+func TrackProtectiveOrder(orderId int64, orderType futures.OrderType, position types.FuturesPosition) {
+	protectiveTrackLock.Lock()
+	defer protectiveTrackLock.Unlock()
+	protectiveTracked[orderId] = protectiveTrack{OrderType: orderType, Position: position}
+}
+
+func untrackProtectiveOrder(orderId int64) {
+	protectiveTrackLock.Lock()
+	defer protectiveTrackLock.Unlock()
+	delete(protectiveTracked, orderId)
+}
+
+// 交易所的止盈止损单成交, 和程序平仓一样记录平仓订单, 自动缩放和风控盈亏, 发送平仓通知
+// ws 推送和定时同步都可能处理同一个订单, 只有第一次(订单还在记录中)处理
+func HandleProtectiveOrderFilled(systemConfig models.Config, orderId int64, avgPrice float64, quantity float64) bool {
+	protectiveTrackLock.Lock()
+	track, ok := protectiveTracked[orderId]
+	delete(protectiveTracked, orderId)
+	protectiveTrackLock.Unlock()
+	if !ok || avgPrice <= 0 || quantity <= 0 {
+		return false
+	}
+	intent := GetProtectiveCloseIntent(systemConfig, track.OrderType, track.Position, avgPrice, quantity)
+	logs.Info("protective order filled:", intent.Symbol, intent.PositionSide, track.OrderType, avgPrice, quantity, intent.Profit)
+	executor := NewOrderExecutor(systemConfig)
+	executor.record(intent, &futures.CreateOrderResponse{OrderID: orderId})
+	executor.notify(intent, nil)
+	return true
+}
+
+// 止盈止损单成交对应的平仓, 收益按照开仓价格和成交均价计算
+func GetProtectiveCloseIntent(systemConfig models.Config, orderType futures.OrderType, position types.FuturesPosition, avgPrice float64, quantity float64) OrderIntent {
+	entryPrice, _ := strconv.ParseFloat(position.EntryPrice, 64)
+	direction := 1.0
+	if position.Side == "SHORT" {
+		direction = -1.0
+	}
+	reason := "stop_loss"
+	if orderType == futures.OrderTypeTakeProfitMarket {
+		reason = "target_profit"
+	} else if orderType == futures.OrderTypeTrailingStopMarket {
+		reason = "trailing_stop"
+	}
+	realizedProfit := 0.0 // 之前分批止盈已实现的收益
+	positionPeakLock.Lock()
+	if peak, ok := positionPeaks[position.Symbol + "_" + position.Side]; ok {
+		realizedProfit = peak.RealizedProfit
+	}
+	positionPeakLock.Unlock()
+	return OrderIntent{
+		Action: OrderActionClose,
+		Symbol: position.Symbol,
+		PositionSide: position.Side,
+		Quantity: quantity,
+		Price: avgPrice,
+		Leverage: position.Leverage,
+		Reason: reason,
+		Profit: (avgPrice - entryPrice) * quantity * direction,
+		RealizedProfit: realizedProfit,
+		StrategyName: getStrategyNameFromConfig(systemConfig),
+	}
+}
+
+// 记录中但是已经不在挂单列表的订单, 查询订单状态, 成交的记录平仓, 撤销/过期的不再记录
+func checkProtectiveOrders(systemConfig models.Config, openOrders []*futures.Order) {
+	openIds := map[int64]bool{}
+	for _, order := range openOrders {
+		openIds[order.OrderID] = true
+	}
+	protectiveTrackLock.Lock()
+	closed := map[int64]string{} // orderId => symbol
+	for orderId, track := range protectiveTracked {
+		if !openIds[orderId] {
+			closed[orderId] = track.Position.Symbol
+		}
+	}
+	protectiveTrackLock.Unlock()
+
+	for orderId, symbol := range closed {
+		order, err := binance.GetOrder(binance.OrderParams{Symbol: symbol, OrderID: orderId})
+		if err != nil {
+			logs.Error("get protective order error:", symbol, orderId, err.Error())
+			continue
+		}
+		switch order.Status {
+			case futures.OrderStatusTypeFilled:
+				avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+				quantity, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+				HandleProtectiveOrderFilled(systemConfig, orderId, avgPrice, quantity)
+			case futures.OrderStatusTypeNew, futures.OrderStatusTypePartiallyFilled:
+				// 刚挂的订单, 下一次同步再处理
+			default:
+				untrackProtectiveOrder(orderId)
+		}
+	}
+}
+
+// 启动时补记停机期间成交的止盈止损单: 本地最后一个订单是开仓(还没有平仓记录), 但是交易所已经没有对应的仓位
+// 查询开仓之后交易所的历史订单, 程序挂的订单成交的按照本地的开仓均价(包括加仓)记录平仓
+func recoverProtectiveFills(systemConfig models.Config) {
+	positions, err := binance.GetPosition(binance.PositionParams{})
+	if err != nil {
+		logs.Error("get position error in recover protective orders:", err.Error())
+		return
+	}
+	holding := map[string]bool{}
+	for _, position := range positions {
+		amount, _ := strconv.ParseFloat(position.PositionAmt, 64)
+		if math.Abs(amount) > 0.0000000001 {
+			holding[position.Symbol + "_" + string(position.PositionSide)] = true
+		}
+	}
+	o := orm.NewOrm()
+	var lastOrders []models.Order
+	_, err = o.Raw("SELECT * FROM `order` WHERE id IN (SELECT MAX(id) FROM `order` GROUP BY symbol, positionSide)").QueryRows(&lastOrders)
+	if err != nil {
+		logs.Error("get last orders error in recover protective orders:", err.Error())
+		return
+	}
+	for _, last := range lastOrders {
+		if last.Side != OrderActionOpen || holding[last.Symbol + "_" + last.PositionSide] {
+			continue
+		}
+		openId := last.ID
+		if last.ParentId != 0 {
+			openId = last.ParentId // 最后一个订单是加仓
+		}
+		var tradeOrders []models.Order
+		cond := orm.NewCondition()
+		cond = cond.AndCond(cond.And("ID", openId).Or("parent_id", openId)).And("side", OrderActionOpen)
+		o.QueryTable("order").SetCond(cond).OrderBy("ID").All(&tradeOrders)
+		position, ok := GetTradeOpenPosition(tradeOrders)
+		if !ok || tradeOrders[0].UpdateTime < time.Now().UnixMilli() - 7 * 24 * 3600 * 1000 {
+			continue // 历史订单最多查询 7 天
+		}
+		exchangeOrders, err := binance.GetOrders(binance.ListOrderParams{
+			OrderParams: binance.OrderParams{Symbol: last.Symbol},
+			StartTime: tradeOrders[0].UpdateTime,
+		})
+		if err != nil {
+			logs.Error("get orders error in recover protective orders:", last.Symbol, err.Error())
+			continue
+		}
+		for _, order := range exchangeOrders {
+			if !isProtectiveOrder(order) || string(order.PositionSide) != last.PositionSide || order.Status != futures.OrderStatusTypeFilled {
+				continue
+			}
+			if o.QueryTable("order").Filter("order_id", order.OrderID).Exist() {
+				continue // 已经记录过
+			}
+			avgPrice, _ := strconv.ParseFloat(order.AvgPrice, 64)
+			quantity, _ := strconv.ParseFloat(order.ExecutedQuantity, 64)
+			TrackProtectiveOrder(order.OrderID, order.Type, position)
+			HandleProtectiveOrderFilled(systemConfig, order.OrderID, avgPrice, quantity)
+		}
+	}
+}
+
+// 根据本地的开仓和加仓订单计算仓位(数量, 开仓均价, 合约倍数)
+func GetTradeOpenPosition(orders []models.Order) (position types.FuturesPosition, ok bool) {
+	amount, cost := 0.0, 0.0
+	for _, order := range orders {
+		orderAmount, _ := strconv.ParseFloat(order.Amount, 64)
+		price, _ := strconv.ParseFloat(order.Avg_price, 64)
+		amount += orderAmount
+		cost += orderAmount * price
+	}
+	if len(orders) == 0 || amount <= 0 {
+		return position, false
+	}
+	return types.FuturesPosition{
+		Symbol: orders[0].Symbol,
+		Side: orders[0].PositionSide,
+		Amount: strconv.FormatFloat(amount, 'f', -1, 64),
+		Leverage: orders[0].Leverage,
+		EntryPrice: fmt.Sprintf("%g", cost / amount),
+	}, true
+}
+
+// 平仓后撤销该仓位的止盈止损单
+func CancelProtectiveOrders(symbol string, positionSide string) {
+	protectiveLock.Lock()
+	defer protectiveLock.Unlock()
+	orders, err := binance.GetOpenOrder(symbol)
+	if err != nil {
+		logs.Error("get protective orders error:", symbol, err.Error())
+		return
+	}
+	for _, order := range orders {
+		if isProtectiveOrder(order) && string(order.PositionSide) == positionSide {
+			cancelProtectiveOrder(order)
+		}
+	}
+}
+
+func syncProtectiveOrders(systemConfig models.Config, reconcile bool) {
+	protectiveLock.Lock()
+	defer protectiveLock.Unlock()
+
+	allCoins, err := GetAllSymbols()
+	if err != nil {
+		logs.Error("GetAllSymbols err in SyncProtectiveOrders:", err.Error())
+		return
+	}
+	enable := systemConfig.FutureProtectiveOrder == 1
+	coinMap := map[string]*models.Symbols{}
+	for _, coin := range allCoins {
+		coinMap[coin.Symbol] = coin
+		if coin.TrailingStop == TrailingStopNative {
+			enable = true
+		}
+	}
+	if !enable && !reconcile && !protectiveOrderExist {
+		return
+	}
+
+	openOrders, err := binance.GetOpenOrder()
+	if err != nil {
+		logs.Error("get protective orders error:", err.Error())
+		return
+	}
+	// symbol_positionSide_type => orders
+	existOrders := map[string][]*futures.Order{}
+	for _, order := range openOrders {
+		if isProtectiveOrder(order) {
+			key := protectiveOrderKey(order.Symbol, string(order.PositionSide), order.Type)
+			existOrders[key] = append(existOrders[key], order)
+		}
+	}
+	protectiveOrderExist = len(existOrders) > 0
+	checkProtectiveOrders(systemConfig, openOrders)
+	if reconcile {
+		recoverProtectiveFills(systemConfig)
+	}
+
+	if enable {
+		positions, err := GetTransformPositions()
+		if err != nil {
+			return
+		}
+		excludeSymbols := GetExcludeSymbolsMap(systemConfig.FutureExcludeSymbols)
+		for symbol := range GetRunningGridSymbols() {
+			excludeSymbols[symbol] = true // 网格交易的币种
+		}
+
+		for _, position := range positions {
+			coin, ok := coinMap[position.Symbol]
+			if !ok || excludeSymbols[position.Symbol] {
+				continue
+			}
+			if position.Side != "LONG" && position.Side != "SHORT" {
+				continue // 只支持双向持仓
+			}
+			if systemConfig.FutureProtectiveOrder == 1 {
+				takeProfitPrice, stopLossPrice := GetProtectivePrices(position, coin)
+				takeProfitKey := protectiveOrderKey(position.Symbol, position.Side, futures.OrderTypeTakeProfitMarket)
+				stopLossKey := protectiveOrderKey(position.Symbol, position.Side, futures.OrderTypeStopMarket)
+				syncProtectiveOrder(position, futures.OrderTypeTakeProfitMarket, takeProfitPrice, existOrders[takeProfitKey])
+				syncProtectiveOrder(position, futures.OrderTypeStopMarket, stopLossPrice, existOrders[stopLossKey])
+				delete(existOrders, takeProfitKey)
+				delete(existOrders, stopLossKey)
+			}
+			if coin.TrailingStop == TrailingStopNative && coin.TrailingCallback > 0 {
+				trailingKey := protectiveOrderKey(position.Symbol, position.Side, futures.OrderTypeTrailingStopMarket)
+				syncTrailingStopOrder(position, coin, existOrders[trailingKey])
+				delete(existOrders, trailingKey)
+			}
+		}
+	}
+
+	// 剩下的都是没有对应仓位(或者功能已关闭)的孤儿订单
+	for _, orders := range existOrders {
+		for _, order := range orders {
+			cancelProtectiveOrder(order)
+		}
+	}
+}
+
+// 根据仓位的开仓价格和合约倍数计算止盈止损价格, 为 0 时不挂单
+// 设置了分批止盈时不挂止盈单(全平条件单会平掉剩余的分批止盈仓位)
+func GetProtectivePrices(position types.FuturesPosition, coin *models.Symbols) (takeProfitPrice float64, stopLossPrice float64) {
+	entryPrice, _ := strconv.ParseFloat(position.EntryPrice, 64)
+	if entryPrice <= 0 {
+		return 0, 0
+	}
+	leverage := float64(position.Leverage)
+	if leverage <= 0 {
+		leverage = math.Max(1, float64(coin.Leverage))
+	}
+	profit, _ := strconv.ParseFloat(coin.Profit, 64)
+	loss, _ := strconv.ParseFloat(coin.Loss, 64)
+	direction := 1.0 // 多单止盈价格在上方, 空单止盈价格在下方
+	if position.Side == "SHORT" {
+		direction = -1.0
+	}
+	if profit > 0 && len(ParseTakeProfitLadder(coin.TakeProfitLadder)) == 0 {
+		takeProfitPrice = entryPrice * (1 + direction * profit / leverage / 100)
+	}
+	if loss > 0 && loss < leverage * 100 {
+		stopLossPrice = entryPrice * (1 - direction * loss / leverage / 100)
+	}
+	if coin.TickSize != "" {
+		takeProfitPrice = utils.GetTradePrecision(takeProfitPrice, coin.TickSize)
+		stopLossPrice = utils.GetTradePrecision(stopLossPrice, coin.TickSize)
+	}
+	return takeProfitPrice, stopLossPrice
+}
+
+// 价格一致则保留, 否则撤销旧的订单再重新挂单(加仓后开仓均价变化, 修改币种止盈止损率)
+func syncProtectiveOrder(position types.FuturesPosition, orderType futures.OrderType, stopPrice float64, orders []*futures.Order) {
+	keep := false
+	for _, order := range orders {
+		orderStopPrice, _ := strconv.ParseFloat(order.StopPrice, 64)
+		if !keep && stopPrice > 0 && math.Abs(orderStopPrice - stopPrice) < stopPrice * 0.00001 {
+			keep = true
+			TrackProtectiveOrder(order.OrderID, orderType, position)
+			continue
+		}
+		cancelProtectiveOrder(order)
+	}
+	if keep || stopPrice <= 0 {
+		return
+	}
+	side := futures.SideTypeSell // 平多
+	if position.Side == "SHORT" {
+		side = futures.SideTypeBuy // 平空
+	}
+	res, err := binance.CreateOrder(binance.CreateOrderParams{
+		Symbol: position.Symbol,
+		Side: side,
+		PositionSide: futures.PositionSideType(position.Side),
+		Type: orderType,
+		StopPrice: stopPrice,
+		ClosePosition: true,
+		ClientOrderID: protectiveOrderPrefix + strings.ToLower(string(orderType)[:1]) + "_" + strconv.FormatInt(time.Now().UnixNano() / 1000, 10),
+	})
+	if err != nil {
+		// 价格已经越过触发价时交易所会拒绝挂单, 由 StartTrade 按收益率平仓
+		logs.Error("create protective order error:", position.Symbol, position.Side, orderType, stopPrice, err.Error())
+		return
+	}
+	TrackProtectiveOrder(res.OrderID, orderType, position)
+	logs.Info("create protective order:", position.Symbol, position.Side, orderType, stopPrice)
+}
+
+// 交易所跟踪止盈单, 回调比例 = 收益率回撤 / 合约倍数(0.1% ~ 5%), 激活价格根据激活收益率计算
+// 跟踪止盈单不支持 closePosition, 使用仓位数量下单, 加仓后数量变化重新挂单
+func syncTrailingStopOrder(position types.FuturesPosition, coin *models.Symbols, orders []*futures.Order) {
+	quantity, _ := strconv.ParseFloat(position.Amount, 64)
+	quantity = math.Abs(quantity)
+	callbackRate, activationPrice := GetTrailingStopParams(position, coin)
+	keep := false
+	for _, order := range orders {
+		orderQuantity, _ := strconv.ParseFloat(order.OrigQuantity, 64)
+		orderCallbackRate, _ := strconv.ParseFloat(order.PriceRate, 64)
+		orderActivationPrice, _ := strconv.ParseFloat(order.ActivatePrice, 64)
+		if !keep && math.Abs(orderQuantity - quantity) < 0.0000001 &&
+			math.Abs(orderCallbackRate - callbackRate) < 0.0000001 &&
+			(activationPrice == 0 || math.Abs(orderActivationPrice - activationPrice) <= activationPrice * 0.00001) {
+			keep = true
+			TrackProtectiveOrder(order.OrderID, futures.OrderTypeTrailingStopMarket, position)
+			continue
+		}
+		cancelProtectiveOrder(order)
+	}
+	if keep || quantity <= 0 {
+		return
+	}
+	side := futures.SideTypeSell // 平多
+	if position.Side == "SHORT" {
+		side = futures.SideTypeBuy // 平空
+	}
+	res, err := binance.CreateOrder(binance.CreateOrderParams{
+		Symbol: position.Symbol,
+		Side: side,
+		PositionSide: futures.PositionSideType(position.Side),
+		Type: futures.OrderTypeTrailingStopMarket,
+		Quantity: quantity,
+		CallbackRate: callbackRate,
+		ActivationPrice: activationPrice,
+		ClientOrderID: protectiveOrderPrefix + "r_" + strconv.FormatInt(time.Now().UnixNano() / 1000, 10),
+	})
+	if err != nil {
+		logs.Error("create trailing stop order error:", position.Symbol, position.Side, callbackRate, activationPrice, err.Error())
+		return
+	}
+	TrackProtectiveOrder(res.OrderID, futures.OrderTypeTrailingStopMarket, position)
+	logs.Info("create trailing stop order:", position.Symbol, position.Side, callbackRate, activationPrice)
+}
+
+// 交易所跟踪止盈单的回调比例(%)和激活价格(为 0 时立即激活)
+func GetTrailingStopParams(position types.FuturesPosition, coin *models.Symbols) (callbackRate float64, activationPrice float64) {
+	leverage := float64(position.Leverage)
+	if leverage <= 0 {
+		leverage = math.Max(1, float64(coin.Leverage))
+	}
+	callbackRate = math.Round(coin.TrailingCallback / leverage * 10) / 10 // 精度 0.1
+	callbackRate = math.Min(5, math.Max(0.1, callbackRate))
+
+	entryPrice, _ := strconv.ParseFloat(position.EntryPrice, 64)
+	if coin.TrailingActivation > 0 && entryPrice > 0 {
+		direction := 1.0
+		if position.Side == "SHORT" {
+			direction = -1.0
+		}
+		activationPrice = entryPrice * (1 + direction * coin.TrailingActivation / leverage / 100)
+		if coin.TickSize != "" {
+			activationPrice = utils.GetTradePrecision(activationPrice, coin.TickSize)
+		}
+	}
+	return callbackRate, activationPrice
+}
+
+func cancelProtectiveOrder(order *futures.Order) {
+	_, err := binance.CancelOrder(order.Symbol, order.OrderID)
+	if err != nil {
+		logs.Error("cancel protective order error:", order.Symbol, order.OrderID, err.Error())
+		return
+	}
+	untrackProtectiveOrder(order.OrderID)
+	logs.Info("cancel protective order:", order.Symbol, order.PositionSide, order.Type, order.StopPrice)
+}
+
+func isProtectiveOrder(order *futures.Order) bool {
+	return IsProtectiveOrder(order.ClientOrderID, string(order.Type))
+}
+
+// 是否是程序挂的止盈止损单(自定义订单id前缀 + 订单类型)
+func IsProtectiveOrder(clientOrderId string, orderType string) bool {
+	return strings.HasPrefix(clientOrderId, protectiveOrderPrefix) &&
+		(orderType == string(futures.OrderTypeStopMarket) || orderType == string(futures.OrderTypeTakeProfitMarket) || orderType == string(futures.OrderTypeTrailingStopMarket))
+}
+
+func protectiveOrderKey(symbol string, positionSide string, orderType futures.OrderType) string {
+	return symbol + "_" + positionSide + "_" + string(orderType)
+}
@@ -0,0 +1,449 @@
+package feature
+
+import (
+	"errors"
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/lang"
+	"go_binance_futures/models"
+	"go_binance_futures/notify"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/config"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 对账差异的类型
+const (
+	ReconcileKindPosition = "position"
+	ReconcileKindOrder = "order"
+	ReconcileMissingLocal = "missing_local" // 交易所有, 本地没有
+	ReconcileStaleLocal = "stale_local" // 本地有, 交易所没有
+	ReconcileMismatch = "mismatch" // 字段不一致
+)
+
+const reconcileGraceMs = 5000 // 对账开始前 5 秒内 ws 更新的本地数据不对账, 避免和推送冲突
+const reconcileNoticeMax = 10 // 通知中最多显示的差异数量
+
+var reconcileInterval = config.DefaultInt("ws::futures_user_data_reconcile", 60)
+var reconcileAlertCount = config.DefaultInt("ws::futures_user_data_reconcile_alert", 3)
+
+type ReconcileDiff struct {
+	Kind string `json:"kind"`
+	Diff string `json:"diff"`
+	Symbol string `json:"symbol"`
+	Side string `json:"side"`
+	OrderId string `json:"order_id"`
+	Field string `json:"field"`
+	LocalValue string `json:"local_value"`
+	RemoteValue string `json:"remote_value"`
+	Healed bool `json:"healed"`
+}
+
+// 对账的运行状态
+type ReconcileStatus struct {
+	Interval int `json:"interval"` // 对账间隔(秒)
+	LastTime int64 `json:"last_time"` // 最近一次对账的时间
+	LastError string `json:"last_error"`
+	LastDiffs int `json:"last_diffs"` // 最近一次对账的差异数量
+	Divergent int `json:"divergent"` // 连续有差异的次数, 达到 futures_user_data_reconcile_alert 时通知
+	Runs int64 `json:"runs"`
+}
+
+var reconcileStatus ReconcileStatus
+var reconcileLock sync.Mutex
+
+// 定时对账, futures_user_data_reconcile 为 0 时不对账
+func StartReconcile() {
+	if reconcileInterval <= 0 {
+		return
+	}
+	for {
+		time.Sleep(time.Second * time.Duration(reconcileInterval))
+		if _, err := Reconcile(); err != nil {
+			logs.Error("reconcile error:", err.Error())
+		}
+	}
+}
+
+func GetReconcileStatus() ReconcileStatus {
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+	status := reconcileStatus
+	status.Interval = reconcileInterval
+	return status
+}
+
+// 本地的持仓和挂单(ws 更新)和交易所对比, 按照交易所的数据修复并记录差异
+func Reconcile() (diffs []ReconcileDiff, err error) {
+	if wsFuturesUserData != "1" {
+		return diffs, errors.New("futures_user_data is not enabled")
+	}
+	reconcileLock.Lock()
+	defer reconcileLock.Unlock()
+
+	startTime := time.Now().UnixMilli()
+	reconcileStatus.LastTime = startTime
+	reconcileStatus.Runs++
+	diffs, err = reconcile(startTime - reconcileGraceMs)
+	if err != nil {
+		reconcileStatus.LastError = err.Error()
+		return diffs, err
+	}
+	reconcileStatus.LastError = ""
+	reconcileStatus.LastDiffs = len(diffs)
+	if len(diffs) == 0 {
+		reconcileStatus.Divergent = 0
+		return diffs, nil
+	}
+	reconcileStatus.Divergent++
+	logs.Warn("reconcile found %d diffs, divergent %d times", len(diffs), reconcileStatus.Divergent)
+	if reconcileAlertCount > 0 && reconcileStatus.Divergent % reconcileAlertCount == 0 {
+		notifyReconcile(diffs, reconcileStatus.Divergent)
+	}
+	return diffs, nil
+}
+
+func reconcile(since int64) (diffs []ReconcileDiff, err error) {
+	remotePositions, err := binance.GetPosition(binance.PositionParams{})
+	if err != nil {
+		return diffs, err
+	}
+	remoteOrders, err := binance.GetOpenOrder()
+	if err != nil {
+		return diffs, err
+	}
+	o := orm.NewOrm()
+	var localPositions []models.FuturesPosition
+	if _, err = o.QueryTable("futures_positions").All(&localPositions); err != nil {
+		return diffs, err
+	}
+	var localOrders []models.FuturesOrder
+	if _, err = o.QueryTable("futures_orders").Filter("status__in", "NEW", "PARTIALLY_FILLED").All(&localOrders); err != nil {
+		return diffs, err
+	}
+
+	positions := []models.FuturesPosition{}
+	for _, position := range remotePositions {
+		positions = append(positions, positionModelOf(position))
+	}
+	orders := []models.FuturesOrder{}
+	for _, order := range remoteOrders {
+		orders = append(orders, orderModelOf(order))
+	}
+
+	positionDiffs := DiffPositions(localPositions, positions, since)
+	healPositions(positionDiffs, localPositions, positions)
+	orderDiffs := DiffOrders(localOrders, orders, since)
+	healOrders(orderDiffs, localOrders, orders)
+
+	diffs = append(positionDiffs, orderDiffs...)
+	now := time.Now().UnixMilli()
+	for _, diff := range diffs {
+		healed := 0
+		if diff.Healed {
+			healed = 1
+		}
+		o.Insert(&models.FuturesReconcileLog{
+			Kind: diff.Kind,
+			Diff: diff.Diff,
+			Symbol: diff.Symbol,
+			Side: diff.Side,
+			OrderId: diff.OrderId,
+			Field: diff.Field,
+			LocalValue: diff.LocalValue,
+			RemoteValue: diff.RemoteValue,
+			Healed: healed,
+			CreateTime: now,
+		})
+	}
+	return diffs, nil
+}
+
+// 对比持仓(symbol + side), 持仓数量为 0 的视为没有持仓, since 之后更新的本地持仓不对比
+func DiffPositions(local []models.FuturesPosition, remote []models.FuturesPosition, since int64) (diffs []ReconcileDiff) {
+	localMap := map[string]models.FuturesPosition{}
+	skip := map[string]bool{}
+	for _, position := range local {
+		key := position.Symbol + "_" + position.Side
+		if position.UpdateTime >= since {
+			skip[key] = true
+		}
+		if !isZeroAmount(position.Amount) {
+			localMap[key] = position
+		}
+	}
+	remoteMap := map[string]models.FuturesPosition{}
+	for _, position := range remote {
+		if !isZeroAmount(position.Amount) {
+			remoteMap[position.Symbol + "_" + position.Side] = position
+		}
+	}
+
+	for _, key := range sortedKeys(localMap, remoteMap) {
+		if skip[key] {
+			continue
+		}
+		localPosition, inLocal := localMap[key]
+		remotePosition, inRemote := remoteMap[key]
+		diff := ReconcileDiff{Kind: ReconcileKindPosition, Symbol: remotePosition.Symbol, Side: remotePosition.Side, Field: "amount"}
+		if !inLocal {
+			diff.Diff, diff.LocalValue, diff.RemoteValue = ReconcileMissingLocal, "0", remotePosition.Amount
+			diffs = append(diffs, diff)
+			continue
+		}
+		if !inRemote {
+			diff.Symbol, diff.Side = localPosition.Symbol, localPosition.Side
+			diff.Diff, diff.LocalValue, diff.RemoteValue = ReconcileStaleLocal, localPosition.Amount, "0"
+			diffs = append(diffs, diff)
+			continue
+		}
+		diff.Diff = ReconcileMismatch
+		if !floatEqual(localPosition.Amount, remotePosition.Amount) {
+			diff.LocalValue, diff.RemoteValue = localPosition.Amount, remotePosition.Amount
+			diffs = append(diffs, diff)
+		}
+		if !floatEqual(localPosition.EntryPrice, remotePosition.EntryPrice) {
+			diff.Field, diff.LocalValue, diff.RemoteValue = "entry_price", localPosition.EntryPrice, remotePosition.EntryPrice
+			diffs = append(diffs, diff)
+		}
+		if localPosition.Leverage != remotePosition.Leverage {
+			diff.Field = "leverage"
+			diff.LocalValue, diff.RemoteValue = strconv.FormatInt(localPosition.Leverage, 10), strconv.FormatInt(remotePosition.Leverage, 10)
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// 对比未成交的挂单(order_id), since 之后更新的本地挂单不对比
+func DiffOrders(local []models.FuturesOrder, remote []models.FuturesOrder, since int64) (diffs []ReconcileDiff) {
+	localMap := map[string]models.FuturesOrder{}
+	skip := map[string]bool{}
+	for _, order := range local {
+		if order.UpdateTime >= since {
+			skip[order.OrderId] = true
+		}
+		localMap[order.OrderId] = order
+	}
+	remoteMap := map[string]models.FuturesOrder{}
+	for _, order := range remote {
+		remoteMap[order.OrderId] = order
+	}
+
+	for _, key := range sortedKeys(localMap, remoteMap) {
+		if skip[key] {
+			continue
+		}
+		localOrder, inLocal := localMap[key]
+		remoteOrder, inRemote := remoteMap[key]
+		diff := ReconcileDiff{Kind: ReconcileKindOrder, Symbol: remoteOrder.Symbol, Side: remoteOrder.PositionSide, OrderId: key, Field: "status"}
+		if !inLocal {
+			diff.Diff, diff.RemoteValue = ReconcileMissingLocal, remoteOrder.Status
+			diffs = append(diffs, diff)
+			continue
+		}
+		if !inRemote {
+			diff.Symbol, diff.Side = localOrder.Symbol, localOrder.PositionSide
+			diff.Diff, diff.LocalValue = ReconcileStaleLocal, localOrder.Status
+			diffs = append(diffs, diff)
+			continue
+		}
+		diff.Diff = ReconcileMismatch
+		if localOrder.Status != remoteOrder.Status {
+			diff.LocalValue, diff.RemoteValue = localOrder.Status, remoteOrder.Status
+			diffs = append(diffs, diff)
+		}
+		if !floatEqual(localOrder.ExecutedQty, remoteOrder.ExecutedQty) {
+			diff.Field, diff.LocalValue, diff.RemoteValue = "executed_qty", localOrder.ExecutedQty, remoteOrder.ExecutedQty
+			diffs = append(diffs, diff)
+		}
+	}
+	return diffs
+}
+
+// 按照交易所的持仓修复本地持仓, 交易所没有的持仓数量改为 0(和 ws 平仓推送一致)
+func healPositions(diffs []ReconcileDiff, local []models.FuturesPosition, remote []models.FuturesPosition) {
+	o := orm.NewOrm()
+	now := time.Now().UnixMilli()
+	healed := map[string]error{}
+	for i, diff := range diffs {
+		key := diff.Symbol + "_" + diff.Side
+		if err, ok := healed[key]; ok {
+			diffs[i].Healed = err == nil
+			continue
+		}
+		var position models.FuturesPosition
+		for _, item := range local {
+			if item.Symbol == diff.Symbol && item.Side == diff.Side {
+				position = item
+			}
+		}
+		if diff.Diff == ReconcileStaleLocal {
+			position.Amount = "0"
+			position.UnrealizedProfit = "0"
+		} else {
+			for _, item := range remote {
+				if item.Symbol == diff.Symbol && item.Side == diff.Side {
+					item.ID, item.CreateTime = position.ID, position.CreateTime
+					position = item
+				}
+			}
+		}
+		position.UpdateTime = now
+		var err error
+		if position.ID == 0 {
+			position.CreateTime = now
+			_, err = o.Insert(&position)
+		} else {
+			_, err = o.Update(&position)
+		}
+		healed[key] = err
+		diffs[i].Healed = err == nil
+	}
+}
+
+// 按照交易所的挂单修复本地挂单, 交易所没有的挂单查询最终状态
+func healOrders(diffs []ReconcileDiff, local []models.FuturesOrder, remote []models.FuturesOrder) {
+	o := orm.NewOrm()
+	now := time.Now().UnixMilli()
+	healed := map[string]error{}
+	for i, diff := range diffs {
+		if err, ok := healed[diff.OrderId]; ok {
+			diffs[i].Healed = err == nil
+			continue
+		}
+		var order models.FuturesOrder
+		for _, item := range local {
+			if item.OrderId == diff.OrderId {
+				order = item
+			}
+		}
+		var err error
+		if diff.Diff == ReconcileStaleLocal {
+			orderId, _ := strconv.ParseInt(diff.OrderId, 10, 64)
+			var res *futures.Order
+			res, err = binance.GetOrder(binance.OrderParams{Symbol: diff.Symbol, OrderID: orderId})
+			if err == nil {
+				remoteOrder := orderModelOf(res)
+				remoteOrder.ID, remoteOrder.CreateTime = order.ID, order.CreateTime
+				order = remoteOrder
+			}
+		} else {
+			for _, item := range remote {
+				if item.OrderId == diff.OrderId {
+					item.ID, item.CreateTime = order.ID, order.CreateTime
+					order = item
+				}
+			}
+		}
+		if err == nil {
+			order.UpdateTime = now
+			if order.ID == 0 {
+				order.CreateTime = now
+				_, err = o.Insert(&order)
+			} else {
+				_, err = o.Update(&order)
+			}
+		}
+		healed[diff.OrderId] = err
+		diffs[i].Healed = err == nil
+	}
+}
+
+// 对账记录
+func GetReconcileLogs(kind string, symbol string, page int, pageSize int) (records []models.FuturesReconcileLog, total int64, err error) {
+	query := orm.NewOrm().QueryTable("futures_reconcile_logs")
+	if kind != "" {
+		query = query.Filter("kind", kind)
+	}
+	if symbol != "" {
+		query = query.Filter("symbol", symbol)
+	}
+	total, err = query.Count()
+	if err != nil {
+		return records, total, err
+	}
+	_, err = query.OrderBy("-ID").Limit(pageSize, (page - 1) * pageSize).All(&records)
+	return records, total, err
+}
+
+func notifyReconcile(diffs []ReconcileDiff, divergent int) {
+	content := []string{fmt.Sprintf("divergent %d times, %d diffs", divergent, len(diffs))}
+	for i, diff := range diffs {
+		if i >= reconcileNoticeMax {
+			content = append(content, "...")
+			break
+		}
+		content = append(content, fmt.Sprintf("%s %s %s %s %s %s: %s -> %s", diff.Kind, diff.Diff, diff.Symbol, diff.Side, diff.OrderId, diff.Field, diff.LocalValue, diff.RemoteValue))
+	}
+	pusher.FuturesAlert(notify.FuturesAlertParams{
+		Title: lang.Lang("futures.reconcile_title"),
+		Content: content,
+	})
+}
+
+func positionModelOf(position *futures.PositionRisk) models.FuturesPosition {
+	leverage, _ := strconv.ParseInt(position.Leverage, 10, 64)
+	return models.FuturesPosition{
+		Symbol: position.Symbol,
+		Side: position.PositionSide,
+		Amount: position.PositionAmt,
+		Leverage: leverage,
+		MarginType: position.MarginType,
+		IsolatedWallet: position.IsolatedWallet,
+		EntryPrice: position.EntryPrice,
+		MarkPrice: position.MarkPrice,
+		UnrealizedProfit: position.UnRealizedProfit,
+		AccumulatedRealized: "0",
+		MaintenanceMarginRequired: "0",
+	}
+}
+
+func orderModelOf(order *futures.Order) models.FuturesOrder {
+	return models.FuturesOrder{
+		Symbol: order.Symbol,
+		ClientOrderId: order.ClientOrderID,
+		OrderId: strconv.FormatInt(order.OrderID, 10),
+		Side: string(order.Side),
+		PositionSide: string(order.PositionSide),
+		Type: string(order.Type),
+		Status: string(order.Status),
+		Price: order.Price,
+		OrigQty: order.OrigQuantity,
+		ExecutedQty: order.ExecutedQuantity,
+		AveragePrice: order.AvgPrice,
+		StopPrice: order.StopPrice,
+	}
+}
+
+func isZeroAmount(amount string) bool {
+	value, _ := strconv.ParseFloat(amount, 64)
+	return math.Abs(value) < 0.0000001
+}
+
+func floatEqual(a string, b string) bool {
+	valueA, _ := strconv.ParseFloat(a, 64)
+	valueB, _ := strconv.ParseFloat(b, 64)
+	return math.Abs(valueA - valueB) <= 0.0000001 * math.Max(1, math.Max(math.Abs(valueA), math.Abs(valueB)))
+}
+
+func sortedKeys[T any](a map[string]T, b map[string]T) []string {
+	keys := []string{}
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
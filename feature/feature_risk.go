@@ -0,0 +1,328 @@
+package feature
+
+import (
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 组合风控的规则
+const (
+	RiskTotalNotional = "total_notional" // 总名义价值
+	RiskMarginPercent = "margin_percent" // 保证金占钱包余额的比例
+	RiskSymbolNotional = "symbol_notional" // 单个币种的名义价值
+	RiskNetNotional = "net_notional" // 多空净敞口
+	RiskCorrelated = "correlated" // 同方向高相关的持仓数量
+)
+
+const riskDefaultCorrelation = 0.8 // 没有设置时的相关系数阈值
+const riskCorrelationInterval = "1h" // 计算相关性的收益率周期
+const riskCorrelationBars = 168 // 最近 7 天的 1h 收益率
+const riskCorrelationCacheTime = time.Hour
+const riskNoticeInterval = 30 * time.Minute // 同一个币种方向和规则的拒绝通知间隔
+
+// 组合风控的限制, 为 0 时不限制
+type RiskLimits struct {
+	MaxNotional float64
+	MaxMarginPercent float64
+	MaxSymbolNotional float64
+	MaxNetNotional float64
+	MaxCorrelated int
+	Correlation float64
+}
+
+// 当前的持仓或者未成交的开仓挂单
+type RiskExposure struct {
+	Symbol string
+	Side string // LONG, SHORT
+	Notional float64
+	Margin float64
+}
+
+// 开仓超过限制时返回的错误
+type RiskLimitError struct {
+	Rule string
+	Detail string
+}
+
+func (e *RiskLimitError) Error() string {
+	return "risk limit " + e.Rule + ": " + e.Detail
+}
+
+var riskCorrelationCache = map[string]riskCorrelationValue{}
+var riskNoticeTime = map[string]time.Time{}
+var riskLock sync.Mutex
+
+type riskCorrelationValue struct {
+	value float64
+	time time.Time
+}
+
+func GetRiskLimits(systemConfig models.Config) RiskLimits {
+	limits := RiskLimits{
+		MaxNotional: systemConfig.RiskMaxNotional,
+		MaxMarginPercent: systemConfig.RiskMaxMarginPercent,
+		MaxSymbolNotional: systemConfig.RiskMaxSymbolNotional,
+		MaxNetNotional: systemConfig.RiskMaxNetNotional,
+		MaxCorrelated: systemConfig.RiskMaxCorrelated,
+		Correlation: systemConfig.RiskCorrelation,
+	}
+	if limits.Correlation <= 0 {
+		limits.Correlation = riskDefaultCorrelation
+	}
+	return limits
+}
+
+func (limits RiskLimits) Enable() bool {
+	return limits.MaxNotional > 0 || limits.MaxMarginPercent > 0 || limits.MaxSymbolNotional > 0 || limits.MaxNetNotional > 0 || limits.MaxCorrelated > 0
+}
+
+// 开仓前检查熔断和组合风控, 使用最新的持仓和挂单
+// 多个订单需要同时成交时(ex: 套利的现货和合约), 下单前先检查, 下单时使用 SkipRiskCheck
+func CheckOpenRisk(systemConfig models.Config, intent OrderIntent) error {
+	if tripped, reason := IsCircuitBreakerTripped(); tripped {
+		return &RiskLimitError{Rule: RiskCircuitBreaker, Detail: reason}
+	}
+	limits := GetRiskLimits(systemConfig)
+	if !limits.Enable() {
+		return nil
+	}
+	positions, err := GetTransformPositions()
+	if err != nil {
+		return &RiskLimitError{Rule: "positions", Detail: err.Error()}
+	}
+	orders, err := getTransformOpenOrders()
+	if err != nil {
+		return &RiskLimitError{Rule: "orders", Detail: err.Error()}
+	}
+	walletBalance := 0.0
+	if limits.MaxMarginPercent > 0 {
+		account, err := binance.GetFuturesAccount()
+		if err != nil {
+			return &RiskLimitError{Rule: RiskMarginPercent, Detail: err.Error()}
+		}
+		walletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	}
+	exposures := GetRiskExposures(positions, orders)
+	order := RiskExposure{
+		Symbol: intent.Symbol,
+		Side: intent.PositionSide,
+		Notional: intent.Quantity * intent.Price,
+		Margin: intent.Quantity * intent.Price / math.Max(1, float64(intent.Leverage)),
+	}
+	return CheckRiskLimits(limits, exposures, order, walletBalance, GetReturnCorrelation)
+}
+
+// 持仓和未成交的开仓挂单的名义价值和保证金
+func GetRiskExposures(positions []types.FuturesPosition, orders []types.FuturesOrder) (exposures []RiskExposure) {
+	leverages := map[string]float64{}
+	for _, position := range positions {
+		amount, _ := strconv.ParseFloat(position.Amount, 64)
+		markPrice, _ := strconv.ParseFloat(position.MarkPrice, 64)
+		leverage := math.Max(1, float64(position.Leverage))
+		leverages[position.Symbol] = leverage
+		if math.Abs(amount) < 0.0000000001 {
+			continue
+		}
+		side := position.Side
+		if side == "BOTH" {
+			side = "LONG"
+			if amount < 0 {
+				side = "SHORT"
+			}
+		}
+		notional := math.Abs(amount) * markPrice
+		exposures = append(exposures, RiskExposure{Symbol: position.Symbol, Side: side, Notional: notional, Margin: notional / leverage})
+	}
+	for _, order := range orders {
+		// 只计算开仓的挂单(开多买入, 开空卖出)
+		isOpen := (order.PositionSide == "LONG" && order.Side == "BUY") || (order.PositionSide == "SHORT" && order.Side == "SELL")
+		if !isOpen || (order.Type != "LIMIT" && order.Type != "MARKET") {
+			continue
+		}
+		price, _ := strconv.ParseFloat(order.Price, 64)
+		origQty, _ := strconv.ParseFloat(order.OrigQty, 64)
+		executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+		notional := (origQty - executedQty) * price
+		if notional <= 0 {
+			continue
+		}
+		leverage := leverages[order.Symbol]
+		if leverage == 0 {
+			leverage = 1
+		}
+		exposures = append(exposures, RiskExposure{Symbol: order.Symbol, Side: order.PositionSide, Notional: notional, Margin: notional / leverage})
+	}
+	return exposures
+}
+
+// 检查新的开仓是否超过限制, correlation 返回两个币种收益率的相关系数
+func CheckRiskLimits(limits RiskLimits, exposures []RiskExposure, order RiskExposure, walletBalance float64, correlation func(a string, b string) (float64, error)) error {
+	totalNotional, totalMargin, symbolNotional, netNotional := order.Notional, order.Margin, order.Notional, 0.0
+	for _, exposure := range append(exposures, order) {
+		if exposure.Side == "SHORT" {
+			netNotional -= exposure.Notional
+		} else {
+			netNotional += exposure.Notional
+		}
+	}
+	for _, exposure := range exposures {
+		totalNotional += exposure.Notional
+		totalMargin += exposure.Margin
+		if exposure.Symbol == order.Symbol {
+			symbolNotional += exposure.Notional
+		}
+	}
+	if limits.MaxNotional > 0 && totalNotional > limits.MaxNotional {
+		return &RiskLimitError{Rule: RiskTotalNotional, Detail: fmt.Sprintf("%.2f > %g", totalNotional, limits.MaxNotional)}
+	}
+	if limits.MaxMarginPercent > 0 {
+		if walletBalance <= 0 {
+			return &RiskLimitError{Rule: RiskMarginPercent, Detail: "wallet balance is 0"}
+		}
+		percent := totalMargin / walletBalance * 100
+		if percent > limits.MaxMarginPercent {
+			return &RiskLimitError{Rule: RiskMarginPercent, Detail: fmt.Sprintf("%.2f%% > %g%%", percent, limits.MaxMarginPercent)}
+		}
+	}
+	if limits.MaxSymbolNotional > 0 && symbolNotional > limits.MaxSymbolNotional {
+		return &RiskLimitError{Rule: RiskSymbolNotional, Detail: fmt.Sprintf("%s %.2f > %g", order.Symbol, symbolNotional, limits.MaxSymbolNotional)}
+	}
+	// 减少净敞口的开仓(对冲)不限制
+	if limits.MaxNetNotional > 0 && math.Abs(netNotional) > limits.MaxNetNotional && math.Abs(netNotional) > math.Abs(netNotional - sideSign(order.Side) * order.Notional) {
+		return &RiskLimitError{Rule: RiskNetNotional, Detail: fmt.Sprintf("%.2f > %g", math.Abs(netNotional), limits.MaxNetNotional)}
+	}
+	if limits.MaxCorrelated > 0 {
+		// 同方向的其他币种中和开仓币种高相关的数量, 加仓不增加数量
+		symbols := map[string]bool{}
+		for _, exposure := range exposures {
+			if exposure.Side == order.Side && exposure.Symbol != order.Symbol {
+				symbols[exposure.Symbol] = true
+			}
+			if exposure.Side == order.Side && exposure.Symbol == order.Symbol {
+				return nil
+			}
+		}
+		correlated := []string{}
+		for symbol := range symbols {
+			value, err := correlation(order.Symbol, symbol)
+			if err != nil {
+				continue // 没有k线数据时不限制
+			}
+			if value >= limits.Correlation {
+				correlated = append(correlated, fmt.Sprintf("%s(%.2f)", symbol, value))
+			}
+		}
+		if len(correlated) + 1 > limits.MaxCorrelated {
+			return &RiskLimitError{Rule: RiskCorrelated, Detail: fmt.Sprintf("%s %s correlated with %v, %d > %d", order.Symbol, order.Side, correlated, len(correlated) + 1, limits.MaxCorrelated)}
+		}
+	}
+	return nil
+}
+
+func sideSign(side string) float64 {
+	if side == "SHORT" {
+		return -1
+	}
+	return 1
+}
+
+// 两个币种最近 7 天 1h 收益率的相关系数, 使用本地保存的k线, 缓存 1 小时
+func GetReturnCorrelation(a string, b string) (float64, error) {
+	if a > b {
+		a, b = b, a
+	}
+	key := a + "_" + b
+	riskLock.Lock()
+	cache, ok := riskCorrelationCache[key]
+	riskLock.Unlock()
+	if ok && time.Since(cache.time) < riskCorrelationCacheTime {
+		return cache.value, nil
+	}
+	endTime := time.Now().Add(-time.Hour).UnixMilli()
+	startTime := endTime - (time.Hour * riskCorrelationBars).Milliseconds()
+	returnsA, err := getKlineReturns(a, startTime, endTime)
+	if err != nil {
+		return 0, err
+	}
+	returnsB, err := getKlineReturns(b, startTime, endTime)
+	if err != nil {
+		return 0, err
+	}
+	x, y := []float64{}, []float64{}
+	for openTime, value := range returnsA {
+		if other, ok := returnsB[openTime]; ok {
+			x = append(x, value)
+			y = append(y, other)
+		}
+	}
+	if len(x) < riskCorrelationBars / 2 {
+		return 0, fmt.Errorf("not enough returns for %s, %s", a, b)
+	}
+	value := CalculateCorrelation(x, y)
+	riskLock.Lock()
+	riskCorrelationCache[key] = riskCorrelationValue{value: value, time: time.Now()}
+	riskLock.Unlock()
+	return value, nil
+}
+
+// 每根k线的收益率, key 为开盘时间
+func getKlineReturns(symbol string, startTime int64, endTime int64) (map[int64]float64, error) {
+	klines, err := binance.GetHistoryKlineDataCached(symbol, riskCorrelationInterval, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	returns := map[int64]float64{}
+	for i := 1; i < len(klines); i++ {
+		prev, _ := strconv.ParseFloat(klines[i - 1].Close, 64)
+		close, _ := strconv.ParseFloat(klines[i].Close, 64)
+		if prev > 0 {
+			returns[klines[i].OpenTime] = close / prev - 1
+		}
+	}
+	return returns, nil
+}
+
+// 皮尔逊相关系数
+func CalculateCorrelation(x []float64, y []float64) float64 {
+	n := math.Min(float64(len(x)), float64(len(y)))
+	if n < 2 {
+		return 0
+	}
+	sumX, sumY := 0.0, 0.0
+	for i := 0; i < int(n); i++ {
+		sumX += x[i]
+		sumY += y[i]
+	}
+	meanX, meanY := sumX / n, sumY / n
+	cov, varX, varY := 0.0, 0.0, 0.0
+	for i := 0; i < int(n); i++ {
+		cov += (x[i] - meanX) * (y[i] - meanY)
+		varX += (x[i] - meanX) * (x[i] - meanX)
+		varY += (y[i] - meanY) * (y[i] - meanY)
+	}
+	if varX == 0 || varY == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varX * varY)
+}
+
+// 同一个币种方向和规则的拒绝, 间隔 30 分钟通知一次
+func shouldNotifyRisk(intent OrderIntent, err error) bool {
+	key := intent.Symbol + "_" + intent.PositionSide
+	if riskErr, ok := err.(*RiskLimitError); ok {
+		key += "_" + riskErr.Rule
+	}
+	riskLock.Lock()
+	defer riskLock.Unlock()
+	if last, ok := riskNoticeTime[key]; ok && time.Since(last) < riskNoticeInterval {
+		return false
+	}
+	riskNoticeTime[key] = time.Now()
+	return true
+}
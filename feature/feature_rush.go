@@ -5,8 +5,6 @@ import (
 	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/lang"
 	"go_binance_futures/models"
-	"go_binance_futures/notify"
-	"go_binance_futures/utils"
 	"strconv"
 
 	"github.com/adshao/go-binance/v2/futures"
@@ -36,7 +34,7 @@ func TryRush(systemConfig models.Config) {
 	
 	for _, coin := range coins {
 		if coin.StepSize != "0" {
-			_, err := tryBuyMarket(coin, coin.StepSize)
+			_, err := tryBuyMarket(systemConfig, coin, coin.StepSize)
 			if err == nil {
 				coin.Enable = 0 // 更新为禁用
 			}
@@ -67,7 +65,7 @@ func TryRush(systemConfig models.Config) {
 		// 找到了币的精度，说明币可能上线了
 		if stepSize, ok := symbolMap[coin.Symbol]; ok {
 			logs.Info("lotSize:", stepSize)
-			_, err := tryBuyMarket(coin, stepSize)
+			_, err := tryBuyMarket(systemConfig, coin, stepSize)
 		
 			if err == nil {
 				coin.Enable = 0 // 更新为禁用
@@ -81,7 +79,7 @@ func TryRush(systemConfig models.Config) {
 	}
 }
 
-func tryBuyMarket(coin models.NewSymbols, stepSize string) (res *futures.CreateOrderResponse, err error) {
+func tryBuyMarket(systemConfig models.Config, coin models.NewSymbols, stepSize string) (res *futures.CreateOrderResponse, err error) {
 	symbol := coin.Symbol
 	usdt := coin.Usdt
 	usdt_float64, _ := strconv.ParseFloat(usdt, 64) // 交易金额
@@ -104,55 +102,35 @@ func tryBuyMarket(coin models.NewSymbols, stepSize string) (res *futures.CreateO
 	}
 	logs.Info("尝试开始合约抢币symbol:", symbol)
 	logs.Info("预计交易价格为:", buyPrice)
-	// 修改仓位模式
-	if coin.MarginType == "ISOLATED" {
-		binance.SetMarginType(symbol, futures.MarginTypeIsolated)
-	} else {
-		binance.SetMarginType(symbol, futures.MarginTypeCrossed)
-	}
-	
-	binance.SetLeverage(symbol, int(coin.Leverage))  // 修改合约倍数
-	leverage_float64 := float64(coin.Leverage) // 合约倍数
-	quantity := (usdt_float64 / buyPrice) * leverage_float64  // 购买数量
-	quantity = utils.GetTradePrecision(quantity, stepSize) // 合理精度的数量
-	// logs.Info("symbol:", symbol, "buyPrice:", buyPrice, "quantity:", quantity)
-	
-	if coin.Side == "buy" {
-		if coin.ExpectPrice != "0" {
-			// 挂单价格
-			res, err = binance.BuyLimit(symbol, quantity, buyPrice, futures.PositionSideTypeLong)
-		} else {
-			// 市价
-			res, err = binance.BuyMarket(symbol, quantity, futures.PositionSideTypeLong)
-		}
-	} else if coin.Side == "sell" {
-		if coin.ExpectPrice != "0" {
-			// 挂单价格
-			res, err = binance.SellLimit(symbol, quantity, buyPrice, futures.PositionSideTypeShort)
-		} else {
-			// 市价
-			res, err = binance.SellMarket(symbol, quantity, futures.PositionSideTypeShort)
-		}
-	}
 	
-	positionSide := "long"
+	positionSide := "LONG"
 	if coin.Side == "sell" {
-		positionSide = "short"
+		positionSide = "SHORT"
+	}
+	orderType := "MARKET" // 市价
+	if coin.ExpectPrice != "0" {
+		orderType = "LIMIT" // 挂单价格
 	}
+	marginType := "CROSSED"
+	if coin.MarginType == "ISOLATED" {
+		marginType = "ISOLATED"
+	}
+	res, err = NewOrderExecutor(systemConfig).Execute(OrderIntent{
+		Action: OrderActionOpen,
+		Symbol: symbol,
+		PositionSide: positionSide,
+		OrderType: orderType,
+		Usdt: usdt_float64,
+		Price: buyPrice,
+		Leverage: coin.Leverage,
+		MarginType: marginType,
+		StepSize: stepSize,
+		Title: lang.Lang("futures.new_coin_rush_notice_title"),
+		SkipFailNotice: true,
+	})
 	if err != nil {
 		logs.Info("rush error symbol: ", symbol)
 		logs.Info("err in feature_rush: ", err.Error())
-	} else {
-		pusher.FuturesOpenOrder(notify.FuturesOrderParams{
-			Title: lang.Lang("futures.new_coin_rush_notice_title"),
-			Symbol: symbol,
-			Side: coin.Side,
-			PositionSide: positionSide,
-			Price: buyPrice,
-			Quantity: quantity,
-			Leverage: leverage_float64,
-			Status: "success",
-		})
 	}
 	return res, err
 }
\ No newline at end of file
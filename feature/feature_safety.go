@@ -0,0 +1,85 @@
+package feature
+
+import (
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"go_binance_futures/utils"
+	"math"
+	"strconv"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 加仓(DCA)的数量, 收益率(相对当前开仓均价)跌破 -safety_order_step% 时加仓, 为 0 时不需要加仓
+// 第 n 次加仓数量 = 开仓数量 * safety_order_multiplier^n
+func GetSafetyOrderQuantity(coin *models.Symbols, peak models.FuturesPositionPeak, positionAmount float64, roi float64) float64 {
+	if coin == nil || coin.SafetyOrderMaxCount <= 0 || coin.SafetyOrderStep <= 0 {
+		return 0
+	}
+	if peak.SafetyCount >= coin.SafetyOrderMaxCount || roi > -coin.SafetyOrderStep {
+		return 0
+	}
+	baseAmount, _ := strconv.ParseFloat(peak.BaseAmount, 64)
+	if baseAmount <= 0 {
+		baseAmount = positionAmount
+	}
+	multiplier := coin.SafetyOrderMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	quantity := baseAmount * math.Pow(multiplier, float64(peak.SafetyCount + 1))
+	if coin.StepSize != "" {
+		quantity = utils.GetTradePrecision(quantity, coin.StepSize)
+	}
+	return quantity
+}
+
+// 仓位最近一次的开仓订单 id, 加仓订单关联到这个订单
+func getParentOrderId(symbol string, positionSide string) int64 {
+	var order models.Order
+	err := orm.NewOrm().QueryTable("order").
+		Filter("symbol", symbol).
+		Filter("positionSide", positionSide).
+		Filter("side", OrderActionOpen).
+		Filter("parent_id", 0).
+		OrderBy("-ID").
+		One(&order)
+	if err != nil {
+		return 0
+	}
+	return order.ID
+}
+
+// 是否允许加仓, 需要满足开仓方向的开关, 持仓和挂单数量没有超过最大持仓数量, 策略没有被冻结
+func canSafetyOrder(systemConfig models.Config, position types.FuturesPosition, positions []types.FuturesPosition, openOrderCount int) bool {
+	if (position.Side == "LONG" && systemConfig.FutureAllowLong != 1) || (position.Side == "SHORT" && systemConfig.FutureAllowShort != 1) {
+		return false
+	}
+	if IsSafetyOrderOverMax(systemConfig.FutureMaxCount, position, positions, openOrderCount) {
+		logs.Info("%s:other position + open order is over max %d, stop safety order", position.Symbol, systemConfig.FutureMaxCount)
+		return false
+	}
+	freezeService := utils.NewFreezeService()
+	strategyName := getStrategyNameFromConfig(systemConfig)
+	if freezeService.IsFrozen(position.Symbol, strategyName, "real") {
+		logs.Info("%s:策略被冻结, 不加仓", position.Symbol)
+		return false
+	}
+	return true
+}
+
+// 加仓不会新增持仓, 不占用新的持仓数量: 其它持仓 + 挂单数量已经达到最大持仓数量(和开仓一样使用 >=)时不加仓
+func IsSafetyOrderOverMax(maxCount int, position types.FuturesPosition, positions []types.FuturesPosition, openOrderCount int) bool {
+	otherCount := 0
+	for _, item := range positions {
+		if item.Symbol == position.Symbol && item.Side == position.Side {
+			continue // 加仓的仓位
+		}
+		amount, _ := strconv.ParseFloat(item.Amount, 64)
+		if math.Abs(amount) > 0.0000000001 {
+			otherCount += 1
+		}
+	}
+	return otherCount + openOrderCount >= maxCount
+}
@@ -0,0 +1,211 @@
+package feature
+
+import (
+	"errors"
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"math"
+	"strconv"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 开仓金额的计算方式
+const (
+	SizingFixedUsdt = "fixed_usdt" // 固定保证金(默认), 币种的 usdt * 合约倍数 / 价格
+	SizingFixedNotional = "fixed_notional" // 固定名义价值, sizing_value(为 0 时使用币种的 usdt) / 价格
+	SizingBalancePercent = "balance_percent" // 可用余额的 sizing_value% 作为保证金
+	SizingFixedRisk = "fixed_risk" // 固定风险, 触发止损时亏损钱包余额的 sizing_value%, 止损距离使用币种的 loss, 没有设置时使用 2 倍的 1h ATR
+	SizingKelly = "kelly" // 凯利公式, 根据币种历史平仓的胜率和盈亏比, 可用余额 * 凯利比例 * sizing_value%(ex: 50 为半凯利)
+)
+
+const kellyMinTrades = 10 // 凯利公式需要的最少交易数量, 不足时使用固定保证金
+const kellyMaxTrades = 100 // 凯利公式统计最近的交易数量
+const atrStopMultiplier = 2.0 // 固定风险没有设置止损率时, 止损距离为 ATR 的倍数
+
+// 开仓金额的计算结果
+type SizingDecision struct {
+	Mode string // 计算方式
+	Usdt float64 // 保证金
+	Detail string // 计算过程, 记录到订单中
+}
+
+// 币种的计算方式, 币种没有设置(global)时使用系统配置
+func GetSizingMode(systemConfig models.Config, coin *models.Symbols) (mode string, value float64) {
+	mode, value = systemConfig.FutureSizingMode, systemConfig.FutureSizingValue
+	if coin.SizingMode != "" && coin.SizingMode != "global" {
+		mode, value = coin.SizingMode, coin.SizingValue
+	}
+	if mode == "" {
+		mode = SizingFixedUsdt
+	}
+	return mode, value
+}
+
+// 计算开仓的保证金
+func GetOrderSizing(systemConfig models.Config, coin *models.Symbols, price float64) (decision SizingDecision, err error) {
+	mode, value := GetSizingMode(systemConfig, coin)
+	usdt, _ := strconv.ParseFloat(coin.Usdt, 64)
+	leverage := math.Max(1, float64(coin.Leverage))
+	decision = SizingDecision{
+		Mode: SizingFixedUsdt,
+		Usdt: usdt,
+		Detail: fmt.Sprintf("usdt %g", usdt),
+	}
+	if mode == SizingFixedUsdt {
+		return decision, nil
+	}
+	if mode == SizingFixedNotional {
+		notional := value
+		if notional <= 0 {
+			notional = usdt
+		}
+		decision.Mode = mode
+		decision.Usdt = notional / leverage
+		decision.Detail = fmt.Sprintf("notional %g / leverage %g", notional, leverage)
+		return decision, nil
+	}
+	if value <= 0 {
+		return decision, errors.New("sizing value must be greater than 0: " + mode)
+	}
+
+	account, err := binance.GetFuturesAccount()
+	if err != nil {
+		return decision, err
+	}
+	availableBalance, _ := strconv.ParseFloat(account.AvailableBalance, 64)
+	walletBalance, _ := strconv.ParseFloat(account.TotalWalletBalance, 64)
+	switch mode {
+		case SizingBalancePercent:
+			decision.Usdt = availableBalance * value / 100
+			decision.Detail = fmt.Sprintf("available %.2f * %g%%", availableBalance, value)
+		case SizingFixedRisk:
+			stopDistance, stopDetail, err := getStopDistance(coin, price, leverage)
+			if err != nil {
+				return decision, err
+			}
+			risk := walletBalance * value / 100
+			decision.Usdt = risk / stopDistance / leverage
+			decision.Detail = fmt.Sprintf("risk %.2f (wallet %.2f * %g%%) / stop %.4f%% (%s) / leverage %g", risk, walletBalance, value, stopDistance * 100, stopDetail, leverage)
+		case SizingKelly:
+			winRate, payoff, count := GetSymbolWinRate(coin.Symbol)
+			if count < kellyMinTrades {
+				decision.Detail = fmt.Sprintf("usdt %g (kelly trades %d < %d)", usdt, count, kellyMinTrades)
+				return decision, nil
+			}
+			fraction := winRate
+			if payoff > 0 {
+				fraction = winRate - (1 - winRate) / payoff
+			}
+			if fraction <= 0 {
+				return decision, fmt.Errorf("kelly fraction %.4f <= 0, win rate %.4f, payoff %.4f", fraction, winRate, payoff)
+			}
+			decision.Usdt = availableBalance * fraction * value / 100
+			decision.Detail = fmt.Sprintf("available %.2f * kelly %.4f (win rate %.4f, payoff %.4f, trades %d) * %g%%", availableBalance, fraction, winRate, payoff, count, value)
+		default:
+			return decision, errors.New("invalid sizing mode: " + mode)
+	}
+	decision.Mode = mode
+	if decision.Usdt > availableBalance {
+		decision.Usdt = availableBalance
+		decision.Detail += fmt.Sprintf(", limit available %.2f", availableBalance)
+	}
+	if decision.Usdt <= 0 {
+		return decision, errors.New("insufficient balance for sizing: " + decision.Detail)
+	}
+	return decision, nil
+}
+
+// 根据计算方式设置开仓的保证金, 计算失败时不开仓
+func applyOrderSizing(systemConfig models.Config, coin *models.Symbols, intent *OrderIntent, price float64) bool {
+	decision, err := GetOrderSizing(systemConfig, coin, price)
+	if err != nil {
+		logs.Error("%s:order sizing error: %s", coin.Symbol, err.Error())
+		return false
+	}
+	intent.Usdt = decision.Usdt
+	intent.SizingMode = decision.Mode
+	intent.SizingDetail = decision.Detail
+	return true
+}
+
+// 止损距离(价格的比例), 优先使用币种的止损率, 没有设置时使用 ATR
+func getStopDistance(coin *models.Symbols, price float64, leverage float64) (distance float64, detail string, err error) {
+	loss, _ := strconv.ParseFloat(coin.Loss, 64)
+	if loss > 0 {
+		return loss / leverage / 100, fmt.Sprintf("loss %g%%", loss), nil
+	}
+	if price <= 0 {
+		return 0, "", errors.New("invalid price for atr stop distance")
+	}
+	klines, err := binance.GetKlineData(coin.Symbol, "1h", 50)
+	if err != nil {
+		return 0, "", err
+	}
+	high, low, close, _ := line.GetLineFloatPrices(klines)
+	atr, err := line.CalculateAtr(high, low, close, 14)
+	if err != nil {
+		return 0, "", err
+	}
+	distance = atr[0] * atrStopMultiplier / price
+	if distance <= 0 {
+		return 0, "", errors.New("invalid atr stop distance")
+	}
+	return distance, fmt.Sprintf("atr_1h_14 %g * %g", atr[0], atrStopMultiplier), nil
+}
+
+// 币种最近平仓订单的胜率和盈亏比(平均盈利 / 平均亏损)
+func GetSymbolWinRate(symbol string) (winRate float64, payoff float64, count int) {
+	var orders []models.Order
+	// 分批止盈时一笔交易有多个平仓订单
+	orm.NewOrm().QueryTable("order").Filter("symbol", symbol).Filter("side", "close").OrderBy("-ID").Limit(kellyMaxTrades * 5).All(&orders)
+	profits := GetTradeProfits(orders)
+	if len(profits) > kellyMaxTrades {
+		profits = profits[:kellyMaxTrades]
+	}
+	return CalculateWinRate(profits)
+}
+
+// 按照交易合并平仓订单的收益, parent_id 相同的平仓属于同一笔交易, parent_id 为 0 时单独计算
+func GetTradeProfits(orders []models.Order) []float64 {
+	profits := []float64{}
+	index := map[int64]int{}
+	for _, order := range orders {
+		profit, _ := strconv.ParseFloat(order.Inexact_profit, 64)
+		if order.ParentId != 0 {
+			if i, ok := index[order.ParentId]; ok {
+				profits[i] += profit
+				continue
+			}
+			index[order.ParentId] = len(profits)
+		}
+		profits = append(profits, profit)
+	}
+	return profits
+}
+
+// 根据每次平仓的收益计算胜率和盈亏比, 没有亏损时盈亏比为 0
+func CalculateWinRate(profits []float64) (winRate float64, payoff float64, count int) {
+	winCount := 0
+	winTotal, lossTotal := 0.0, 0.0
+	for _, profit := range profits {
+		if profit > 0 {
+			winCount += 1
+			winTotal += profit
+		} else {
+			lossTotal += -profit
+		}
+	}
+	count = len(profits)
+	if count == 0 {
+		return 0, 0, 0
+	}
+	winRate = float64(winCount) / float64(count)
+	if winCount > 0 && count > winCount && lossTotal > 0 {
+		payoff = (winTotal / float64(winCount)) / (lossTotal / float64(count - winCount))
+	}
+	return winRate, payoff, count
+}
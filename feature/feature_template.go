@@ -0,0 +1,467 @@
+package feature
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"go_binance_futures/technology"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	TemplateRolloutAction = "rollout" // 发布版本
+	TemplateRollbackAction = "rollback" // 回滚发布
+)
+
+// 模板中会复制到币种的策略内容, 版本之间只比较这些字段
+// 币种保存的是关联版本内容的副本, 交易时不会再读取模板, 模板的修改需要发布后才会写入币种
+type TemplateContent struct {
+	Technology string `json:"technology"`
+	Strategy string `json:"strategy"`
+	TrailingStop int `json:"trailing_stop"`
+	TrailingActivation float64 `json:"trailing_activation"`
+	TrailingCallback float64 `json:"trailing_callback"`
+	TakeProfitLadder string `json:"take_profit_ladder"`
+	SafetyOrderStep float64 `json:"safety_order_step"`
+	SafetyOrderMultiplier float64 `json:"safety_order_multiplier"`
+	SafetyOrderMaxCount int `json:"safety_order_max_count"`
+}
+
+// 发布前币种的配置, 回滚时恢复
+type SymbolTemplateSnapshot struct {
+	Symbol string `json:"symbol"`
+	TemplateId int64 `json:"template_id"`
+	TemplateVersion int64 `json:"template_version"`
+	Content TemplateContent `json:"content"`
+}
+
+// 两个版本之间的差异
+type TemplateDiff struct {
+	TemplateId int64 `json:"template_id"`
+	From int64 `json:"from"`
+	To int64 `json:"to"`
+	Fields []TemplateFieldDiff `json:"fields"` // 跟踪止盈, 分批止盈, 加仓等字段
+	Technology []TemplateItemDiff `json:"technology"` // 按照指标名称比较
+	Strategy []TemplateItemDiff `json:"strategy"` // 按照策略名称比较
+}
+
+type TemplateFieldDiff struct {
+	Field string `json:"field"`
+	From interface{} `json:"from"`
+	To interface{} `json:"to"`
+}
+
+type TemplateItemDiff struct {
+	Name string `json:"name"`
+	Change string `json:"change"` // added, removed, changed
+	From interface{} `json:"from"`
+	To interface{} `json:"to"`
+}
+
+func TemplateContentOf(template models.StrategyTemplates) TemplateContent {
+	return TemplateContent{
+		Technology: template.Technology,
+		Strategy: template.Strategy,
+		TrailingStop: template.TrailingStop,
+		TrailingActivation: template.TrailingActivation,
+		TrailingCallback: template.TrailingCallback,
+		TakeProfitLadder: template.TakeProfitLadder,
+		SafetyOrderStep: template.SafetyOrderStep,
+		SafetyOrderMultiplier: template.SafetyOrderMultiplier,
+		SafetyOrderMaxCount: template.SafetyOrderMaxCount,
+	}
+}
+
+func VersionContentOf(version models.StrategyTemplateVersion) TemplateContent {
+	return TemplateContent{
+		Technology: version.Technology,
+		Strategy: version.Strategy,
+		TrailingStop: version.TrailingStop,
+		TrailingActivation: version.TrailingActivation,
+		TrailingCallback: version.TrailingCallback,
+		TakeProfitLadder: version.TakeProfitLadder,
+		SafetyOrderStep: version.SafetyOrderStep,
+		SafetyOrderMultiplier: version.SafetyOrderMultiplier,
+		SafetyOrderMaxCount: version.SafetyOrderMaxCount,
+	}
+}
+
+func SymbolContentOf(symbol models.Symbols) TemplateContent {
+	return TemplateContent{
+		Technology: symbol.Technology,
+		Strategy: symbol.Strategy,
+		TrailingStop: symbol.TrailingStop,
+		TrailingActivation: symbol.TrailingActivation,
+		TrailingCallback: symbol.TrailingCallback,
+		TakeProfitLadder: symbol.TakeProfitLadder,
+		SafetyOrderStep: symbol.SafetyOrderStep,
+		SafetyOrderMultiplier: symbol.SafetyOrderMultiplier,
+		SafetyOrderMaxCount: symbol.SafetyOrderMaxCount,
+	}
+}
+
+// 把策略内容写入币种
+func ApplyTemplateContent(symbol *models.Symbols, content TemplateContent) {
+	symbol.Technology = content.Technology
+	symbol.Strategy = content.Strategy
+	symbol.TrailingStop = content.TrailingStop
+	symbol.TrailingActivation = content.TrailingActivation
+	symbol.TrailingCallback = content.TrailingCallback
+	symbol.TakeProfitLadder = content.TakeProfitLadder
+	symbol.SafetyOrderStep = content.SafetyOrderStep
+	symbol.SafetyOrderMultiplier = content.SafetyOrderMultiplier
+	symbol.SafetyOrderMaxCount = content.SafetyOrderMaxCount
+}
+
+// 把策略内容写入模板
+func ApplyTemplateContentToTemplate(template *models.StrategyTemplates, content TemplateContent) {
+	template.Technology = content.Technology
+	template.Strategy = content.Strategy
+	template.TrailingStop = content.TrailingStop
+	template.TrailingActivation = content.TrailingActivation
+	template.TrailingCallback = content.TrailingCallback
+	template.TakeProfitLadder = content.TakeProfitLadder
+	template.SafetyOrderStep = content.SafetyOrderStep
+	template.SafetyOrderMultiplier = content.SafetyOrderMultiplier
+	template.SafetyOrderMaxCount = content.SafetyOrderMaxCount
+}
+
+// 币种更新时写入的策略内容字段
+var symbolTemplateColumns = []string{"Technology", "Strategy", "TrailingStop", "TrailingActivation", "TrailingCallback", "TakeProfitLadder", "SafetyOrderStep", "SafetyOrderMultiplier", "SafetyOrderMaxCount", "TemplateId", "TemplateVersion"}
+
+// 模板的策略内容和最新版本不同时保存一个新的版本, 返回最新的版本
+func SaveTemplateVersion(template *models.StrategyTemplates, comment string) (version models.StrategyTemplateVersion, created bool, err error) {
+	return saveTemplateVersion(orm.NewOrm(), template, comment)
+}
+
+func saveTemplateVersion(o orm.QueryExecutor, template *models.StrategyTemplates, comment string) (version models.StrategyTemplateVersion, created bool, err error) {
+	content := TemplateContentOf(*template)
+	if template.Version > 0 {
+		var latest models.StrategyTemplateVersion
+		err := o.QueryTable("strategy_template_versions").Filter("TemplateId", template.ID).Filter("Version", template.Version).One(&latest)
+		if err == nil && VersionContentOf(latest) == content {
+			return latest, false, nil
+		}
+	}
+	maxVersion := int64(0)
+	o.Raw("SELECT IFNULL(MAX(version), 0) FROM strategy_template_versions WHERE template_id = ?", template.ID).QueryRow(&maxVersion)
+	version = models.StrategyTemplateVersion{
+		TemplateId: template.ID,
+		Version: maxVersion + 1,
+		Technology: content.Technology,
+		Strategy: content.Strategy,
+		TrailingStop: content.TrailingStop,
+		TrailingActivation: content.TrailingActivation,
+		TrailingCallback: content.TrailingCallback,
+		TakeProfitLadder: content.TakeProfitLadder,
+		SafetyOrderStep: content.SafetyOrderStep,
+		SafetyOrderMultiplier: content.SafetyOrderMultiplier,
+		SafetyOrderMaxCount: content.SafetyOrderMaxCount,
+		Comment: comment,
+		CreateTime: time.Now().UnixMilli(),
+	}
+	id, err := o.Insert(&version)
+	if err != nil {
+		return version, false, err
+	}
+	version.ID = id
+	template.Version = version.Version
+	_, err = o.Update(template, "Version")
+	return version, true, err
+}
+
+// 模板的版本, version 为 0 时返回最新的版本(没有版本时保存当前的模板为第一个版本)
+func GetTemplateVersion(templateId int64, version int64) (templateVersion models.StrategyTemplateVersion, err error) {
+	o := orm.NewOrm()
+	if version <= 0 {
+		var template models.StrategyTemplates
+		err = o.QueryTable("strategy_templates").Filter("Id", templateId).One(&template)
+		if err != nil {
+			return templateVersion, errors.New("template not found")
+		}
+		if template.Version == 0 {
+			templateVersion, _, err = SaveTemplateVersion(&template, "init")
+			return templateVersion, err
+		}
+		version = template.Version
+	}
+	err = o.QueryTable("strategy_template_versions").Filter("TemplateId", templateId).Filter("Version", version).One(&templateVersion)
+	if err != nil {
+		return templateVersion, fmt.Errorf("template version %d not found", version)
+	}
+	return templateVersion, nil
+}
+
+// 模板恢复到某个版本的内容, 保存为新的版本(保留历史)
+func RevertTemplate(templateId int64, version int64) (templateVersion models.StrategyTemplateVersion, err error) {
+	oldVersion, err := GetTemplateVersion(templateId, version)
+	if err != nil {
+		return templateVersion, err
+	}
+	return SaveTemplateContent(templateId, VersionContentOf(oldVersion), fmt.Sprintf("revert to v%d", version))
+}
+
+// 更新模板的策略内容并保存为新的版本(内容和最新版本相同时不保存), 需要发布后币种才会更新
+func SaveTemplateContent(templateId int64, content TemplateContent, comment string) (templateVersion models.StrategyTemplateVersion, err error) {
+	var template models.StrategyTemplates
+	o := orm.NewOrm()
+	err = o.QueryTable("strategy_templates").Filter("Id", templateId).One(&template)
+	if err != nil {
+		return templateVersion, err
+	}
+	ApplyTemplateContentToTemplate(&template, content)
+	template.UpdateTime = time.Now().UnixMilli()
+	_, err = o.Update(&template)
+	if err != nil {
+		return templateVersion, err
+	}
+	line.InvalidateProgramCache("")
+	templateVersion, _, err = SaveTemplateVersion(&template, comment)
+	return templateVersion, err
+}
+
+// 币种保存前同步模板: 选择了模板版本时复制版本的内容
+// 手动修改了策略内容时清空 template_id / template_version 不再关联模板, detached 为 true
+func SyncSymbolTemplate(old models.Symbols, symbol *models.Symbols) (detached bool, err error) {
+	if symbol.TemplateId != 0 && (symbol.TemplateId != old.TemplateId || symbol.TemplateVersion != old.TemplateVersion) {
+		version, err := GetTemplateVersion(symbol.TemplateId, symbol.TemplateVersion)
+		if err != nil {
+			return false, err
+		}
+		symbol.TemplateVersion = version.Version
+		ApplyTemplateContent(symbol, VersionContentOf(version))
+		return false, nil
+	}
+	if symbol.TemplateId != 0 && SymbolContentOf(*symbol) != SymbolContentOf(old) {
+		logs.Info(fmt.Sprintf("%s 手动修改了策略内容, 不再关联模板 %d 版本 %d", symbol.Symbol, symbol.TemplateId, symbol.TemplateVersion))
+		symbol.TemplateId = 0
+		symbol.TemplateVersion = 0
+		return true, nil
+	}
+	return false, nil
+}
+
+// 发布模板的版本到币种, symbols 为空时发布到所有使用这个模板的币种, version 为 0 时发布最新的版本
+// 自定义策略的币种会先编译检查, 有错误时都不发布
+func RolloutTemplateVersion(templateId int64, version int64, symbols []string, comment string) (rollout models.StrategyTemplateRollout, err error) {
+	templateVersion, err := GetTemplateVersion(templateId, version)
+	if err != nil {
+		return rollout, err
+	}
+	content := VersionContentOf(templateVersion)
+	o := orm.NewOrm()
+	var coins []models.Symbols
+	query := o.QueryTable("symbols")
+	if len(symbols) > 0 {
+		query = query.Filter("Symbol__in", symbols)
+	} else {
+		query = query.Filter("TemplateId", templateId)
+	}
+	_, err = query.All(&coins)
+	if err != nil {
+		return rollout, err
+	}
+	if len(coins) == 0 {
+		return rollout, errors.New("no symbols to rollout")
+	}
+	for _, coin := range coins {
+		if coin.StrategyType == "custom" && content.Strategy != "" {
+			if err := line.ValidateStrategy(coin.Symbol, content.Technology, content.Strategy); err != nil {
+				return rollout, fmt.Errorf("%s: %s", coin.Symbol, err.Error())
+			}
+		}
+	}
+
+	snapshots := []SymbolTemplateSnapshot{}
+	names := []string{}
+	for i := range coins {
+		snapshots = append(snapshots, SymbolTemplateSnapshot{
+			Symbol: coins[i].Symbol,
+			TemplateId: coins[i].TemplateId,
+			TemplateVersion: coins[i].TemplateVersion,
+			Content: SymbolContentOf(coins[i]),
+		})
+		names = append(names, coins[i].Symbol)
+		ApplyTemplateContent(&coins[i], content)
+		coins[i].TemplateId = templateId
+		coins[i].TemplateVersion = templateVersion.Version
+	}
+	rollout = models.StrategyTemplateRollout{
+		TemplateId: templateId,
+		Version: templateVersion.Version,
+		Action: TemplateRolloutAction,
+		Comment: comment,
+	}
+	err = saveTemplateRollout(&rollout, coins, names, snapshots)
+	return rollout, err
+}
+
+// 回滚一次发布, 币种恢复到发布前的配置, 回滚也会记录为一次发布, 可以再次回滚
+func RollbackTemplateRollout(rolloutId int64) (rollout models.StrategyTemplateRollout, err error) {
+	o := orm.NewOrm()
+	var target models.StrategyTemplateRollout
+	err = o.QueryTable("strategy_template_rollouts").Filter("Id", rolloutId).One(&target)
+	if err != nil {
+		return rollout, errors.New("rollout not found")
+	}
+	var snapshots []SymbolTemplateSnapshot
+	err = json.Unmarshal([]byte(target.Snapshot), &snapshots)
+	if err != nil {
+		return rollout, err
+	}
+	snapshotMap := map[string]SymbolTemplateSnapshot{}
+	names := []string{}
+	for _, snapshot := range snapshots {
+		snapshotMap[snapshot.Symbol] = snapshot
+		names = append(names, snapshot.Symbol)
+	}
+	var coins []models.Symbols
+	_, err = o.QueryTable("symbols").Filter("Symbol__in", names).All(&coins)
+	if err != nil {
+		return rollout, err
+	}
+	currentSnapshots := []SymbolTemplateSnapshot{}
+	names = []string{}
+	for i := range coins {
+		snapshot := snapshotMap[coins[i].Symbol]
+		currentSnapshots = append(currentSnapshots, SymbolTemplateSnapshot{
+			Symbol: coins[i].Symbol,
+			TemplateId: coins[i].TemplateId,
+			TemplateVersion: coins[i].TemplateVersion,
+			Content: SymbolContentOf(coins[i]),
+		})
+		names = append(names, coins[i].Symbol)
+		ApplyTemplateContent(&coins[i], snapshot.Content)
+		coins[i].TemplateId = snapshot.TemplateId
+		coins[i].TemplateVersion = snapshot.TemplateVersion
+	}
+	rollout = models.StrategyTemplateRollout{
+		TemplateId: target.TemplateId,
+		Action: TemplateRollbackAction,
+		RollbackId: target.ID,
+		Comment: fmt.Sprintf("rollback #%d (v%d)", target.ID, target.Version),
+	}
+	err = saveTemplateRollout(&rollout, coins, names, currentSnapshots)
+	return rollout, err
+}
+
+// 在一个事务中更新币种并保存发布记录
+func saveTemplateRollout(rollout *models.StrategyTemplateRollout, coins []models.Symbols, names []string, snapshots []SymbolTemplateSnapshot) error {
+	sort.Strings(names)
+	symbolsJson, _ := json.Marshal(names)
+	snapshotJson, _ := json.Marshal(snapshots)
+	rollout.Symbols = string(symbolsJson)
+	rollout.Snapshot = string(snapshotJson)
+	rollout.CreateTime = time.Now().UnixMilli()
+
+	to, err := orm.NewOrm().Begin()
+	if err != nil {
+		return err
+	}
+	for i := range coins {
+		_, err = to.Update(&coins[i], symbolTemplateColumns...)
+		if err != nil {
+			to.Rollback()
+			return fmt.Errorf("%s: %s", coins[i].Symbol, err.Error())
+		}
+	}
+	id, err := to.Insert(rollout)
+	if err != nil {
+		to.Rollback()
+		return err
+	}
+	rollout.ID = id
+	err = to.Commit()
+	if err != nil {
+		return err
+	}
+	for _, coin := range coins {
+		line.InvalidateProgramCache(coin.Symbol)
+	}
+	logs.Info("template rollout:", rollout.Action, rollout.TemplateId, rollout.Version, rollout.Symbols)
+	return nil
+}
+
+// 比较两个版本的差异
+func DiffTemplateVersions(from models.StrategyTemplateVersion, to models.StrategyTemplateVersion) TemplateDiff {
+	diff := TemplateDiff{
+		TemplateId: to.TemplateId,
+		From: from.Version,
+		To: to.Version,
+		Fields: []TemplateFieldDiff{},
+	}
+	fromContent, toContent := VersionContentOf(from), VersionContentOf(to)
+	fromValue, toValue := reflect.ValueOf(fromContent), reflect.ValueOf(toContent)
+	contentType := fromValue.Type()
+	for i := 0; i < contentType.NumField(); i++ {
+		field := contentType.Field(i)
+		if field.Name == "Technology" || field.Name == "Strategy" {
+			continue
+		}
+		if fromValue.Field(i).Interface() != toValue.Field(i).Interface() {
+			diff.Fields = append(diff.Fields, TemplateFieldDiff{
+				Field: field.Tag.Get("json"),
+				From: fromValue.Field(i).Interface(),
+				To: toValue.Field(i).Interface(),
+			})
+		}
+	}
+
+	fromIndicators, _ := line.ParseIndicatorConfigs(from.Technology)
+	toIndicators, _ := line.ParseIndicatorConfigs(to.Technology)
+	fromItems, toItems := map[string]interface{}{}, map[string]interface{}{}
+	for _, item := range fromIndicators {
+		fromItems[item.Name] = item
+	}
+	for _, item := range toIndicators {
+		toItems[item.Name] = item
+	}
+	diff.Technology = diffItems(fromItems, toItems)
+
+	var fromStrategy, toStrategy technology.StrategyConfig
+	json.Unmarshal([]byte(from.Strategy), &fromStrategy)
+	json.Unmarshal([]byte(to.Strategy), &toStrategy)
+	fromItems, toItems = map[string]interface{}{}, map[string]interface{}{}
+	for _, item := range fromStrategy {
+		fromItems[item.Name] = item
+	}
+	for _, item := range toStrategy {
+		toItems[item.Name] = item
+	}
+	diff.Strategy = diffItems(fromItems, toItems)
+	return diff
+}
+
+// 按照名称比较, 结果按照名称排序
+func diffItems(fromItems map[string]interface{}, toItems map[string]interface{}) []TemplateItemDiff {
+	names := []string{}
+	for name := range fromItems {
+		names = append(names, name)
+	}
+	for name := range toItems {
+		if _, ok := fromItems[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diffs := []TemplateItemDiff{}
+	for _, name := range names {
+		fromItem, inFrom := fromItems[name]
+		toItem, inTo := toItems[name]
+		if !inFrom {
+			diffs = append(diffs, TemplateItemDiff{Name: name, Change: "added", To: toItem})
+		} else if !inTo {
+			diffs = append(diffs, TemplateItemDiff{Name: name, Change: "removed", From: fromItem})
+		} else if !reflect.DeepEqual(fromItem, toItem) {
+			diffs = append(diffs, TemplateItemDiff{Name: name, Change: "changed", From: fromItem, To: toItem})
+		}
+	}
+	return diffs
+}
@@ -110,7 +110,7 @@ func NoticeAllSymbolByStrategy(systemConfig models.Config) {
 		env := line.InitParseEnv(coin.Symbol, coin.Technology)
 		for _, strategy := range strategyConfig {
 			if strategy.Enable && (strategy.Type == "long" || strategy.Type == "short") {
-				program, err := expr.Compile(strategy.Code, expr.Env(env))
+				program, err := line.GetProgram(coin.Symbol, coin.Technology, strategy.Type, strategy.Code, env)
 				if err != nil {
 					logs.Error("Error Strategy Compile Symbol: ", coin.Symbol)
 					logs.Error("Error Strategy Compile:", err.Error())
@@ -267,7 +267,7 @@ func CheckTestResults(systemConfig models.Config) {
 					continue
 				}
 				
-				program, err := expr.Compile(strategy.Code, expr.Env(env))
+				program, err := line.GetProgram(result.Symbol, result.Technology, strategy.Type, strategy.Code, env)
 				if err != nil {
 					logs.Error("Error Strategy Compile Symbol: ", result.Symbol)
 					logs.Error("Error Strategy Compile:", err.Error())
@@ -292,18 +292,10 @@ func CheckTestResults(systemConfig models.Config) {
 					strategyName := "test_strategy"
 					tradeType := "test"
 					
-					if unRealizedProfit < 0 {
-						// 亏损，记录亏损次数
-						err := freezeService.RecordLoss(result.Symbol, strategyName, tradeType)
-						if err != nil {
-							logs.Error("记录测试策略亏损失败:", err)
-						}
-					} else {
-						// 盈利，清零亏损次数
-						err := freezeService.RecordProfit(result.Symbol, strategyName, tradeType)
-						if err != nil {
-							logs.Error("记录测试策略盈利失败:", err)
-						}
+					// 亏损时增加亏损次数并检查冻结规则，盈利时清零亏损次数
+					err := freezeService.RecordTrade(result.Symbol, strategyName, tradeType, unRealizedProfit)
+					if err != nil {
+						logs.Error("记录测试策略盈亏失败:", err)
 					}
 					
 					// 平仓通知
@@ -0,0 +1,206 @@
+package feature
+
+import (
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	TrailingStopDisable = 0 // 关闭
+	TrailingStopLocal = 1 // 程序跟踪, 回撤后市价平仓
+	TrailingStopNative = 2 // 交易所 TRAILING_STOP_MARKET 订单
+)
+
+var positionPeaks = map[string]*models.FuturesPositionPeak{} // symbol_side => 最高收益率
+var positionPeaksLoaded = false
+var positionPeakLock sync.Mutex
+
+const positionStatsSaveInterval = 60 * 1000 // 持仓统计没有变化时写入数据库的间隔
+const positionStatsMaxGap = 5 * 60 * 1000 // 两次统计间隔超过 5 分钟时不计入盈利时长
+
+// 更新持仓的最高收益率和持仓期间的统计, 创新高, 开仓价格变化(加仓)或者最低收益率变化时写入数据库, 其它情况每分钟写入一次
+// 开仓价格变化(加仓)时重新统计最高收益率和分批止盈的进度, 已实现的收益和持仓期间的统计保留
+func UpdatePositionPeak(position types.FuturesPosition, roi float64, markPrice float64) models.FuturesPositionPeak {
+	positionPeakLock.Lock()
+	defer positionPeakLock.Unlock()
+	loadPositionPeaks()
+
+	now := time.Now().UnixMilli()
+	key := position.Symbol + "_" + position.Side
+	peak, ok := positionPeaks[key]
+	if !ok {
+		peak = &models.FuturesPositionPeak{
+			Symbol: position.Symbol,
+			Side: position.Side,
+			EntryPrice: position.EntryPrice,
+			PeakRoi: roi,
+			PeakPrice: markPrice,
+			Amount: absAmount(position.Amount),
+			BaseAmount: absAmount(position.Amount),
+			MaxRoi: roi,
+			MinRoi: roi,
+			CheckTime: now,
+			CreateTime: now,
+			UpdateTime: now,
+		}
+		id, err := orm.NewOrm().Insert(peak)
+		if err != nil {
+			logs.Error("insert position peak error:", position.Symbol, err.Error())
+		}
+		peak.ID = id
+		positionPeaks[key] = peak
+		return *peak
+	}
+	changed := updatePositionStats(peak, roi, now)
+	if peak.EntryPrice != position.EntryPrice {
+		// 加仓后开仓价格变化, 重新统计
+		peak.EntryPrice = position.EntryPrice
+		peak.PeakRoi = roi
+		peak.PeakPrice = markPrice
+		peak.Amount = absAmount(position.Amount)
+		peak.LadderStep = 0
+		changed = true
+	} else if roi > peak.PeakRoi {
+		peak.PeakRoi = roi
+		peak.PeakPrice = markPrice
+		changed = true
+	}
+	if !changed && now - peak.UpdateTime < positionStatsSaveInterval {
+		return *peak
+	}
+	peak.UpdateTime = now
+	_, err := orm.NewOrm().Update(peak)
+	if err != nil {
+		logs.Error("update position peak error:", position.Symbol, err.Error())
+	}
+	return *peak
+}
+
+// 更新最高最低收益率和盈利时长, 两次统计间隔太长(程序停止)时不计入盈利时长, 返回最高最低收益率是否变化
+func updatePositionStats(peak *models.FuturesPositionPeak, roi float64, now int64) (changed bool) {
+	if peak.CheckTime > 0 && roi > 0 && now - peak.CheckTime < positionStatsMaxGap {
+		peak.ProfitTime += now - peak.CheckTime
+	}
+	peak.CheckTime = now
+	if roi > peak.MaxRoi {
+		peak.MaxRoi = roi
+		changed = true
+	}
+	if roi < peak.MinRoi {
+		peak.MinRoi = roi
+		changed = true
+	}
+	return changed
+}
+
+// 平仓策略中使用的持仓期间的统计
+func GetPositionStats(peak models.FuturesPositionPeak, now int64) types.PositionStats {
+	stats := types.PositionStats{
+		MaxROI: peak.MaxRoi,
+		MinROI: peak.MinRoi,
+		ProfitTime: peak.ProfitTime,
+	}
+	if peak.CreateTime > 0 && now > peak.CreateTime {
+		stats.HoldTime = now - peak.CreateTime
+		stats.ProfitRatio = math.Min(1, float64(peak.ProfitTime) / float64(stats.HoldTime))
+	}
+	return stats
+}
+
+// 分批止盈完成一档, 记录进度和已实现的收益
+func RecordLadderStep(symbol string, side string, step int, profit float64) {
+	positionPeakLock.Lock()
+	defer positionPeakLock.Unlock()
+	peak, ok := positionPeaks[symbol + "_" + side]
+	if !ok {
+		return
+	}
+	peak.LadderStep = step
+	peak.RealizedProfit += profit
+	peak.UpdateTime = time.Now().UnixMilli()
+	_, err := orm.NewOrm().Update(peak)
+	if err != nil {
+		logs.Error("update position ladder step error:", symbol, err.Error())
+	}
+}
+
+// 加仓(DCA)完成一次, 记录加仓次数和关联的开仓订单
+func RecordSafetyOrder(symbol string, side string, count int, parentOrderId int64) {
+	positionPeakLock.Lock()
+	defer positionPeakLock.Unlock()
+	peak, ok := positionPeaks[symbol + "_" + side]
+	if !ok {
+		return
+	}
+	peak.SafetyCount = count
+	peak.ParentOrderId = parentOrderId
+	peak.UpdateTime = time.Now().UnixMilli()
+	_, err := orm.NewOrm().Update(peak)
+	if err != nil {
+		logs.Error("update position safety order error:", symbol, err.Error())
+	}
+}
+
+// 删除已经平仓的最高收益率记录和持仓状态
+func CleanPositionPeaks(positions []types.FuturesPosition) {
+	positionPeakLock.Lock()
+	defer positionPeakLock.Unlock()
+	loadPositionPeaks()
+
+	exist := map[string]bool{}
+	for _, position := range positions {
+		exist[position.Symbol + "_" + position.Side] = true
+	}
+	line.CleanPositionStates(exist)
+	for key, peak := range positionPeaks {
+		if exist[key] {
+			continue
+		}
+		_, err := orm.NewOrm().Delete(peak)
+		if err != nil {
+			logs.Error("delete position peak error:", peak.Symbol, err.Error())
+			continue
+		}
+		delete(positionPeaks, key)
+	}
+}
+
+// 是否触发跟踪止盈, 最高收益率达到激活值后, 收益率回撤超过回调值
+func IsTrailingStop(coin *models.Symbols, peakRoi float64, roi float64) bool {
+	if coin == nil || coin.TrailingStop == TrailingStopDisable || coin.TrailingCallback <= 0 {
+		return false
+	}
+	if peakRoi <= 0 || peakRoi < coin.TrailingActivation {
+		return false
+	}
+	return peakRoi - roi >= coin.TrailingCallback
+}
+
+func loadPositionPeaks() {
+	if positionPeaksLoaded {
+		return
+	}
+	var peaks []*models.FuturesPositionPeak
+	_, err := orm.NewOrm().QueryTable("futures_position_peaks").All(&peaks)
+	if err != nil {
+		logs.Error("load position peaks error:", err.Error())
+		return
+	}
+	for _, peak := range peaks {
+		positionPeaks[peak.Symbol + "_" + peak.Side] = peak
+	}
+	positionPeaksLoaded = true
+}
+
+func absAmount(amount string) string {
+	amountFloat, _ := strconv.ParseFloat(amount, 64)
+	return strconv.FormatFloat(math.Abs(amountFloat), 'f', -1, 64)
+}
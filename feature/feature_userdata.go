@@ -15,9 +15,13 @@ import (
 func SyncUserData() {
 	deleteOldUserData()
 	getNowUserData()
-	go func() {
-		binance.WsUserData()
-	}()
+	// 重连后对账, 修复断开期间漏掉的推送
+	binance.OnUserDataResync(func() {
+		if _, err := Reconcile(); err != nil {
+			logs.Error("futures_user_data resync error:", err.Error())
+		}
+	})
+	go binance.WsUserData()
 }
 
 // 删除数据表旧数据
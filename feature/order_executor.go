@@ -0,0 +1,249 @@
+package feature
+
+import (
+	"errors"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/lang"
+	"go_binance_futures/models"
+	"go_binance_futures/notify"
+	"go_binance_futures/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	OrderActionOpen = "open"
+	OrderActionClose = "close"
+)
+
+// 下单意图
+type OrderIntent struct {
+	Action       string  // open(开仓), close(平仓)
+	Symbol       string
+	PositionSide string  // LONG, SHORT
+	OrderType    string  // MARKET, LIMIT, 默认 MARKET
+	Quantity     float64 // 数量, 开仓时为 0 则根据 Usdt 和 Leverage 计算
+	Usdt         float64 // 开仓金额
+	Price        float64 // 限价单的价格, 市价单为参考价格(用于计算数量和记录订单)
+	Leverage     int64   // 合约倍数, 开仓时 > 0 则修改合约倍数
+	MarginType   string  // ISOLATED, CROSSED, 开仓时不为空则修改仓位模式
+	TickSize     string  // 价格精度
+	StepSize     string  // 数量精度
+	Reason       string  // 下单原因, 对应语言包 futures.xxx, ex: stop_loss, target_profit, wind_of_change
+	Title        string  // 通知标题, 默认为开仓/平仓通知
+	Profit       float64 // 平仓时的预估收益(未实现盈亏)
+	Partial      bool    // 部分平仓(分批止盈), 不撤销止盈止损单, 不记录风控盈亏
+	RealizedProfit float64 // 之前部分平仓已实现的收益, 平仓后风控按照整笔交易的收益记录
+	StrategyName string  // 策略名称, 不为空时平仓后记录风控冻结的盈亏和自动缩放
+	SkipFailNotice bool  // 下单失败时不通知(ex: 抢购会不断重试)
+	SizingMode   string  // 开仓金额计算方式, 记录到订单
+	SizingDetail string  // 开仓金额计算过程, 记录到订单
+	ParentOrderId int64  // 加仓(DCA)时关联的开仓订单 id
+	SkipRiskCheck bool   // 开仓前已经调用 CheckOpenRisk 检查过风控
+}
+
+// 下单执行器, 统一处理精度, 倍率和仓位模式, 下单, 订单入库, 风控记录和通知
+type OrderExecutor struct {
+	SystemConfig models.Config
+}
+
+func NewOrderExecutor(systemConfig models.Config) *OrderExecutor {
+	return &OrderExecutor{SystemConfig: systemConfig}
+}
+
+func (e *OrderExecutor) Execute(intent OrderIntent) (order *futures.CreateOrderResponse, err error) {
+	if intent.OrderType == "" {
+		intent.OrderType = string(futures.OrderTypeMarket)
+	}
+	if intent.TickSize != "" && intent.Price > 0 {
+		intent.Price = utils.GetTradePrecision(intent.Price, intent.TickSize) // 合理精度的价格
+	}
+	if intent.Quantity == 0 && intent.Usdt > 0 && intent.Price > 0 {
+		leverage := math.Max(1, float64(intent.Leverage))
+		intent.Quantity = (intent.Usdt / intent.Price) * leverage // 购买数量
+	}
+	if intent.StepSize != "" {
+		intent.Quantity = utils.GetTradePrecision(intent.Quantity, intent.StepSize) // 合理精度的数量
+	}
+
+	if intent.PositionSide != "LONG" && intent.PositionSide != "SHORT" {
+		err = errors.New("invalid position side: " + intent.PositionSide)
+	} else if intent.Quantity <= 0 {
+		err = errors.New("invalid order quantity")
+	} else if intent.Action == OrderActionOpen {
+		if !intent.SkipRiskCheck {
+			err = CheckOpenRisk(e.SystemConfig, intent) // 组合风控
+		}
+		if err != nil {
+			logs.Info("%s:open %s rejected, %s", intent.Symbol, intent.PositionSide, err.Error())
+			if !intent.SkipFailNotice && shouldNotifyRisk(intent, err) {
+				intent.Title = lang.Lang("futures.risk_limit_title")
+				e.notify(intent, err)
+			}
+			return nil, err
+		}
+		e.prepare(intent)
+		order, err = e.create(intent)
+	} else {
+		order, err = e.create(intent)
+	}
+	if err != nil {
+		logs.Error("%s order error, symbol: %s, position side: %s, err: %s", intent.Action, intent.Symbol, intent.PositionSide, err.Error())
+	} else {
+		e.record(intent, order)
+	}
+	if err == nil || !intent.SkipFailNotice {
+		e.notify(intent, err)
+	}
+	return order, err
+}
+
+func (e *OrderExecutor) create(intent OrderIntent) (*futures.CreateOrderResponse, error) {
+	return binance.CreateOrder(binance.CreateOrderParams{
+		Symbol: intent.Symbol,
+		Side: intent.side(),
+		PositionSide: futures.PositionSideType(intent.PositionSide),
+		Type: futures.OrderType(intent.OrderType),
+		TimeInForce: intent.timeInForce(),
+		Quantity: intent.Quantity,
+		Price: intent.limitPrice(),
+	})
+}
+
+// 修改合约倍数和仓位模式
+func (e *OrderExecutor) prepare(intent OrderIntent) {
+	if intent.Leverage > 0 {
+		binance.SetLeverage(intent.Symbol, int(intent.Leverage))
+	}
+	if intent.MarginType == "CROSSED" {
+		binance.SetMarginType(intent.Symbol, futures.MarginTypeCrossed)
+	} else if intent.MarginType != "" {
+		binance.SetMarginType(intent.Symbol, futures.MarginTypeIsolated)
+	}
+}
+
+// 订单入库, 平仓后记录风控盈亏
+func (e *OrderExecutor) record(intent OrderIntent, res *futures.CreateOrderResponse) {
+	order := new(models.Order)
+	order.Symbol = intent.Symbol
+	order.Amount = strconv.FormatFloat(intent.Quantity, 'f', -1, 64)
+	order.Avg_price = strconv.FormatFloat(intent.recordPrice(), 'f', -1, 64)
+	order.PositionSide = intent.PositionSide
+	order.Leverage = intent.Leverage
+	order.Side = intent.Action
+	order.Inexact_profit = "0.0" // 预估收益
+	if intent.Action == OrderActionClose {
+		order.Inexact_profit = strconv.FormatFloat(intent.Profit, 'f', -1, 64)
+	}
+	order.OrderId = res.OrderID
+	order.SizingMode = intent.SizingMode
+	order.SizingDetail = intent.SizingDetail
+	order.ParentId = intent.ParentOrderId
+	if intent.Action == OrderActionClose && order.ParentId == 0 {
+		order.ParentId = getParentOrderId(intent.Symbol, intent.PositionSide) // 同一笔交易的平仓(包括分批止盈)关联到开仓订单
+	}
+	order.UpdateTime = time.Now().Unix() * 1000
+	orm.NewOrm().Insert(order)
+
+	if intent.Action == OrderActionOpen {
+		// 市价开仓(包括加仓)成交后马上按照新的开仓均价挂交易所止盈止损单, 不等待定时同步
+		if e.SystemConfig.FutureEnable == 1 && intent.OrderType == string(futures.OrderTypeMarket) {
+			go SyncProtectiveOrders(e.SystemConfig)
+		}
+		return
+	}
+	if intent.Partial {
+		return
+	}
+	go CancelProtectiveOrders(intent.Symbol, intent.PositionSide) // 撤销交易所的止盈止损单和跟踪止盈单
+	if intent.StrategyName == "" {
+		return
+	}
+	tradeProfit := intent.Profit + intent.RealizedProfit // 整笔交易的收益
+	// 自动缩放
+	AutoLossScale(e.SystemConfig, tradeProfit >= 0)
+
+	// 风控处理 - 记录盈亏
+	freezeService := utils.NewFreezeService()
+	tradeType := "real" // 默认为实盘交易
+	// 亏损时增加亏损次数并检查冻结规则，盈利时清零亏损次数
+	err := freezeService.RecordTrade(intent.Symbol, intent.StrategyName, tradeType, tradeProfit)
+	if err != nil {
+		logs.Error("记录策略盈亏失败:", err)
+	}
+}
+
+func (e *OrderExecutor) notify(intent OrderIntent, err error) {
+	params := notify.FuturesOrderParams{
+		Title: intent.Title,
+		Symbol: intent.Symbol,
+		Side: strings.ToLower(string(intent.side())),
+		PositionSide: strings.ToLower(intent.PositionSide),
+		Price: intent.recordPrice(),
+		Quantity: intent.Quantity,
+		Leverage: float64(intent.Leverage),
+		Status: "success",
+	}
+	if intent.Reason != "" {
+		params.Remarks = lang.Lang("futures." + intent.Reason)
+	}
+	if err != nil {
+		params.Status = "fail"
+		params.Error = err.Error()
+	}
+	if intent.Action == OrderActionClose {
+		if params.Title == "" {
+			params.Title = lang.Lang("futures.close_notice_title")
+		}
+		params.Profit = intent.Profit
+		pusher.FuturesCloseOrder(params)
+	} else {
+		if params.Title == "" {
+			params.Title = lang.Lang("futures.open_notice_title")
+		}
+		pusher.FuturesOpenOrder(params)
+	}
+}
+
+// 开多和平空是买入, 开空和平多是卖出
+func (intent OrderIntent) side() futures.SideType {
+	if (intent.Action == OrderActionOpen) == (intent.PositionSide == "LONG") {
+		return futures.SideTypeBuy
+	}
+	return futures.SideTypeSell
+}
+
+func (intent OrderIntent) timeInForce() futures.TimeInForceType {
+	if intent.OrderType == string(futures.OrderTypeLimit) {
+		return futures.TimeInForceTypeGTC
+	}
+	return ""
+}
+
+func (intent OrderIntent) limitPrice() float64 {
+	if intent.OrderType == string(futures.OrderTypeLimit) {
+		return intent.Price
+	}
+	return 0
+}
+
+// 记录的订单价格, 市价开仓时价格不利方向偏移 0.12%(市价成交通常会比当前价格差)
+func (intent OrderIntent) recordPrice() float64 {
+	if intent.Action != OrderActionOpen || intent.OrderType != string(futures.OrderTypeMarket) {
+		return intent.Price
+	}
+	price := intent.Price * 0.9988
+	if intent.side() == futures.SideTypeBuy {
+		price = intent.Price * 1.0012
+	}
+	if intent.TickSize != "" {
+		price = utils.GetTradePrecision(price, intent.TickSize)
+	}
+	return price
+}
@@ -1,6 +1,7 @@
 package coin
 
 import (
+	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 	"sort"
 )
@@ -9,8 +10,8 @@ type TradeCoin1 struct {
 }
 
 // 策略: 从涨幅榜中前6个里面随机选取2个，从跌幅榜中的前6个里面随机选取2个, 最近5min交易过的币不再交易
-func (tradeCoin1 TradeCoin1) SelectCoins(allCoins []*models.Symbols) (coins []*models.Symbols) {
-	exclude_symbols_map := getLimitMinOrder(5)
+func (tradeCoin1 TradeCoin1) SelectCoins(allCoins []*models.Symbols, exchange binance.FuturesExchange) (coins []*models.Symbols) {
+	exclude_symbols_map := getLimitMinOrder(exchange, 5)
 	sort.SliceStable(allCoins, func(i, j int) bool {
 		return allCoins[i].PercentChange < allCoins[j].PercentChange // 涨幅从小到大排序
 	})
@@ -1,6 +1,7 @@
 package coin
 
 import (
+	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 	"sort"
 )
@@ -9,8 +10,8 @@ type TradeCoin3 struct {
 }
 
 // 策略: 最近10min交易过的币不再交易,随机选取2个
-func (tradeCoin3 TradeCoin3) SelectCoins(allCoins []*models.Symbols) (coins []*models.Symbols) {
-	exclude_symbols_map := getLimitMinOrder(10)
+func (tradeCoin3 TradeCoin3) SelectCoins(allCoins []*models.Symbols, exchange binance.FuturesExchange) (coins []*models.Symbols) {
+	exclude_symbols_map := getLimitMinOrder(exchange, 10)
 	sort.SliceStable(allCoins, func(i, j int) bool {
 		return allCoins[i].PercentChange < allCoins[j].PercentChange // 涨幅从小到大排序
 	})
@@ -1,6 +1,7 @@
 package coin
 
 import (
+	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 )
 
@@ -8,8 +9,8 @@ type TradeCoin5 struct {
 }
 
 // 策略: 最近5min交易过的币不再交易,随机选取5个
-func (tradeCoin5 TradeCoin5) SelectCoins(allCoins []*models.Symbols) (coins []*models.Symbols) {
-	exclude_symbols_map := getLimitMinLocalOrder(5)
+func (tradeCoin5 TradeCoin5) SelectCoins(allCoins []*models.Symbols, exchange binance.FuturesExchange) (coins []*models.Symbols) {
+	exclude_symbols_map := getLimitMinLocalOrder(exchange, 5)
 	// sort.SliceStable(allCoins, func(i, j int) bool {
 	// 	return allCoins[i].PercentChange < allCoins[j].PercentChange // 涨幅从小到大排序
 	// })
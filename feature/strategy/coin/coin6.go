@@ -1,6 +1,7 @@
 package coin
 
 import (
+	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 	"sort"
 )
@@ -9,8 +10,8 @@ type TradeCoin6 struct {
 }
 
 // 策略: 最近5min交易过的币不再交易,从交易额最高的前150个币中随机选取5个
-func (tradeCoin TradeCoin6) SelectCoins(allCoins []*models.Symbols) (coins []*models.Symbols) {
-	exclude_symbols_map := getLimitMinLocalOrder(5)
+func (tradeCoin TradeCoin6) SelectCoins(allCoins []*models.Symbols, exchange binance.FuturesExchange) (coins []*models.Symbols) {
+	exclude_symbols_map := getLimitMinLocalOrder(exchange, 5)
 	filterCoins := []*models.Symbols{}
 	for _, coin := range allCoins {
 		if _, exist := exclude_symbols_map[coin.Symbol]; exist {
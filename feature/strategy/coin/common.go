@@ -4,17 +4,23 @@ import (
 	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 	"math/rand"
-	"time"
 
+	"github.com/adshao/go-binance/v2/futures"
 	"github.com/beego/beego/v2/client/orm"
 )
 
 // 最近5min交易过的币种订单
-func getLimitMinOrder(minute int64) (symbols map[string]bool) {
-	nowTime := time.Now().Unix() * 1000 // 毫秒时间戳
-	orders, _ := binance.GetOrders(binance.ListOrderParams{
+func getLimitMinOrder(exchange binance.FuturesExchange, minute int64) (symbols map[string]bool) {
+	nowTime := binance.GetNowTime(exchange) // 毫秒时间戳
+	listOrderParams := binance.ListOrderParams{
 		StartTime: nowTime - minute * 60 * 1000,
-	})
+	}
+	var orders []*futures.Order
+	if exchange == nil {
+		orders, _ = binance.GetOrders(listOrderParams)
+	} else {
+		orders, _ = exchange.GetOrders(listOrderParams)
+	}
 	symbols = make(map[string]bool)
 	for _, order := range orders {
 		symbols[order.Symbol] = true
@@ -23,15 +29,26 @@ func getLimitMinOrder(minute int64) (symbols map[string]bool) {
 }
 
 // 最近min交易过的币种local订单
-func getLimitMinLocalOrder(minute int64) (symbols map[string]bool) {
-	startTime := time.Now().Unix() * 1000 - minute * 60 * 1000 // 毫秒时间戳
+func getLimitMinLocalOrder(exchange binance.FuturesExchange, minute int64) (symbols map[string]bool) {
+	startTime := binance.GetNowTime(exchange) - minute * 60 * 1000 // 毫秒时间戳
+	symbols = make(map[string]bool)
+	if exchange != nil {
+		// 回测时没有本地订单, 使用交易所的平仓订单
+		orders, _ := exchange.GetOrders(binance.ListOrderParams{StartTime: startTime})
+		for _, order := range orders {
+			if (order.PositionSide == futures.PositionSideTypeLong && order.Side == futures.SideTypeSell) ||
+				(order.PositionSide == futures.PositionSideTypeShort && order.Side == futures.SideTypeBuy) {
+				symbols[order.Symbol] = true
+			}
+		}
+		return symbols
+	}
 	o := orm.NewOrm()
 	var orders []models.Order
 	_, _ = o.QueryTable("order").
 		Filter("UpdateTime__gte", startTime).
 		Filter("Side", "close").
 		All(&orders)
-	symbols = make(map[string]bool)
 	for _, order := range orders {
 		symbols[order.Symbol] = true
 	}
@@ -1,8 +1,11 @@
 package strategy
 
-import "go_binance_futures/models"
+import (
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+)
 
 type CoinStrategy interface {
-    SelectCoins(allCoins []*models.Symbols) (coins []*models.Symbols)
+    // exchange 为 nil 时使用当前交易所(回测时为回放的历史数据)
+    SelectCoins(allCoins []*models.Symbols, exchange binance.FuturesExchange) (coins []*models.Symbols)
 }
-
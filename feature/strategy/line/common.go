@@ -1,7 +1,9 @@
 package line
 
 import (
+	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
+	"math"
 	"strconv"
 
 	"github.com/adshao/go-binance/v2/futures"
@@ -131,6 +133,17 @@ func GetLineFloatValues(data []*futures.Kline) (high, low, close, open, amount,
 	return high, low, close, open, qps, amount
 }
 
+// 获取成交量和开盘时间, 数据时间由新到旧
+func GetLineVolumes(data []*futures.Kline) (volume []float64, openTime []int64) {
+	volume = make([]float64, len(data))
+	openTime = make([]int64, len(data))
+	for key, item := range data {
+		volume[key], _ = strconv.ParseFloat(item.Volume, 64)
+		openTime[key] = item.OpenTime
+	}
+	return volume, openTime
+}
+
 // 获取某中类型的line的数量是否超过阈值
 func getRightLine(data []*Line, position string) bool {
 	positionCount := 0
@@ -149,10 +162,60 @@ func GetAllSymbols() (symbols []*models.Symbols, err error) {
 	return symbols, err
 }
 
+// 获取币种的24小时行情, exchange 为 nil 时从数据库获取(ws 实时更新的价格), 回测时根据回放的 1h k线计算
+// @param symbols 为空时获取所有币种
+func GetSymbolsFrom(exchange binance.FuturesExchange, symbols ...string) (coins []*models.Symbols, err error) {
+	if exchange == nil {
+		if len(symbols) == 0 {
+			return GetAllSymbols()
+		}
+		_, err = orm.NewOrm().QueryTable("symbols").Filter("symbol__in", symbols).OrderBy("ID").All(&coins)
+		return coins, err
+	}
+	if len(symbols) == 0 {
+		info, err := exchange.GetExchangeInfo()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range info.Symbols {
+			symbols = append(symbols, item.Symbol)
+		}
+	}
+	for _, symbol := range symbols {
+		klines, err := binance.GetKlineDataFrom(exchange, symbol, "1h", 24)
+		if err != nil || len(klines) == 0 {
+			continue
+		}
+		close, _ := strconv.ParseFloat(klines[0].Close, 64)
+		open, _ := strconv.ParseFloat(klines[len(klines) - 1].Open, 64)
+		high, low := 0.0, math.MaxFloat64
+		for _, kline := range klines {
+			klineHigh, _ := strconv.ParseFloat(kline.High, 64)
+			klineLow, _ := strconv.ParseFloat(kline.Low, 64)
+			high = math.Max(high, klineHigh)
+			low = math.Min(low, klineLow)
+		}
+		percentChange := 0.0
+		if open != 0 {
+			percentChange = (close - open) / open * 100
+		}
+		coins = append(coins, &models.Symbols{
+			Symbol: symbol,
+			Close: klines[0].Close,
+			Open: klines[len(klines) - 1].Open,
+			High: strconv.FormatFloat(high, 'f', -1, 64),
+			Low: strconv.FormatFloat(low, 'f', -1, 64),
+			PercentChange: percentChange,
+			Enable: 1,
+		})
+	}
+	return coins, nil
+}
+
 // 判断 btc 的涨跌是否大于 5%，判断 当前所有币种涨跌数量是否 80%，是否是一个单项行情
-func BaseCheckCanLongOrShort() (canLong bool, canShort bool) {
-	coins, err := GetAllSymbols()
-	if err != nil {
+func BaseCheckCanLongOrShort(exchange binance.FuturesExchange) (canLong bool, canShort bool) {
+	coins, err := GetSymbolsFrom(exchange)
+	if err != nil || len(coins) == 0 {
 		return false, false
 	}
 	canLong, canShort = true, true
@@ -188,23 +251,8 @@ func BaseCheckCanLongOrShort() (canLong bool, canShort bool) {
 	return canLong, canShort
 }
 
-func BaseTrend() float64 {
-	o := orm.NewOrm()
-	var symbols []models.Symbols
-	sql := "SELECT * FROM symbols WHERE symbol = ? OR symbol = ? OR symbol = ? OR symbol = ?"
-	o.Raw(sql, "BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT").QueryRows(&symbols)
-	
-	basicTrend := 0.0
-	for _, v := range symbols {
-		if v.Symbol == "BTCUSDT" {
-			basicTrend += v.PercentChange * 0.6
-		} else if v.Symbol == "ETHUSDT" {
-			basicTrend += v.PercentChange * 0.3
-		} else if v.Symbol == "SOLUSDT" {
-			basicTrend += v.PercentChange * 0.05
-		} else if v.Symbol == "BNBUSDT" {
-			basicTrend += v.PercentChange * 0.05
-		}
-	}
-	return basicTrend
+func BaseTrend(exchange binance.FuturesExchange) float64 {
+	basket := GetBenchmarkBasket()
+	symbols, _ := GetSymbolsFrom(exchange, benchmarkSymbols(basket)...)
+	return CalculateBasicTrend(basket, symbols)
 }
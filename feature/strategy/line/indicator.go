@@ -0,0 +1,232 @@
+package line
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// 技术指标, 新增指标只需要实现该接口并调用 RegisterIndicator 注册
+type Indicator interface {
+	Type() string // 指标类型, 对应技术指标配置中的 type
+	Description() string // 指标说明
+	Params() []IndicatorParam // 参数和默认值
+	Compute(kline KLinePrice, params IndicatorParams) (ConfigData, error) // 根据k线计算指标, 数据时间由新到旧
+}
+
+// 指标参数
+type IndicatorParam struct {
+	Name        string  `json:"name"` // 参数名称
+	Type        string  `json:"type"` // int, float
+	Default     float64 `json:"default"` // 默认值
+	Description string  `json:"description"` // 参数说明
+}
+
+// 指标参数值, 计算前已经填充了默认值
+type IndicatorParams map[string]float64
+
+func (p IndicatorParams) Int(name string) int {
+	return int(p[name])
+}
+
+func (p IndicatorParams) Float(name string) float64 {
+	return p[name]
+}
+
+// 指标的描述信息, 用于前端展示
+type IndicatorOption struct {
+	Type        string           `json:"type"`
+	Description string           `json:"description"`
+	Params      []IndicatorParam `json:"params"`
+}
+
+var indicatorLock sync.RWMutex
+var indicators = map[string]Indicator{}
+var indicatorTypes = []string{} // 注册顺序
+
+// 注册技术指标, 类型相同时覆盖
+func RegisterIndicator(indicator Indicator) {
+	indicatorLock.Lock()
+	defer indicatorLock.Unlock()
+	if _, ok := indicators[indicator.Type()]; !ok {
+		indicatorTypes = append(indicatorTypes, indicator.Type())
+	}
+	indicators[indicator.Type()] = indicator
+}
+
+func GetIndicator(indicatorType string) (Indicator, bool) {
+	indicatorLock.RLock()
+	defer indicatorLock.RUnlock()
+	indicator, ok := indicators[indicatorType]
+	return indicator, ok
+}
+
+// 所有已注册的指标和参数
+func GetIndicatorOptions() []IndicatorOption {
+	indicatorLock.RLock()
+	defer indicatorLock.RUnlock()
+	options := make([]IndicatorOption, 0, len(indicatorTypes))
+	for _, indicatorType := range indicatorTypes {
+		indicator := indicators[indicatorType]
+		options = append(options, IndicatorOption{
+			Type: indicator.Type(),
+			Description: indicator.Description(),
+			Params: indicator.Params(),
+		})
+	}
+	return options
+}
+
+// 计算指标, 没有填写的参数使用默认值(填写 0 时使用 0), int 类型的参数取整
+func ComputeIndicator(indicator Indicator, kline KLinePrice, params map[string]float64) (ConfigData, error) {
+	values := IndicatorParams{}
+	for _, param := range indicator.Params() {
+		value, ok := params[param.Name]
+		if !ok {
+			value = param.Default
+		}
+		if param.Type == "int" {
+			value = math.Round(value)
+		}
+		values[param.Name] = value
+	}
+	data, err := indicator.Compute(kline, values)
+	if err != nil {
+		return data, fmt.Errorf("%s: %s", indicator.Type(), err.Error())
+	}
+	data.Params = values
+	return data, nil
+}
+
+// 通过函数定义指标
+type funcIndicator struct {
+	indicatorType string
+	description string
+	params []IndicatorParam
+	compute func(kline KLinePrice, params IndicatorParams) (ConfigData, error)
+}
+
+func (f *funcIndicator) Type() string { return f.indicatorType }
+func (f *funcIndicator) Description() string { return f.description }
+func (f *funcIndicator) Params() []IndicatorParam { return f.params }
+func (f *funcIndicator) Compute(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+	return f.compute(kline, params)
+}
+
+func NewIndicator(indicatorType string, description string, params []IndicatorParam, compute func(kline KLinePrice, params IndicatorParams) (ConfigData, error)) Indicator {
+	return &funcIndicator{
+		indicatorType: indicatorType,
+		description: description,
+		params: params,
+		compute: compute,
+	}
+}
+
+func intParam(name string, defaultValue float64, description string) IndicatorParam {
+	return IndicatorParam{Name: name, Type: "int", Default: defaultValue, Description: description}
+}
+
+func floatParam(name string, defaultValue float64, description string) IndicatorParam {
+	return IndicatorParam{Name: name, Type: "float", Default: defaultValue, Description: description}
+}
+
+// 内置指标, 输出的字段见 STRATEGY.md
+func init() {
+	RegisterIndicator(NewIndicator("ma", "简单移动平均线", []IndicatorParam{
+		intParam("period", 14, "周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		data, err := CalculateSimpleMovingAverage(kline.Close, params.Int("period"))
+		return ConfigData{Period: params.Int("period"), Data: data}, err
+	}))
+	RegisterIndicator(NewIndicator("ema", "指数移动平均线", []IndicatorParam{
+		intParam("period", 14, "周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		data, err := CalculateExponentialMovingAverage(kline.Close, params.Int("period"))
+		return ConfigData{Period: params.Int("period"), Data: data}, err
+	}))
+	RegisterIndicator(NewIndicator("rsi", "相对强弱指数", []IndicatorParam{
+		intParam("period", 14, "周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		data, err := CalculateRSI(kline.Close, params.Int("period"))
+		return ConfigData{Period: params.Int("period"), Data: data}, err
+	}))
+	RegisterIndicator(NewIndicator("kc", "肯纳特通道", []IndicatorParam{
+		intParam("period", 20, "周期"),
+		floatParam("multiplier", 2, "ATR 倍数"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		if len(kline.Close) < params.Int("period") {
+			return ConfigData{}, fmt.Errorf("insufficient data for period %d", params.Int("period"))
+		}
+		high, mid, low := CalculateKeltnerChannels(kline.High, kline.Low, kline.Close, params.Int("period"), params.Float("multiplier"))
+		return ConfigData{Period: params.Int("period"), Multiplier: params.Float("multiplier"), High: high, Mid: mid, Low: low}, nil
+	}))
+	RegisterIndicator(NewIndicator("boll", "布林带", []IndicatorParam{
+		intParam("period", 21, "周期"),
+		floatParam("std_dev_multiplier", 2, "标准差倍数"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		up, mid, dn, err := CalculateBollingerBands(kline.Close, params.Int("period"), params.Float("std_dev_multiplier"))
+		return ConfigData{Period: params.Int("period"), StdDevMultiplier: params.Float("std_dev_multiplier"), High: up, Mid: mid, Low: dn}, err
+	}))
+	RegisterIndicator(NewIndicator("atr", "平均真实波幅", []IndicatorParam{
+		intParam("period", 14, "周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		data, err := CalculateAtr(kline.High, kline.Low, kline.Close, params.Int("period"))
+		return ConfigData{Period: params.Int("period"), Data: data}, err
+	}))
+	RegisterIndicator(NewIndicator("macd", "指数平滑异同移动平均线, Data: DIF, Mid: DEA, High: 柱", []IndicatorParam{
+		intParam("fast_period", 12, "快线周期"),
+		intParam("slow_period", 26, "慢线周期"),
+		intParam("signal_period", 9, "信号线周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		dif, dea, hist, err := CalculateMacd(kline.Close, params.Int("fast_period"), params.Int("slow_period"), params.Int("signal_period"))
+		return ConfigData{Period: params.Int("slow_period"), Data: dif, Mid: dea, High: hist}, err
+	}))
+	RegisterIndicator(NewIndicator("kdj", "随机指标, Data: K, Mid: D, High: J", []IndicatorParam{
+		intParam("period", 9, "周期"),
+		intParam("k_period", 3, "K 平滑周期"),
+		intParam("d_period", 3, "D 平滑周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		k, d, j, err := CalculateKdj(kline.High, kline.Low, kline.Close, params.Int("period"), params.Int("k_period"), params.Int("d_period"))
+		return ConfigData{Period: params.Int("period"), Data: k, Mid: d, High: j}, err
+	}))
+	RegisterIndicator(NewIndicator("stoch_rsi", "随机相对强弱指数, Data: K, Mid: D", []IndicatorParam{
+		intParam("period", 14, "rsi 周期"),
+		intParam("stoch_period", 14, "随机指标周期"),
+		intParam("k_period", 3, "K 平滑周期"),
+		intParam("d_period", 3, "D 平滑周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		k, d, err := CalculateStochRsi(kline.Close, params.Int("period"), params.Int("stoch_period"), params.Int("k_period"), params.Int("d_period"))
+		return ConfigData{Period: params.Int("period"), Data: k, Mid: d}, err
+	}))
+	RegisterIndicator(NewIndicator("adx", "平均趋向指数, Data: ADX, High: +DI, Low: -DI", []IndicatorParam{
+		intParam("period", 14, "周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		adx, plusDi, minusDi, err := CalculateAdx(kline.High, kline.Low, kline.Close, params.Int("period"))
+		return ConfigData{Period: params.Int("period"), Data: adx, High: plusDi, Low: minusDi}, err
+	}))
+	RegisterIndicator(NewIndicator("super_trend", "超级趋势, Data: 趋势线, High: 上轨, Low: 下轨, Mid: 方向(1 上涨, -1 下跌)", []IndicatorParam{
+		intParam("period", 10, "ATR 周期"),
+		floatParam("multiplier", 3, "ATR 倍数"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		trend, upper, lower, direction, err := CalculateSuperTrend(kline.High, kline.Low, kline.Close, params.Int("period"), params.Float("multiplier"))
+		return ConfigData{Period: params.Int("period"), Multiplier: params.Float("multiplier"), Data: trend, High: upper, Low: lower, Mid: direction}, err
+	}))
+	RegisterIndicator(NewIndicator("vwap", "成交量加权平均价, 按 UTC 日重新累计", []IndicatorParam{},
+	func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		data, err := CalculateVwap(kline.High, kline.Low, kline.Close, kline.Volume, kline.OpenTime)
+		return ConfigData{Data: data}, err
+	}))
+	RegisterIndicator(NewIndicator("obv", "能量潮", []IndicatorParam{},
+	func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		data, err := CalculateObv(kline.Close, kline.Volume)
+		return ConfigData{Data: data}, err
+	}))
+	RegisterIndicator(NewIndicator("ichimoku", "一目均衡表, Data: 转换线, Mid: 基准线, High: 先行带A, Low: 先行带B", []IndicatorParam{
+		intParam("conversion_period", 9, "转换线周期"),
+		intParam("base_period", 26, "基准线周期和云层位移"),
+		intParam("span_period", 52, "先行带B周期"),
+	}, func(kline KLinePrice, params IndicatorParams) (ConfigData, error) {
+		conversion, base, spanA, spanB, err := CalculateIchimoku(kline.High, kline.Low, params.Int("conversion_period"), params.Int("base_period"), params.Int("span_period"))
+		return ConfigData{Period: params.Int("base_period"), Data: conversion, Mid: base, High: spanA, Low: spanB}, err
+	}))
+}
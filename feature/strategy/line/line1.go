@@ -21,7 +21,7 @@ func (tradeLine1 TradeLine1) GetCanLongOrShort(openParams strategy.OpenParams) (
 	openResult.CanLong = false
 	openResult.CanShort = false
 	
-	kline_3m, err := binance.GetKlineData(symbols.Symbol, "3m", 50)
+	kline_3m, err := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "3m", 50)
 	if err != nil {
 		return openResult
 	}
@@ -41,7 +41,7 @@ func (tradeLine1 TradeLine1) CanOrderComplete(closeParams strategy.CloseParams)
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "1m", 2) // 1min 线最近2条
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "1m", 2) // 1min 线最近2条
 	if err != nil {
 		return closeResult
 	}
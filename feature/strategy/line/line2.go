@@ -22,7 +22,7 @@ func (TradeLine2 TradeLine2) GetCanLongOrShort(openParams strategy.OpenParams) (
 	openResult.CanLong = false
 	openResult.CanShort = false
 	
-	kline_6h, err1 := binance.GetKlineData(symbols.Symbol, "6h", 50)
+	kline_6h, err1 := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "6h", 50)
 	if err1 != nil {
 		return openResult
 	}
@@ -55,7 +55,7 @@ func (TradeLine2 TradeLine2) CanOrderComplete(closeParams strategy.CloseParams)
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "1m", 2) // 1min 线最近2条
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "1m", 2) // 1min 线最近2条
 	if err != nil {
 		closeResult.Complete = true
 		return closeResult
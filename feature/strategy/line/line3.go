@@ -29,7 +29,7 @@ func (TradeLine3 TradeLine3) GetCanLongOrShort(openParams strategy.OpenParams) (
 	ema_period1 := 3
 	ema_period2 := 7
 	
-	kline_1, err := binance.GetKlineData(symbols.Symbol, kline_interval1, limit)
+	kline_1, err := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, kline_interval1, limit)
 	if err != nil {
 		return openResult
 	}
@@ -42,7 +42,7 @@ func (TradeLine3 TradeLine3) GetCanLongOrShort(openParams strategy.OpenParams) (
 	
 	rsi1, _ := CalculateRSI(close1, rsi_period1) // 获取 rsi
 	
-	baseTrend := BaseTrend() // 基础趋势涨跌幅
+	baseTrend := BaseTrend(openParams.Exchange) // 基础趋势涨跌幅
 	
 	if ((Kdj(ema1, ema2, 3) && rsi1[0] > 40) || (lineData.Line[0].Position == "LONG" && rsi1[0] < 18)) &&
 		baseTrend > -3 && // 基础趋势不是大幅下跌
@@ -69,7 +69,7 @@ func (TradeLine3 TradeLine3) CanOrderComplete(closeParams strategy.CloseParams)
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "5m", 2)
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "5m", 2)
 	if err != nil {
 		closeResult.Complete = true
 		return closeResult
@@ -96,12 +96,12 @@ func (TradeLine3 TradeLine3) AutoStopOrder(closeParams strategy.CloseParams) (cl
 		closeResult.Complete = false
 		return closeResult
 	}
-	closeResult.Complete = TradeLine3.MarketReversal(position.Symbol, position.Side)
+	closeResult.Complete = TradeLine3.MarketReversal(closeParams.Exchange, position.Symbol, position.Side)
 	return closeResult
 }
 
-func (TradeLine3 TradeLine3) MarketReversal(symbol string, positionSide string) (isReversal bool) {
-	kline_1d, err1 := binance.GetKlineData(symbol, "1d", 50)
+func (TradeLine3 TradeLine3) MarketReversal(exchange binance.FuturesExchange, symbol string, positionSide string) (isReversal bool) {
+	kline_1d, err1 := binance.GetKlineDataFrom(exchange, symbol, "1d", 50)
 	if err1 != nil {
 		return false
 	}
@@ -25,8 +25,8 @@ func (TradeLine4 TradeLine4) GetCanLongOrShort(openParams strategy.OpenParams) (
 	openResult.CanLong = false
 	openResult.CanShort = false
 	
-	kline_6h, err1 := binance.GetKlineData(symbols.Symbol, "6h", 50)
-	kline_1h, err2 := binance.GetKlineData(symbols.Symbol, "2h", 24)
+	kline_6h, err1 := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "6h", 50)
+	kline_1h, err2 := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "2h", 24)
 	if err1 != nil || err2 != nil {
 		return openResult
 	}
@@ -40,7 +40,7 @@ func (TradeLine4 TradeLine4) GetCanLongOrShort(openParams strategy.OpenParams) (
 		// 开盘小于 4.5 天
 		return openResult
 	}
-	baseCanLong, baseCanShort := BaseCheckCanLongOrShort() // 基本盘
+	baseCanLong, baseCanShort := BaseCheckCanLongOrShort(openParams.Exchange) // 基本盘
 	isRsi := rsi6[0] < 80 && rsi6[0] > 30 && rsi14[0] < 75 && rsi14[0] > 28
 	// logs.Info(symbol, Kdj(ma6h_3, ma6h_7, 4), Kdj(ma6h_7, ma6h_3, 4), rsi6[1], rsi14[1])
 	if Kdj(ma6h_3, ma6h_7, 4) && TradeLine4.checkLongLine(kline_1h) && isRsi && baseCanLong{ // 1天之内发生过金叉, rsi 没有超买
@@ -62,7 +62,7 @@ func (TradeLine4 TradeLine4) CanOrderComplete(closeParams strategy.CloseParams)
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "5m", 2)
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "5m", 2)
 	if err != nil {
 		closeResult.Complete = true
 		return closeResult
@@ -89,12 +89,12 @@ func (TradeLine4 TradeLine4) AutoStopOrder(closeParams strategy.CloseParams) (cl
 		closeResult.Complete = false
 		return closeResult
 	}
-	closeResult.Complete = TradeLine4.MarketReversal(position.Symbol, position.Side)
+	closeResult.Complete = TradeLine4.MarketReversal(closeParams.Exchange, position.Symbol, position.Side)
 	return closeResult
 }
 
-func (TradeLine4 TradeLine4) MarketReversal(symbol string, positionSide string) (isReversal bool) {
-	kline_1d, err1 := binance.GetKlineData(symbol, "1d", 50)
+func (TradeLine4 TradeLine4) MarketReversal(exchange binance.FuturesExchange, symbol string, positionSide string) (isReversal bool) {
+	kline_1d, err1 := binance.GetKlineDataFrom(exchange, symbol, "1d", 50)
 	if err1 != nil {
 		return false
 	}
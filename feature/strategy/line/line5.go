@@ -22,7 +22,7 @@ func (TradeLine5 TradeLine5) GetCanLongOrShort(openParams strategy.OpenParams) (
 	openResult.CanLong = false
 	openResult.CanShort = false
 	
-	kline_1, err1 := binance.GetKlineData(symbols.Symbol, "1m", 30)
+	kline_1, err1 := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "1m", 30)
 	if err1 != nil {
 		return openResult
 	}
@@ -53,7 +53,7 @@ func (TradeLine5 TradeLine5) CanOrderComplete(closeParams strategy.CloseParams)
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "5m", 2)
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "5m", 2)
 	if err != nil {
 		closeResult.Complete = true
 		return closeResult
@@ -80,12 +80,12 @@ func (TradeLine5 TradeLine5) AutoStopOrder(closeParams strategy.CloseParams) (cl
 		closeResult.Complete = false
 		return closeResult
 	}
-	closeResult.Complete = TradeLine5.MarketReversal(position.Symbol, position.Side)
+	closeResult.Complete = TradeLine5.MarketReversal(closeParams.Exchange, position.Symbol, position.Side)
 	return closeResult
 }
 
-func (TradeLine5 TradeLine5) MarketReversal(symbol string, positionSide string) (isReversal bool) {
-	kline_1d, err1 := binance.GetKlineData(symbol, "1d", 50)
+func (TradeLine5 TradeLine5) MarketReversal(exchange binance.FuturesExchange, symbol string, positionSide string) (isReversal bool) {
+	kline_1d, err1 := binance.GetKlineDataFrom(exchange, symbol, "1d", 50)
 	if err1 != nil {
 		return false
 	}
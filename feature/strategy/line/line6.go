@@ -20,7 +20,7 @@ func (TradeLine6 TradeLine6) GetCanLongOrShort(openParams strategy.OpenParams) (
 	openResult.CanLong = false
 	openResult.CanShort = false
 	
-	kline_1, err1 := binance.GetKlineData(symbols.Symbol, "3m", 20)
+	kline_1, err1 := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "3m", 20)
 	if err1 != nil {
 		return openResult
 	}
@@ -47,7 +47,7 @@ func (TradeLine6 TradeLine6) CanOrderComplete(closeParams strategy.CloseParams)
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "3m", 2)
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "3m", 2)
 	if err != nil {
 		closeResult.Complete = true
 		return closeResult
@@ -74,12 +74,12 @@ func (TradeLine6 TradeLine6) AutoStopOrder(closeParams strategy.CloseParams) (cl
 		closeResult.Complete = false
 		return closeResult
 	}
-	closeResult.Complete = TradeLine6.MarketReversal(position.Symbol, position.Side)
+	closeResult.Complete = TradeLine6.MarketReversal(closeParams.Exchange, position.Symbol, position.Side)
 	return closeResult
 }
 
-func (TradeLine6 TradeLine6) MarketReversal(symbol string, positionSide string) (isReversal bool) {
-	kline_1d, err1 := binance.GetKlineData(symbol, "1d", 50)
+func (TradeLine6 TradeLine6) MarketReversal(exchange binance.FuturesExchange, symbol string, positionSide string) (isReversal bool) {
+	kline_1d, err1 := binance.GetKlineDataFrom(exchange, symbol, "1d", 50)
 	if err1 != nil {
 		return false
 	}
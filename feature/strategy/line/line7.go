@@ -25,11 +25,11 @@ func (TradeLine TradeLine7) GetCanLongOrShort(openParams strategy.OpenParams) (o
 	period := 50 
 	multiplier1 := 2.75 // 窄通道
 	multiplier2 := 3.75 // 宽通道
-	kline_1, err := binance.GetKlineData(symbols.Symbol, "4h", limit)
+	kline_1, err := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "4h", limit)
 	if err != nil {
 		return openResult
 	}
-	kline_2, err := binance.GetKlineData(symbols.Symbol, "12h", limit)
+	kline_2, err := binance.GetKlineDataFrom(openParams.Exchange, symbols.Symbol, "12h", limit)
 	if err != nil {
 		return openResult
 	}
@@ -87,7 +87,7 @@ func (TradeLine TradeLine7) CanOrderComplete(closeParams strategy.CloseParams) (
 	position := closeParams.Position // 当前仓位
 	closeResult.Complete = false
 	
-	lines, err := binance.GetKlineData(symbols.Symbol, "3m", 2)
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, symbols.Symbol, "3m", 2)
 	if err != nil {
 		closeResult.Complete = true
 		return closeResult
@@ -114,11 +114,11 @@ func (TradeLine TradeLine7) AutoStopOrder(closeParams strategy.CloseParams) (clo
 		closeResult.Complete = false
 		return closeResult
 	}
-	closeResult.Complete = TradeLine.MarketReversal(position.Symbol, position.Side)
+	closeResult.Complete = TradeLine.MarketReversal(closeParams.Exchange, position.Symbol, position.Side)
 	return closeResult
 }
 
-func (TradeLine TradeLine7) MarketReversal(symbol string, positionSide string) (isReversal bool) {
+func (TradeLine TradeLine7) MarketReversal(exchange binance.FuturesExchange, symbol string, positionSide string) (isReversal bool) {
 	// kline_1d, err1 := binance.GetKlineData(symbol, "1d", 50)
 	// if err1 != nil {
 	// 	return false
@@ -28,10 +28,10 @@ func (TradeLine TradeLineCustom) GetCanLongOrShort(openParams strategy.OpenParam
 		logs.Error("Error unmarshalling JSON:", err.Error())
 		return openResult
 	}
-	env := InitParseEnv(coin.Symbol, coin.Technology)
+	env := InitParseEnv(coin.Symbol, coin.Technology, openParams.Exchange)
 	for _, strategy := range strategyConfig {
 		if strategy.Enable && (strategy.Type == "long" || strategy.Type == "short") {
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := GetProgram(coin.Symbol, coin.Technology, strategy.Type, strategy.Code, env)
 			if err != nil {
 				logs.Error("Error Strategy Compile Symbol: ", coin.Symbol)
 				logs.Error("Error Strategy Compile:", err.Error())
@@ -70,8 +70,21 @@ func (TradeLine TradeLineCustom) CanOrderComplete(closeParams strategy.ClosePara
 		return closeResult
 	}
 	findStrategy := false
-	env := InitParseEnv(coin.Symbol, coin.Technology)
+	env := InitParseEnv(coin.Symbol, coin.Technology, closeParams.Exchange)
 	env["ROI"] = closeParams.NowProfit // 当前收益率
+	env["PeakROI"] = closeParams.PeakProfit // 持仓期间的最高收益率
+	env["PeakPrice"] = closeParams.PeakPrice // 最高收益率时的价格
+	env["Stats"] = closeParams.Stats // 持仓期间的统计
+	var state strategy.PositionState = closeParams.State
+	if state == nil {
+		if closeParams.Exchange == nil {
+			state = GetPositionState(coin.Symbol, position.Side)
+		} else {
+			state = NewMemoryPositionState(coin.Symbol, position.Side, nil)
+		}
+	}
+	env["Get"] = state.Get // 读取持仓状态
+	env["Set"] = state.Set // 保存持仓状态
 	env["Position"] = types.FuturesPositionCode{
 		Symbol: coin.Symbol,
 		Side: position.Side,
@@ -123,7 +136,7 @@ func (TradeLine TradeLineCustom) CanOrderComplete(closeParams strategy.ClosePara
 				continue
 			}
 			
-			program, err := expr.Compile(strategy.Code, expr.Env(env))
+			program, err := GetProgram(coin.Symbol, coin.Technology, strategy.Type, strategy.Code, env)
 			if err != nil {
 				logs.Error("Error Strategy Compile Symbol: ", coin.Symbol)
 				logs.Error("Error Strategy Compile:", err.Error())
@@ -165,7 +178,7 @@ func (TradeLine TradeLineCustom) simpleCloseStrategy(closeParams strategy.CloseP
 		return closeResult
 	}
 	
-	lines, err := binance.GetKlineData(coin.Symbol, "5m", 2)
+	lines, err := binance.GetKlineDataFrom(closeParams.Exchange, coin.Symbol, "5m", 2)
 	if err != nil {
 		logs.Error("Error GetKlineData Symbol in line_custom: ", coin.Symbol)
 		logs.Error("Error GetKlineData Symbol in line_custom:", err.Error())
@@ -3,13 +3,11 @@ package line
 import (
 	"encoding/json"
 	"go_binance_futures/feature/api/binance"
-	"go_binance_futures/models"
 	"go_binance_futures/technology"
 	"go_binance_futures/utils"
 	"strconv"
-	"time"
+	"strings"
 
-	"github.com/beego/beego/v2/client/orm"
 	"github.com/beego/beego/v2/core/config"
 	"github.com/beego/beego/v2/core/logs"
 )
@@ -23,6 +21,7 @@ type ConfigData struct {
 	High []float64 `json:"high,omitempty"` // 可选字段
 	Low []float64 `json:"low,omitempty"` // 可选字段
 	Mid []float64 `json:"mid,omitempty"` // 可选字段
+	Params map[string]float64 `json:"params,omitempty"` // 计算时使用的参数(包括默认值)
 }
 
 type KLinePrice struct {
@@ -32,236 +31,123 @@ type KLinePrice struct {
 	Open []float64 `json:"open"` // 开盘价
 	Amount []float64 `json:"amount"` // 成交额(成交量 * 平均价格)
 	Qps []float64 `json:"qps"` // 单位时间成交额
+	Volume []float64 `json:"volume"` // 成交量
+	OpenTime []int64 `json:"open_time"` // 开盘时间
 }
 
-func ParseTechnologyConfig(symbol string, strTechnology string) (config map[string]interface{}, klineMap map[string]KLinePrice) {
-	var (
-		technologyConfig technology.TechnologyConfig
-	)
+// @param exchanges 可选, 行情来源(回测时为回放的交易所)
+func ParseTechnologyConfig(symbol string, strTechnology string, exchanges ...binance.FuturesExchange) (config map[string]interface{}, klineMap map[string]KLinePrice) {
 	config = make(map[string]interface{})
 	klineMap = make(map[string]KLinePrice)
-	err := json.Unmarshal([]byte(strTechnology), &technologyConfig)
+	indicatorConfigs, err := ParseIndicatorConfigs(strTechnology)
 	if err != nil {
 		logs.Error("Error unmarshalling JSON:", err.Error())
 		return config, klineMap
 	}
 	
 	limit := 150
-	for _, item := range technologyConfig.MA {
-		if item.Enable {
-			klinePrice, ok := klineMap[item.KlineInterval]
-			if !ok {
-				kline, err := binance.GetKlineData(symbol, item.KlineInterval, limit)
-				if err != nil {
-					logs.Error("kline error, symbol:", symbol)
-					logs.Error("kline error in ParseTechnologyConfig:", err.Error())
-					continue
-				}
-				high, low, close, open, amount, qps := GetLineFloatValues(kline)
-				klinePrice = KLinePrice{
-					High: high,
-					Low: low,
-					Close: close,
-					Open: open,
-					Amount: amount,
-					Qps: qps,
-				}
-				klineMap[item.KlineInterval] = klinePrice
-			}
-			maArr, err := CalculateSimpleMovingAverage(klinePrice.Close, item.Period)
-			if err != nil {
-				logs.Error("CalculateSimpleMovingAverage error:", err.Error())
-				continue
-			}
-			config[item.Name] = ConfigData{
-				KlineInterval: item.KlineInterval,
-				Period: item.Period,
-				Data: maArr,
-			}
+	exchange := getExchange(exchanges)
+	for _, item := range indicatorConfigs {
+		if !item.IsEnable() {
+			continue
 		}
-	}
-	for _, item := range technologyConfig.EMA {
-		if item.Enable {
-			klinePrice, ok := klineMap[item.KlineInterval]
-			if !ok {
-				kline, err := binance.GetKlineData(symbol, item.KlineInterval, limit)
-				if err != nil {
-					logs.Error("kline error, symbol:", symbol)
-					logs.Error("kline error in ParseTechnologyConfig:", err.Error())
-					continue
-				}
-				high, low, close, open, amount, qps := GetLineFloatValues(kline)
-				klinePrice = KLinePrice{
-					High: high,
-					Low: low,
-					Close: close,
-					Open: open,
-					Amount: amount,
-					Qps: qps,
-				}
-				klineMap[item.KlineInterval] = klinePrice
-			}
-			
-			emaArr, err := CalculateExponentialMovingAverage(klinePrice.Close, item.Period)
-			if err != nil {
-				logs.Error("CalculateExponentialMovingAverage error:", err.Error())
-				continue
-			}
-			config[item.Name] = ConfigData{
-				KlineInterval: item.KlineInterval,
-				Period: item.Period,
-				Data: emaArr,
-			}
+		indicator, ok := GetIndicator(item.Type)
+		if !ok {
+			logs.Error("unknown indicator type:", item.Type, item.Name)
+			continue
 		}
-	}
-	for _, item := range technologyConfig.RSI {
-		if item.Enable {
-			klinePrice, ok := klineMap[item.KlineInterval]
-			if !ok {
-				kline, err := binance.GetKlineData(symbol, item.KlineInterval, limit)
-				if err != nil {
-					logs.Error("kline error, symbol:", symbol)
-					logs.Error("kline error in ParseTechnologyConfig:", err.Error())
-					continue
-				}
-				high, low, close, open, amount, qps := GetLineFloatValues(kline)
-				klinePrice = KLinePrice{
-					High: high,
-					Low: low,
-					Close: close,
-					Open: open,
-					Amount: amount,
-					Qps: qps,
-				}
-				klineMap[item.KlineInterval] = klinePrice
-			}
-			rsiArr, err := CalculateRSI(klinePrice.Close, item.Period)
+		// 同一个k线周期只请求一次
+		klinePrice, ok := klineMap[item.KlineInterval]
+		if !ok {
+			kline, err := binance.GetKlineDataFrom(exchange, symbol, item.KlineInterval, limit)
 			if err != nil {
-				logs.Error("CalculateRSI error:", err.Error())
+				logs.Error("kline error, symbol:", symbol)
+				logs.Error("kline error in ParseTechnologyConfig:", err.Error())
 				continue
 			}
-			config[item.Name] = ConfigData{
-				KlineInterval: item.KlineInterval,
-				Period: item.Period,
-				Data: rsiArr,
-			}
-		}
-	}
-	for _, item := range technologyConfig.KC {
-		if item.Enable {
-			klinePrice, ok := klineMap[item.KlineInterval]
-			if !ok {
-				kline, err := binance.GetKlineData(symbol, item.KlineInterval, limit)
-				if err != nil {
-					logs.Error("kline error, symbol:", symbol)
-					logs.Error("kline error in ParseTechnologyConfig:", err.Error())
-					continue
-				}
-				high, low, close, open, amount, qps := GetLineFloatValues(kline)
-				klinePrice = KLinePrice{
-					High: high,
-					Low: low,
-					Close: close,
-					Open: open,
-					Amount: amount,
-					Qps: qps,
-				}
-				klineMap[item.KlineInterval] = klinePrice
-			}
-			
-			high, mid, low := CalculateKeltnerChannels(klinePrice.High, klinePrice.Low, klinePrice.Close, item.Period, item.Multiplier)
-			
-			config[item.Name] = ConfigData{
-				KlineInterval: item.KlineInterval,
-				Period: item.Period,
-				Multiplier: item.Multiplier,
+			high, low, close, open, amount, qps := GetLineFloatValues(kline)
+			volume, openTime := GetLineVolumes(kline)
+			klinePrice = KLinePrice{
 				High: high,
 				Low: low,
-				Mid: mid,
-			}
+				Close: close,
+				Open: open,
+				Amount: amount,
+				Qps: qps,
+				Volume: volume,
+				OpenTime: openTime,
+			}
+			klineMap[item.KlineInterval] = klinePrice
 		}
+		data, err := ComputeIndicator(indicator, klinePrice, item.Params)
+		if err != nil {
+			logs.Error("ComputeIndicator error:", item.Name, err.Error())
+			continue
+		}
+		data.KlineInterval = item.KlineInterval
+		config[item.Name] = data
 	}
-	for _, item := range technologyConfig.BOLL {
-		if item.Enable {
-			klinePrice, ok := klineMap[item.KlineInterval]
-			if !ok {
-				kline, err := binance.GetKlineData(symbol, item.KlineInterval, limit)
-				if err != nil {
-					logs.Error("kline error, symbol:", symbol)
-					logs.Error("kline error in ParseTechnologyConfig:", err.Error())
-					continue
-				}
-				high, low, close, open, amount, qps := GetLineFloatValues(kline)
-				klinePrice = KLinePrice{
-					High: high,
-					Low: low,
-					Close: close,
-					Open: open,
-					Amount: amount,
-					Qps: qps,
-				}
-				klineMap[item.KlineInterval] = klinePrice
-			}
-			
-			up, mid, dn, err := CalculateBollingerBands(klinePrice.Close, item.Period, item.StdDevMultiplier)
-			if err != nil {
-				logs.Error("CalculateBollingerBands error:", err.Error())
-				continue
-			}
-			config[item.Name] = ConfigData{
-				KlineInterval: item.KlineInterval,
-				Period: item.Period,
-				Multiplier: item.Multiplier,
-				StdDevMultiplier: item.StdDevMultiplier,
-				High: up,
-				Mid: mid,
-				Low: dn,
-			}
+	
+	return config, klineMap
+}
+
+// 解析技术指标配置, 支持列表 [{type, name, kline_interval, params, enable}]
+// 和旧的格式 {"ma": [{name, kline_interval, period, enable}], ...}, 旧格式中除了 name, kline_interval, enable 以外的数值都作为参数
+func ParseIndicatorConfigs(strTechnology string) (configs []technology.IndicatorConfig, err error) {
+	strTechnology = strings.TrimSpace(strTechnology)
+	if strings.HasPrefix(strTechnology, "[") {
+		err = json.Unmarshal([]byte(strTechnology), &configs)
+		return configs, err
+	}
+	
+	var legacyConfig map[string][]map[string]interface{}
+	err = json.Unmarshal([]byte(strTechnology), &legacyConfig)
+	if err != nil {
+		return configs, err
+	}
+	// 按照指标的注册顺序, 保证结果稳定
+	indicatorTypes := []string{}
+	for _, option := range GetIndicatorOptions() {
+		indicatorTypes = append(indicatorTypes, option.Type)
+	}
+	for indicatorType := range legacyConfig {
+		if _, ok := GetIndicator(indicatorType); !ok {
+			indicatorTypes = append(indicatorTypes, indicatorType) // 未知的指标, 解析时会记录错误
 		}
 	}
-	for _, item := range technologyConfig.ATR {
-		if item.Enable {
-			klinePrice, ok := klineMap[item.KlineInterval]
-			if !ok {
-				kline, err := binance.GetKlineData(symbol, item.KlineInterval, limit)
-				if err != nil {
-					logs.Error("kline error, symbol:", symbol)
-					logs.Error("kline error in ParseTechnologyConfig:", err.Error())
-					continue
-				}
-				high, low, close, open, amount, qps := GetLineFloatValues(kline)
-				klinePrice = KLinePrice{
-					High: high,
-					Low: low,
-					Close: close,
-					Open: open,
-					Amount: amount,
-					Qps: qps,
+	for _, indicatorType := range indicatorTypes {
+		for _, item := range legacyConfig[indicatorType] {
+			config := technology.IndicatorConfig{
+				Type: indicatorType,
+				Params: map[string]float64{},
+			}
+			enable := false
+			for key, value := range item {
+				switch key {
+					case "name":
+						config.Name, _ = value.(string)
+					case "kline_interval":
+						config.KlineInterval, _ = value.(string)
+					case "enable":
+						enable, _ = value.(bool)
+					default:
+						if number, ok := value.(float64); ok {
+							config.Params[key] = number
+						}
 				}
-				klineMap[item.KlineInterval] = klinePrice
-			}
-			atrArr, err := CalculateAtr(klinePrice.High, klinePrice.Low, klinePrice.Close, item.Period)
-			if err != nil {
-				logs.Error("CalculateAtr error:", err.Error())
-				continue
-			}
-			config[item.Name] = ConfigData{
-				KlineInterval: item.KlineInterval,
-				Period: item.Period,
-				Data: atrArr,
 			}
+			config.Enable = &enable
+			configs = append(configs, config)
 		}
 	}
-	
-	return config, klineMap
+	return configs, nil
 }
 
-func InitParseEnv(symbol string, strTechnology string) (map[string]interface{}) {
-	o := orm.NewOrm()
-	var symbols []models.Symbols
-	
-	sql := "SELECT * FROM symbols WHERE symbol = ? OR symbol = ? OR symbol = ? OR symbol = ? OR symbol = ?"
-	_, err := o.Raw(sql, "BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", symbol).QueryRows(&symbols)
+// @param exchanges 可选, 行情来源(回测时为回放的交易所)
+func InitParseEnv(symbol string, strTechnology string, exchanges ...binance.FuturesExchange) (map[string]interface{}) {
+	exchange := getExchange(exchanges)
+	basket := GetBenchmarkBasket()
+	symbols, err := GetSymbolsFrom(exchange, envSymbols(basket, symbol)...)
 	if err != nil {
 		logs.Error("error", err.Error())
 	}
@@ -270,42 +156,32 @@ func InitParseEnv(symbol string, strTechnology string) (map[string]interface{})
 	// nowPrice, _ := strconv.ParseFloat(resPrice[0].Price, 64)
 	system_start_time_str, _ := config.String("system_start_time")
 	system_start_time_int, _ := strconv.ParseInt(system_start_time_str, 10, 64)
-	tConfig, klineMap := ParseTechnologyConfig(symbol, strTechnology)
+	tConfig, klineMap := ParseTechnologyConfig(symbol, strTechnology, exchange)
 	env := map[string]interface{} {
 		// build-in
 		// "NowPrice": nowPrice, // 当前价格
 		"SystemStartTime": system_start_time_int, // 系统启动时间, 毫秒时间戳
-		"NowTime": time.Now().Unix() * 1000, // 毫秒时间戳
+		"NowTime": binance.GetNowTime(exchange), // 毫秒时间戳
+		"PeakROI": 0.0, // 持仓期间的最高收益率(平仓策略中有效)
+		"PeakPrice": 0.0, // 最高收益率时的价格(平仓策略中有效)
 		
 		// function
 		"Kdj": Kdj, // 计算是否是金叉,
 		"IsAsc": utils.IsAsc, // 是否是升序数组
 		"IsDesc": utils.IsDesc, // 是否是降序数组,
-		"BasicTrend": 0.0, // 基础趋势涨跌幅 (btc * 0.6 + eth * 0.3 + sol * 0.05 + bnb * 0.05)
+		"BasicTrend": 0.0, // 基础趋势涨跌幅, 基准币种涨跌幅的加权和 (默认 btc * 0.6 + eth * 0.3 + sol * 0.05 + bnb * 0.05)
 	}
-	basicTrend := 0.0
+	resolver := newSymbolResolver(exchange)
+	env["Sym"] = resolver.Sym // 其它币种的行情, Sym("ETHUSDT").Close
+	env["SymValue"] = resolver.SymValue // 其它币种的k线和指标, Sym("ETHUSDT").ema_1h_20 编译时转换为 SymValue("ETHUSDT", "ema_1h_20")
+	env["Breadth"] = resolver.Breadth // 市场宽度, Breadth().Advancers
 	
 	for _, v := range symbols {
-		if v.Symbol == "BTCUSDT" {
-			basicTrend += v.PercentChange * 0.6
-		} else if v.Symbol == "ETHUSDT" {
-			basicTrend += v.PercentChange * 0.3
-		} else if v.Symbol == "SOLUSDT" {
-			basicTrend += v.PercentChange * 0.05
-		} else if v.Symbol == "BNBUSDT" {
-			basicTrend += v.PercentChange * 0.05
-		}
-		close, _ := strconv.ParseFloat(v.Close, 64)
-		open, _ := strconv.ParseFloat(v.Open, 64)
-		low, _ := strconv.ParseFloat(v.Low, 64)
-		high, _ := strconv.ParseFloat(v.High, 64)
-		item := map[string]interface{}{
-			"PercentChange": v.PercentChange,
-			"Close": close,
-			"Open": open,
-			"Low": low,
-			"High": high,
-		}
+		item := tickerValues(v)
+		close := item["Close"].(float64)
+		open := item["Open"].(float64)
+		low := item["Low"].(float64)
+		high := item["High"].(float64)
 		env[v.Symbol] = item
 		if (v.Symbol == symbol) {
 			env["NowPrice"] = close // 当前价格
@@ -316,7 +192,10 @@ func InitParseEnv(symbol string, strTechnology string) (map[string]interface{})
 			env["NowSymbolHigh"] = high
 		}
 	}
-	env["BasicTrend"] = basicTrend
+	env["BasicTrend"] = CalculateBasicTrend(basket, symbols)
+	for k, v := range marketPriceValues(symbol, exchange) {
+		env[k] = v
+	}
 	
 	// technology
 	for k, v := range tConfig {
@@ -330,4 +209,39 @@ func InitParseEnv(symbol string, strTechnology string) (map[string]interface{})
 	
 	// logs.Info(utils.ToJson(klineMap))
 	return env
+}
+
+// 行情 ws 推送的最新价格, 标记价格, 资金费率和最优挂单, 没有推送(或者回测)时为 0
+// 实盘时最新价格比数据库中定时写入的 Close 更新, 覆盖 NowPrice
+func marketPriceValues(symbol string, exchange binance.FuturesExchange) map[string]interface{} {
+	values := map[string]interface{} {
+		"MarkPrice": 0.0, // 标记价格
+		"IndexPrice": 0.0, // 指数价格
+		"FundingRate": 0.0, // 资金费率
+		"BidPrice": 0.0, // 买一价
+		"AskPrice": 0.0, // 卖一价
+	}
+	if exchange != nil {
+		return values
+	}
+	price, ok := binance.GetFuturesPrice(symbol)
+	if !ok {
+		return values
+	}
+	if close, err := strconv.ParseFloat(price.Close, 64); err == nil && close > 0 {
+		values["NowPrice"] = close
+	}
+	values["MarkPrice"], _ = strconv.ParseFloat(price.MarkPrice, 64)
+	values["IndexPrice"], _ = strconv.ParseFloat(price.IndexPrice, 64)
+	values["FundingRate"], _ = strconv.ParseFloat(price.FundingRate, 64)
+	values["BidPrice"], _ = strconv.ParseFloat(price.BidPrice, 64)
+	values["AskPrice"], _ = strconv.ParseFloat(price.AskPrice, 64)
+	return values
+}
+
+func getExchange(exchanges []binance.FuturesExchange) binance.FuturesExchange {
+	if len(exchanges) > 0 {
+		return exchanges[0]
+	}
+	return nil
 }
\ No newline at end of file
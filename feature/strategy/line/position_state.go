@@ -0,0 +1,183 @@
+package line
+
+import (
+	"encoding/json"
+	"go_binance_futures/models"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// 持仓状态的 key 和 value 长度限制
+const positionStateMaxKeyLength = 100
+const positionStateMaxValueLength = 1000
+
+var positionStates = map[string]*PositionState{} // symbol_side => 状态
+var positionStatesLoaded = false
+var positionStatesLock sync.Mutex
+
+// 自定义平仓策略的持仓状态, 值保存为 json, 数字读取后都是 float64
+type PositionState struct {
+	Symbol string
+	Side string
+	persist bool // 是否保存到数据库, 回测和调试时只保存在内存中
+	lock sync.Mutex
+	values map[string]interface{}
+	rows map[string]*models.FuturesPositionState
+}
+
+// 数据库保存的持仓状态
+func GetPositionState(symbol string, side string) *PositionState {
+	positionStatesLock.Lock()
+	defer positionStatesLock.Unlock()
+	loadPositionStates()
+	key := symbol + "_" + side
+	state, ok := positionStates[key]
+	if !ok {
+		state = newPositionState(symbol, side, true)
+		positionStates[key] = state
+	}
+	return state
+}
+
+// 只保存在内存中的持仓状态, values 为初始值
+func NewMemoryPositionState(symbol string, side string, values map[string]interface{}) *PositionState {
+	state := newPositionState(symbol, side, false)
+	for k, v := range values {
+		state.values[k] = v
+	}
+	return state
+}
+
+func newPositionState(symbol string, side string, persist bool) *PositionState {
+	return &PositionState{
+		Symbol: symbol,
+		Side: side,
+		persist: persist,
+		values: map[string]interface{}{},
+		rows: map[string]*models.FuturesPositionState{},
+	}
+}
+
+func (state *PositionState) Get(key string, defaults ...interface{}) interface{} {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if value, ok := state.values[key]; ok {
+		return value
+	}
+	if len(defaults) > 0 {
+		return defaults[0]
+	}
+	return nil
+}
+
+func (state *PositionState) Set(key string, value interface{}) bool {
+	if key == "" || len(key) > positionStateMaxKeyLength {
+		logs.Error("invalid position state key:", state.Symbol, key)
+		return false
+	}
+	// 转换为 json 再解析, 和从数据库读取的类型保持一致
+	data, err := json.Marshal(value)
+	if err != nil || len(data) > positionStateMaxValueLength {
+		logs.Error("invalid position state value:", state.Symbol, key, value)
+		return false
+	}
+	var normalized interface{}
+	json.Unmarshal(data, &normalized)
+
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	if old, ok := state.values[key]; ok && reflect.DeepEqual(old, normalized) {
+		return true
+	}
+	state.values[key] = normalized
+	if !state.persist {
+		return true
+	}
+	now := time.Now().UnixMilli()
+	row, ok := state.rows[key]
+	if !ok {
+		row = &models.FuturesPositionState{
+			Symbol: state.Symbol,
+			Side: state.Side,
+			StateKey: key,
+			Value: string(data),
+			CreateTime: now,
+			UpdateTime: now,
+		}
+		id, err := orm.NewOrm().Insert(row)
+		if err != nil {
+			logs.Error("insert position state error:", state.Symbol, key, err.Error())
+			return true
+		}
+		row.ID = id
+		state.rows[key] = row
+		return true
+	}
+	row.Value = string(data)
+	row.UpdateTime = now
+	_, err = orm.NewOrm().Update(row, "Value", "UpdateTime")
+	if err != nil {
+		logs.Error("update position state error:", state.Symbol, key, err.Error())
+	}
+	return true
+}
+
+// 所有状态的副本
+func (state *PositionState) Values() map[string]interface{} {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	values := map[string]interface{}{}
+	for k, v := range state.values {
+		values[k] = v
+	}
+	return values
+}
+
+// 删除已经平仓的持仓状态, exist 为 symbol_side => 是否持仓
+func CleanPositionStates(exist map[string]bool) {
+	positionStatesLock.Lock()
+	defer positionStatesLock.Unlock()
+	loadPositionStates()
+	for key, state := range positionStates {
+		if exist[key] {
+			continue
+		}
+		_, err := orm.NewOrm().QueryTable("futures_position_states").Filter("symbol", state.Symbol).Filter("side", state.Side).Delete()
+		if err != nil {
+			logs.Error("delete position state error:", state.Symbol, err.Error())
+			continue
+		}
+		delete(positionStates, key)
+	}
+}
+
+func loadPositionStates() {
+	if positionStatesLoaded {
+		return
+	}
+	var rows []*models.FuturesPositionState
+	_, err := orm.NewOrm().QueryTable("futures_position_states").All(&rows)
+	if err != nil {
+		logs.Error("load position states error:", err.Error())
+		return
+	}
+	for _, row := range rows {
+		key := row.Symbol + "_" + row.Side
+		state, ok := positionStates[key]
+		if !ok {
+			state = newPositionState(row.Symbol, row.Side, true)
+			positionStates[key] = state
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(row.Value), &value); err != nil {
+			continue
+		}
+		state.values[row.StateKey] = value
+		state.rows[row.StateKey] = row
+	}
+	positionStatesLoaded = true
+}
@@ -0,0 +1,161 @@
+package line
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go_binance_futures/technology"
+	"go_binance_futures/types"
+	"go_binance_futures/utils"
+	"strings"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+const programCacheMaxSize = 5000 // 超过后清空, 避免修改过的策略一直占用内存
+
+// 编译后的自定义策略, key 为 symbol + 策略哈希(技术指标配置 + 策略类型 + 代码)
+// 技术指标配置和策略类型(开仓/平仓)决定 env 的结构, 结构相同的 env 可以复用编译结果
+var programCache = map[string]*vm.Program{}
+var programCacheLock sync.RWMutex
+
+// 获取编译后的策略, 没有缓存时使用当前的 env 编译, 编译失败不缓存(指标的k线获取失败时 env 会缺少变量)
+func GetProgram(symbol string, strTechnology string, strategyType string, code string, env map[string]interface{}) (*vm.Program, error) {
+	key := symbol + "_" + strategyHash(strTechnology, strategyType, code)
+	programCacheLock.RLock()
+	program, ok := programCache[key]
+	programCacheLock.RUnlock()
+	if ok {
+		return program, nil
+	}
+	
+	program, err := expr.Compile(code, StrategyOptions(env)...)
+	if err != nil {
+		return nil, err
+	}
+	programCacheLock.Lock()
+	if len(programCache) >= programCacheMaxSize {
+		programCache = map[string]*vm.Program{}
+	}
+	programCache[key] = program
+	programCacheLock.Unlock()
+	return program, nil
+}
+
+// 修改币种、监听币种或者策略模板后清除缓存, symbol 为空时清除所有
+func InvalidateProgramCache(symbol string) {
+	programCacheLock.Lock()
+	defer programCacheLock.Unlock()
+	if symbol == "" {
+		programCache = map[string]*vm.Program{}
+		return
+	}
+	for key := range programCache {
+		if strings.HasPrefix(key, symbol + "_") {
+			delete(programCache, key)
+		}
+	}
+}
+
+func ProgramCacheSize() int {
+	programCacheLock.RLock()
+	defer programCacheLock.RUnlock()
+	return len(programCache)
+}
+
+func strategyHash(strTechnology string, strategyType string, code string) string {
+	hash := sha1.Sum([]byte(strTechnology + "\x00" + strategyType + "\x00" + code))
+	return hex.EncodeToString(hash[:])
+}
+
+// 保存时检查自定义策略, 使用和 InitParseEnv 结构相同的 env 编译所有启用的规则(不请求行情)
+func ValidateStrategy(symbol string, strTechnology string, strStrategy string) error {
+	var strategyConfig technology.StrategyConfig
+	err := json.Unmarshal([]byte(strStrategy), &strategyConfig)
+	if err != nil {
+		return fmt.Errorf("strategy json error: %s", err.Error())
+	}
+	indicatorConfigs, err := ParseIndicatorConfigs(strTechnology)
+	if err != nil {
+		return fmt.Errorf("technology json error: %s", err.Error())
+	}
+	for _, item := range indicatorConfigs {
+		if _, ok := GetIndicator(item.Type); !ok && item.IsEnable() {
+			return fmt.Errorf("technology %s: unknown indicator type %s", item.Name, item.Type)
+		}
+	}
+	
+	errs := []string{}
+	for _, strategy := range strategyConfig {
+		if !strategy.Enable {
+			continue
+		}
+		env := InitSchemaEnv(symbol, strTechnology, strategy.Type)
+		_, err := expr.Compile(strategy.Code, StrategyOptions(env)...)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("strategy %s(%s): %s", strategy.Name, strategy.Type, err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// 和 InitParseEnv 结构相同的 env, 值都为空, 平仓策略(close_long, close_short)额外有 ROI, Position, Stats 和 Get/Set
+func InitSchemaEnv(symbol string, strTechnology string, strategyType string) map[string]interface{} {
+	env := map[string]interface{} {
+		"SystemStartTime": int64(0),
+		"NowTime": int64(0),
+		"PeakROI": 0.0,
+		"PeakPrice": 0.0,
+		"Kdj": Kdj,
+		"IsAsc": utils.IsAsc,
+		"IsDesc": utils.IsDesc,
+		"BasicTrend": 0.0,
+		"NowPrice": 0.0,
+		"NowSymbolPercentChange": 0.0,
+		"NowSymbolClose": 0.0,
+		"NowSymbolOpen": 0.0,
+		"NowSymbolLow": 0.0,
+		"NowSymbolHigh": 0.0,
+		"MarkPrice": 0.0,
+		"IndexPrice": 0.0,
+		"FundingRate": 0.0,
+		"BidPrice": 0.0,
+		"AskPrice": 0.0,
+		"Sym": func(symbol string) map[string]interface{} { return nil },
+		"SymValue": func(symbol string, name string) interface{} { return nil },
+		"Breadth": func() map[string]interface{} { return nil },
+	}
+	for _, item := range envSymbols(GetBenchmarkBasket(), symbol) {
+		env[item] = map[string]interface{}{
+			"PercentChange": 0.0,
+			"Close": 0.0,
+			"Open": 0.0,
+			"Low": 0.0,
+			"High": 0.0,
+		}
+	}
+	indicatorConfigs, _ := ParseIndicatorConfigs(strTechnology)
+	for _, item := range indicatorConfigs {
+		if !item.IsEnable() {
+			continue
+		}
+		env[item.Name] = ConfigData{}
+		env["kline_" + item.KlineInterval] = KLinePrice{}
+	}
+	if strategyType == "close_long" || strategyType == "close_short" {
+		env["ROI"] = 0.0
+		env["Position"] = types.FuturesPositionCode{}
+		env["Stats"] = types.PositionStats{}
+		state := NewMemoryPositionState(symbol, "", nil)
+		env["Get"] = state.Get
+		env["Set"] = state.Set
+	}
+	return env
+}
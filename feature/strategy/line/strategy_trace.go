@@ -0,0 +1,128 @@
+package line
+
+import (
+	"reflect"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+)
+
+const traceMaxArrayLength = 10 // 数组只返回最近的几个值
+
+// 子表达式的计算结果
+type StrategyTrace struct {
+	Expr  string      `json:"expr"`
+	Value interface{} `json:"value"`
+}
+
+// 单条策略的检查结果
+type StrategyCheckResult struct {
+	Name         string          `json:"name"`
+	Type         string          `json:"type"`
+	Code         string          `json:"code"`
+	Enable       bool            `json:"enable"`
+	CompileError string          `json:"compile_error,omitempty"` // 使用 env 结构检查的编译错误, 和保存时的检查一致
+	RunError     string          `json:"run_error,omitempty"` // 使用当前行情运行的错误
+	Result       interface{}     `json:"result"`
+	Pass         bool            `json:"pass"` // 结果为 true
+	Trace        []StrategyTrace `json:"trace"` // 子表达式的值, 由内到外
+}
+
+// 检查并运行单条策略, 返回每个子表达式的值, 用于排查策略为什么没有触发
+// env 为 InitParseEnv 返回的 env(平仓策略需要设置 ROI 和 Position)
+func CheckStrategyRule(symbol string, strTechnology string, name string, strategyType string, code string, enable bool, env map[string]interface{}) (result StrategyCheckResult) {
+	result = StrategyCheckResult{
+		Name: name,
+		Type: strategyType,
+		Code: code,
+		Enable: enable,
+		Trace: []StrategyTrace{},
+	}
+	_, err := expr.Compile(code, StrategyOptions(InitSchemaEnv(symbol, strTechnology, strategyType))...)
+	if err != nil {
+		result.CompileError = err.Error()
+		return result
+	}
+	program, err := expr.Compile(code, StrategyOptions(env)...)
+	if err != nil {
+		result.RunError = err.Error()
+		return result
+	}
+	output, err := expr.Run(program, env)
+	if err != nil {
+		result.RunError = err.Error()
+		return result
+	}
+	result.Result = output
+	result.Pass, _ = output.(bool)
+	result.Trace = TraceStrategy(code, env)
+	return result
+}
+
+// 计算表达式中每个子表达式的值, 闭包中的子表达式(#)和变量无法单独计算, 跳过
+func TraceStrategy(code string, env map[string]interface{}) []StrategyTrace {
+	traces := []StrategyTrace{}
+	tree, err := parser.Parse(code)
+	if err != nil {
+		return traces
+	}
+	visitor := &traceVisitor{exist: map[string]bool{}}
+	ast.Walk(&tree.Node, visitor)
+	for _, subExpr := range visitor.exprs {
+		program, err := expr.Compile(subExpr, StrategyOptions(env)...)
+		if err != nil {
+			continue
+		}
+		output, err := expr.Run(program, env)
+		if err != nil {
+			continue
+		}
+		value, ok := traceValue(output)
+		if !ok {
+			continue
+		}
+		traces = append(traces, StrategyTrace{
+			Expr: subExpr,
+			Value: value,
+		})
+	}
+	return traces
+}
+
+type traceVisitor struct {
+	exprs []string
+	exist map[string]bool
+}
+
+func (v *traceVisitor) Visit(node *ast.Node) {
+	switch (*node).(type) {
+		case *ast.NilNode, *ast.IntegerNode, *ast.FloatNode, *ast.BoolNode, *ast.StringNode, *ast.ConstantNode, *ast.ClosureNode, *ast.PointerNode:
+			return // 常量和闭包
+	}
+	str := (*node).String()
+	if v.exist[str] {
+		return
+	}
+	v.exist[str] = true
+	v.exprs = append(v.exprs, str)
+}
+
+// 只返回数字, 布尔, 字符串和数字数组(最近的几个值), 指标对象和函数不返回
+func traceValue(output interface{}) (interface{}, bool) {
+	if output == nil {
+		return nil, true
+	}
+	if arr, ok := output.([]float64); ok {
+		if len(arr) > traceMaxArrayLength {
+			return arr[:traceMaxArrayLength], true
+		}
+		return arr, true
+	}
+	switch reflect.TypeOf(output).Kind() {
+		case reflect.Bool, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return output, true
+	}
+	return nil, false
+}
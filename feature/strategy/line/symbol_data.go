@@ -0,0 +1,358 @@
+package line
+
+import (
+	"encoding/json"
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+)
+
+// 默认的基准币种和权重, 系统配置 future_benchmark_basket 为空时使用
+var defaultBenchmarkBasket = map[string]float64{
+	"BTCUSDT": 0.6,
+	"ETHUSDT": 0.3,
+	"SOLUSDT": 0.05,
+	"BNBUSDT": 0.05,
+}
+
+const breadthCacheTime = 10 * 1000 // 市场宽度缓存 10 秒
+
+var benchmarkBasket = defaultBenchmarkBasket
+var breadthCache map[string]interface{}
+var breadthUpdateTime int64 = 0
+var symbolDataLock sync.Mutex
+
+// 编译自定义策略的参数, Sym("ETHUSDT").ema_1h_20 在编译时转换为 SymValue("ETHUSDT", "ema_1h_20")
+func StrategyOptions(env map[string]interface{}) []expr.Option {
+	return []expr.Option{expr.Env(env), expr.Patch(&symbolPatcher{})}
+}
+
+type symbolPatcher struct{}
+
+func (symbolPatcher) Visit(node *ast.Node) {
+	member, ok := (*node).(*ast.MemberNode)
+	if !ok {
+		return
+	}
+	call, ok := member.Node.(*ast.CallNode)
+	if !ok || len(call.Arguments) != 1 {
+		return
+	}
+	callee, ok := call.Callee.(*ast.IdentifierNode)
+	if !ok || callee.Value != "Sym" {
+		return
+	}
+	property, ok := member.Property.(*ast.StringNode)
+	if !ok {
+		return
+	}
+	ast.Patch(node, &ast.CallNode{
+		Callee: &ast.IdentifierNode{Value: "SymValue"},
+		Arguments: []ast.Node{call.Arguments[0], &ast.StringNode{Value: property.Value}},
+	})
+}
+
+// 设置基准币种和权重(系统配置 future_benchmark_basket), 格式 {"BTCUSDT": 0.6, "ETHUSDT": 0.4}, 为空或者格式错误时使用默认值
+func SetBenchmarkBasket(str string) {
+	basket := defaultBenchmarkBasket
+	if str != "" {
+		value, err := ParseBenchmarkBasket(str)
+		if err != nil {
+			logs.Error("future_benchmark_basket error:", err.Error())
+		} else {
+			basket = value
+		}
+	}
+	symbolDataLock.Lock()
+	defer symbolDataLock.Unlock()
+	benchmarkBasket = basket
+}
+
+func GetBenchmarkBasket() map[string]float64 {
+	symbolDataLock.Lock()
+	defer symbolDataLock.Unlock()
+	return benchmarkBasket
+}
+
+func ParseBenchmarkBasket(str string) (basket map[string]float64, err error) {
+	err = json.Unmarshal([]byte(str), &basket)
+	if err != nil {
+		return nil, err
+	}
+	if len(basket) == 0 {
+		return nil, fmt.Errorf("empty benchmark basket")
+	}
+	return basket, nil
+}
+
+// 基准涨跌幅, 基准币种涨跌幅的加权和
+func CalculateBasicTrend(basket map[string]float64, symbols []*models.Symbols) float64 {
+	basicTrend := 0.0
+	for _, v := range symbols {
+		if weight, ok := basket[v.Symbol]; ok {
+			basicTrend += v.PercentChange * weight
+		}
+	}
+	return basicTrend
+}
+
+// 市场宽度, 根据 symbols 表中所有币种的涨跌幅计算
+// Advancers 上涨数量, Decliners 下跌数量, Unchanged 不变数量, AdvanceDeclineRatio 上涨数量 / 下跌数量, VolumeWeightedChange 成交额加权涨跌幅
+func CalculateBreadth(symbols []*models.Symbols) map[string]interface{} {
+	advancers, decliners, unchanged := 0, 0, 0
+	changeTotal, volumeTotal := 0.0, 0.0
+	for _, v := range symbols {
+		if v.PercentChange > 0 {
+			advancers++
+		} else if v.PercentChange < 0 {
+			decliners++
+		} else {
+			unchanged++
+		}
+		changeTotal += v.PercentChange * v.QuoteVolume
+		volumeTotal += v.QuoteVolume
+	}
+	ratio := float64(advancers)
+	if decliners > 0 {
+		ratio = float64(advancers) / float64(decliners)
+	}
+	volumeWeightedChange := 0.0
+	if volumeTotal > 0 {
+		volumeWeightedChange = changeTotal / volumeTotal
+	}
+	return map[string]interface{}{
+		"Advancers": advancers,
+		"Decliners": decliners,
+		"Unchanged": unchanged,
+		"Total": len(symbols),
+		"AdvanceDeclineRatio": ratio,
+		"VolumeWeightedChange": volumeWeightedChange,
+	}
+}
+
+// 当前交易所使用 symbols 表(缓存 10 秒), 回测时使用回放的交易所的所有币种
+func getBreadth(exchange binance.FuturesExchange) map[string]interface{} {
+	if exchange != nil {
+		symbols, err := GetSymbolsFrom(exchange)
+		if err != nil {
+			logs.Error("get breadth error:", err.Error())
+		}
+		return CalculateBreadth(symbols)
+	}
+	symbolDataLock.Lock()
+	defer symbolDataLock.Unlock()
+	now := time.Now().UnixMilli()
+	if breadthCache != nil && now - breadthUpdateTime < breadthCacheTime {
+		return breadthCache
+	}
+	var symbols []*models.Symbols
+	_, err := orm.NewOrm().QueryTable("symbols").All(&symbols, "Symbol", "PercentChange", "QuoteVolume")
+	if err != nil {
+		logs.Error("get breadth error:", err.Error())
+	}
+	breadthCache = CalculateBreadth(symbols)
+	breadthUpdateTime = now
+	return breadthCache
+}
+
+// 其它币种的数据, 同一个 env 中相同的数据只获取一次
+type symbolResolver struct {
+	exchange binance.FuturesExchange
+	lock sync.Mutex
+	values map[string]interface{} // symbol_name => value
+	klines map[string]KLinePrice // symbol_interval => k线
+	breadth map[string]interface{}
+}
+
+func newSymbolResolver(exchange binance.FuturesExchange) *symbolResolver {
+	return &symbolResolver{
+		exchange: exchange,
+		values: map[string]interface{}{},
+		klines: map[string]KLinePrice{},
+	}
+}
+
+// 币种的行情(Close, Open, High, Low, PercentChange)
+func (r *symbolResolver) Sym(symbol string) map[string]interface{} {
+	symbols, err := GetSymbolsFrom(r.exchange, symbol)
+	if err != nil || len(symbols) == 0 {
+		return map[string]interface{}{}
+	}
+	return tickerValues(symbols[0])
+}
+
+// 币种的行情字段, k线(kline_1h)或者指标(类型_k线周期_参数, ex: ema_1h_20, boll_4h_21_2, macd_1h_12_26_9), 参数按照指标参数的顺序, 没有填写的使用默认值
+func (r *symbolResolver) SymValue(symbol string, name string) interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := symbol + "_" + name
+	if value, ok := r.values[key]; ok {
+		return value
+	}
+	value, err := r.resolve(symbol, name)
+	if err != nil {
+		logs.Error("Sym error:", symbol, name, err.Error())
+		value = nil
+	}
+	r.values[key] = value
+	return value
+}
+
+func (r *symbolResolver) Breadth() map[string]interface{} {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.breadth == nil {
+		r.breadth = getBreadth(r.exchange)
+	}
+	return r.breadth
+}
+
+func (r *symbolResolver) resolve(symbol string, name string) (interface{}, error) {
+	switch name {
+		case "Close", "Open", "High", "Low", "PercentChange":
+			symbols, err := GetSymbolsFrom(r.exchange, symbol)
+			if err != nil {
+				return nil, err
+			}
+			if len(symbols) == 0 {
+				return nil, fmt.Errorf("symbol not found")
+			}
+			return tickerValues(symbols[0])[name], nil
+	}
+	if strings.HasPrefix(name, "kline_") {
+		return r.getKlinePrice(symbol, strings.TrimPrefix(name, "kline_"))
+	}
+	indicatorType, interval, params, err := ParseIndicatorName(name)
+	if err != nil {
+		return nil, err
+	}
+	indicator, _ := GetIndicator(indicatorType)
+	klinePrice, err := r.getKlinePrice(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ComputeIndicator(indicator, klinePrice, params)
+	if err != nil {
+		return nil, err
+	}
+	data.KlineInterval = interval
+	return data, nil
+}
+
+func (r *symbolResolver) getKlinePrice(symbol string, interval string) (KLinePrice, error) {
+	key := symbol + "_" + interval
+	if klinePrice, ok := r.klines[key]; ok {
+		return klinePrice, nil
+	}
+	kline, err := binance.GetKlineDataFrom(r.exchange, symbol, interval, 150)
+	if err != nil {
+		return KLinePrice{}, err
+	}
+	high, low, close, open, amount, qps := GetLineFloatValues(kline)
+	volume, openTime := GetLineVolumes(kline)
+	klinePrice := KLinePrice{
+		High: high,
+		Low: low,
+		Close: close,
+		Open: open,
+		Amount: amount,
+		Qps: qps,
+		Volume: volume,
+		OpenTime: openTime,
+	}
+	r.klines[key] = klinePrice
+	return klinePrice, nil
+}
+
+// 解析指标名称, 类型_k线周期_参数1_参数2..., ex: ema_1h_20 => ema, 1h, {period: 20}
+func ParseIndicatorName(name string) (indicatorType string, interval string, params map[string]float64, err error) {
+	parts := strings.Split(name, "_")
+	intervals := map[string]bool{}
+	for _, item := range utils.Intervals() {
+		intervals[item] = true
+	}
+	index := -1
+	for i := 1; i < len(parts); i++ {
+		if intervals[parts[i]] {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return "", "", nil, fmt.Errorf("invalid indicator name %s, ex: ema_1h_20", name)
+	}
+	indicatorType = strings.Join(parts[:index], "_")
+	interval = parts[index]
+	indicator, ok := GetIndicator(indicatorType)
+	if !ok {
+		return "", "", nil, fmt.Errorf("unknown indicator type %s", indicatorType)
+	}
+	indicatorParams := indicator.Params()
+	values := parts[index+1:]
+	if len(values) > len(indicatorParams) {
+		return "", "", nil, fmt.Errorf("too many params for %s, params: %s", indicatorType, paramNames(indicatorParams))
+	}
+	params = map[string]float64{}
+	for i, value := range values {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", "", nil, fmt.Errorf("invalid param %s of %s", value, name)
+		}
+		params[indicatorParams[i].Name] = number
+	}
+	return indicatorType, interval, params, nil
+}
+
+func paramNames(params []IndicatorParam) string {
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func tickerValues(coin *models.Symbols) map[string]interface{} {
+	close, _ := strconv.ParseFloat(coin.Close, 64)
+	open, _ := strconv.ParseFloat(coin.Open, 64)
+	low, _ := strconv.ParseFloat(coin.Low, 64)
+	high, _ := strconv.ParseFloat(coin.High, 64)
+	return map[string]interface{}{
+		"PercentChange": coin.PercentChange,
+		"Close": close,
+		"Open": open,
+		"Low": low,
+		"High": high,
+	}
+}
+
+// 基准币种排序后的列表
+func benchmarkSymbols(basket map[string]float64) []string {
+	symbols := []string{}
+	for symbol := range basket {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// env 中的币种行情, 兼容之前固定的 btc, eth, sol, bnb, 加上基准币种和当前币种
+func envSymbols(basket map[string]float64, symbol string) []string {
+	symbols := []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT"}
+	for _, item := range benchmarkSymbols(basket) {
+		if _, ok := defaultBenchmarkBasket[item]; !ok {
+			symbols = append(symbols, item)
+		}
+	}
+	return append(symbols, symbol)
+}
@@ -278,4 +278,301 @@ func IsDarkCloudCover(first, second Candle) bool {
     closesInsideFirstBody := second.Close < first.Open && second.Close > first.Close * 0.5 + first.Open * 0.5
 
     return isFirstBullish && isSecondBearish && opensAboveFirstClose && closesInsideFirstBody
-}
\ No newline at end of file
+}
+// MACD 数据时间由新到旧, dif = ema(fast) - ema(slow), dea = ema(dif, signal), hist = dif - dea
+func CalculateMacd(close []float64, fastPeriod, slowPeriod, signalPeriod int) (dif, dea, hist []float64, err error) {
+	if fastPeriod <= 0 || slowPeriod <= 0 || signalPeriod <= 0 {
+		return nil, nil, nil, fmt.Errorf("invalid macd period %d, %d, %d", fastPeriod, slowPeriod, signalPeriod)
+	}
+	fast, err := CalculateExponentialMovingAverage(close, fastPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	slow, err := CalculateExponentialMovingAverage(close, slowPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	dif = make([]float64, len(close))
+	for i := range close {
+		dif[i] = fast[i] - slow[i]
+	}
+	dea, err = CalculateExponentialMovingAverage(dif, signalPeriod)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	hist = make([]float64, len(dif))
+	for i := range dif {
+		hist[i] = dif[i] - dea[i]
+	}
+	return dif, dea, hist, nil
+}
+
+// KDJ 数据时间由新到旧, rsv = (close - n日最低) / (n日最高 - n日最低) * 100
+// k = (kPeriod - 1) / kPeriod * 前k + rsv / kPeriod, d = (dPeriod - 1) / dPeriod * 前d + k / dPeriod, j = 3k - 2d, k 和 d 的初始值为 50
+func CalculateKdj(high, low, close []float64, period, kPeriod, dPeriod int) (k, d, j []float64, err error) {
+	if period <= 0 || kPeriod <= 0 || dPeriod <= 0 {
+		return nil, nil, nil, fmt.Errorf("invalid kdj period %d, %d, %d", period, kPeriod, dPeriod)
+	}
+	if len(close) < period || len(high) != len(close) || len(low) != len(close) {
+		return nil, nil, nil, fmt.Errorf("insufficient data for period %d", period)
+	}
+	high = utils.ReverseArray(high) // 时间由远到近
+	low = utils.ReverseArray(low)
+	close = utils.ReverseArray(close)
+	
+	size := len(close) - period + 1
+	k = make([]float64, size)
+	d = make([]float64, size)
+	j = make([]float64, size)
+	prevK, prevD := 50.0, 50.0
+	for i := period - 1; i < len(close); i++ {
+		highest, lowest := getHighest(high[i-period+1:i+1]), getLowest(low[i-period+1:i+1])
+		rsv := 50.0
+		if highest > lowest {
+			rsv = (close[i] - lowest) / (highest - lowest) * 100
+		}
+		prevK = (float64(kPeriod - 1) * prevK + rsv) / float64(kPeriod)
+		prevD = (float64(dPeriod - 1) * prevD + prevK) / float64(dPeriod)
+		k[i-period+1] = prevK
+		d[i-period+1] = prevD
+		j[i-period+1] = 3 * prevK - 2 * prevD
+	}
+	return utils.ReverseArray(k), utils.ReverseArray(d), utils.ReverseArray(j), nil
+}
+
+// 随机相对强弱指数 数据时间由新到旧, stoch = (rsi - n日最低rsi) / (n日最高rsi - n日最低rsi) * 100, k = ma(stoch, kPeriod), d = ma(k, dPeriod)
+func CalculateStochRsi(close []float64, rsiPeriod, stochPeriod, kPeriod, dPeriod int) (k, d []float64, err error) {
+	if stochPeriod <= 0 || kPeriod <= 0 || dPeriod <= 0 {
+		return nil, nil, fmt.Errorf("invalid stoch rsi period %d, %d, %d", stochPeriod, kPeriod, dPeriod)
+	}
+	rsi, err := CalculateRSI(close, rsiPeriod)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rsi) < stochPeriod {
+		return nil, nil, fmt.Errorf("insufficient data for period %d", stochPeriod)
+	}
+	stoch := make([]float64, len(rsi)-stochPeriod+1)
+	for i := range stoch {
+		highest, lowest := getHighest(rsi[i:i+stochPeriod]), getLowest(rsi[i:i+stochPeriod])
+		if highest > lowest {
+			stoch[i] = (rsi[i] - lowest) / (highest - lowest) * 100
+		}
+	}
+	k, err = CalculateSimpleMovingAverage(stoch, kPeriod)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err = CalculateSimpleMovingAverage(k, dPeriod)
+	if err != nil {
+		return nil, nil, err
+	}
+	return k, d, nil
+}
+
+// 平均趋向指数 数据时间由新到旧, 使用 Wilder 平滑, 返回 adx, +di, -di
+func CalculateAdx(high, low, close []float64, period int) (adx, plusDi, minusDi []float64, err error) {
+	if period <= 0 {
+		return nil, nil, nil, fmt.Errorf("invalid adx period %d", period)
+	}
+	if len(close) < period * 2 + 1 || len(high) != len(close) || len(low) != len(close) {
+		return nil, nil, nil, fmt.Errorf("insufficient data for period %d", period)
+	}
+	high = utils.ReverseArray(high) // 时间由远到近
+	low = utils.ReverseArray(low)
+	close = utils.ReverseArray(close)
+	
+	size := len(close) - 1
+	tr := make([]float64, size)
+	plusDm := make([]float64, size)
+	minusDm := make([]float64, size)
+	for i := 1; i < len(close); i++ {
+		up := high[i] - high[i-1]
+		down := low[i-1] - low[i]
+		if up > down && up > 0 {
+			plusDm[i-1] = up
+		}
+		if down > up && down > 0 {
+			minusDm[i-1] = down
+		}
+		tr[i-1] = math.Max(high[i] - low[i], math.Max(math.Abs(high[i] - close[i-1]), math.Abs(low[i] - close[i-1])))
+	}
+	
+	var smoothTr, smoothPlus, smoothMinus float64
+	dx := []float64{}
+	for i := 0; i < size; i++ {
+		if i < period {
+			smoothTr += tr[i]
+			smoothPlus += plusDm[i]
+			smoothMinus += minusDm[i]
+			if i < period - 1 {
+				continue
+			}
+		} else {
+			smoothTr = smoothTr - smoothTr / float64(period) + tr[i]
+			smoothPlus = smoothPlus - smoothPlus / float64(period) + plusDm[i]
+			smoothMinus = smoothMinus - smoothMinus / float64(period) + minusDm[i]
+		}
+		plus, minus := 0.0, 0.0
+		if smoothTr > 0 {
+			plus = smoothPlus / smoothTr * 100
+			minus = smoothMinus / smoothTr * 100
+		}
+		plusDi = append(plusDi, plus)
+		minusDi = append(minusDi, minus)
+		if plus + minus > 0 {
+			dx = append(dx, math.Abs(plus - minus) / (plus + minus) * 100)
+		} else {
+			dx = append(dx, 0)
+		}
+	}
+	
+	adx = make([]float64, len(dx)-period+1)
+	adx[0] = calculateAverage(dx[0:period])
+	for i := period; i < len(dx); i++ {
+		adx[i-period+1] = (adx[i-period] * float64(period - 1) + dx[i]) / float64(period)
+	}
+	return utils.ReverseArray(adx), utils.ReverseArray(plusDi), utils.ReverseArray(minusDi), nil
+}
+
+// 超级趋势 数据时间由新到旧, 上下轨 = (high + low) / 2 ± multiplier * atr
+// 返回超级趋势线(上涨趋势为下轨, 下跌趋势为上轨), 上轨, 下轨, 趋势方向(1 上涨, -1 下跌)
+func CalculateSuperTrend(high, low, close []float64, period int, multiplier float64) (trend, upper, lower, direction []float64, err error) {
+	atr, err := CalculateAtr(high, low, close, period)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	atr = utils.ReverseArray(atr) // 时间由远到近
+	high = utils.ReverseArray(high)
+	low = utils.ReverseArray(low)
+	close = utils.ReverseArray(close)
+	
+	size := len(close)
+	trend = make([]float64, size)
+	upper = make([]float64, size)
+	lower = make([]float64, size)
+	direction = make([]float64, size)
+	for i := 0; i < size; i++ {
+		mid := (high[i] + low[i]) / 2
+		upper[i] = mid + multiplier * atr[i]
+		lower[i] = mid - multiplier * atr[i]
+		direction[i] = 1
+		if i == 0 {
+			trend[i] = lower[i]
+			continue
+		}
+		// 上轨只能下移, 下轨只能上移, 除非前一根k线收盘价突破
+		if upper[i] > upper[i-1] && close[i-1] <= upper[i-1] {
+			upper[i] = upper[i-1]
+		}
+		if lower[i] < lower[i-1] && close[i-1] >= lower[i-1] {
+			lower[i] = lower[i-1]
+		}
+		direction[i] = direction[i-1]
+		if direction[i-1] == 1 && close[i] < lower[i] {
+			direction[i] = -1
+		} else if direction[i-1] == -1 && close[i] > upper[i] {
+			direction[i] = 1
+		}
+		if direction[i] == 1 {
+			trend[i] = lower[i]
+		} else {
+			trend[i] = upper[i]
+		}
+	}
+	return utils.ReverseArray(trend), utils.ReverseArray(upper), utils.ReverseArray(lower), utils.ReverseArray(direction), nil
+}
+
+// 成交量加权平均价 数据时间由新到旧, 每个 UTC 日重新累计, vwap = sum(典型价格 * 成交量) / sum(成交量), 典型价格 = (high + low + close) / 3
+func CalculateVwap(high, low, close, volume []float64, openTime []int64) ([]float64, error) {
+	if len(high) != len(close) || len(low) != len(close) || len(volume) != len(close) || len(openTime) != len(close) {
+		return nil, fmt.Errorf("invalid data length for vwap")
+	}
+	size := len(close)
+	vwap := make([]float64, size)
+	var sumPrice, sumVolume float64
+	var day int64 = -1
+	for i := size - 1; i >= 0; i-- { // 时间由远到近
+		nowDay := openTime[i] / 86400000
+		if nowDay != day {
+			day = nowDay
+			sumPrice, sumVolume = 0, 0
+		}
+		typical := (high[i] + low[i] + close[i]) / 3
+		sumPrice += typical * volume[i]
+		sumVolume += volume[i]
+		if sumVolume > 0 {
+			vwap[i] = sumPrice / sumVolume
+		} else {
+			vwap[i] = typical
+		}
+	}
+	return vwap, nil
+}
+
+// 能量潮 数据时间由新到旧, 收盘价上涨累加成交量, 下跌累减成交量, 从最远的一根k线开始累计
+func CalculateObv(close, volume []float64) ([]float64, error) {
+	if len(volume) != len(close) {
+		return nil, fmt.Errorf("invalid data length for obv")
+	}
+	size := len(close)
+	obv := make([]float64, size)
+	for i := size - 2; i >= 0; i-- { // 时间由远到近
+		obv[i] = obv[i+1]
+		if close[i] > close[i+1] {
+			obv[i] += volume[i]
+		} else if close[i] < close[i+1] {
+			obv[i] -= volume[i]
+		}
+	}
+	return obv, nil
+}
+
+// 一目均衡表 数据时间由新到旧, 转换线 = conversion 周期的 (最高 + 最低) / 2, 基准线 = base 周期的 (最高 + 最低) / 2
+// 当前k线对应的云层: 先行带A = base 根k线之前的 (转换线 + 基准线) / 2, 先行带B = base 根k线之前的 span 周期的 (最高 + 最低) / 2
+func CalculateIchimoku(high, low []float64, conversionPeriod, basePeriod, spanPeriod int) (conversion, base, spanA, spanB []float64, err error) {
+	if conversionPeriod <= 0 || basePeriod <= 0 || spanPeriod <= 0 {
+		return nil, nil, nil, nil, fmt.Errorf("invalid ichimoku period %d, %d, %d", conversionPeriod, basePeriod, spanPeriod)
+	}
+	if len(high) != len(low) || len(high) < spanPeriod + basePeriod {
+		return nil, nil, nil, nil, fmt.Errorf("insufficient data for period %d", spanPeriod + basePeriod)
+	}
+	conversion = calculateMidpoint(high, low, conversionPeriod)
+	base = calculateMidpoint(high, low, basePeriod)
+	span := calculateMidpoint(high, low, spanPeriod)
+	for i := 0; i + basePeriod < len(base); i++ {
+		spanA = append(spanA, (conversion[i+basePeriod] + base[i+basePeriod]) / 2)
+	}
+	for i := 0; i + basePeriod < len(span); i++ {
+		spanB = append(spanB, span[i+basePeriod])
+	}
+	return conversion, base, spanA, spanB, nil
+}
+
+// 周期内 (最高 + 最低) / 2, 数据时间由新到旧, 返回的切片长度 len(high)-period+1
+func calculateMidpoint(high, low []float64, period int) []float64 {
+	if len(high) < period {
+		return []float64{}
+	}
+	midpoint := make([]float64, len(high)-period+1)
+	for i := range midpoint {
+		midpoint[i] = (getHighest(high[i:i+period]) + getLowest(low[i:i+period])) / 2
+	}
+	return midpoint
+}
+
+func getHighest(values []float64) float64 {
+	highest := math.Inf(-1)
+	for _, value := range values {
+		highest = math.Max(highest, value)
+	}
+	return highest
+}
+
+func getLowest(values []float64) float64 {
+	lowest := math.Inf(1)
+	for _, value := range values {
+		lowest = math.Min(lowest, value)
+	}
+	return lowest
+}
@@ -1,6 +1,7 @@
 package strategy
 
 import (
+	"go_binance_futures/feature/api/binance"
 	"go_binance_futures/models"
 	"go_binance_futures/types"
 )
@@ -8,6 +9,7 @@ import (
 
 type OpenParams struct {
     Symbols *models.Symbols
+    Exchange binance.FuturesExchange // 行情来源, 为 nil 时使用当前交易所(回测时为回放的历史数据)
 }
 
 type OpenResult struct {
@@ -19,6 +21,19 @@ type CloseParams struct {
     Symbols *models.Symbols
     Position types.FuturesPosition
     NowProfit float64 // 当前收益率%
+    PeakProfit float64 // 持仓期间的最高收益率%
+    PeakPrice float64 // 最高收益率时的价格
+    Stats types.PositionStats // 持仓期间的统计
+    State PositionState // 持仓状态, 为 nil 时使用数据库保存的状态(回测时为内存中的状态)
+    Exchange binance.FuturesExchange // 行情来源, 为 nil 时使用当前交易所(回测时为回放的历史数据)
+}
+
+// 自定义平仓策略的持仓状态, 同一个持仓的多次判断之间共享
+type PositionState interface {
+    // 获取状态, 不存在时返回默认值(没有默认值时为 nil)
+    Get(key string, defaults ...interface{}) interface{}
+    // 保存状态, 成功时返回 true, 可以在策略中使用 Set("a", 1) && ...
+    Set(key string, value interface{}) bool
 }
 
 type CloseResult struct {
@@ -5,6 +5,8 @@ import (
 	"go_binance_futures/command"
 	"go_binance_futures/feature"
 	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/feature/backtest"
+	"go_binance_futures/feature/strategy/line"
 	"go_binance_futures/middlewares"
 	"go_binance_futures/models"
 	"go_binance_futures/rate"
@@ -12,6 +14,7 @@ import (
 	"go_binance_futures/spot"
 	spot_api "go_binance_futures/spot/api/binance"
 	"go_binance_futures/utils"
+	"os"
 	"time"
 
 	"github.com/beego/beego/v2/client/orm"
@@ -23,7 +26,7 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
-var dbVersion int64 = 7 // 每次变动数据库版本号 +1
+var dbVersion int64 = 21 // 每次变动数据库版本号 +1
 var debug, _ = config.String("debug")
 var webPort, _ = config.String("web::port")
 var webIndex, _ = config.String("web::index")
@@ -82,6 +85,18 @@ func registerModels() {
 	orm.RegisterModel(new(models.FuturesPosition))
 	orm.RegisterModel(new(models.FuturesOrder))
 	orm.RegisterModel(new(models.StrategyFreeze))
+	orm.RegisterModel(new(models.FuturesKline))
+	orm.RegisterModel(new(models.FuturesPositionPeak))
+	orm.RegisterModel(new(models.FuturesGrid))
+	orm.RegisterModel(new(models.FuturesGridOrder))
+	orm.RegisterModel(new(models.FuturesPositionState))
+	orm.RegisterModel(new(models.StrategyTemplateVersion))
+	orm.RegisterModel(new(models.StrategyTemplateRollout))
+	orm.RegisterModel(new(models.FuturesCircuitBreaker))
+	orm.RegisterModel(new(models.FuturesCircuitBreakerEvent))
+	orm.RegisterModel(new(models.StrategyFreezeTrade))
+	orm.RegisterModel(new(models.StrategyFreezeHistory))
+	orm.RegisterModel(new(models.FuturesReconcileLog))
 	
 	setDriver(driver) // 设置数据库驱动
 	syncDb() // 同步数据库
@@ -112,6 +127,7 @@ func syncDb() {
 		orm.NewOrm().Update(&config)
 	}
 	SystemConfig = config
+	line.SetBenchmarkBasket(config.FutureBenchmarkBasket)
 }
 
 func registerMiddlewares() {
@@ -125,10 +141,17 @@ func updateSystemConfig() {
 		logs.Error("GetSystemConfig:", err.Error())
 	} else {
 		SystemConfig = systemConfig // 更新配置信息
+		line.SetBenchmarkBasket(systemConfig.FutureBenchmarkBasket)
 	}
 }
 
 func main() {
+	// 命令行回测 ex: ./go_binance_futures backtest -symbols BTCUSDT -start 2024-01-01 -end 2024-01-08
+	if len(os.Args) > 1 && os.Args[1] == "backtest" {
+		backtest.RunCli(os.Args[2:])
+		return
+	}
+	
 	// debug
 	if debug == "1" {
 		// feature.GoTestApi()
@@ -157,7 +180,12 @@ func main() {
 	// 如果开启，则使用本地数据库管理仓位信息，不再每次请求查询 api 接口，可以有效降低请求频率(openOrders, getPosition) 
 	// 但是需要注意，这里面的仓位信息推送，只有仓位发生变化时才会推送数据(当前仓位的盈利多少变化不会推送，需要根据 symbols 表的 close 价格计算)
 	if wsFuturesUserData == "1" {
-		feature.SyncUserData()	
+		if binance.IsMockExchange() {
+			logs.Warn("mock exchange not support ws futures_user_data")
+		} else {
+			feature.SyncUserData()	
+			go feature.StartReconcile() // 定时和交易所对账, 修复 ws 漏掉的推送
+		}
 	}
 	
 	// 读取最新配置信息
@@ -180,12 +208,24 @@ func main() {
 	
 	// websocket 订阅更新币种价格
 	go func() {
+		if binance.IsMockExchange() {
+			logs.Info("futures mock exchange start: auto update symbols price")
+			binance.UpdateCoinByMock(&SystemConfig)
+			return
+		}
 		logs.Info("futures websocket start: auto update symbols price")
-		binance.UpdateCoinByWs(&SystemConfig, 0)
+		binance.StartFuturesMarket(&SystemConfig)
 	}()
+	// websocket 同步k线, 策略和监听使用本地的k线
+	if binance.IsKlineSyncEnable() {
+		go func() {
+			logs.Info("futures kline websocket start: sync klines")
+			binance.StartKlineSync()
+		}()
+	}
 	go func() {
 		logs.Info("spot websocket start: auto update symbols price")
-		spot_api.UpdateCoinByWs(&SystemConfig, 0)
+		spot_api.StartSpotMarket(&SystemConfig)
 	}()
 	go func() {
 		return
@@ -203,6 +243,34 @@ func main() {
 		}
 	}()
 	
+	// 交易所止盈止损单, 和自动合约交易一样只在开启合约交易时同步, 开启后第一次先对账撤销遗留的订单
+	go func() {
+		feature.WatchProtectiveOrders()
+		reconciled := false
+		for {
+			if SystemConfig.FutureEnable == 1 {
+				if reconciled {
+					feature.SyncProtectiveOrders(SystemConfig)
+				} else {
+					feature.ReconcileProtectiveOrders(SystemConfig)
+					reconciled = true
+				}
+			}
+			time.Sleep(time.Second * 10) // 10秒间隔
+		}
+	}()
+	
+	// 合约网格
+	go feature.StartGridBot()
+	
+	// 账户熔断(当天/本周亏损, 权益回撤)
+	go func() {
+		for {
+			feature.CheckCircuitBreaker(SystemConfig)
+			time.Sleep(time.Second * 60) // 60秒间隔
+		}
+	}()
+	
 	// 自动合约交易
 	go func() {
 		for {
@@ -276,6 +344,17 @@ func main() {
 		}
 	}()
 	
+	// 每天清理过期的数据(本地k线, 风控交易记录)
+	go func() {
+		for {
+			binance.CleanLocalKlines()
+			if err := utils.NewFreezeService().CleanTrades(); err != nil {
+				logs.Error("clean strategy freeze trades error:", err)
+			}
+			time.Sleep(time.Hour * 24) // 24 小时清理一次
+		}
+	}()
+	
 	// 监听套利情况
 	go func() {
 		return
@@ -0,0 +1,37 @@
+package models
+
+// 账户熔断的状态, 只有一条记录(id = 1)
+type FuturesCircuitBreaker struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Tripped int `orm:"column(tripped)" json:"tripped"` // 1: 已熔断, 停止开仓, 需要手动恢复
+	Reason string `orm:"column(reason);size(500)" json:"reason"` // 熔断原因
+	TripTime int64 `orm:"column(trip_time)" json:"trip_time"`
+	RearmTime int64 `orm:"column(rearm_time)" json:"rearm_time"` // 手动恢复的时间, 之后的亏损重新统计
+	DayPnl float64 `orm:"column(day_pnl)" json:"day_pnl"` // 当天(UTC)的已实现盈亏
+	WeekPnl float64 `orm:"column(week_pnl)" json:"week_pnl"` // 本周(UTC, 周一开始)的已实现盈亏
+	PnlSource string `orm:"column(pnl_source);size(20)" json:"pnl_source"` // income: 资金流水, order: 订单表的预估收益(资金流水获取失败时)
+	Equity float64 `orm:"column(equity)" json:"equity"` // 当前权益(包含未实现盈亏)
+	HighWater float64 `orm:"column(high_water)" json:"high_water"` // 权益的最高点
+	Drawdown float64 `orm:"column(drawdown)" json:"drawdown"` // 从最高点的回撤%
+	CheckTime int64 `orm:"column(check_time)" json:"check_time"`
+}
+
+func (u *FuturesCircuitBreaker) TableName() string {
+	return "futures_circuit_breaker"
+}
+
+// 熔断和恢复的记录
+type FuturesCircuitBreakerEvent struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Action string `orm:"column(action);size(20)" json:"action"` // trip: 熔断, rearm: 恢复, flatten: 平仓
+	Reason string `orm:"column(reason);size(500)" json:"reason"`
+	DayPnl float64 `orm:"column(day_pnl)" json:"day_pnl"`
+	WeekPnl float64 `orm:"column(week_pnl)" json:"week_pnl"`
+	Equity float64 `orm:"column(equity)" json:"equity"`
+	Drawdown float64 `orm:"column(drawdown)" json:"drawdown"`
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+}
+
+func (u *FuturesCircuitBreakerEvent) TableName() string {
+	return "futures_circuit_breaker_events"
+}
@@ -0,0 +1,53 @@
+package models
+
+// 合约网格
+type FuturesGrid struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	Mode string `orm:"column(mode)" json:"mode"` // neutral(中性), long(做多), short(做空)
+	LowerPrice float64 `orm:"column(lower_price)" json:"lower_price"` // 网格最低价
+	UpperPrice float64 `orm:"column(upper_price)" json:"upper_price"` // 网格最高价
+	GridCount int `orm:"column(grid_count)" json:"grid_count"` // 网格数量
+	Investment float64 `orm:"column(investment)" json:"investment"` // 投入的保证金 usdt
+	Leverage int64 `orm:"column(leverage)" json:"leverage"` // 合约倍数
+	Quantity float64 `orm:"column(quantity)" json:"quantity"` // 每格的下单数量, 启动时计算
+	Status string `orm:"column(status)" json:"status"` // running, stopped
+	RealizedProfit float64 `orm:"column(realized_profit)" json:"realized_profit"` // 已实现的网格收益(不含手续费)
+	FilledCount int64 `orm:"column(filled_count)" json:"filled_count"` // 完成的网格套利次数
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+	UpdateTime int64 `orm:"column(update_time)" json:"update_time"`
+}
+
+func (u *FuturesGrid) TableName() string {
+	return "futures_grids"
+}
+
+// 网格挂单, 开仓单成交后在相邻的格子挂平仓单, 平仓单成交后在原来的格子重新挂开仓单
+type FuturesGridOrder struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	GridId int64 `orm:"column(grid_id)" json:"grid_id"`
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	Level int `orm:"column(level)" json:"level"` // 开仓单所在的格子, 0 ~ grid_count
+	Kind string `orm:"column(kind)" json:"kind"` // open(开仓), close(平仓)
+	Side string `orm:"column(side)" json:"side"` // BUY, SELL
+	PositionSide string `orm:"column(position_side)" json:"position_side"` // LONG, SHORT
+	Price float64 `orm:"column(price)" json:"price"` // 挂单价格
+	OpenPrice float64 `orm:"column(open_price)" json:"open_price"` // 平仓单对应的开仓成交价格
+	Quantity float64 `orm:"column(quantity)" json:"quantity"`
+	OrderId int64 `orm:"column(order_id)" json:"order_id"`
+	Status string `orm:"column(status)" json:"status"` // NEW, FILLED, CANCELED
+	Profit float64 `orm:"column(profit)" json:"profit"` // 平仓单成交后的收益
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+	UpdateTime int64 `orm:"column(update_time)" json:"update_time"`
+}
+
+func (u *FuturesGridOrder) TableName() string {
+	return "futures_grid_orders"
+}
+
+func (u *FuturesGridOrder) TableIndex() [][]string {
+	return [][]string{
+		{"GridId", "Status"},
+		{"OrderId"},
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+// 合约k线(只保存已经完结的k线)
+type FuturesKline struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	KlineInterval string `orm:"column(kline_interval)" json:"kline_interval"` // 1m, 5m, 1h ...
+	OpenTime int64 `orm:"column(open_time)" json:"open_time"` // 开盘时间, 毫秒时间戳
+	CloseTime int64 `orm:"column(close_time)" json:"close_time"` // 收盘时间, 毫秒时间戳
+	Open string `orm:"column(open)" json:"open"`
+	High string `orm:"column(high)" json:"high"`
+	Low string `orm:"column(low)" json:"low"`
+	Close string `orm:"column(close)" json:"close"`
+	Volume string `orm:"column(volume)" json:"volume"` // 成交量
+	QuoteAssetVolume string `orm:"column(quote_asset_volume)" json:"quote_asset_volume"` // 成交额
+	TradeNum int64 `orm:"column(trade_num)" json:"trade_num"` // 成交笔数
+}
+
+func (u *FuturesKline) TableName() string {
+    return "futures_klines"
+}
+
+func (u *FuturesKline) TableUnique() [][]string {
+	return [][]string{
+		{"Symbol", "KlineInterval", "OpenTime"},
+	}
+}
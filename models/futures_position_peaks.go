@@ -0,0 +1,33 @@
+package models
+
+// 持仓期间的跟踪信息, 最高收益率用于跟踪止盈, 分批止盈的进度
+type FuturesPositionPeak struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	Side string `orm:"column(side)" json:"side"` // 持仓方向, LONG, SHORT
+	EntryPrice string `orm:"column(entry_price)" json:"entry_price"` // 开仓价格, 变化(加仓)后重新统计
+	PeakRoi float64 `orm:"column(peak_roi)" json:"peak_roi"` // 最高收益率%
+	PeakPrice float64 `orm:"column(peak_price)" json:"peak_price"` // 最高收益率时的标记价格
+	Amount string `orm:"column(amount)" json:"amount"` // 开始统计时的持仓数量(绝对值), 分批止盈按照这个数量的比例平仓
+	LadderStep int `orm:"column(ladder_step)" json:"ladder_step"` // 已经完成的分批止盈档位数量
+	RealizedProfit float64 `orm:"column(realized_profit)" json:"realized_profit"` // 分批止盈已实现的收益
+	BaseAmount string `orm:"column(base_amount)" json:"base_amount"` // 第一次统计时的持仓数量(绝对值), 加仓数量按照这个数量的倍数计算
+	SafetyCount int `orm:"column(safety_count)" json:"safety_count"` // 已经加仓的次数
+	ParentOrderId int64 `orm:"column(parent_order_id)" json:"parent_order_id"` // 开仓订单 id, 加仓订单关联到这个订单
+	MaxRoi float64 `orm:"column(max_roi)" json:"max_roi"` // 持仓期间的最高收益率%(加仓后不重置)
+	MinRoi float64 `orm:"column(min_roi)" json:"min_roi"` // 持仓期间的最低收益率%
+	ProfitTime int64 `orm:"column(profit_time)" json:"profit_time"` // 处于盈利状态的时长, 毫秒
+	CheckTime int64 `orm:"column(check_time)" json:"check_time"` // 最后一次统计的时间
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+	UpdateTime int64 `orm:"column(update_time)" json:"update_time"`
+}
+
+func (u *FuturesPositionPeak) TableName() string {
+	return "futures_position_peaks"
+}
+
+func (u *FuturesPositionPeak) TableUnique() [][]string {
+	return [][]string{
+		{"Symbol", "Side"},
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+// 自定义平仓策略的持仓状态(Get/Set), 按照 币种 + 持仓方向 保存, 平仓后删除
+type FuturesPositionState struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	Side string `orm:"column(side)" json:"side"` // 持仓方向, LONG, SHORT
+	StateKey string `orm:"column(state_key);size(100)" json:"state_key"`
+	Value string `orm:"column(value);size(1000)" json:"value"` // json
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+	UpdateTime int64 `orm:"column(update_time)" json:"update_time"`
+}
+
+func (u *FuturesPositionState) TableName() string {
+	return "futures_position_states"
+}
+
+func (u *FuturesPositionState) TableUnique() [][]string {
+	return [][]string{
+		{"Symbol", "Side", "StateKey"},
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+// 本地持仓/挂单和交易所的对账差异记录
+type FuturesReconcileLog struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Kind string `orm:"column(kind);size(20)" json:"kind"` // position, order
+	Diff string `orm:"column(diff);size(20)" json:"diff"` // missing_local(交易所有, 本地没有), stale_local(本地有, 交易所没有), mismatch(字段不一致)
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	Side string `orm:"column(side)" json:"side"` // 持仓方向 LONG, SHORT
+	OrderId string `orm:"column(order_id)" json:"order_id"`
+	Field string `orm:"column(field)" json:"field"` // 不一致的字段 amount, entry_price, leverage, status, executed_qty
+	LocalValue string `orm:"column(local_value)" json:"local_value"`
+	RemoteValue string `orm:"column(remote_value)" json:"remote_value"`
+	Healed int `orm:"column(healed)" json:"healed"` // 是否已经按照交易所的数据修复
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+}
+
+func (u *FuturesReconcileLog) TableName() string {
+	return "futures_reconcile_logs"
+}
@@ -4,6 +4,40 @@ import (
     "github.com/beego/beego/v2/client/orm"
 )
 
+// 策略的每笔交易盈亏, 用于计算冻结规则的窗口
+type StrategyFreezeTrade struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	Symbol string `orm:"column(symbol)" json:"symbol"`
+	StrategyName string `orm:"column(strategy_name)" json:"strategy_name"`
+	TradeType string `orm:"column(trade_type)" json:"trade_type"` // real, test
+	Profit float64 `orm:"column(profit)" json:"profit"` // 整笔交易的收益(usdt)
+	CreatedAt int64 `orm:"column(created_at)" json:"created_at"`
+}
+
+func (u *StrategyFreezeTrade) TableName() string {
+    return "strategy_freeze_trades"
+}
+
+// 冻结和手动解冻的记录
+type StrategyFreezeHistory struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	FreezeId int64 `orm:"column(freeze_id)" json:"freeze_id"` // strategy_freeze 的 id
+	Symbol string `orm:"column(symbol)" json:"symbol"` // * 为策略级别(所有币种)
+	StrategyName string `orm:"column(strategy_name)" json:"strategy_name"`
+	TradeType string `orm:"column(trade_type)" json:"trade_type"`
+	Action string `orm:"column(action)" json:"action"` // freeze, unfreeze
+	Rule string `orm:"column(rule)" json:"rule"` // 触发的规则 loss_count, loss_amount, recent_loss
+	Detail string `orm:"column(detail);size(500)" json:"detail"`
+	Level int `orm:"column(level)" json:"level"` // 窗口内之前的冻结次数(升级次数)
+	FreezeHours float64 `orm:"column(freeze_hours)" json:"freeze_hours"` // 本次冻结的小时数
+	FreezeUntil int64 `orm:"column(freeze_until)" json:"freeze_until"`
+	CreatedAt int64 `orm:"column(created_at)" json:"created_at"`
+}
+
+func (u *StrategyFreezeHistory) TableName() string {
+    return "strategy_freeze_history"
+}
+
 func DeleteStrategyFreeze(id int64) error {
     o := orm.NewOrm()
     _, err := o.Raw("DELETE FROM strategy_freeze WHERE id = ?", id).Exec()
@@ -0,0 +1,46 @@
+package models
+
+// 策略模板的版本, 每次修改模板的策略内容时保存一个新的版本
+type StrategyTemplateVersion struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	TemplateId int64 `orm:"column(template_id)" json:"template_id"`
+	Version int64 `orm:"column(version)" json:"version"` // 从 1 开始
+	Technology string `orm:"column(technology);type(text)" json:"technology"` // 技术指标配置 json
+	Strategy string `orm:"column(strategy);type(text)" json:"strategy"` // 策略 json
+	TrailingStop int `orm:"column(trailing_stop)" json:"trailing_stop"`
+	TrailingActivation float64 `orm:"column(trailing_activation)" json:"trailing_activation"`
+	TrailingCallback float64 `orm:"column(trailing_callback)" json:"trailing_callback"`
+	TakeProfitLadder string `orm:"column(take_profit_ladder);size(1000)" json:"take_profit_ladder"`
+	SafetyOrderStep float64 `orm:"column(safety_order_step)" json:"safety_order_step"`
+	SafetyOrderMultiplier float64 `orm:"column(safety_order_multiplier)" json:"safety_order_multiplier"`
+	SafetyOrderMaxCount int `orm:"column(safety_order_max_count)" json:"safety_order_max_count"`
+	Comment string `orm:"column(comment);size(255)" json:"comment"` // 版本说明
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+}
+
+func (u *StrategyTemplateVersion) TableName() string {
+	return "strategy_template_versions"
+}
+
+func (u *StrategyTemplateVersion) TableUnique() [][]string {
+	return [][]string{
+		{"TemplateId", "Version"},
+	}
+}
+
+// 模板版本的发布记录, 保存发布前币种的配置, 用于回滚
+type StrategyTemplateRollout struct {
+	ID int64 `orm:"column(id)" json:"id"`
+	TemplateId int64 `orm:"column(template_id)" json:"template_id"`
+	Version int64 `orm:"column(version)" json:"version"` // 发布的版本, 回滚时为 0
+	Action string `orm:"column(action)" json:"action"` // rollout(发布), rollback(回滚)
+	RollbackId int64 `orm:"column(rollback_id)" json:"rollback_id"` // 回滚的发布记录 id
+	Symbols string `orm:"column(symbols);type(text)" json:"symbols"` // 币种 json, ex: ["BTCUSDT","ETHUSDT"]
+	Snapshot string `orm:"column(snapshot);type(text)" json:"snapshot"` // 发布前币种的配置 json
+	Comment string `orm:"column(comment);size(255)" json:"comment"`
+	CreateTime int64 `orm:"column(create_time)" json:"create_time"`
+}
+
+func (u *StrategyTemplateRollout) TableName() string {
+	return "strategy_template_rollouts"
+}
@@ -27,6 +27,20 @@ type Config struct {
 	WsDeliveryEnable int `orm:"column(ws_delivery_enable)" json:"ws_delivery_enable"`
 	LossMaxCount int `orm:"column(loss_max_count)" json:"loss_max_count"` // 允许开仓的最大亏损仓位临界值
 	LossAutoScale int `orm:"column(loss_auto_scale)" json:"loss_auto_scale"` // 是否自动缩放 loss_max_count
+	FutureProtectiveOrder int `orm:"column(future_protective_order)" json:"future_protective_order"` // 是否在交易所挂止盈止损条件单
+	FutureSizingMode string `orm:"column(future_sizing_mode)" json:"future_sizing_mode"` // 开仓金额计算方式 fixed_usdt, fixed_notional, balance_percent, fixed_risk, kelly
+	FutureSizingValue float64 `orm:"column(future_sizing_value)" json:"future_sizing_value"` // 开仓金额计算方式的参数
+	FutureBenchmarkBasket string `orm:"column(future_benchmark_basket);size(500)" json:"future_benchmark_basket"` // 基准币种和权重(json), 为空时使用默认 {"BTCUSDT":0.6,"ETHUSDT":0.3,"SOLUSDT":0.05,"BNBUSDT":0.05}
+	RiskMaxNotional float64 `orm:"column(risk_max_notional)" json:"risk_max_notional"` // 所有持仓和开仓挂单的名义价值上限(usdt), 0 不限制
+	RiskMaxMarginPercent float64 `orm:"column(risk_max_margin_percent)" json:"risk_max_margin_percent"` // 保证金占钱包余额的最大比例%, 0 不限制
+	RiskMaxSymbolNotional float64 `orm:"column(risk_max_symbol_notional)" json:"risk_max_symbol_notional"` // 单个币种的名义价值上限(usdt), 0 不限制
+	RiskMaxNetNotional float64 `orm:"column(risk_max_net_notional)" json:"risk_max_net_notional"` // 多空净敞口上限(usdt), 0 不限制
+	RiskMaxCorrelated int `orm:"column(risk_max_correlated)" json:"risk_max_correlated"` // 同方向高相关持仓的最大数量, 0 不限制
+	RiskCorrelation float64 `orm:"column(risk_correlation)" json:"risk_correlation"` // 高相关的相关系数阈值, 默认 0.8
+	BreakerDailyLoss float64 `orm:"column(breaker_daily_loss)" json:"breaker_daily_loss"` // 当天(UTC)亏损达到后熔断(usdt), 0 不限制
+	BreakerWeeklyLoss float64 `orm:"column(breaker_weekly_loss)" json:"breaker_weekly_loss"` // 本周(UTC)亏损达到后熔断(usdt), 0 不限制
+	BreakerMaxDrawdown float64 `orm:"column(breaker_max_drawdown)" json:"breaker_max_drawdown"` // 权益从最高点回撤%达到后熔断, 0 不限制
+	BreakerFlatten int `orm:"column(breaker_flatten)" json:"breaker_flatten"` // 熔断时是否平掉所有仓位
 }
 
 // 切记需要注册model后才能使用
@@ -47,6 +61,9 @@ type Order struct {
 	PositionSide string `orm:"column(positionSide)" json:"positionSide"` // LONG, SHORT
 	OrderId int64 `orm:"column(order_id)" json:"order_id"`
 	UpdateTime int64 `orm:"column(updateTime)" json:"updateTime"`
+	SizingMode string `orm:"column(sizing_mode)" json:"sizing_mode"` // 开仓金额计算方式
+	SizingDetail string `orm:"column(sizing_detail)" json:"sizing_detail"` // 开仓金额计算过程
+	ParentId int64 `orm:"column(parent_id)" json:"parent_id"` // 加仓(DCA)和平仓订单对应的开仓订单 id, 0 为开仓订单
 }
 
 type Symbols struct {
@@ -80,6 +97,17 @@ type Symbols struct {
 	Pin int64 `orm:"column(pin)" json:"pin"` // 置顶
 	Sort int64 `orm:"column(sort)" json:"sort"` // 排序
 	Type string `orm:"column(type)" json:"type"` // USDT, USDC
+	TrailingStop int `orm:"column(trailing_stop)" json:"trailing_stop"` // 跟踪止盈 0:关闭 1:程序跟踪平仓 2:交易所 TRAILING_STOP_MARKET 订单
+	TrailingActivation float64 `orm:"column(trailing_activation)" json:"trailing_activation"` // 最高收益率达到多少%后开始跟踪
+	TrailingCallback float64 `orm:"column(trailing_callback)" json:"trailing_callback"` // 收益率从最高点回撤多少%后平仓
+	TakeProfitLadder string `orm:"column(take_profit_ladder);size(1000)" json:"take_profit_ladder"` // 分批止盈 json, ex: [{"roi":10,"percent":50},{"roi":20,"percent":30}]
+	SafetyOrderStep float64 `orm:"column(safety_order_step)" json:"safety_order_step"` // 加仓(DCA), 收益率(相对当前开仓均价)每下跌多少%加仓一次
+	SafetyOrderMultiplier float64 `orm:"column(safety_order_multiplier)" json:"safety_order_multiplier"` // 加仓数量的倍数, 第 n 次加仓数量 = 开仓数量 * 倍数^n
+	SafetyOrderMaxCount int `orm:"column(safety_order_max_count)" json:"safety_order_max_count"` // 最多加仓次数, 0 为关闭
+	SizingMode string `orm:"column(sizing_mode)" json:"sizing_mode"` // 开仓金额计算方式, global(使用系统配置), fixed_usdt, fixed_notional, balance_percent, fixed_risk, kelly
+	SizingValue float64 `orm:"column(sizing_value)" json:"sizing_value"` // 开仓金额计算方式的参数
+	TemplateId int64 `orm:"column(template_id)" json:"template_id"` // 使用的策略模板, 0 为不使用模板
+	TemplateVersion int64 `orm:"column(template_version)" json:"template_version"` // 使用的策略模板版本, 策略内容和这个版本一致, 手动修改后不再关联模板
 }
 
 type NewSymbols struct {
@@ -192,6 +220,14 @@ type StrategyTemplates struct {
 	Name string `orm:"column(name)" json:"name"`
 	Technology string `orm:"column(technology);type(text)" json:"technology"` // 技术指标配置 json
 	Strategy string `orm:"column(strategy);type(text)" json:"strategy"` // 策略 json
+	TrailingStop int `orm:"column(trailing_stop)" json:"trailing_stop"` // 跟踪止盈 0:关闭 1:程序跟踪平仓 2:交易所 TRAILING_STOP_MARKET 订单
+	TrailingActivation float64 `orm:"column(trailing_activation)" json:"trailing_activation"` // 最高收益率达到多少%后开始跟踪
+	TrailingCallback float64 `orm:"column(trailing_callback)" json:"trailing_callback"` // 收益率从最高点回撤多少%后平仓
+	TakeProfitLadder string `orm:"column(take_profit_ladder);size(1000)" json:"take_profit_ladder"` // 分批止盈 json, ex: [{"roi":10,"percent":50},{"roi":20,"percent":30}]
+	SafetyOrderStep float64 `orm:"column(safety_order_step)" json:"safety_order_step"` // 加仓(DCA), 收益率(相对当前开仓均价)每下跌多少%加仓一次
+	SafetyOrderMultiplier float64 `orm:"column(safety_order_multiplier)" json:"safety_order_multiplier"` // 加仓数量的倍数, 第 n 次加仓数量 = 开仓数量 * 倍数^n
+	SafetyOrderMaxCount int `orm:"column(safety_order_max_count)" json:"safety_order_max_count"` // 最多加仓次数, 0 为关闭
+	Version int64 `orm:"column(version)" json:"version"` // 最新的版本, 0 为还没有保存版本
 	CreateTime int64 `orm:"column(createTime)" json:"createTime"`
 	UpdateTime int64 `orm:"column(updateTime)" json:"updateTime"`
 }
@@ -268,6 +304,14 @@ type StrategyFreeze struct {
 	LossCount int `orm:"column(loss_count)" json:"loss_count"` // 当前亏损次数
 	FreezeOnLossCount int `orm:"column(freeze_on_loss_count)" json:"freeze_on_loss_count"` // 达到此亏损次数后冻结
 	FreezeHours int `orm:"column(freeze_hours)" json:"freeze_hours"` // 冻结小时数
+	LossAmount float64 `orm:"column(loss_amount)" json:"loss_amount"` // 窗口内累计亏损(usdt)达到后冻结, 0 不限制
+	LossWindowHours int `orm:"column(loss_window_hours)" json:"loss_window_hours"` // 累计亏损的滚动窗口小时数, 0 默认 24
+	RecentTrades int `orm:"column(recent_trades)" json:"recent_trades"` // 最近 N 笔交易
+	RecentLossCount int `orm:"column(recent_loss_count)" json:"recent_loss_count"` // 最近 N 笔交易中亏损次数达到后冻结(不要求连续), 0 不限制
+	EscalateFactor float64 `orm:"column(escalate_factor)" json:"escalate_factor"` // 重复冻结时冻结时长的倍数, <= 1 不升级
+	EscalateWindowHours int `orm:"column(escalate_window_hours)" json:"escalate_window_hours"` // 统计重复冻结次数的窗口小时数, 0 默认 168
+	MaxFreezeHours int `orm:"column(max_freeze_hours)" json:"max_freeze_hours"` // 升级后的最大冻结小时数, 0 不限制
+	LastFreezeAt int64 `orm:"column(last_freeze_at)" json:"last_freeze_at"` // 最近一次冻结的时间, 之前的交易不再计入窗口
 	CreatedAt int64 `orm:"column(created_at)" json:"created_at"`
 	UpdatedAt int64 `orm:"column(updated_at)" json:"updated_at"`
 }
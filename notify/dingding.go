@@ -389,3 +389,12 @@ func (pusher DingDing) FuturesPositionConvert(params FuturesPositionConvertParam
   )
   DingDingApi(text)
 }
+
+func (pusher DingDing) FuturesAlert(params FuturesAlertParams) {
+  text := "\n## " + params.Title + "\n"
+  for _, line := range params.Content {
+    text += "#### " + line + "\n"
+  }
+  text += fmt.Sprintf(lang.LangMatch("#### **{futures.time}**：%s\n\n> author <lessl@vip.qq.com>"), nowTime())
+  DingDingApi(text)
+}
@@ -105,10 +105,17 @@ type FuturesPositionConvertParams struct {
 	UnRealizedProfit string
 }
 
+// 系统告警(熔断、对账异常等), Content 每行一条信息
+type FuturesAlertParams struct {
+	Title string
+	Content []string
+}
+
 type Pusher interface {
 	TestPusher()
 	FuturesCustomStrategyTest(params FuturesTestParams)
 	FuturesPositionConvert(params FuturesPositionConvertParams)
+	FuturesAlert(params FuturesAlertParams)
 	
 	FuturesOpenOrder(params FuturesOrderParams)
 	FuturesCloseOrder(params FuturesOrderParams)
@@ -394,4 +394,13 @@ func (pusher Slack) FuturesPositionConvert(params FuturesPositionConvertParams)
     nowTime(),
   )
   DingDingApi(text)
-}
\ No newline at end of file
+}
+
+func (pusher Slack) FuturesAlert(params FuturesAlertParams) {
+  text := "\n## " + params.Title + "\n"
+  for _, line := range params.Content {
+    text += line + "\n"
+  }
+  text += fmt.Sprintf(lang.LangMatch("{futures.time}：%s\n\n> author <sorry510sf@gmail.com>"), nowTime())
+  SlackApi(text)
+}
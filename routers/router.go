@@ -18,6 +18,7 @@ func init() {
 	web.Router("/features/enable/:flag", &controllers.FeatureController{}, "put:UpdateEnable") // 修改所有的合约交易对开启关闭
 	web.Router("/features/batch", &controllers.FeatureController{}, "put:BatchEdit") // 修改所有的合约交易
 	web.Router("/features/strategy-rule/test/:id", &controllers.FeatureController{}, "post:TestStrategyRule") // 测试策略规则
+	web.Router("/features/strategy-rule/validate/:id", &controllers.FeatureController{}, "post:ValidateStrategyRule") // 检查策略规则, 返回每个子表达式的值
 	
 	web.Router("/test-strategy-results", &controllers.TestStrategyResultController{}, "get:Get;delete:DeleteAll") // 测试策略的下单和平仓
 	web.Router("/test-strategy-results/:id", &controllers.TestStrategyResultController{}, "delete:Delete") // 删除某个测试策略结果
@@ -55,6 +56,10 @@ func init() {
 	web.Router("/futures/local/positions", &controllers.AccountController{}, "get:GetLocalFuturesPositions") // 获取本地存储的合约持仓信息
 	web.Router("/futures/local/positions/:id", &controllers.AccountController{}, "put:EditLocalFuturesPositions;delete:DelLocalFuturesPositions") // 修复和删除本地存储的合约持仓信息
 	web.Router("/futures/local/open-orders", &controllers.AccountController{}, "get:GetLocalFuturesOpenOrders") // 获取本地存储的挂单信息
+	web.Router("/futures/reconcile", &controllers.ReconcileController{}, "get:Get;post:Post") // 本地持仓/挂单和交易所的对账记录, 立即对账
+	web.Router("/futures/ws/user-data", &controllers.WsController{}, "get:UserData") // 用户数据 ws 的连接状态
+	web.Router("/futures/ws/market", &controllers.WsController{}, "get:Market") // 合约和现货行情 ws 的连接状态
+	web.Router("/futures/klines", &controllers.KlineController{}, "get:Get") // 获取本地存储的k线
 	
 	web.Router("/fund-rate/eat", &controllers.EatRateController{}, "get:Get;post:Post") // 列表查询和新增
 	web.Router("/fund-rate/eat/:id", &controllers.EatRateController{}, "delete:Delete;put:Edit") // 更新和删除
@@ -64,6 +69,21 @@ func init() {
 	web.Router("/strategy-templates", &controllers.StrategyTemplateController{}, "get:Get;post:Post") // 策略模板
 	web.Router("/strategy-templates/:id", &controllers.StrategyTemplateController{}, "delete:Delete;put:Edit") // 策略模板更新
 	web.Router("/strategy-templates/test/:symbol", &controllers.StrategyTemplateController{}, "post:TestStrategyRule") // 测试策略规则
+	web.Router("/strategy-templates/versions/:id", &controllers.StrategyTemplateController{}, "get:Versions") // 模板的版本
+	web.Router("/strategy-templates/diff/:id", &controllers.StrategyTemplateController{}, "get:Diff") // 比较模板的版本 ?from=1&to=2
+	web.Router("/strategy-templates/revert/:id/:version", &controllers.StrategyTemplateController{}, "post:Revert") // 模板恢复到某个版本
+	web.Router("/strategy-templates/rollout/:id", &controllers.StrategyTemplateController{}, "post:Rollout") // 发布模板的版本到币种
+	web.Router("/strategy-templates/rollouts/:id", &controllers.StrategyTemplateController{}, "get:Rollouts") // 发布记录
+	web.Router("/strategy-templates/rollback/:id", &controllers.StrategyTemplateController{}, "post:Rollback") // 回滚发布
+	
+	web.Router("/bundle/export", &controllers.BundleController{}, "get:Export") // 导出配置包
+	web.Router("/bundle/import", &controllers.BundleController{}, "post:Import") // 导入配置包 ?mode=preview|merge|overwrite
+	
+	web.Router("/backtest", &controllers.BacktestController{}, "post:Post") // 回测交易策略
+	web.Router("/backtest/optimize", &controllers.BacktestController{}, "get:OptimizeJobs;post:Optimize") // 策略模板参数优化(后台任务)
+	web.Router("/backtest/optimize/:id", &controllers.BacktestController{}, "get:OptimizeJob") // 优化的进度和结果
+	web.Router("/backtest/optimize/cancel/:id", &controllers.BacktestController{}, "post:CancelOptimize") // 取消优化
+	web.Router("/backtest/optimize/save/:id", &controllers.BacktestController{}, "post:SaveOptimize") // 保存为模板的新版本
 	
 	web.Router("/strategy-freeze", &controllers.StrategyFreezeController{}, "get:Get;post:Post") // 策略冻结配置
 	web.Router("/strategy-freeze/:id", &controllers.StrategyFreezeController{}, "delete:Delete;put:Edit") // 更新策略冻结配置
@@ -71,6 +91,17 @@ func init() {
 	web.Router("/strategy-freeze/unfreeze", &controllers.StrategyFreezeController{}, "post:Unfreeze") // 手动解除冻结
 	web.Router("/strategy-freeze/reset-loss", &controllers.StrategyFreezeController{}, "post:ResetLossCount") // 重置亏损次数
 	web.Router("/strategy-freeze/options", &controllers.StrategyFreezeController{}, "get:Options") // 获取选项
+	web.Router("/strategy-freeze/history", &controllers.StrategyFreezeController{}, "get:History") // 冻结历史
+	
+	web.Router("/futures/grids", &controllers.GridController{}, "get:Get;post:Post") // 合约网格
+	web.Router("/futures/grids/:id", &controllers.GridController{}, "delete:Delete;put:Edit") // 更新和删除合约网格
+	web.Router("/futures/grids/start/:id", &controllers.GridController{}, "post:Start") // 启动合约网格
+	web.Router("/futures/grids/stop/:id", &controllers.GridController{}, "post:Stop") // 停止合约网格
+	web.Router("/futures/grids/orders/:id", &controllers.GridController{}, "get:Orders") // 合约网格的挂单记录
+	
+	web.Router("/circuit-breaker", &controllers.CircuitBreakerController{}, "get:Get") // 账户熔断状态和记录
+	web.Router("/circuit-breaker/trip", &controllers.CircuitBreakerController{}, "post:Trip") // 手动熔断
+	web.Router("/circuit-breaker/rearm", &controllers.CircuitBreakerController{}, "post:Rearm") // 手动恢复开仓
 	
 	web.Router("/start", &controllers.CommandController{}, "post:Start") // start
 	web.Router("/stop", &controllers.CommandController{}, "post:Stop") // stop
@@ -2,15 +2,12 @@ package binance
 
 import (
 	"context"
-	"go_binance_futures/models"
 	"go_binance_futures/utils"
 	"sort"
 	"strconv"
-	"time"
 
 	"github.com/adshao/go-binance/v2"
 	"github.com/beego/beego/v2/adapter/logs"
-	"github.com/beego/beego/v2/client/orm"
 	"github.com/beego/beego/v2/core/config"
 )
 
@@ -231,62 +228,4 @@ func GetOrder(orderParams OrderParams) (res *binance.Order, err error) {
 		return nil, err
 	}
 	return res, err
-}
-
-// websocket 订阅全市场最新价格变化，只有币价格变化才会推送(24小时变化)
-// @doc https://developers.binance.com/docs/zh-CN/binance-spot-api-docs/web-socket-streams#%E6%8C%89symbol%E7%9A%84%E5%AE%8C%E6%95%B4ticker
-var flagWsSpot = 0
-func UpdateCoinByWs(systemConfig *models.Config, retryNum int64) {
-	if retryNum > 0 {
-		logs.Info("spot ws restart num:", retryNum)
-	}
-	
-	// binance.BaseWsMainURL = "wss://testnet.binance.vision/ws"
-	var lock = false
-	var o = orm.NewOrm()
-	_, _, err := binance.WsAllMarketsStatServe(func(event binance.WsAllMarketsStatEvent) {
-		if (systemConfig.WsSpotEnable == 1) {
-			if (flagWsSpot == 0) {
-				logs.Info("spot ws start")
-				flagWsSpot = 1
-			}
-		} else {
-			if (flagWsSpot == 1) {
-				logs.Info("spot ws stop")
-				flagWsSpot = 0
-			}
-			lock = false
-			return
-		}
-		if !lock {
-			lock = true
-			for _, ticker := range event {
-				o.Raw(
-					"UPDATE `spot_symbols` set `percentChange` = ?, `close` = ?, `open` = ?, `low` = ?, `high` = ?, `updateTime` = ?, `baseVolume` = ?, `quoteVolume` = ?, `closeQty` = ?,  `tradeCount` = ?, `lastClose` = close, `lastUpdateTime` = updateTime WHERE `symbol` = ?",
-					ticker.PriceChangePercent,
-					ticker.LastPrice, // 当前价格
-					ticker.OpenPrice,
-					ticker.LowPrice,
-					ticker.HighPrice,
-					ticker.Time,
-					ticker.BaseVolume, // 成交量
-					ticker.QuoteVolume, // 成交额
-					ticker.CloseQty, // 最新成交价格上的成交量
-					ticker.Count, // 成交数
-					
-					ticker.Symbol,
-				).Exec()
-			}
-			lock = false
-		}
-	}, func(err error) {
-		logs.Error("spot ws run error:", err.Error())
-		UpdateCoinByWs(systemConfig, retryNum + 1)
-	})
-	if err != nil {
-		logs.Error("spot ws start error:", err.Error())
-		time.Sleep(time.Second * 60 * 3) // 3min retry
-		UpdateCoinByWs(systemConfig, retryNum + 1)
-		return
-	}
 }
\ No newline at end of file
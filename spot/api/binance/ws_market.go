@@ -0,0 +1,72 @@
+package binance
+
+import (
+	"encoding/json"
+	fu "go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+
+	"github.com/adshao/go-binance/v2"
+	"github.com/beego/beego/v2/adapter/logs"
+)
+
+// 现货行情, 使用合约的行情 ws 管理(多路复用, 退避重连, 内存缓存, 批量写入数据库)
+// @doc https://developers.binance.com/docs/zh-CN/binance-spot-api-docs/web-socket-streams#%E6%8C%89symbol%E7%9A%84%E5%AE%8C%E6%95%B4ticker
+var spotPrices = fu.NewPriceCache()
+var spotMarket = fu.NewMarketStream("spot", func() string {
+	if binance.UseTestnet {
+		return binance.BaseCombinedTestnetURL
+	}
+	return binance.BaseCombinedMainURL
+}, HandleSpotMarketMessage)
+
+// 分发现货行情推送
+func HandleSpotMarketMessage(stream string, data []byte) {
+	if stream != "!ticker@arr" {
+		return
+	}
+	var event binance.WsAllMarketsStatEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		logs.Error("spot ticker parse error:", err.Error())
+		return
+	}
+	for _, ticker := range event {
+		spotPrices.SetTicker(fu.MarketPrice{
+			Symbol: ticker.Symbol,
+			Close: ticker.LastPrice, // 当前价格
+			Open: ticker.OpenPrice,
+			High: ticker.HighPrice,
+			Low: ticker.LowPrice,
+			PercentChange: ticker.PriceChangePercent,
+			BaseVolume: ticker.BaseVolume,
+			QuoteVolume: ticker.QuoteVolume,
+			CloseQty: ticker.CloseQty,
+			TradeCount: ticker.Count,
+			UpdateTime: ticker.Time,
+			BidPrice: ticker.BidPrice,
+			BidQty: ticker.BidQty,
+			AskPrice: ticker.AskPrice,
+			AskQty: ticker.AskQty,
+		})
+	}
+}
+
+// 启动现货行情 ws, 首页开启 websocket 时定时批量写入 spot_symbols 表
+func StartSpotMarket(systemConfig *models.Config) {
+	spotMarket.Subscribe("!ticker@arr")
+	fu.RunPriceFlush("spot", spotPrices, "spot_symbols", func() bool {
+		return systemConfig.WsSpotEnable == 1
+	})
+}
+
+// ws 推送的最新行情, 超过 60 秒没有推送时返回 false
+func GetSpotPrice(symbol string) (fu.MarketPrice, bool) {
+	return spotPrices.Get(symbol)
+}
+
+func GetSpotLatestPrice(symbol string) (float64, bool) {
+	return spotPrices.GetLatestPrice(symbol)
+}
+
+func GetSpotMarketHealth() fu.MarketStreamHealth {
+	return spotMarket.Health()
+}
@@ -1,22 +1,17 @@
 package technology
 
+// 技术指标配置, 新的格式为列表 [{"type": "ema", "name": "ema1", "kline_interval": "1h", "params": {"period": 14}}]
+// 兼容旧的格式 {"ema": [{"name": "ema1", "kline_interval": "1h", "period": 14, "enable": true}]}
 type IndicatorConfig struct {
+	Type          string  `json:"type"` // 指标类型, 见 GET /features-options 返回的 indicators
 	Name          string  `json:"name"` // 指标名称
-	Enable		  bool    `json:"enable"` // 是否启用
+	Enable        *bool   `json:"enable,omitempty"` // 是否启用, 不填写时启用
 	KlineInterval string  `json:"kline_interval"` // K线周期
-	Period        int     `json:"period"` // 周期
-	Multiplier    float64 `json:"multiplier,omitempty"` // 可选字段
-	StdDevMultiplier float64 `json:"std_dev_multiplier,omitempty"` // 可选字段
+	Params        map[string]float64 `json:"params,omitempty"` // 指标参数, 没有填写的使用默认值
 }
 
-// 顶层技术指标配置结构
-type TechnologyConfig struct {
-	MA       []IndicatorConfig     `json:"ma"`   // 简单移动平均线
-	EMA      []IndicatorConfig     `json:"ema"`  // 指数移动平均线
-	RSI      []IndicatorConfig     `json:"rsi"`  // 相对强弱指数
-	KC       []IndicatorConfig     `json:"kc"`   // 肯特纳通道
-	BOLL     []IndicatorConfig     `json:"boll"` // 布林带
-	ATR      []IndicatorConfig     `json:"atr"` // 平均真实波幅
+func (c IndicatorConfig) IsEnable() bool {
+	return c.Enable == nil || *c.Enable
 }
 
 type StrategyConfig [] struct {
@@ -24,4 +19,4 @@ type StrategyConfig [] struct {
 	Enable bool   `json:"enable"` // 是否启用 
 	Code   string `json:"code"`   // 自定义规则的表达式
 	Type   string `json:"type"`   // long,short,close_long,close_short
-}
\ No newline at end of file
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"go_binance_futures/feature/backtest"
+	"strconv"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBacktest(t *testing.T) {
+	Convey("replay exchange", t, func() {
+		start := int64(1704067200000) // 2024-01-01 00:00:00 UTC
+		klines := []*futures.Kline{}
+		for i := 0; i < 10; i++ {
+			price := strconv.Itoa(100 + i)
+			klines = append(klines, &futures.Kline{
+				OpenTime: start + int64(i) * 60000,
+				Open: price,
+				High: strconv.Itoa(101 + i),
+				Low: strconv.Itoa(99 + i),
+				Close: price,
+				Volume: "1",
+				CloseTime: start + int64(i + 1) * 60000 - 1,
+			})
+		}
+		replay, err := backtest.NewReplayExchange("1m", map[string][]*futures.Kline{"BTCUSDT": klines}, 1000)
+		So(err, ShouldBeNil)
+		timeline := replay.Timeline()
+		So(len(timeline), ShouldEqual, 10)
+		for _, openTime := range timeline[:7] {
+			replay.Step(openTime)
+		}
+		So(replay.NowTime(), ShouldEqual, start + 7 * 60000 - 1)
+		
+		// 只能获取到当前回放时间之前的k线
+		items, err := replay.GetKlineData("BTCUSDT", "1m", 100)
+		So(err, ShouldBeNil)
+		So(len(items), ShouldEqual, 7)
+		
+		// 5m 由 1m 合成, 最后一根未完成
+		items, err = replay.GetKlineData("BTCUSDT", "5m", 100)
+		So(err, ShouldBeNil)
+		So(len(items), ShouldEqual, 2)
+		So(items[0].Open, ShouldEqual, "100")
+		So(items[0].Close, ShouldEqual, "104")
+		So(items[0].High, ShouldEqual, "105")
+		So(items[1].Close, ShouldEqual, "106")
+		
+		prices, _ := replay.GetTickerPrice("BTCUSDT")
+		So(prices[0].Price, ShouldEqual, "106")
+	})
+	
+	Convey("stats", t, func() {
+		trades := []backtest.Trade{{Profit: 30}, {Profit: -10}, {Profit: 20}, {Profit: -15}}
+		equity := []backtest.EquityPoint{
+			{Time: 0, Equity: 1000},
+			{Time: 1, Equity: 1100},
+			{Time: 2, Equity: 990},
+			{Time: 3, Equity: 1025},
+		}
+		stats := backtest.CalculateStats(trades, equity, 1000)
+		So(stats.TradeCount, ShouldEqual, 4)
+		So(stats.WinRate, ShouldEqual, 50)
+		So(stats.ProfitFactor, ShouldEqual, 2)
+		So(stats.MaxDrawdown, ShouldAlmostEqual, 10)
+		So(stats.TotalProfit, ShouldEqual, 25)
+	})
+
+	Convey("limits", t, func() {
+		day := int64(24 * 3600 * 1000)
+		params := backtest.Params{StartTime: 0, EndTime: day * 30}
+		So(backtest.CheckLimits(params, 5), ShouldBeNil)
+		So(backtest.CheckLimits(params, 21), ShouldNotBeNil)
+		params.EndTime = day * 91
+		So(backtest.CheckLimits(params, 1), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConfigBundle(t *testing.T) {
+	bundle := feature.ConfigBundle{
+		Version: feature.BundleFormatVersion,
+		ExportTime: 1700000000000,
+		StrategyTemplates: []feature.BundleTemplate{
+			{Name: "ema", TemplateContent: feature.TemplateContent{Technology: `[{"type":"ema","name":"ema1","kline_interval":"1h"}]`, TrailingCallback: 5}},
+		},
+		Symbols: []feature.BundleSymbol{
+			{Symbol: "BTCUSDT", Enable: 1, Leverage: 3, MarginType: "CROSSED", Usdt: "10", StrategyType: "custom", Template: "ema"},
+		},
+		StrategyFreeze: []feature.BundleStrategyFreeze{
+			{Symbol: "BTCUSDT", StrategyName: "long1", TradeType: "futures", FreezeOnLossCount: 3, FreezeHours: 2},
+		},
+	}
+	
+	Convey("encode and decode bundle", t, func() {
+		for _, format := range []string{"json", "yaml"} {
+			data, err := feature.EncodeConfigBundle(bundle, format)
+			So(err, ShouldBeNil)
+			if format == "yaml" {
+				So(string(data), ShouldContainSubstring, "strategy_templates:")
+				So(strings.HasPrefix(string(data), "{"), ShouldBeFalse)
+			}
+			decoded, err := feature.DecodeConfigBundle(data)
+			So(err, ShouldBeNil)
+			So(decoded.Version, ShouldEqual, bundle.Version)
+			So(decoded.StrategyTemplates[0].Name, ShouldEqual, "ema")
+			So(decoded.StrategyTemplates[0].Technology, ShouldEqual, bundle.StrategyTemplates[0].Technology)
+			So(decoded.Symbols[0], ShouldResemble, bundle.Symbols[0])
+			So(decoded.StrategyFreeze[0], ShouldResemble, bundle.StrategyFreeze[0])
+			So(len(decoded.ListenSymbols), ShouldEqual, 0)
+		}
+	})
+	
+	Convey("decode invalid bundle", t, func() {
+		_, err := feature.DecodeConfigBundle([]byte(""))
+		So(err, ShouldNotBeNil)
+		_, err = feature.DecodeConfigBundle([]byte(`{"version": 99}`))
+		So(err, ShouldNotBeNil)
+		_, err = feature.DecodeConfigBundle([]byte("version: [1"))
+		So(err, ShouldNotBeNil)
+	})
+	
+	Convey("diff bundle item", t, func() {
+		local := bundle.Symbols[0]
+		So(len(feature.DiffBundleItem(local, bundle.Symbols[0])), ShouldEqual, 0)
+		
+		local.Leverage = 5
+		local.Usdt = "20"
+		conflicts := feature.DiffBundleItem(local, bundle.Symbols[0])
+		So(len(conflicts), ShouldEqual, 2)
+		So(conflicts[0].Field, ShouldEqual, "leverage")
+		So(conflicts[0].Local, ShouldEqual, 5)
+		So(conflicts[0].Bundle, ShouldEqual, 3)
+		So(conflicts[1].Field, ShouldEqual, "usdt")
+	})
+}
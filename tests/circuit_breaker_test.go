@@ -0,0 +1,57 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	Convey("熔断的阈值", t, func() {
+		limits := feature.BreakerLimits{DailyLoss: 100, WeeklyLoss: 300, MaxDrawdown: 10}
+		So(limits.Enable(), ShouldBeTrue)
+		So(feature.BreakerLimits{}.Enable(), ShouldBeFalse)
+		
+		So(feature.EvaluateBreaker(limits, -50, -200, 5), ShouldEqual, "")
+		So(feature.EvaluateBreaker(limits, 500, 500, 0), ShouldEqual, "")
+		So(feature.EvaluateBreaker(limits, -100, -100, 0), ShouldStartWith, "daily loss")
+		So(feature.EvaluateBreaker(limits, -10, -300, 0), ShouldStartWith, "weekly loss")
+		So(feature.EvaluateBreaker(limits, 0, 0, 12.5), ShouldStartWith, "drawdown")
+		// 为 0 时不限制
+		So(feature.EvaluateBreaker(feature.BreakerLimits{WeeklyLoss: 300}, -1000, -200, 50), ShouldEqual, "")
+	})
+	
+	Convey("当天和本周的开始时间(UTC, 周一开始)", t, func() {
+		// 2024-01-10 是周三
+		now := time.Date(2024, 1, 10, 15, 30, 0, 0, time.UTC)
+		dayStart, weekStart := feature.BreakerPeriodStart(now)
+		So(dayStart, ShouldEqual, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC).UnixMilli())
+		So(weekStart, ShouldEqual, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC).UnixMilli())
+		
+		// 周日属于上一周
+		_, weekStart = feature.BreakerPeriodStart(time.Date(2024, 1, 14, 23, 0, 0, 0, time.UTC))
+		So(weekStart, ShouldEqual, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC).UnixMilli())
+		
+		// 其他时区转换为 UTC
+		local := time.Date(2024, 1, 15, 2, 0, 0, 0, time.FixedZone("UTC+8", 8 * 3600))
+		dayStart, weekStart = feature.BreakerPeriodStart(local)
+		So(dayStart, ShouldEqual, time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC).UnixMilli())
+		So(weekStart, ShouldEqual, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC).UnixMilli())
+	})
+	
+	Convey("权益最高点和回撤", t, func() {
+		highWater, drawdown := feature.UpdateHighWater(0, 1000)
+		So(highWater, ShouldEqual, 1000)
+		So(drawdown, ShouldEqual, 0)
+		
+		highWater, drawdown = feature.UpdateHighWater(highWater, 900)
+		So(highWater, ShouldEqual, 1000)
+		So(drawdown, ShouldAlmostEqual, 10)
+		
+		highWater, drawdown = feature.UpdateHighWater(highWater, 1200)
+		So(highWater, ShouldEqual, 1200)
+		So(drawdown, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"go_binance_futures/models"
+	"go_binance_futures/utils"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestFreezeRules(t *testing.T) {
+	now := int64(1700000000)
+	trades := []models.StrategyFreezeTrade{ // 新的在前
+		{Profit: -30, CreatedAt: now - 60},
+		{Profit: 20, CreatedAt: now - 3600},
+		{Profit: -40, CreatedAt: now - 7200},
+		{Profit: -50, CreatedAt: now - 30 * 3600},
+	}
+	
+	Convey("连续亏损次数", t, func() {
+		freeze := models.StrategyFreeze{FreezeOnLossCount: 3, LossCount: 3}
+		rule, _ := utils.EvaluateFreezeRules(freeze, nil, now)
+		So(rule, ShouldEqual, utils.FreezeRuleLossCount)
+		
+		freeze.LossCount = 2
+		rule, _ = utils.EvaluateFreezeRules(freeze, nil, now)
+		So(rule, ShouldEqual, "")
+	})
+	
+	Convey("解冻后重新统计连续亏损次数", t, func() {
+		freeze := models.StrategyFreeze{FreezeOnLossCount: 3, LossCount: 3}
+		utils.StartFreeze(&freeze, 2, now)
+		So(freeze.LossCount, ShouldEqual, 0)
+		So(freeze.FreezeUntil, ShouldEqual, now + 7200)
+		
+		// 解冻后的第一次亏损不会再次冻结
+		freeze.LossCount++
+		rule, _ := utils.EvaluateFreezeRules(freeze, nil, freeze.FreezeUntil + 60)
+		So(rule, ShouldEqual, "")
+	})
+	
+	Convey("窗口内累计亏损", t, func() {
+		freeze := models.StrategyFreeze{LossAmount: 70}
+		rule, detail := utils.EvaluateFreezeRules(freeze, trades, now) // 默认 24h 内亏损 70
+		So(rule, ShouldEqual, utils.FreezeRuleLossAmount)
+		So(detail, ShouldContainSubstring, "70.00")
+		
+		freeze.LossAmount = 100
+		rule, _ = utils.EvaluateFreezeRules(freeze, trades, now)
+		So(rule, ShouldEqual, "")
+		
+		freeze.LossWindowHours = 48 // 48h 内亏损 120
+		rule, _ = utils.EvaluateFreezeRules(freeze, trades, now)
+		So(rule, ShouldEqual, utils.FreezeRuleLossAmount)
+	})
+	
+	Convey("最近 N 笔交易中的亏损次数", t, func() {
+		freeze := models.StrategyFreeze{RecentTrades: 3, RecentLossCount: 2}
+		rule, _ := utils.EvaluateFreezeRules(freeze, trades, now)
+		So(rule, ShouldEqual, utils.FreezeRuleRecentLoss)
+		
+		freeze.RecentLossCount = 3
+		rule, _ = utils.EvaluateFreezeRules(freeze, trades, now)
+		So(rule, ShouldEqual, "")
+		
+		freeze.RecentTrades = 4
+		rule, _ = utils.EvaluateFreezeRules(freeze, trades, now)
+		So(rule, ShouldEqual, utils.FreezeRuleRecentLoss)
+	})
+	
+	Convey("重复冻结升级", t, func() {
+		freeze := models.StrategyFreeze{FreezeHours: 2}
+		So(utils.FreezeDuration(freeze, 3), ShouldEqual, 2)
+		
+		freeze.EscalateFactor = 2
+		So(utils.FreezeDuration(freeze, 0), ShouldEqual, 2)
+		So(utils.FreezeDuration(freeze, 1), ShouldEqual, 4)
+		So(utils.FreezeDuration(freeze, 3), ShouldEqual, 16)
+		
+		freeze.MaxFreezeHours = 10
+		So(utils.FreezeDuration(freeze, 3), ShouldEqual, 10)
+	})
+}
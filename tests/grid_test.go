@@ -0,0 +1,40 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGrid(t *testing.T) {
+	Convey("grid prices and open levels", t, func() {
+		grid := models.FuturesGrid{Mode: feature.GridModeNeutral, LowerPrice: 100, UpperPrice: 200, GridCount: 4}
+		prices := feature.GetGridPrices(grid, "0.01")
+		So(prices, ShouldResemble, []float64{100, 125, 150, 175, 200})
+
+		longLevels, shortLevels := feature.GetGridOpenLevels(grid, prices, 160)
+		So(longLevels, ShouldResemble, []int{0, 1, 2})
+		So(shortLevels, ShouldResemble, []int{3, 4})
+
+		grid.Mode = feature.GridModeLong
+		longLevels, shortLevels = feature.GetGridOpenLevels(grid, prices, 160)
+		So(longLevels, ShouldResemble, []int{0, 1, 2})
+		So(shortLevels, ShouldBeEmpty)
+
+		grid.UpperPrice = 100 // 无效的区间
+		So(feature.GetGridPrices(grid, ""), ShouldBeEmpty)
+	})
+
+	Convey("grid profit", t, func() {
+		So(feature.GetGridProfit("LONG", 100, 125, 0.1), ShouldAlmostEqual, 2.5, 0.000001)
+		So(feature.GetGridProfit("SHORT", 125, 100, 0.1), ShouldAlmostEqual, 2.5, 0.000001)
+	})
+
+	Convey("grid remain quantity after partial fill", t, func() {
+		So(feature.GetGridRemainQuantity(0.3, 0.1, "0.001"), ShouldAlmostEqual, 0.2, 0.000001)
+		So(feature.GetGridRemainQuantity(0.3, 0, "0.001"), ShouldAlmostEqual, 0.3, 0.000001)
+		So(feature.GetGridRemainQuantity(0.3, 0.3, "0.001"), ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/utils"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIndicator(t *testing.T) {
+	// 数据时间由新到旧, 价格持续上涨
+	size := 120
+	high, low, close, volume := make([]float64, size), make([]float64, size), make([]float64, size), make([]float64, size)
+	openTime := make([]int64, size)
+	for i := 0; i < size; i++ {
+		price := float64(200 - i)
+		close[i] = price
+		high[i] = price + 1
+		low[i] = price - 1
+		volume[i] = 10
+		openTime[i] = int64(size - i) * 3600000
+	}
+
+	Convey("reverse array", t, func() {
+		// 奇数长度时保留中间的元素
+		So(utils.ReverseArray([]float64{1, 2, 3}), ShouldResemble, []float64{3, 2, 1})
+		So(utils.ReverseArray([]float64{1, 2}), ShouldResemble, []float64{2, 1})
+	})
+
+	Convey("macd kdj stoch rsi", t, func() {
+		dif, dea, hist, err := line.CalculateMacd(close, 12, 26, 9)
+		So(err, ShouldBeNil)
+		So(len(dif), ShouldEqual, size)
+		So(dif[0], ShouldBeGreaterThan, 0)
+		So(hist[0], ShouldAlmostEqual, dif[0] - dea[0], 0.000001)
+
+		k, d, j, err := line.CalculateKdj(high, low, close, 9, 3, 3)
+		So(err, ShouldBeNil)
+		So(len(k), ShouldEqual, size - 8)
+		So(k[0], ShouldBeGreaterThan, 80)
+		So(j[0], ShouldAlmostEqual, 3 * k[0] - 2 * d[0], 0.000001)
+
+		_, _, err = line.CalculateStochRsi(close[:10], 14, 14, 3, 3)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("adx super trend", t, func() {
+		adx, plusDi, minusDi, err := line.CalculateAdx(high, low, close, 14)
+		So(err, ShouldBeNil)
+		So(plusDi[0], ShouldBeGreaterThan, minusDi[0])
+		So(adx[0], ShouldBeGreaterThan, 50)
+
+		trend, _, lower, direction, err := line.CalculateSuperTrend(high, low, close, 10, 3)
+		So(err, ShouldBeNil)
+		So(direction[0], ShouldEqual, 1)
+		So(trend[0], ShouldEqual, lower[0])
+		So(trend[0], ShouldBeLessThan, close[0])
+	})
+
+	Convey("vwap obv ichimoku", t, func() {
+		vwap, err := line.CalculateVwap([]float64{12, 11}, []float64{8, 9}, []float64{10, 10}, []float64{1, 3}, []int64{3600000, 0})
+		So(err, ShouldBeNil)
+		So(vwap, ShouldResemble, []float64{10, 10})
+
+		obv, err := line.CalculateObv([]float64{3, 2, 2, 1}, []float64{5, 4, 3, 2})
+		So(err, ShouldBeNil)
+		So(obv, ShouldResemble, []float64{8, 3, 3, 0})
+
+		conversion, base, spanA, spanB, err := line.CalculateIchimoku(high, low, 9, 26, 52)
+		So(err, ShouldBeNil)
+		So(conversion[0], ShouldEqual, 196)
+		So(base[0], ShouldEqual, 187.5)
+		So(spanA[0], ShouldEqual, (conversion[26] + base[26]) / 2)
+		So(len(spanB), ShouldEqual, size - 52 + 1 - 26)
+	})
+}
+
+func TestIndicatorRegistry(t *testing.T) {
+	Convey("parse indicator configs", t, func() {
+		// 旧的格式
+		configs, err := line.ParseIndicatorConfigs(`{"ma":[{"name":"ma1","kline_interval":"1h","period":20,"enable":true}],"boll":[{"name":"boll1","kline_interval":"4h","period":21,"std_dev_multiplier":2,"enable":false}]}`)
+		So(err, ShouldBeNil)
+		So(len(configs), ShouldEqual, 2)
+		So(configs[0].Type, ShouldEqual, "ma")
+		So(configs[0].Name, ShouldEqual, "ma1")
+		So(configs[0].Params["period"], ShouldEqual, 20)
+		So(configs[0].IsEnable(), ShouldBeTrue)
+		So(configs[1].Type, ShouldEqual, "boll")
+		So(configs[1].IsEnable(), ShouldBeFalse)
+
+		// 通用格式
+		configs, err = line.ParseIndicatorConfigs(`[{"type":"macd","name":"macd1","kline_interval":"1h","params":{"fast_period":10}}]`)
+		So(err, ShouldBeNil)
+		So(configs[0].IsEnable(), ShouldBeTrue)
+		So(configs[0].Params["fast_period"], ShouldEqual, 10)
+	})
+
+	Convey("compute with default params", t, func() {
+		indicator, ok := line.GetIndicator("macd")
+		So(ok, ShouldBeTrue)
+		close := make([]float64, 100)
+		for i := range close {
+			close[i] = float64(200 - i)
+		}
+		data, err := line.ComputeIndicator(indicator, line.KLinePrice{Close: close}, map[string]float64{"fast_period": 10})
+		So(err, ShouldBeNil)
+		So(data.Params["fast_period"], ShouldEqual, 10)
+		So(data.Params["slow_period"], ShouldEqual, 26)
+		So(len(data.Data), ShouldEqual, 100)
+
+		boll, _ := line.GetIndicator("boll")
+		data, err = line.ComputeIndicator(boll, line.KLinePrice{Close: close}, map[string]float64{"std_dev_multiplier": 0})
+		So(err, ShouldBeNil)
+		So(data.Params["std_dev_multiplier"], ShouldEqual, 0) // 填写的 0 不使用默认值
+		So(data.Params["period"], ShouldEqual, 21)
+
+		options := line.GetIndicatorOptions()
+		So(len(options), ShouldBeGreaterThanOrEqualTo, 14)
+		So(options[0].Type, ShouldEqual, "ma")
+	})
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"fmt"
+	"go_binance_futures/feature/api/binance"
+	"strings"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func testKlines(start int64, count int) (klines []*futures.Kline) {
+	for i := 0; i < count; i++ {
+		openTime := start + int64(i) * 60000
+		klines = append(klines, &futures.Kline{OpenTime: openTime, CloseTime: openTime + 59999, Close: "1"})
+	}
+	return klines
+}
+
+func pushKline(symbol string, openTime int64, close string) {
+	data := fmt.Sprintf(`{"e":"kline","E":%d,"s":"%s","k":{"t":%d,"T":%d,"s":"%s","i":"1m","o":"1","c":"%s","h":"2","l":"0.5","v":"10","n":5,"x":false}}`, openTime, symbol, openTime, openTime + 59999, symbol, close)
+	binance.HandleFuturesMarketMessage(strings.ToLower(symbol) + "@kline_1m", []byte(data))
+}
+
+func TestKlineStore(t *testing.T) {
+	Convey("k线缓存", t, func() {
+		symbol := "KLINETESTUSDT"
+		start := int64(1700000040000)
+
+		// http api 填充的缓存马上可用
+		binance.SetCachedKlineData(symbol, "1m", testKlines(start, 3))
+		klines, ok := binance.GetCachedKlineData(symbol, "1m", 3)
+		So(ok, ShouldBeTrue)
+		So(len(klines), ShouldEqual, 3)
+		So(klines[2].OpenTime, ShouldEqual, start + 120000)
+		_, ok = binance.GetCachedKlineData(symbol, "1m", 4)
+		So(ok, ShouldBeFalse)
+
+		// 推送最后一根更新, 下一根追加
+		pushKline(symbol, start + 120000, "2")
+		pushKline(symbol, start + 180000, "3")
+		klines, ok = binance.GetCachedKlineData(symbol, "1m", 4)
+		So(ok, ShouldBeTrue)
+		So(klines[2].Close, ShouldEqual, "2")
+		So(klines[3].Close, ShouldEqual, "3")
+
+		// 中间缺失k线时缓存不可用
+		pushKline(symbol, start + 300000, "5")
+		_, ok = binance.GetCachedKlineData(symbol, "1m", 1)
+		So(ok, ShouldBeFalse)
+
+		// http api 重新填充缺失的k线, 保留更新的 ws k线
+		binance.SetCachedKlineData(symbol, "1m", testKlines(start, 5))
+		klines, ok = binance.GetCachedKlineData(symbol, "1m", 6)
+		So(ok, ShouldBeTrue)
+		So(klines[4].OpenTime, ShouldEqual, start + 240000)
+		So(klines[5].OpenTime, ShouldEqual, start + 300000)
+		So(klines[5].Close, ShouldEqual, "5")
+	})
+}
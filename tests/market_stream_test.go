@@ -0,0 +1,78 @@
+package test
+
+import (
+	"go_binance_futures/feature/api/binance"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPriceCache(t *testing.T) {
+	Convey("行情缓存", t, func() {
+		cache := binance.NewPriceCache()
+		_, ok := cache.Get("BTCUSDT")
+		So(ok, ShouldBeFalse)
+
+		cache.SetTicker(binance.MarketPrice{Symbol: "BTCUSDT", Close: "60000.5", TradeCount: 10})
+		cache.SetMarkPrice("BTCUSDT", "60001", "60000", "0.0001", 1700000000000)
+		cache.SetBookTicker("ETHUSDT", "3000", "1", "3000.1", "2")
+
+		price, ok := cache.Get("BTCUSDT")
+		So(ok, ShouldBeTrue)
+		So(price.Close, ShouldEqual, "60000.5")
+		So(price.MarkPrice, ShouldEqual, "60001")
+		So(price.FundingRate, ShouldEqual, "0.0001")
+		latest, ok := cache.GetLatestPrice("BTCUSDT")
+		So(ok, ShouldBeTrue)
+		So(latest, ShouldEqual, 60000.5)
+
+		// 只有 ticker 变化的币种需要写入数据库
+		_, ok = cache.GetLatestPrice("ETHUSDT")
+		So(ok, ShouldBeFalse)
+		dirty := cache.TakeDirty()
+		So(len(dirty), ShouldEqual, 1)
+		So(dirty[0].Symbol, ShouldEqual, "BTCUSDT")
+		So(len(cache.TakeDirty()), ShouldEqual, 0)
+	})
+
+	Convey("行情缓存分别记录每种推送的时间", t, func() {
+		cache := binance.NewPriceCache()
+		// 只有标记价格推送时没有最新成交价格
+		cache.SetMarkPrice("BTCUSDT", "60001", "60000", "0.0001", 1700000000000)
+		price, ok := cache.Get("BTCUSDT")
+		So(ok, ShouldBeTrue)
+		So(price.MarkPrice, ShouldEqual, "60001")
+		So(price.Close, ShouldEqual, "")
+		_, ok = cache.GetLatestPrice("BTCUSDT")
+		So(ok, ShouldBeFalse)
+
+		// 标记价格和最优挂单的推送不会刷新 ticker 的时间
+		cache.SetTicker(binance.MarketPrice{Symbol: "BTCUSDT", Close: "60000.5"})
+		price, _ = cache.Get("BTCUSDT")
+		tickerTime := price.TickerTime
+		time.Sleep(time.Millisecond * 5)
+		cache.SetMarkPrice("BTCUSDT", "60002", "60000", "0.0001", 1700000000000)
+		cache.SetBookTicker("BTCUSDT", "60000", "1", "60000.1", "2")
+		price, _ = cache.Get("BTCUSDT")
+		So(price.TickerTime, ShouldEqual, tickerTime)
+		So(price.MarkTime, ShouldBeGreaterThan, tickerTime)
+		So(price.BookTime, ShouldBeGreaterThan, tickerTime)
+		So(price.Close, ShouldEqual, "60000.5")
+	})
+
+	Convey("分发合约行情推送", t, func() {
+		binance.HandleFuturesMarketMessage("!ticker@arr", []byte(`[{"e":"24hrTicker","E":1700000000000,"s":"TESTUSDT","P":"1.5","c":"2.5","Q":"3","o":"2.4","h":"2.6","l":"2.3","v":"100","q":"250","n":42}]`))
+		binance.HandleFuturesMarketMessage("!markPrice@arr@1s", []byte(`[{"e":"markPriceUpdate","E":1700000000000,"s":"TESTUSDT","p":"2.51","i":"2.5","r":"0.0002","T":1700003600000}]`))
+		binance.HandleFuturesMarketMessage("!bookTicker", []byte(`{"e":"bookTicker","s":"TESTUSDT","b":"2.49","B":"10","a":"2.52","A":"20"}`))
+
+		price, ok := binance.GetFuturesPrice("TESTUSDT")
+		So(ok, ShouldBeTrue)
+		So(price.Close, ShouldEqual, "2.5")
+		So(price.TradeCount, ShouldEqual, 42)
+		So(price.MarkPrice, ShouldEqual, "2.51")
+		So(price.NextFundingTime, ShouldEqual, 1700003600000)
+		So(price.BidPrice, ShouldEqual, "2.49")
+		So(price.AskQty, ShouldEqual, "20")
+	})
+}
@@ -0,0 +1,82 @@
+package test
+
+import (
+	"go_binance_futures/feature/api/binance"
+	"strconv"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMockExchange(t *testing.T) {
+	Convey("测试模拟交易所", t, func() {
+		mock := binance.NewMockExchange(10000)
+		mock.SetFeeRate(0)
+		mock.SetPrice("BTCUSDT", 100)
+		binance.SetExchange(mock)
+
+		Convey("市价开多后上涨平仓, 盈利计入余额", func() {
+			_, err := binance.BuyMarket("BTCUSDT", 2, futures.PositionSideTypeLong)
+			So(err, ShouldBeNil)
+
+			positions, _ := binance.GetPosition(binance.PositionParams{Symbol: "BTCUSDT"})
+			So(len(positions), ShouldEqual, 1)
+			So(positions[0].PositionAmt, ShouldEqual, "2")
+			So(positions[0].EntryPrice, ShouldEqual, "100")
+
+			mock.SetPrice("BTCUSDT", 110)
+			_, err = binance.SellMarket("BTCUSDT", 2, futures.PositionSideTypeLong)
+			So(err, ShouldBeNil)
+
+			account, _ := binance.GetFuturesAccount()
+			So(account.TotalWalletBalance, ShouldEqual, "10020")
+		})
+
+		Convey("没有仓位时不能平仓", func() {
+			_, err := binance.SellMarket("BTCUSDT", 1, futures.PositionSideTypeLong)
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("限价单和止损单在价格穿越时成交", func() {
+			order, err := binance.SellLimit("BTCUSDT", 1, 105, futures.PositionSideTypeShort)
+			So(err, ShouldBeNil)
+			So(order.Status, ShouldEqual, futures.OrderStatusTypeNew)
+
+			mock.SetPrice("BTCUSDT", 106)
+			openOrders, _ := binance.GetOpenOrder("BTCUSDT")
+			So(len(openOrders), ShouldEqual, 0)
+
+			stopOrder, err := binance.OrderStopLoss("BTCUSDT", 110, futures.SideTypeBuy, futures.PositionSideTypeShort)
+			So(err, ShouldBeNil)
+			mock.SetPrice("BTCUSDT", 111)
+			filled, _ := binance.GetOrder(binance.OrderParams{Symbol: "BTCUSDT", OrderID: stopOrder.OrderID})
+			So(filled.Status, ShouldEqual, futures.OrderStatusTypeFilled)
+
+			account, _ := binance.GetFuturesAccount()
+			balance, _ := strconv.ParseFloat(account.TotalWalletBalance, 64)
+			So(balance, ShouldAlmostEqual, 10000 - 6, 0.0001)
+		})
+
+		Convey("k 线按时间降序且最新收盘价为当前价格", func() {
+			klines, err := binance.GetKlineData("BTCUSDT", "1h", 50)
+			So(err, ShouldBeNil)
+			So(len(klines), ShouldEqual, 50)
+			So(klines[0].OpenTime, ShouldBeGreaterThan, klines[1].OpenTime)
+			So(klines[0].Close, ShouldEqual, "100")
+		})
+
+		Convey("历史k线和 listenKey 不需要网络", func() {
+			startTime := int64(1700000000000)
+			klines, err := binance.GetHistoryKlineData("BTCUSDT", "1h", startTime, startTime + 3600000 * 10)
+			So(err, ShouldBeNil)
+			So(len(klines), ShouldEqual, 10)
+			So(klines[0].OpenTime, ShouldBeGreaterThanOrEqualTo, startTime)
+			So(klines[1].OpenTime - klines[0].OpenTime, ShouldEqual, 3600000)
+			So(klines[len(klines) - 1].Close, ShouldEqual, "100")
+
+			_, err = binance.GetListenKey()
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
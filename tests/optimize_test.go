@@ -0,0 +1,92 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/feature/backtest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOptimize(t *testing.T) {
+	Convey("walk forward windows", t, func() {
+		windows := backtest.WalkForwardWindows(0, 1300, 4, 0.7)
+		So(len(windows), ShouldEqual, 4)
+		So(windows[0].TrainStart, ShouldEqual, 0)
+		So(windows[0].TrainEnd, ShouldEqual, windows[0].TestStart)
+		for i := 1; i < len(windows); i++ {
+			So(windows[i].TestStart, ShouldEqual, windows[i - 1].TestEnd) // 样本外连续不重叠
+		}
+		So(windows[3].TestEnd, ShouldEqual, 1300)
+	})
+	
+	Convey("generate candidates", t, func() {
+		parameters := []backtest.OptimizeParameter{
+			{Name: "technology.ema1.period", Min: 10, Max: 20, Step: 5},
+			{Name: "trailing_callback", Values: []float64{1, 2}},
+		}
+		candidates, err := backtest.GenerateCandidates(parameters, "grid", 0, 100, 1)
+		So(err, ShouldBeNil)
+		So(len(candidates), ShouldEqual, 6)
+		So(candidates[5]["technology.ema1.period"], ShouldEqual, 20)
+		
+		_, err = backtest.GenerateCandidates(parameters, "grid", 0, 5, 1)
+		So(err, ShouldNotBeNil)
+		
+		candidates, err = backtest.GenerateCandidates(parameters, "random", 4, 100, 1)
+		So(err, ShouldBeNil)
+		So(len(candidates), ShouldEqual, 4)
+		
+		_, err = backtest.GenerateCandidates([]backtest.OptimizeParameter{{Name: "trailing_callback", Min: 1, Max: 2}}, "grid", 0, 100, 1)
+		So(err, ShouldNotBeNil)
+	})
+	
+	Convey("number literals", t, func() {
+		code := `ROI > 10 && ema1.Data[0] > ma2.Data[1] * 1.5 && Sym("BTC2USDT").close > 3`
+		literals := backtest.NumberLiterals(code)
+		So(len(literals), ShouldEqual, 3)
+		So(code[literals[0][0]:literals[0][1]], ShouldEqual, "10")
+		So(code[literals[1][0]:literals[1][1]], ShouldEqual, "1.5")
+		So(code[literals[2][0]:literals[2][1]], ShouldEqual, "3")
+	})
+	
+	Convey("apply optimize values", t, func() {
+		content := feature.TemplateContent{
+			Technology: `[{"type":"ema","name":"ema1","kline_interval":"1h","params":{"period":20}}]`,
+			Strategy: `[{"name":"close1","enable":true,"type":"close_long","code":"ROI > 10 || ROI < -5"}]`,
+			TrailingCallback: 1,
+		}
+		value, err := backtest.GetTemplateParam(content, "strategy.close1.2")
+		So(err, ShouldBeNil)
+		So(value, ShouldEqual, 5)
+		
+		result, err := backtest.ApplyOptimizeValues(content, map[string]float64{
+			"technology.ema1.period": 30,
+			"strategy.close1.1": 15,
+			"trailing_callback": 2,
+		})
+		So(err, ShouldBeNil)
+		value, _ = backtest.GetTemplateParam(result, "technology.ema1.period")
+		So(value, ShouldEqual, 30)
+		So(result.Strategy, ShouldContainSubstring, "ROI > 15 || ROI < -5")
+		So(result.TrailingCallback, ShouldEqual, 2)
+		So(content.TrailingCallback, ShouldEqual, 1)
+		
+		_, err = backtest.ApplyOptimizeValues(content, map[string]float64{"strategy.close1.3": 1})
+		So(err, ShouldNotBeNil)
+		_, err = backtest.ApplyOptimizeValues(content, map[string]float64{"technology.ma1.period": 1})
+		So(err, ShouldNotBeNil)
+	})
+	
+	Convey("rank candidates", t, func() {
+		candidates := []backtest.OptimizeCandidate{
+			{Index: 0, OutSample: 5, Folds: make([]backtest.OptimizeFold, 2)},
+			{Index: 1, OutSample: 9, Folds: make([]backtest.OptimizeFold, 1)},
+			{Index: 2, OutSample: 7, Folds: make([]backtest.OptimizeFold, 2)},
+		}
+		ranked := backtest.RankCandidates(candidates, 2)
+		So(ranked[0].Index, ShouldEqual, 2)
+		So(ranked[1].Index, ShouldEqual, 0)
+		So(ranked[2].Index, ShouldEqual, 1)
+	})
+}
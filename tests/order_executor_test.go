@@ -0,0 +1,122 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/feature/api/binance"
+	"go_binance_futures/models"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/beego/beego/v2/client/orm"
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func init() {
+	orm.RegisterDriver("sqlite", orm.DRSqlite)
+	orm.RegisterDataBase("default", "sqlite3", ":memory:")
+	orm.RegisterModel(new(models.Order), new(models.FuturesCircuitBreaker))
+	orm.RunSyncdb("default", false, false)
+}
+
+func lastOrderRow(symbol string) (order models.Order) {
+	orm.NewOrm().QueryTable("order").Filter("symbol", symbol).OrderBy("-id").One(&order)
+	return order
+}
+
+func TestOrderExecutor(t *testing.T) {
+	Convey("下单执行器", t, func() {
+		mock := binance.NewMockExchange(10000)
+		mock.SetFeeRate(0)
+		mock.SetPrice("EXECUSDT", 100)
+		binance.SetExchange(mock)
+		orm.NewOrm().Raw("DELETE FROM `order`").Exec()
+		executor := feature.NewOrderExecutor(models.Config{})
+
+		Convey("市价开多买入, 按照金额和倍数计算数量, 记录价格向不利方向偏移", func() {
+			_, err := executor.Execute(feature.OrderIntent{
+				Action: feature.OrderActionOpen,
+				Symbol: "EXECUSDT",
+				PositionSide: "LONG",
+				Usdt: 100,
+				Price: 100,
+				Leverage: 2,
+				TickSize: "0.01",
+				StepSize: "0.001",
+			})
+			So(err, ShouldBeNil)
+			positions, _ := binance.GetPosition(binance.PositionParams{Symbol: "EXECUSDT"})
+			So(len(positions), ShouldEqual, 1)
+			So(positions[0].PositionAmt, ShouldEqual, "2")
+
+			order := lastOrderRow("EXECUSDT")
+			So(order.Side, ShouldEqual, "open")
+			So(order.PositionSide, ShouldEqual, "LONG")
+			So(order.Amount, ShouldEqual, "2")
+			So(order.Avg_price, ShouldEqual, "100.12")
+			So(order.Leverage, ShouldEqual, 2)
+			So(order.Inexact_profit, ShouldEqual, "0.0")
+
+			Convey("平多卖出, 记录预估收益和原价格", func() {
+				mock.SetPrice("EXECUSDT", 110)
+				_, err := executor.Execute(feature.OrderIntent{
+					Action: feature.OrderActionClose,
+					Symbol: "EXECUSDT",
+					PositionSide: "LONG",
+					Quantity: 2,
+					Price: 110,
+					Profit: 20,
+				})
+				So(err, ShouldBeNil)
+				positions, _ := binance.GetPosition(binance.PositionParams{Symbol: "EXECUSDT"})
+				So(positions[0].PositionAmt, ShouldEqual, "0")
+
+				order := lastOrderRow("EXECUSDT")
+				So(order.Side, ShouldEqual, "close")
+				So(order.Avg_price, ShouldEqual, "110")
+				So(order.Inexact_profit, ShouldEqual, "20")
+			})
+		})
+
+		Convey("市价开空卖出, 记录价格向下偏移", func() {
+			_, err := executor.Execute(feature.OrderIntent{
+				Action: feature.OrderActionOpen,
+				Symbol: "EXECUSDT",
+				PositionSide: "SHORT",
+				Quantity: 1,
+				Price: 100,
+				TickSize: "0.01",
+			})
+			So(err, ShouldBeNil)
+			positions, _ := binance.GetPosition(binance.PositionParams{Symbol: "EXECUSDT"})
+			So(len(positions), ShouldEqual, 1)
+			So(positions[0].PositionAmt, ShouldEqual, "-1")
+			So(lastOrderRow("EXECUSDT").Avg_price, ShouldEqual, "99.88")
+		})
+
+		Convey("限价开空挂卖单, 记录挂单价格", func() {
+			_, err := executor.Execute(feature.OrderIntent{
+				Action: feature.OrderActionOpen,
+				Symbol: "EXECUSDT",
+				PositionSide: "SHORT",
+				OrderType: "LIMIT",
+				Quantity: 1,
+				Price: 105,
+			})
+			So(err, ShouldBeNil)
+			orders, _ := binance.GetOpenOrder("EXECUSDT")
+			So(len(orders), ShouldEqual, 1)
+			So(orders[0].Side, ShouldEqual, futures.SideTypeSell)
+			So(orders[0].PositionSide, ShouldEqual, futures.PositionSideTypeShort)
+			So(lastOrderRow("EXECUSDT").Avg_price, ShouldEqual, "105")
+		})
+
+		Convey("无效的方向和数量不下单", func() {
+			_, err := executor.Execute(feature.OrderIntent{Action: feature.OrderActionOpen, Symbol: "EXECUSDT", PositionSide: "BOTH", Quantity: 1, Price: 100})
+			So(err, ShouldNotBeNil)
+			_, err = executor.Execute(feature.OrderIntent{Action: feature.OrderActionOpen, Symbol: "EXECUSDT", PositionSide: "LONG", Price: 100})
+			So(err, ShouldNotBeNil)
+			So(lastOrderRow("EXECUSDT").ID, ShouldEqual, 0)
+		})
+	})
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOrderSizing(t *testing.T) {
+	Convey("win rate and payoff", t, func() {
+		winRate, payoff, count := feature.CalculateWinRate([]float64{10, 20, -5, -10})
+		So(count, ShouldEqual, 4)
+		So(winRate, ShouldAlmostEqual, 0.5, 0.000001)
+		So(payoff, ShouldAlmostEqual, 2, 0.000001) // 平均盈利 15 / 平均亏损 7.5
+	})
+
+	Convey("trade profits merge partial closes", t, func() {
+		profits := feature.GetTradeProfits([]models.Order{
+			{Inexact_profit: "-8", ParentId: 2},
+			{Inexact_profit: "5", ParentId: 2}, // 分批止盈
+			{Inexact_profit: "3", ParentId: 1},
+			{Inexact_profit: "-1"},
+		})
+		So(profits, ShouldResemble, []float64{-3, 3, -1})
+	})
+
+	Convey("sizing mode", t, func() {
+		systemConfig := models.Config{FutureSizingMode: feature.SizingFixedNotional, FutureSizingValue: 200}
+		coin := &models.Symbols{Symbol: "BTCUSDT", Usdt: "10", Leverage: 4, SizingMode: "global"}
+		decision, err := feature.GetOrderSizing(systemConfig, coin, 100)
+		So(err, ShouldBeNil)
+		So(decision.Mode, ShouldEqual, feature.SizingFixedNotional)
+		So(decision.Usdt, ShouldAlmostEqual, 50, 0.000001) // 名义价值 200 / 4 倍
+
+		coin.SizingMode = feature.SizingFixedUsdt // 币种覆盖系统配置
+		decision, err = feature.GetOrderSizing(systemConfig, coin, 100)
+		So(err, ShouldBeNil)
+		So(decision.Usdt, ShouldAlmostEqual, 10, 0.000001)
+	})
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"testing"
+
+	"github.com/expr-lang/expr"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPositionState(t *testing.T) {
+	Convey("memory position state", t, func() {
+		state := line.NewMemoryPositionState("BTCUSDT", "LONG", map[string]interface{}{"a": 1.0})
+		So(state.Get("a"), ShouldEqual, 1.0)
+		So(state.Get("b"), ShouldBeNil)
+		So(state.Get("b", 2), ShouldEqual, 2)
+		So(state.Set("b", 3), ShouldBeTrue)
+		So(state.Get("b"), ShouldEqual, 3.0) // 和数据库读取的类型一致
+		So(state.Set("", 1), ShouldBeFalse)
+		So(len(state.Values()), ShouldEqual, 2)
+	})
+
+	Convey("stateful close rule", t, func() {
+		env := line.InitSchemaEnv("BTCUSDT", `[]`, "close_long")
+		state := line.NewMemoryPositionState("BTCUSDT", "LONG", nil)
+		env["Get"] = state.Get
+		env["Set"] = state.Set
+		code := `(ROI > 15 ? Set("armed", true) : true) && Get("armed", false) == true && ROI < 8`
+		program, err := expr.Compile(code, line.StrategyOptions(env)...)
+		So(err, ShouldBeNil)
+		for _, item := range []struct{ roi float64; result bool }{{10, false}, {16, false}, {9, false}, {7, true}} {
+			env["ROI"] = item.roi
+			output, err := expr.Run(program, env)
+			So(err, ShouldBeNil)
+			So(output, ShouldEqual, item.result)
+		}
+	})
+
+	Convey("position stats", t, func() {
+		stats := feature.GetPositionStats(models.FuturesPositionPeak{
+			MaxRoi: 20,
+			MinRoi: -5,
+			ProfitTime: 30 * 60 * 1000,
+			CreateTime: 1000,
+		}, 1000 + 2 * 60 * 60 * 1000)
+		So(stats.MaxROI, ShouldEqual, 20)
+		So(stats.MinROI, ShouldEqual, -5)
+		So(stats.HoldTime, ShouldEqual, 2 * 60 * 60 * 1000)
+		So(stats.ProfitRatio, ShouldEqual, 0.25)
+		So(stats.Bars("1h"), ShouldEqual, 2)
+		So(stats.Bars("15m"), ShouldEqual, 8)
+		So(types.PositionStats{}.Bars("x"), ShouldEqual, 0)
+
+		So(line.ValidateStrategy("BTCUSDT", `[]`, `[{"name":"a","enable":true,"type":"close_long","code":"Stats.MaxROI > 15 && ROI < 8 && Stats.Bars(\"1h\") > 2"}]`), ShouldBeNil)
+		So(line.ValidateStrategy("BTCUSDT", `[]`, `[{"name":"a","enable":true,"type":"long","code":"Get(\"a\") == 1"}]`), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,42 @@
+package test
+
+import (
+	"go_binance_futures/feature/strategy/line"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProgramCache(t *testing.T) {
+	Convey("compile once and invalidate", t, func() {
+		line.InvalidateProgramCache("")
+		technology := `[{"type":"ema","name":"ema1","kline_interval":"1h"}]`
+		env := line.InitSchemaEnv("BTCUSDT", technology, "long")
+		program1, err := line.GetProgram("BTCUSDT", technology, "long", "NowPrice > 0", env)
+		So(err, ShouldBeNil)
+		program2, err := line.GetProgram("BTCUSDT", technology, "long", "NowPrice > 0", env)
+		So(err, ShouldBeNil)
+		So(program2, ShouldEqual, program1)
+		So(line.ProgramCacheSize(), ShouldEqual, 1)
+
+		_, err = line.GetProgram("BTCUSDT", technology, "long", "Unknown > 0", env)
+		So(err, ShouldNotBeNil)
+		So(line.ProgramCacheSize(), ShouldEqual, 1) // 编译失败不缓存
+
+		line.InvalidateProgramCache("ETHUSDT")
+		So(line.ProgramCacheSize(), ShouldEqual, 1)
+		line.InvalidateProgramCache("BTCUSDT")
+		So(line.ProgramCacheSize(), ShouldEqual, 0)
+	})
+
+	Convey("validate strategy", t, func() {
+		technology := `{"ema":[{"name":"ema1","kline_interval":"1h","period":14,"enable":true}]}`
+		So(line.ValidateStrategy("BTCUSDT", technology, `[{"name":"a","enable":true,"type":"long","code":"ema1.Data[0] > NowPrice"}]`), ShouldBeNil)
+		So(line.ValidateStrategy("BTCUSDT", technology, `[{"name":"b","enable":true,"type":"close_long","code":"ROI > 10 && Position.Side == \"LONG\""}]`), ShouldBeNil)
+		// 开仓策略没有 ROI
+		So(line.ValidateStrategy("BTCUSDT", technology, `[{"name":"c","enable":true,"type":"long","code":"ROI > 10"}]`), ShouldNotBeNil)
+		So(line.ValidateStrategy("BTCUSDT", technology, `[{"name":"d","enable":true,"type":"long","code":"ema2.Data[0] > 1"}]`), ShouldNotBeNil)
+		So(line.ValidateStrategy("BTCUSDT", technology, `[{"name":"e","enable":false,"type":"long","code":"ema2.Data[0] > 1"}]`), ShouldBeNil)
+		So(line.ValidateStrategy("BTCUSDT", technology, `not json`), ShouldNotBeNil)
+	})
+}
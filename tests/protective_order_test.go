@@ -0,0 +1,83 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProtectivePrices(t *testing.T) {
+	Convey("protective order prices", t, func() {
+		coin := &models.Symbols{Profit: "20", Loss: "10", TickSize: "0.01", Leverage: 10}
+		position := types.FuturesPosition{Symbol: "BTCUSDT", Side: "LONG", EntryPrice: "100", Leverage: 10}
+		takeProfit, stopLoss := feature.GetProtectivePrices(position, coin)
+		So(takeProfit, ShouldAlmostEqual, 102, 0.000001)
+		So(stopLoss, ShouldAlmostEqual, 99, 0.000001)
+
+		position.Side = "SHORT"
+		takeProfit, stopLoss = feature.GetProtectivePrices(position, coin)
+		So(takeProfit, ShouldAlmostEqual, 98, 0.000001)
+		So(stopLoss, ShouldAlmostEqual, 101, 0.000001)
+
+		coin.TakeProfitLadder = `[{"roi":10,"percent":50}]` // 分批止盈时不挂止盈单
+		takeProfit, stopLoss = feature.GetProtectivePrices(position, coin)
+		So(takeProfit, ShouldEqual, 0)
+		So(stopLoss, ShouldAlmostEqual, 101, 0.000001)
+
+		coin.TakeProfitLadder = ""
+		coin.Profit = "0" // 不设置止盈
+		takeProfit, _ = feature.GetProtectivePrices(position, coin)
+		So(takeProfit, ShouldEqual, 0)
+	})
+}
+
+func TestIsProtectiveOrder(t *testing.T) {
+	Convey("protective order filter", t, func() {
+		So(feature.IsProtectiveOrder("protect_s_1700000000000000", "STOP_MARKET"), ShouldBeTrue)
+		So(feature.IsProtectiveOrder("protect_r_1700000000000000", "TRAILING_STOP_MARKET"), ShouldBeTrue)
+		// 手动挂的止损单和程序的限价开仓单不过滤
+		So(feature.IsProtectiveOrder("web_1700000000000000", "STOP_MARKET"), ShouldBeFalse)
+		So(feature.IsProtectiveOrder("protect_l_1700000000000000", "LIMIT"), ShouldBeFalse)
+	})
+}
+
+
+func TestProtectiveCloseIntent(t *testing.T) {
+	Convey("protective order filled", t, func() {
+		position := types.FuturesPosition{Symbol: "BTCUSDT", Side: "SHORT", EntryPrice: "100", Leverage: 10}
+		intent := feature.GetProtectiveCloseIntent(models.Config{FutureStrategyTrade: "line", FutureStrategyCoin: "rate"}, futures.OrderTypeStopMarket, position, 101, 2)
+		So(intent.Action, ShouldEqual, feature.OrderActionClose)
+		So(intent.PositionSide, ShouldEqual, "SHORT")
+		So(intent.Reason, ShouldEqual, "stop_loss")
+		So(intent.Profit, ShouldAlmostEqual, -2, 0.000001)
+		So(intent.Leverage, ShouldEqual, 10)
+		So(intent.StrategyName, ShouldEqual, "line_rate")
+
+		position.Side = "LONG"
+		intent = feature.GetProtectiveCloseIntent(models.Config{}, futures.OrderTypeTrailingStopMarket, position, 103, 1)
+		So(intent.Reason, ShouldEqual, "trailing_stop")
+		So(intent.Profit, ShouldAlmostEqual, 3, 0.000001)
+
+		// 没有记录的订单(手动挂单, 已经处理过的订单)不处理
+		So(feature.HandleProtectiveOrderFilled(models.Config{}, 123456789, 101, 2), ShouldBeFalse)
+	})
+
+	Convey("position from local open orders", t, func() {
+		position, ok := feature.GetTradeOpenPosition([]models.Order{
+			{Symbol: "BTCUSDT", PositionSide: "LONG", Amount: "1", Avg_price: "100", Leverage: 10},
+			{Symbol: "BTCUSDT", PositionSide: "LONG", Amount: "3", Avg_price: "90", Leverage: 10, ParentId: 1}, // 加仓
+		})
+		So(ok, ShouldBeTrue)
+		So(position.Side, ShouldEqual, "LONG")
+		So(position.Amount, ShouldEqual, "4")
+		So(position.EntryPrice, ShouldEqual, "92.5")
+		So(position.Leverage, ShouldEqual, 10)
+
+		_, ok = feature.GetTradeOpenPosition([]models.Order{})
+		So(ok, ShouldBeFalse)
+	})
+}
\ No newline at end of file
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReconcile(t *testing.T) {
+	since := int64(1700000000000)
+	
+	Convey("对比持仓", t, func() {
+		local := []models.FuturesPosition{
+			{Symbol: "BTCUSDT", Side: "LONG", Amount: "0.01", EntryPrice: "40000", Leverage: 10, UpdateTime: since - 60000},
+			{Symbol: "ETHUSDT", Side: "SHORT", Amount: "-1", EntryPrice: "2000", Leverage: 5, UpdateTime: since - 60000},
+			{Symbol: "SOLUSDT", Side: "LONG", Amount: "0", Leverage: 5, UpdateTime: since - 60000},
+			{Symbol: "BNBUSDT", Side: "LONG", Amount: "1", Leverage: 5, UpdateTime: since + 1000}, // ws 刚更新过
+		}
+		remote := []models.FuturesPosition{
+			{Symbol: "BTCUSDT", Side: "LONG", Amount: "0.02", EntryPrice: "40000.00", Leverage: 20},
+			{Symbol: "SOLUSDT", Side: "LONG", Amount: "3", EntryPrice: "100", Leverage: 5},
+			{Symbol: "XRPUSDT", Side: "LONG", Amount: "0", Leverage: 5},
+		}
+		diffs := feature.DiffPositions(local, remote, since)
+		So(len(diffs), ShouldEqual, 4)
+		
+		So(diffs[0].Symbol, ShouldEqual, "BTCUSDT")
+		So(diffs[0].Diff, ShouldEqual, feature.ReconcileMismatch)
+		So(diffs[0].Field, ShouldEqual, "amount")
+		So(diffs[0].RemoteValue, ShouldEqual, "0.02")
+		So(diffs[1].Field, ShouldEqual, "leverage")
+		
+		So(diffs[2].Symbol, ShouldEqual, "ETHUSDT")
+		So(diffs[2].Diff, ShouldEqual, feature.ReconcileStaleLocal)
+		So(diffs[2].LocalValue, ShouldEqual, "-1")
+		
+		So(diffs[3].Symbol, ShouldEqual, "SOLUSDT")
+		So(diffs[3].Diff, ShouldEqual, feature.ReconcileMissingLocal)
+		
+		So(feature.DiffPositions(remote, remote, 0), ShouldBeEmpty)
+	})
+	
+	Convey("对比挂单", t, func() {
+		local := []models.FuturesOrder{
+			{Symbol: "BTCUSDT", OrderId: "1", PositionSide: "LONG", Status: "NEW", ExecutedQty: "0", UpdateTime: since - 60000},
+			{Symbol: "ETHUSDT", OrderId: "2", PositionSide: "SHORT", Status: "NEW", ExecutedQty: "0", UpdateTime: since - 60000},
+			{Symbol: "ETHUSDT", OrderId: "4", PositionSide: "SHORT", Status: "NEW", ExecutedQty: "0", UpdateTime: since},
+		}
+		remote := []models.FuturesOrder{
+			{Symbol: "BTCUSDT", OrderId: "1", PositionSide: "LONG", Status: "PARTIALLY_FILLED", ExecutedQty: "0.5"},
+			{Symbol: "SOLUSDT", OrderId: "3", PositionSide: "LONG", Status: "NEW", ExecutedQty: "0"},
+		}
+		diffs := feature.DiffOrders(local, remote, since)
+		So(len(diffs), ShouldEqual, 4)
+		So(diffs[0].OrderId, ShouldEqual, "1")
+		So(diffs[0].Field, ShouldEqual, "status")
+		So(diffs[1].Field, ShouldEqual, "executed_qty")
+		So(diffs[2].OrderId, ShouldEqual, "2")
+		So(diffs[2].Diff, ShouldEqual, feature.ReconcileStaleLocal)
+		So(diffs[3].OrderId, ShouldEqual, "3")
+		So(diffs[3].Diff, ShouldEqual, feature.ReconcileMissingLocal)
+	})
+}
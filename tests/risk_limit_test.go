@@ -0,0 +1,99 @@
+package test
+
+import (
+	"errors"
+	"go_binance_futures/feature"
+	"go_binance_futures/types"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRiskLimits(t *testing.T) {
+	exposures := []feature.RiskExposure{
+		{Symbol: "BTCUSDT", Side: "LONG", Notional: 500, Margin: 100},
+		{Symbol: "ETHUSDT", Side: "LONG", Notional: 300, Margin: 100},
+		{Symbol: "XRPUSDT", Side: "SHORT", Notional: 200, Margin: 50},
+	}
+	order := feature.RiskExposure{Symbol: "SOLUSDT", Side: "LONG", Notional: 200, Margin: 40}
+	correlation := func(a string, b string) (float64, error) {
+		if b == "XRPUSDT" {
+			return 0, errors.New("no klines")
+		}
+		return 0.9, nil
+	}
+	ruleOf := func(err error) string {
+		if riskErr, ok := err.(*feature.RiskLimitError); ok {
+			return riskErr.Rule
+		}
+		return ""
+	}
+	
+	Convey("no limits", t, func() {
+		So(feature.CheckRiskLimits(feature.RiskLimits{}, exposures, order, 1000, correlation), ShouldBeNil)
+	})
+	
+	Convey("notional and margin limits", t, func() {
+		err := feature.CheckRiskLimits(feature.RiskLimits{MaxNotional: 1100}, exposures, order, 1000, correlation)
+		So(ruleOf(err), ShouldEqual, feature.RiskTotalNotional)
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxNotional: 1200}, exposures, order, 1000, correlation), ShouldBeNil)
+		
+		err = feature.CheckRiskLimits(feature.RiskLimits{MaxMarginPercent: 25}, exposures, order, 1000, correlation)
+		So(ruleOf(err), ShouldEqual, feature.RiskMarginPercent)
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxMarginPercent: 30}, exposures, order, 1000, correlation), ShouldBeNil)
+		
+		add := feature.RiskExposure{Symbol: "BTCUSDT", Side: "LONG", Notional: 200, Margin: 40}
+		err = feature.CheckRiskLimits(feature.RiskLimits{MaxSymbolNotional: 600}, exposures, add, 1000, correlation)
+		So(ruleOf(err), ShouldEqual, feature.RiskSymbolNotional)
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxSymbolNotional: 600}, exposures, order, 1000, correlation), ShouldBeNil)
+	})
+	
+	Convey("net notional limit", t, func() {
+		// 净敞口 500 + 300 - 200 + 200 = 800
+		err := feature.CheckRiskLimits(feature.RiskLimits{MaxNetNotional: 700}, exposures, order, 1000, correlation)
+		So(ruleOf(err), ShouldEqual, feature.RiskNetNotional)
+		// 开空减少净敞口
+		hedge := feature.RiskExposure{Symbol: "SOLUSDT", Side: "SHORT", Notional: 200, Margin: 40}
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxNetNotional: 300}, exposures, hedge, 1000, correlation), ShouldBeNil)
+	})
+	
+	Convey("correlated limit", t, func() {
+		err := feature.CheckRiskLimits(feature.RiskLimits{MaxCorrelated: 2, Correlation: 0.8}, exposures, order, 1000, correlation)
+		So(ruleOf(err), ShouldEqual, feature.RiskCorrelated)
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxCorrelated: 3, Correlation: 0.8}, exposures, order, 1000, correlation), ShouldBeNil)
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxCorrelated: 2, Correlation: 0.95}, exposures, order, 1000, correlation), ShouldBeNil)
+		// 没有k线数据的币种和加仓不限制
+		short := feature.RiskExposure{Symbol: "SOLUSDT", Side: "SHORT", Notional: 100, Margin: 20}
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxCorrelated: 1, Correlation: 0.8}, exposures, short, 1000, correlation), ShouldBeNil)
+		add := feature.RiskExposure{Symbol: "BTCUSDT", Side: "LONG", Notional: 100, Margin: 20}
+		So(feature.CheckRiskLimits(feature.RiskLimits{MaxCorrelated: 1, Correlation: 0.8}, exposures, add, 1000, correlation), ShouldBeNil)
+	})
+	
+	Convey("exposures from positions and orders", t, func() {
+		positions := []types.FuturesPosition{
+			{Symbol: "BTCUSDT", Side: "LONG", Amount: "0.01", MarkPrice: "50000", Leverage: 5},
+			{Symbol: "ETHUSDT", Side: "BOTH", Amount: "-1", MarkPrice: "3000", Leverage: 10},
+			{Symbol: "SOLUSDT", Side: "LONG", Amount: "0", MarkPrice: "100", Leverage: 3},
+		}
+		orders := []types.FuturesOrder{
+			{Symbol: "SOLUSDT", Side: "BUY", PositionSide: "LONG", Type: "LIMIT", Price: "100", OrigQty: "2", ExecutedQty: "0.5"},
+			{Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG", Type: "LIMIT", Price: "60000", OrigQty: "0.01", ExecutedQty: "0"},
+			{Symbol: "BTCUSDT", Side: "SELL", PositionSide: "LONG", Type: "STOP_MARKET", Price: "0", OrigQty: "0.01", ExecutedQty: "0"},
+		}
+		exposures := feature.GetRiskExposures(positions, orders)
+		So(len(exposures), ShouldEqual, 3)
+		So(exposures[0].Notional, ShouldEqual, 500)
+		So(exposures[0].Margin, ShouldEqual, 100)
+		So(exposures[1].Side, ShouldEqual, "SHORT")
+		So(exposures[1].Notional, ShouldEqual, 3000)
+		So(exposures[2].Symbol, ShouldEqual, "SOLUSDT")
+		So(exposures[2].Notional, ShouldEqual, 150)
+		So(exposures[2].Margin, ShouldEqual, 50)
+	})
+	
+	Convey("correlation", t, func() {
+		So(feature.CalculateCorrelation([]float64{1, 2, 3, 4}, []float64{2, 4, 6, 8}), ShouldAlmostEqual, 1)
+		So(feature.CalculateCorrelation([]float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}), ShouldAlmostEqual, -1)
+		So(feature.CalculateCorrelation([]float64{1, 1, 1}, []float64{1, 2, 3}), ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,40 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSafetyOrder(t *testing.T) {
+	Convey("safety order quantity", t, func() {
+		coin := &models.Symbols{StepSize: "0.001", SafetyOrderStep: 10, SafetyOrderMultiplier: 2, SafetyOrderMaxCount: 2}
+		peak := models.FuturesPositionPeak{BaseAmount: "0.1"}
+
+		So(feature.GetSafetyOrderQuantity(coin, peak, 0.1, -5), ShouldEqual, 0) // 未达到加仓的跌幅
+		So(feature.GetSafetyOrderQuantity(coin, peak, 0.1, -12), ShouldAlmostEqual, 0.2, 0.000001)
+
+		peak.SafetyCount = 1
+		So(feature.GetSafetyOrderQuantity(coin, peak, 0.3, -12), ShouldAlmostEqual, 0.4, 0.000001)
+
+		peak.SafetyCount = 2 // 达到最多加仓次数
+		So(feature.GetSafetyOrderQuantity(coin, peak, 0.7, -30), ShouldEqual, 0)
+	})
+
+	Convey("safety order max count", t, func() {
+		position := types.FuturesPosition{Symbol: "BTCUSDT", Side: "LONG", Amount: "0.1"}
+		positions := []types.FuturesPosition{
+			position,
+			{Symbol: "ETHUSDT", Side: "LONG", Amount: "1"},
+			{Symbol: "SOLUSDT", Side: "SHORT", Amount: "0"}, // 没有持仓
+		}
+		// 加仓的仓位不计入, 持仓数量等于最大持仓数量时可以加仓
+		So(feature.IsSafetyOrderOverMax(2, position, positions, 0), ShouldBeFalse)
+		// 其它持仓 + 挂单数量等于最大持仓数量时不加仓
+		So(feature.IsSafetyOrderOverMax(2, position, positions, 1), ShouldBeTrue)
+		So(feature.IsSafetyOrderOverMax(1, position, positions, 0), ShouldBeTrue)
+	})
+}
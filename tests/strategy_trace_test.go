@@ -0,0 +1,46 @@
+package test
+
+import (
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/types"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestStrategyTrace(t *testing.T) {
+	technology := `[{"type":"ema","name":"ema1","kline_interval":"1h"}]`
+
+	Convey("trace sub expressions", t, func() {
+		env := line.InitSchemaEnv("BTCUSDT", technology, "long")
+		env["NowPrice"] = 100.0
+		env["ema1"] = line.ConfigData{Data: []float64{105, 104, 103}}
+
+		result := line.CheckStrategyRule("BTCUSDT", technology, "a", "long", "ema1.Data[0] < NowPrice && NowPrice > 50", true, env)
+		So(result.CompileError, ShouldBeEmpty)
+		So(result.Pass, ShouldBeFalse)
+		values := map[string]interface{}{}
+		for _, trace := range result.Trace {
+			values[trace.Expr] = trace.Value
+		}
+		So(values["ema1.Data[0]"], ShouldEqual, 105)
+		So(values["ema1.Data[0] < NowPrice"], ShouldEqual, false)
+		So(values["NowPrice > 50"], ShouldEqual, true)
+		So(values["ema1.Data"], ShouldResemble, []float64{105, 104, 103})
+		_, ok := values["ema1"] // 指标对象不返回
+		So(ok, ShouldBeFalse)
+	})
+
+	Convey("check with mock position", t, func() {
+		env := line.InitSchemaEnv("BTCUSDT", technology, "close_long")
+		env["ROI"] = 12.0
+		env["Position"] = types.FuturesPositionCode{Side: "LONG", EntryPrice: 100}
+
+		result := line.CheckStrategyRule("BTCUSDT", technology, "b", "close_long", `ROI > 10 && Position.Side == "LONG"`, true, env)
+		So(result.Pass, ShouldBeTrue)
+
+		// 开仓策略不能使用 ROI
+		result = line.CheckStrategyRule("BTCUSDT", technology, "c", "long", `ROI > 10`, true, env)
+		So(result.CompileError, ShouldNotBeEmpty)
+	})
+}
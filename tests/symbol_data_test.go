@@ -0,0 +1,69 @@
+package test
+
+import (
+	"go_binance_futures/feature/strategy/line"
+	"go_binance_futures/models"
+	"testing"
+
+	"github.com/expr-lang/expr"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSymbolData(t *testing.T) {
+	Convey("parse indicator name", t, func() {
+		indicatorType, interval, params, err := line.ParseIndicatorName("ema_1h_20")
+		So(err, ShouldBeNil)
+		So(indicatorType, ShouldEqual, "ema")
+		So(interval, ShouldEqual, "1h")
+		So(params["period"], ShouldEqual, 20)
+
+		indicatorType, interval, params, err = line.ParseIndicatorName("stoch_rsi_4h")
+		So(err, ShouldBeNil)
+		So(indicatorType, ShouldEqual, "stoch_rsi")
+		So(interval, ShouldEqual, "4h")
+		So(len(params), ShouldEqual, 0)
+
+		_, _, _, err = line.ParseIndicatorName("ema_20")
+		So(err, ShouldNotBeNil)
+		_, _, _, err = line.ParseIndicatorName("unknown_1h_20")
+		So(err, ShouldNotBeNil)
+		_, _, _, err = line.ParseIndicatorName("ema_1h_20_30")
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Sym member access", t, func() {
+		env := map[string]interface{}{
+			"Sym": func(symbol string) map[string]interface{} { return nil },
+			"SymValue": func(symbol string, name string) interface{} {
+				return symbol + ":" + name
+			},
+		}
+		program, err := expr.Compile(`Sym("ETHUSDT").ema_1h_20`, line.StrategyOptions(env)...)
+		So(err, ShouldBeNil)
+		output, err := expr.Run(program, env)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "ETHUSDT:ema_1h_20")
+
+		strategy := `[{"name":"a","enable":true,"type":"long","code":"Sym(\"ETHUSDT\").ema_1h_20.Data[0] > Sym(\"ETHUSDT\").Close && Breadth().Advancers > 10"}]`
+		So(line.ValidateStrategy("BTCUSDT", `[]`, strategy), ShouldBeNil)
+	})
+
+	Convey("benchmark basket and breadth", t, func() {
+		symbols := []*models.Symbols{
+			{Symbol: "BTCUSDT", PercentChange: 2, QuoteVolume: 300},
+			{Symbol: "ETHUSDT", PercentChange: -1, QuoteVolume: 100},
+			{Symbol: "DOGEUSDT", PercentChange: 0, QuoteVolume: 100},
+		}
+		basket, err := line.ParseBenchmarkBasket(`{"BTCUSDT": 0.5, "ETHUSDT": 0.5}`)
+		So(err, ShouldBeNil)
+		So(line.CalculateBasicTrend(basket, symbols), ShouldEqual, 0.5)
+		_, err = line.ParseBenchmarkBasket(`{}`)
+		So(err, ShouldNotBeNil)
+
+		breadth := line.CalculateBreadth(symbols)
+		So(breadth["Advancers"], ShouldEqual, 1)
+		So(breadth["Decliners"], ShouldEqual, 1)
+		So(breadth["Unchanged"], ShouldEqual, 1)
+		So(breadth["VolumeWeightedChange"], ShouldEqual, 1) // (600 - 100) / 500
+	})
+}
@@ -0,0 +1,37 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTakeProfitLadder(t *testing.T) {
+	Convey("take profit ladder", t, func() {
+		coin := &models.Symbols{StepSize: "0.001", TakeProfitLadder: `[{"roi":10,"percent":50},{"roi":20,"percent":30},{"roi":30,"percent":20}]`}
+		peak := models.FuturesPositionPeak{Amount: "1"}
+
+		quantity, all := feature.GetLadderCloseQuantity(coin, peak, 1, 5) // 未达到第一档
+		So(quantity, ShouldEqual, 0)
+
+		quantity, all = feature.GetLadderCloseQuantity(coin, peak, 1, 12)
+		So(quantity, ShouldAlmostEqual, 0.5, 0.000001)
+		So(all, ShouldBeFalse)
+
+		peak.LadderStep = 1 // 第二档按照开仓数量的比例
+		quantity, all = feature.GetLadderCloseQuantity(coin, peak, 0.5, 21)
+		So(quantity, ShouldAlmostEqual, 0.3, 0.000001)
+		So(all, ShouldBeFalse)
+
+		peak.LadderStep = 2 // 最后一档平掉剩余的仓位
+		quantity, all = feature.GetLadderCloseQuantity(coin, peak, 0.2, 35)
+		So(quantity, ShouldAlmostEqual, 0.2, 0.000001)
+		So(all, ShouldBeTrue)
+
+		peak.LadderStep = 3
+		quantity, _ = feature.GetLadderCloseQuantity(coin, peak, 0.2, 50)
+		So(quantity, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTemplateVersion(t *testing.T) {
+	Convey("diff template versions", t, func() {
+		from := models.StrategyTemplateVersion{
+			TemplateId: 1,
+			Version: 1,
+			Technology: `[{"type":"ema","name":"ema1","kline_interval":"1h","params":{"period":20}},{"type":"rsi","name":"rsi1","kline_interval":"4h"}]`,
+			Strategy: `[{"name":"long1","enable":true,"type":"long","code":"ema1.Data[0] > NowPrice"}]`,
+			TrailingCallback: 5,
+		}
+		to := from
+		to.Version = 2
+		to.Technology = `[{"type":"ema","name":"ema1","kline_interval":"1h","params":{"period":30}},{"type":"macd","name":"macd1","kline_interval":"4h"}]`
+		to.Strategy = `[{"name":"long1","enable":true,"type":"long","code":"ema1.Data[0] > NowPrice"},{"name":"close1","enable":true,"type":"close_long","code":"ROI > 10"}]`
+		to.TrailingCallback = 3
+
+		diff := feature.DiffTemplateVersions(from, to)
+		So(diff.From, ShouldEqual, 1)
+		So(diff.To, ShouldEqual, 2)
+		So(len(diff.Fields), ShouldEqual, 1)
+		So(diff.Fields[0].Field, ShouldEqual, "trailing_callback")
+		So(len(diff.Technology), ShouldEqual, 3)
+		So(diff.Technology[0].Name, ShouldEqual, "ema1")
+		So(diff.Technology[0].Change, ShouldEqual, "changed")
+		So(diff.Technology[1].Name, ShouldEqual, "macd1")
+		So(diff.Technology[1].Change, ShouldEqual, "added")
+		So(diff.Technology[2].Name, ShouldEqual, "rsi1")
+		So(diff.Technology[2].Change, ShouldEqual, "removed")
+		So(len(diff.Strategy), ShouldEqual, 1)
+		So(diff.Strategy[0].Change, ShouldEqual, "added")
+
+		So(len(feature.DiffTemplateVersions(from, from).Technology), ShouldEqual, 0)
+	})
+
+	Convey("edit symbol using template", t, func() {
+		old := models.Symbols{Symbol: "BTCUSDT", Technology: "[]", Strategy: "[]", TemplateId: 1, TemplateVersion: 2}
+		symbol := old
+		symbol.Usdt = "20" // 不是策略内容
+		detached, err := feature.SyncSymbolTemplate(old, &symbol)
+		So(err, ShouldBeNil)
+		So(detached, ShouldBeFalse)
+		So(symbol.TemplateId, ShouldEqual, 1)
+		So(symbol.TemplateVersion, ShouldEqual, 2)
+
+		symbol.Strategy = `[{"name":"a","enable":true,"type":"long","code":"true"}]` // 手动修改策略
+		detached, err = feature.SyncSymbolTemplate(old, &symbol)
+		So(err, ShouldBeNil)
+		So(detached, ShouldBeTrue)
+		So(symbol.TemplateId, ShouldEqual, 0)
+		So(symbol.TemplateVersion, ShouldEqual, 0)
+	})
+}
@@ -0,0 +1,34 @@
+package test
+
+import (
+	"go_binance_futures/feature"
+	"go_binance_futures/models"
+	"go_binance_futures/types"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTrailingStop(t *testing.T) {
+	Convey("trailing stop", t, func() {
+		coin := &models.Symbols{TrailingStop: feature.TrailingStopLocal, TrailingActivation: 20, TrailingCallback: 10, TickSize: "0.01", Leverage: 10}
+		So(feature.IsTrailingStop(coin, 15, 2), ShouldBeFalse) // 未达到激活收益率
+		So(feature.IsTrailingStop(coin, 30, 25), ShouldBeFalse) // 回撤不足
+		So(feature.IsTrailingStop(coin, 30, 20), ShouldBeTrue)
+
+		coin.TrailingStop = feature.TrailingStopDisable
+		So(feature.IsTrailingStop(coin, 30, 20), ShouldBeFalse)
+	})
+
+	Convey("native trailing stop params", t, func() {
+		coin := &models.Symbols{TrailingStop: feature.TrailingStopNative, TrailingActivation: 20, TrailingCallback: 10, TickSize: "0.01", Leverage: 10}
+		position := types.FuturesPosition{Symbol: "BTCUSDT", Side: "SHORT", EntryPrice: "100", Leverage: 10}
+		callbackRate, activationPrice := feature.GetTrailingStopParams(position, coin)
+		So(callbackRate, ShouldAlmostEqual, 1, 0.000001)
+		So(activationPrice, ShouldAlmostEqual, 98, 0.000001)
+
+		coin.TrailingCallback = 100 // 最大 5%
+		callbackRate, _ = feature.GetTrailingStopParams(position, coin)
+		So(callbackRate, ShouldEqual, 5)
+	})
+}
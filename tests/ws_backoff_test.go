@@ -0,0 +1,28 @@
+package test
+
+import (
+	"go_binance_futures/feature/api/binance"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWsBackoff(t *testing.T) {
+	Convey("ws 重连间隔", t, func() {
+		min, max := time.Second, time.Minute
+		backoff := binance.NextBackoff(0, false, min, max)
+		So(backoff, ShouldEqual, time.Second)
+		
+		backoff = binance.NextBackoff(backoff, false, min, max)
+		So(backoff, ShouldEqual, 2 * time.Second)
+		
+		for i := 0; i < 10; i++ {
+			backoff = binance.NextBackoff(backoff, false, min, max)
+		}
+		So(backoff, ShouldEqual, time.Minute)
+		
+		// 连接稳定后重置
+		So(binance.NextBackoff(backoff, true, min, max), ShouldEqual, time.Second)
+	})
+}
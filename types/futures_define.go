@@ -1,5 +1,7 @@
 package types
 
+import "go_binance_futures/utils"
+
 type FuturesPosition struct {
 	Symbol string `orm:"column(symbol)" json:"symbol"`
 	Side string `orm:"column(side)" json:"side"` // 持仓方向, BOTH, LONG, SHORT
@@ -40,4 +42,21 @@ type FuturesOrder struct {
 	OrigQty string `orm:"column(orig_qty)" json:"orig_qty"` // 下单委托数量
 	ExecutedQty string `orm:"column(executed_qty)" json:"executed_qty"` // 已成交数量
 	UpdateTime int64 `orm:"column(updateTime)" json:"updateTime"`
-}
\ No newline at end of file
+}
+// 持仓期间的统计(平仓策略中的 Stats)
+type PositionStats struct {
+	MaxROI float64 `json:"max_roi"` // 持仓期间的最高收益率%(加仓后不重置)
+	MinROI float64 `json:"min_roi"` // 持仓期间的最低收益率%
+	HoldTime int64 `json:"hold_time"` // 持仓时长, 毫秒
+	ProfitTime int64 `json:"profit_time"` // 处于盈利状态的时长, 毫秒
+	ProfitRatio float64 `json:"profit_ratio"` // 处于盈利状态的时长占比 0 ~ 1
+}
+
+// 持仓的k线数量, ex: Stats.Bars("1h")
+func (stats PositionStats) Bars(interval string) int64 {
+	duration, err := utils.IntervalDuration(interval)
+	if err != nil || duration <= 0 {
+		return 0
+	}
+	return stats.HoldTime / duration.Milliseconds()
+}
@@ -3,12 +3,31 @@ package utils
 import (
 	"fmt"
 	"go_binance_futures/models"
+	"math"
 	"time"
 
 	"github.com/beego/beego/v2/client/orm"
 	"github.com/beego/beego/v2/core/logs"
 )
 
+const FreezeAllSymbols = "*" // 策略级别的冻结, 统计该策略所有币种的交易
+
+// 冻结规则
+const (
+	FreezeRuleLossCount = "loss_count" // 连续亏损次数
+	FreezeRuleLossAmount = "loss_amount" // 窗口内累计亏损
+	FreezeRuleRecentLoss = "recent_loss" // 最近 N 笔交易中的亏损次数
+)
+
+// 冻结记录的类型
+const (
+	FreezeActionFreeze = "freeze"
+	FreezeActionUnfreeze = "unfreeze"
+)
+
+const freezeTradeKeepDays = 30 // 交易记录保留天数, 窗口最大 30 天
+const freezeTradeLimit = 1000 // 计算规则时最多读取的交易数量
+
 // 风控服务
 type FreezeService struct {
 	orm orm.Ormer
@@ -20,25 +39,20 @@ func NewFreezeService() *FreezeService {
 	}
 }
 
-// 检查是否被冻结
+// 检查是否被冻结(币种或策略级别)
 func (fs *FreezeService) IsFrozen(symbol, strategyName, tradeType string) bool {
-	var freeze models.StrategyFreeze
-	err := fs.orm.QueryTable("strategy_freeze").
-		Filter("symbol", symbol).
+	return fs.GetRemainingFreezeTime(symbol, strategyName, tradeType) > 0
+}
+
+// 币种和策略级别(*)的冻结配置, 不存在时不创建
+func (fs *FreezeService) getFreezeConfigs(symbol, strategyName, tradeType string) ([]models.StrategyFreeze, error) {
+	var freezes []models.StrategyFreeze
+	_, err := fs.orm.QueryTable("strategy_freeze").
+		Filter("symbol__in", symbol, FreezeAllSymbols).
 		Filter("strategy_name", strategyName).
 		Filter("trade_type", tradeType).
-		One(&freeze)
-
-	if err != nil {
-		if err == orm.ErrNoRows {
-			return false
-		}
-		logs.Error("查询冻结状态失败:", err)
-		return false
-	}
-
-	now := time.Now().Unix()
-	return freeze.FreezeUntil > now
+		All(&freezes)
+	return freezes, err
 }
 
 // 获取冻结配置
@@ -87,55 +101,239 @@ func (fs *FreezeService) CreateDefaultFreezeConfig(symbol, strategyName, tradeTy
 
 // 记录盈利（清零亏损次数）
 func (fs *FreezeService) RecordProfit(symbol, strategyName, tradeType string) error {
+	return fs.recordTrade(symbol, strategyName, tradeType, 0, false)
+}
+
+// 记录亏损（增加亏损次数，检查是否需要冻结）, 没有亏损金额
+func (fs *FreezeService) RecordLoss(symbol, strategyName, tradeType string) error {
+	return fs.recordTrade(symbol, strategyName, tradeType, 0, true)
+}
+
+// 记录整笔交易的盈亏, 检查币种和策略级别的冻结规则
+func (fs *FreezeService) RecordTrade(symbol, strategyName, tradeType string, profit float64) error {
+	return fs.recordTrade(symbol, strategyName, tradeType, profit, profit < 0)
+}
+
+func (fs *FreezeService) recordTrade(symbol, strategyName, tradeType string, profit float64, loss bool) error {
+	now := time.Now().Unix()
+	trade := models.StrategyFreezeTrade{
+		Symbol: symbol,
+		StrategyName: strategyName,
+		TradeType: tradeType,
+		Profit: profit,
+		CreatedAt: now,
+	}
+	// 交易记录写入失败时亏损金额和近期亏损规则会少算, 直接返回错误
+	if _, err := fs.orm.Insert(&trade); err != nil {
+		return err
+	}
+
 	freeze, err := fs.GetFreezeConfig(symbol, strategyName, tradeType)
 	if err != nil {
 		return err
 	}
+	if err = fs.applyTrade(freeze, loss); err != nil {
+		return err
+	}
+	if symbol == FreezeAllSymbols {
+		return nil
+	}
+	// 策略级别的冻结配置(需要手动创建)
+	var strategyFreeze models.StrategyFreeze
+	err = fs.orm.QueryTable("strategy_freeze").
+		Filter("symbol", FreezeAllSymbols).
+		Filter("strategy_name", strategyName).
+		Filter("trade_type", tradeType).
+		One(&strategyFreeze)
+	if err == orm.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fs.applyTrade(&strategyFreeze, loss)
+}
 
-	freeze.LossCount = 0
-	freeze.UpdatedAt = time.Now().Unix()
+// 删除超过保留天数的交易记录(每天清理一次)
+func (fs *FreezeService) CleanTrades() error {
+	expire := time.Now().Unix() - freezeTradeKeepDays * 86400
+	_, err := fs.orm.QueryTable("strategy_freeze_trades").Filter("created_at__lt", expire).Delete()
+	return err
+}
 
-	_, err = fs.orm.Update(freeze, "loss_count", "updated_at")
-	if err != nil {
-		logs.Error("更新盈利状态失败:", err)
+// 更新亏损次数, 达到任意规则时冻结
+func (fs *FreezeService) applyTrade(freeze *models.StrategyFreeze, loss bool) error {
+	now := time.Now().Unix()
+	if loss {
+		freeze.LossCount++
+	} else {
+		freeze.LossCount = 0
+	}
+	freeze.UpdatedAt = now
+
+	rule, detail := "", ""
+	if loss && freeze.FreezeUntil <= now { // 冻结中不重复冻结
+		rule, detail = EvaluateFreezeRules(*freeze, fs.getTrades(freeze, now), now)
+	}
+	if rule == "" {
+		if loss {
+			logs.Info("策略亏损次数+1: %s-%s-%s, 当前亏损次数: %d/%d",
+				freeze.Symbol, freeze.StrategyName, freeze.TradeType, freeze.LossCount, freeze.FreezeOnLossCount)
+		} else {
+			logs.Info("策略盈利，清零亏损次数: %s-%s-%s", freeze.Symbol, freeze.StrategyName, freeze.TradeType)
+		}
+		_, err := fs.orm.Update(freeze, "loss_count", "updated_at")
+		if err != nil {
+			logs.Error("更新盈亏状态失败:", err)
+		}
 		return err
 	}
 
-	logs.Info("策略盈利，清零亏损次数: %s-%s-%s", symbol, strategyName, tradeType)
+	// 窗口内之前的冻结次数, 重复冻结时延长冻结时间
+	escalateWindowHours := freeze.EscalateWindowHours
+	if escalateWindowHours <= 0 {
+		escalateWindowHours = 168
+	}
+	level, _ := fs.orm.QueryTable("strategy_freeze_history").
+		Filter("freeze_id", freeze.ID).
+		Filter("action", FreezeActionFreeze).
+		Filter("created_at__gte", now - int64(escalateWindowHours) * 3600).
+		Count()
+	hours := FreezeDuration(*freeze, int(level))
+	StartFreeze(freeze, hours, now)
+	logs.Info("策略达到冻结条件，开始冻结: %s-%s-%s, 规则: %s(%s), 冻结至: %d",
+		freeze.Symbol, freeze.StrategyName, freeze.TradeType, rule, detail, freeze.FreezeUntil)
+
+	_, err := fs.orm.Update(freeze, "loss_count", "freeze_until", "last_freeze_at", "updated_at")
+	if err != nil {
+		logs.Error("更新亏损状态失败:", err)
+		return err
+	}
+	fs.addHistory(freeze, FreezeActionFreeze, rule, detail, int(level), hours)
 	return nil
 }
 
-// 记录亏损（增加亏损次数，检查是否需要冻结）
-func (fs *FreezeService) RecordLoss(symbol, strategyName, tradeType string) error {
-	freeze, err := fs.GetFreezeConfig(symbol, strategyName, tradeType)
+// 开始冻结, 亏损次数清零, 解冻后重新统计(和其它规则一样只统计上次冻结之后的交易)
+func StartFreeze(freeze *models.StrategyFreeze, hours float64, now int64) {
+	freeze.FreezeUntil = now + int64(hours * 3600)
+	freeze.LastFreezeAt = now
+	freeze.LossCount = 0
+}
+
+// 上次冻结之后的交易(新的在前), 策略级别统计所有币种
+func (fs *FreezeService) getTrades(freeze *models.StrategyFreeze, now int64) []models.StrategyFreezeTrade {
+	var trades []models.StrategyFreezeTrade
+	if freeze.LossAmount <= 0 && freeze.RecentLossCount <= 0 {
+		return trades
+	}
+	qs := fs.orm.QueryTable("strategy_freeze_trades").
+		Filter("strategy_name", freeze.StrategyName).
+		Filter("trade_type", freeze.TradeType).
+		Filter("created_at__gt", freeze.LastFreezeAt)
+	if freeze.Symbol != FreezeAllSymbols {
+		qs = qs.Filter("symbol", freeze.Symbol)
+	}
+	_, err := qs.OrderBy("-id").Limit(freezeTradeLimit).All(&trades)
 	if err != nil {
-		return err
+		logs.Error("查询策略交易失败:", err)
 	}
+	return trades
+}
 
-	freeze.LossCount++
-	freeze.UpdatedAt = time.Now().Unix()
+// 检查冻结规则, 返回触发的规则和说明, trades 为上次冻结之后的交易(新的在前)
+func EvaluateFreezeRules(freeze models.StrategyFreeze, trades []models.StrategyFreezeTrade, now int64) (rule string, detail string) {
+	if freeze.FreezeOnLossCount > 0 && freeze.LossCount >= freeze.FreezeOnLossCount {
+		return FreezeRuleLossCount, fmt.Sprintf("loss count %d >= %d", freeze.LossCount, freeze.FreezeOnLossCount)
+	}
+	if freeze.LossAmount > 0 {
+		windowHours := freeze.LossWindowHours
+		if windowHours <= 0 {
+			windowHours = 24
+		}
+		lossAmount := 0.0
+		for _, trade := range trades {
+			if trade.CreatedAt >= now - int64(windowHours) * 3600 && trade.Profit < 0 {
+				lossAmount -= trade.Profit
+			}
+		}
+		if lossAmount >= freeze.LossAmount {
+			return FreezeRuleLossAmount, fmt.Sprintf("loss %.2f usdt in %dh >= %g", lossAmount, windowHours, freeze.LossAmount)
+		}
+	}
+	if freeze.RecentLossCount > 0 && freeze.RecentTrades > 0 {
+		lossCount := 0
+		for i, trade := range trades {
+			if i >= freeze.RecentTrades {
+				break
+			}
+			if trade.Profit < 0 {
+				lossCount++
+			}
+		}
+		if lossCount >= freeze.RecentLossCount {
+			return FreezeRuleRecentLoss, fmt.Sprintf("%d losses in last %d trades >= %d", lossCount, freeze.RecentTrades, freeze.RecentLossCount)
+		}
+	}
+	return "", ""
+}
 
-	// 检查是否达到冻结条件
-	if freeze.LossCount >= freeze.FreezeOnLossCount {
-		// 设置冻结时间
-		freeze.FreezeUntil = time.Now().Add(time.Duration(freeze.FreezeHours) * time.Hour).Unix()
-		logs.Info("策略达到冻结条件，开始冻结: %s-%s-%s, 亏损次数: %d, 冻结至: %d",
-			symbol, strategyName, tradeType, freeze.LossCount, freeze.FreezeUntil)
+// 冻结小时数, level 为窗口内之前的冻结次数, 每次乘以 EscalateFactor, 不超过 MaxFreezeHours
+func FreezeDuration(freeze models.StrategyFreeze, level int) float64 {
+	hours := float64(freeze.FreezeHours)
+	if freeze.EscalateFactor > 1 && level > 0 {
+		hours = hours * math.Pow(freeze.EscalateFactor, float64(level))
+	}
+	if freeze.MaxFreezeHours > 0 && hours > float64(freeze.MaxFreezeHours) {
+		hours = float64(freeze.MaxFreezeHours)
+	}
+	return hours
+}
 
-		_, err = fs.orm.Update(freeze, "loss_count", "freeze_until", "updated_at")
-	} else {
-		logs.Info("策略亏损次数+1: %s-%s-%s, 当前亏损次数: %d/%d",
-			symbol, strategyName, tradeType, freeze.LossCount, freeze.FreezeOnLossCount)
+func (fs *FreezeService) addHistory(freeze *models.StrategyFreeze, action, rule, detail string, level int, hours float64) {
+	history := models.StrategyFreezeHistory{
+		FreezeId: freeze.ID,
+		Symbol: freeze.Symbol,
+		StrategyName: freeze.StrategyName,
+		TradeType: freeze.TradeType,
+		Action: action,
+		Rule: rule,
+		Detail: detail,
+		Level: level,
+		FreezeHours: hours,
+		FreezeUntil: freeze.FreezeUntil,
+		CreatedAt: time.Now().Unix(),
+	}
+	if _, err := fs.orm.Insert(&history); err != nil {
+		logs.Error("记录冻结历史失败:", err)
+	}
+}
 
-		_, err = fs.orm.Update(freeze, "loss_count", "updated_at")
+// 获取冻结历史
+func (fs *FreezeService) GetFreezeHistory(symbol, strategyName, tradeType string, page, pageSize int) ([]models.StrategyFreezeHistory, int64, error) {
+	var histories []models.StrategyFreezeHistory
+	qs := fs.orm.QueryTable("strategy_freeze_history")
+	if symbol != "" {
+		qs = qs.Filter("symbol", symbol)
+	}
+	if strategyName != "" {
+		qs = qs.Filter("strategy_name", strategyName)
+	}
+	if tradeType != "" {
+		qs = qs.Filter("trade_type", tradeType)
 	}
 
+	total, err := qs.Count()
 	if err != nil {
-		logs.Error("更新亏损状态失败:", err)
-		return err
+		return nil, 0, err
 	}
 
-	return nil
+	offset := (page - 1) * pageSize
+	_, err = qs.OrderBy("-id").Limit(pageSize, offset).All(&histories)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return histories, total, nil
 }
 
 // 更新冻结配置
@@ -170,19 +368,23 @@ func (fs *FreezeService) GetAllFreezeConfigs(page, pageSize int) ([]models.Strat
 	return freezes, total, nil
 }
 
-// 获取剩余冻结时间（秒）
+// 获取剩余冻结时间（秒）, 币种和策略级别中较长的
 func (fs *FreezeService) GetRemainingFreezeTime(symbol, strategyName, tradeType string) int64 {
-	freeze, err := fs.GetFreezeConfig(symbol, strategyName, tradeType)
+	freezes, err := fs.getFreezeConfigs(symbol, strategyName, tradeType)
 	if err != nil {
+		logs.Error("查询冻结状态失败:", err)
 		return 0
 	}
 
 	now := time.Now().Unix()
-	if freeze.FreezeUntil > now {
-		return freeze.FreezeUntil - now
+	remaining := int64(0)
+	for _, freeze := range freezes {
+		if freeze.FreezeUntil - now > remaining {
+			remaining = freeze.FreezeUntil - now
+		}
 	}
 
-	return 0
+	return remaining
 }
 
 // 手动解除冻结
@@ -200,6 +402,7 @@ func (fs *FreezeService) UnfreezeManually(symbol, strategyName, tradeType string
 		logs.Error("手动解除冻结失败:", err)
 		return err
 	}
+	fs.addHistory(freeze, FreezeActionUnfreeze, "", "manual", 0, 0)
 
 	logs.Info("手动解除冻结: %s-%s-%s", symbol, strategyName, tradeType)
 	return nil
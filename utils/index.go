@@ -2,9 +2,11 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/beego/beego/v2/core/config"
 )
@@ -101,7 +103,7 @@ func ReverseArray(arr []float64) []float64 {
 	reversed := make([]float64, n)
 
 	left, right := 0, n-1
-	for left < right {
+	for left <= right {
 		reversed[left], reversed[right] = arr[right], arr[left]
 		left++
 		right--
@@ -114,6 +116,31 @@ func Intervals() []string {
 	return []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M"}
 }
 
+// k线周期对应的时长(1M 的时长不固定, 不支持)
+func IntervalDuration(interval string) (time.Duration, error) {
+	durations := map[string]time.Duration{
+		"1m": time.Minute,
+		"3m": time.Minute * 3,
+		"5m": time.Minute * 5,
+		"15m": time.Minute * 15,
+		"30m": time.Minute * 30,
+		"1h": time.Hour,
+		"2h": time.Hour * 2,
+		"4h": time.Hour * 4,
+		"6h": time.Hour * 6,
+		"8h": time.Hour * 8,
+		"12h": time.Hour * 12,
+		"1d": time.Hour * 24,
+		"3d": time.Hour * 24 * 3,
+		"1w": time.Hour * 24 * 7,
+	}
+	duration, ok := durations[interval]
+	if !ok {
+		return 0, errors.New("invalid interval: " + interval)
+	}
+	return duration, nil
+}
+
 func EscapeJSON(jsonStr string) string {
 	if driver == "mysql" {
 		return strings.ReplaceAll(jsonStr, "\\n", "\\\\n")